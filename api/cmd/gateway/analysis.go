@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/omega-realm/api/pkg/anticheat"
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+// reportTimeout bounds each score report, so a slow API cannot hold up a
+// closing session for long
+const reportTimeout = 5 * time.Second
+
+// analysis scores one verified player's traffic for cheating and reports the
+// scores to the API every -report-interval while they change, and once more
+// when the session ends
+type analysis struct {
+	api    *apiClient
+	scores scoresRequest
+
+	mu       sync.Mutex
+	player   *anticheat.Player
+	reported anticheat.Report
+
+	// confirm, event, state and delta are reused to decode the instance's
+	// packets. snapshots rebuilds the snapshots of a client that negotiated
+	// STATE_DELTA, so the player's entity can be found in them.
+	confirm   protocol.ActionConfirm
+	event     protocol.GameEvent
+	state     protocol.StateUpdate
+	delta     protocol.StateDelta
+	snapshots *protocol.DeltaDecoder
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newAnalysis(api *apiClient, region string, player *verifiedPlayer) *analysis {
+	return &analysis{
+		api: api,
+		scores: scoresRequest{
+			ConnectionID: newConnectionID(),
+			UserID:       player.UserID,
+			CharacterID:  player.CharacterID,
+			InstanceID:   player.InstanceID,
+			Region:       region,
+			StartedAt:    time.Now(),
+		},
+		player:    anticheat.NewPlayer(anticheat.DefaultConfig()),
+		snapshots: protocol.NewDeltaDecoder(),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// newConnectionID identifies a session's scores to the API, which keeps one
+// row per connection
+func newConnectionID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// input records a PLAYER_INPUT the client sent
+func (a *analysis) input(at time.Time, input *protocol.PlayerInput) {
+	a.mu.Lock()
+	a.player.Input(at, input)
+	a.mu.Unlock()
+}
+
+// serverFrame records the packets in a frame from the instance that the
+// analysis uses. The instance is trusted, so packets that do not decode are
+// skipped rather than refused. Snapshots are only needed until they have
+// shown which entity is the player.
+func (a *analysis) serverFrame(at time.Time, data []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for len(data) > 0 {
+		packet, rest, err := protocol.Split(data)
+		if err != nil {
+			return
+		}
+		data = rest
+
+		switch protocol.Type(packet[0]) {
+		case protocol.TypeActionConfirm:
+			if protocol.DecodeInto(packet, &a.confirm) == nil {
+				a.player.Confirm(at, &a.confirm)
+			}
+		case protocol.TypeGameEvent:
+			if protocol.DecodeInto(packet, &a.event) == nil {
+				a.player.Event(at, &a.event)
+			}
+		case protocol.TypeStateUpdate:
+			if a.player.EntityID() == 0 && protocol.DecodeInto(packet, &a.state) == nil {
+				a.player.State(at, &a.state)
+			}
+		case protocol.TypeStateDelta:
+			if a.player.EntityID() != 0 || protocol.DecodeInto(packet, &a.delta) != nil {
+				continue
+			}
+			entities, complete, err := a.snapshots.Apply(&a.delta)
+			if err == nil && complete {
+				a.player.State(at, &protocol.StateUpdate{ServerTick: a.delta.ServerTick, Entities: entities})
+			}
+		}
+	}
+}
+
+// run reports the scores every interval until finish is called
+func (a *analysis) run(interval time.Duration) {
+	defer close(a.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.report()
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// finish stops the periodic reports and sends the final scores
+func (a *analysis) finish() {
+	close(a.stop)
+	<-a.done
+	a.report()
+}
+
+// report sends the scores to the API, unless the player has sent no input
+// or nothing has changed since the last report
+func (a *analysis) report() {
+	a.mu.Lock()
+	report := a.player.Report(time.Now())
+	changed := report.Inputs > 0 && report != a.reported
+	a.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	scores := a.scores
+	scores.Report = report
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+	if err := a.api.reportScores(ctx, &scores); err != nil {
+		logf("connection %s (user %d): %v", scores.ConnectionID, scores.UserID, err)
+		return
+	}
+
+	a.mu.Lock()
+	a.reported = report
+	a.mu.Unlock()
+}
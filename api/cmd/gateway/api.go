@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/omega-realm/api/pkg/anticheat"
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+const (
+	verifyPath = "/api/internal/servers/verify"
+	scoresPath = "/api/internal/anticheat/scores"
+)
+
+// apiClient calls the API's internal endpoints as a game server would
+type apiClient struct {
+	baseURL   string
+	serverKey string
+	http      *http.Client
+}
+
+func newAPIClient(baseURL, serverKey string) *apiClient {
+	return &apiClient{baseURL: baseURL, serverKey: serverKey, http: &http.Client{Timeout: 5 * time.Second}}
+}
+
+type verifyRequest struct {
+	Region          string                `json:"region"`
+	Token           string                `json:"token"`
+	CharacterID     int                   `json:"character_id"`
+	ProtocolVersion uint16                `json:"protocol_version"`
+	Capabilities    protocol.Capabilities `json:"capabilities"`
+}
+
+// verifiedPlayer is an accepted CONNECT_AUTH and where to send the player
+type verifiedPlayer struct {
+	InstanceID  string `json:"instance_id"`
+	Address     string `json:"address"`
+	UserID      int    `json:"user_id"`
+	Username    string `json:"username"`
+	CharacterID int    `json:"character_id"`
+}
+
+// verify checks a CONNECT_AUTH with the API. A refused player is returned as
+// a *rejection; any other error means the API could not answer.
+func (c *apiClient) verify(ctx context.Context, region string, auth *protocol.ConnectAuth) (*verifiedPlayer, error) {
+	characterID, err := strconv.Atoi(auth.CharacterID)
+	if err != nil {
+		return nil, &rejection{Message: "Character ID is not a number", Reason: protocol.DisconnectInvalidAuth}
+	}
+
+	var payload bytes.Buffer
+	if err := json.NewEncoder(&payload).Encode(verifyRequest{
+		Region:          region,
+		Token:           auth.Token,
+		CharacterID:     characterID,
+		ProtocolVersion: auth.ProtocolVersion,
+		Capabilities:    auth.Capabilities,
+	}); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+verifyPath, &payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Server-Key", c.serverKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Error            string                     `json:"error"`
+			DisconnectReason *protocol.DisconnectReason `json:"disconnect_reason"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		// Only rejections of the player carry a reason; anything else is
+		// the API or the gateway failing
+		if errResp.DisconnectReason == nil {
+			return nil, fmt.Errorf("%s returned %d: %s", verifyPath, resp.StatusCode, errResp.Error)
+		}
+		return nil, &rejection{Message: errResp.Error, Reason: *errResp.DisconnectReason}
+	}
+
+	var player verifiedPlayer
+	if err := json.NewDecoder(resp.Body).Decode(&player); err != nil {
+		return nil, fmt.Errorf("failed to decode %s response: %w", verifyPath, err)
+	}
+	if player.Address == "" {
+		return nil, fmt.Errorf("%s returned no instance address", verifyPath)
+	}
+	return &player, nil
+}
+
+// scoresRequest is a connection's anti-cheat scores, as the API stores them
+type scoresRequest struct {
+	ConnectionID string    `json:"connection_id"`
+	UserID       int       `json:"user_id"`
+	CharacterID  int       `json:"character_id"`
+	InstanceID   string    `json:"instance_id"`
+	Region       string    `json:"region"`
+	StartedAt    time.Time `json:"started_at"`
+	anticheat.Report
+}
+
+// reportScores sends a connection's latest scores to the API
+func (c *apiClient) reportScores(ctx context.Context, scores *scoresRequest) error {
+	var payload bytes.Buffer
+	if err := json.NewEncoder(&payload).Encode(scores); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+scoresPath, &payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Server-Key", c.serverKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("%s returned %d: %s", scoresPath, resp.StatusCode, errResp.Error)
+	}
+	return nil
+}
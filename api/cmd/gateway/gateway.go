@@ -0,0 +1,458 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+const (
+	// closeTimeout is how long the other side gets to answer a close
+	closeTimeout = 5 * time.Second
+
+	writeTimeout = 10 * time.Second
+	dialTimeout  = 10 * time.Second
+)
+
+// clientTypes are the packets a client may send once it has authenticated
+var clientTypes = []protocol.Type{
+	protocol.TypePlayerInput,
+	protocol.TypeHeartbeat,
+	protocol.TypeDisconnect,
+	protocol.TypeStateAck,
+}
+
+// gateway accepts client connections and forwards each verified player to
+// their instance
+type gateway struct {
+	config   *Config
+	api      *apiClient
+	upgrader websocket.Upgrader
+	nextID   atomic.Uint32
+
+	mu       sync.Mutex
+	sessions map[uint32]*session
+}
+
+func newGateway(config *Config, api *apiClient) *gateway {
+	return &gateway{
+		config: config,
+		api:    api,
+		upgrader: websocket.Upgrader{
+			// Godot sends no Origin, and players are authenticated by
+			// CONNECT_AUTH rather than cookies
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		sessions: make(map[uint32]*session),
+	}
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := g.nextID.Add(1)
+	client, err := g.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logf("conn %d: upgrade failed: %v", id, err)
+		return
+	}
+
+	s := newSession(g, id, client)
+	g.mu.Lock()
+	g.sessions[id] = s
+	g.mu.Unlock()
+
+	reason := s.serve(r)
+	logf("conn %d closed (%s): %s", id, reason, s.summary())
+
+	g.mu.Lock()
+	delete(g.sessions, id)
+	g.mu.Unlock()
+}
+
+// closeAll drops every open session
+func (g *gateway) closeAll() {
+	g.mu.Lock()
+	sessions := make([]*session, 0, len(g.sessions))
+	for _, s := range g.sessions {
+		sessions = append(sessions, s)
+	}
+	g.mu.Unlock()
+
+	for _, s := range sessions {
+		s.close()
+		<-s.done
+	}
+}
+
+// rejection is a player being turned away, with the reason to send them in a
+// DISCONNECT
+type rejection struct {
+	Message string
+	Reason  protocol.DisconnectReason
+}
+
+func (r *rejection) Error() string {
+	return fmt.Sprintf("rejected (%s): %s", r.Reason, r.Message)
+}
+
+// kicked is a client breaking the gateway's rules
+func kicked(format string, args ...any) *rejection {
+	return &rejection{Message: fmt.Sprintf(format, args...), Reason: protocol.DisconnectKicked}
+}
+
+// rateLimiter is a token bucket of packets
+type rateLimiter struct {
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// allow takes a token for a packet received at now, if there is one
+func (l *rateLimiter) allow(now time.Time) bool {
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// session is one client connection and, once the player is verified, its
+// connection to their instance
+type session struct {
+	gateway *gateway
+	id      uint32
+	client  *websocket.Conn
+	limiter *rateLimiter
+	done    chan struct{}
+
+	// packets are reused to decode client packets as they are checked
+	packets map[protocol.Type]protocol.Packet
+
+	// clientMu serializes writes to the client, which both directions make
+	clientMu sync.Mutex
+
+	// analysis scores the verified player's traffic, if -report-interval
+	// is set
+	analysis *analysis
+
+	mu     sync.Mutex
+	server *websocket.Conn
+	closed bool
+
+	// reason is why the first side to stop reading stopped
+	endOnce sync.Once
+	reason  string
+
+	fromClient, fromServer atomic.Int64
+}
+
+func newSession(g *gateway, id uint32, client *websocket.Conn) *session {
+	s := &session{
+		gateway: g,
+		id:      id,
+		client:  client,
+		limiter: newRateLimiter(g.config.Rate, g.config.Burst),
+		done:    make(chan struct{}),
+		packets: make(map[protocol.Type]protocol.Packet),
+	}
+	for _, t := range clientTypes {
+		s.packets[t], _ = protocol.New(t)
+	}
+	s.packets[protocol.TypeConnectAuth] = &protocol.ConnectAuth{}
+	return s
+}
+
+// serve verifies the player, connects them to their instance and forwards
+// traffic until either side leaves. It returns why the session ended.
+func (s *session) serve(r *http.Request) string {
+	defer close(s.done)
+	defer s.close()
+
+	frame, auth, err := s.readAuth()
+	if err != nil {
+		return s.fail(err)
+	}
+
+	config := s.gateway.config
+	player, err := s.gateway.api.verify(r.Context(), config.Region, auth)
+	var rejected *rejection
+	if errors.As(err, &rejected) {
+		s.reject(rejected, websocket.CloseNormalClosure)
+		return rejected.Error()
+	}
+	if err != nil {
+		// The player is not at fault, so they are free to retry
+		logf("conn %d: %v", s.id, err)
+		s.reject(&rejection{Message: "Verification unavailable", Reason: protocol.DisconnectTimeout}, websocket.CloseTryAgainLater)
+		return "verification unavailable"
+	}
+
+	dialer := websocket.Dialer{HandshakeTimeout: dialTimeout}
+	server, _, err := dialer.DialContext(r.Context(), player.Address, nil)
+	if err != nil {
+		logf("conn %d: instance %s at %s: %v", s.id, player.InstanceID, player.Address, err)
+		s.reject(&rejection{Message: "Game server unavailable", Reason: protocol.DisconnectTimeout}, websocket.CloseTryAgainLater)
+		return "instance unavailable"
+	}
+	if !s.setServer(server) {
+		server.Close()
+		return "closed"
+	}
+
+	// The instance verifies the player itself, so it gets the original
+	// CONNECT_AUTH
+	server.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := server.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+		return "server: " + err.Error()
+	}
+	logf("conn %d: %s (user %d, character %d) from %s -> %s",
+		s.id, player.Username, player.UserID, player.CharacterID, r.RemoteAddr, player.InstanceID)
+
+	if config.ReportInterval > 0 {
+		s.analysis = newAnalysis(s.gateway.api, config.Region, player)
+		go s.analysis.run(config.ReportInterval)
+		defer s.analysis.finish()
+	}
+	return s.forward(server)
+}
+
+// readFrame reads one frame from the client, failing once it is longer than
+// the largest packet allowed rather than buffering all of it
+func (s *session) readFrame(deadline time.Duration) (int, []byte, error) {
+	s.client.SetReadDeadline(time.Now().Add(deadline))
+	messageType, reader, err := s.client.NextReader()
+	if err != nil {
+		return 0, nil, err
+	}
+	limit := s.gateway.config.MaxPacketSize
+	data, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(data) > limit {
+		return 0, nil, kicked("Frame exceeds %d bytes", limit)
+	}
+	return messageType, data, nil
+}
+
+// readAuth reads the CONNECT_AUTH that must open the connection, on its own
+// in one frame
+func (s *session) readAuth() ([]byte, *protocol.ConnectAuth, error) {
+	messageType, data, err := s.readFrame(s.gateway.config.AuthTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.fromClient.Add(1)
+	if messageType != websocket.BinaryMessage {
+		return nil, nil, kicked("Expected CONNECT_AUTH, got a text frame")
+	}
+	packet, rest, err := protocol.Split(data)
+	if err != nil || len(rest) > 0 || protocol.Type(packet[0]) != protocol.TypeConnectAuth {
+		return nil, nil, &rejection{Message: "Expected CONNECT_AUTH", Reason: protocol.DisconnectInvalidAuth}
+	}
+	auth := s.packets[protocol.TypeConnectAuth].(*protocol.ConnectAuth)
+	if err := protocol.DecodeInto(packet, auth); err != nil {
+		return nil, nil, &rejection{Message: "Malformed CONNECT_AUTH", Reason: protocol.DisconnectInvalidAuth}
+	}
+	return data, auth, nil
+}
+
+// check returns why a frame from an authenticated client must not reach the
+// instance, or nil if it may. Frames can carry several packets back to back.
+func (s *session) check(messageType int, data []byte, now time.Time) *rejection {
+	if messageType != websocket.BinaryMessage {
+		return kicked("Text frames are not allowed")
+	}
+	if len(data) == 0 {
+		return kicked("Empty frame")
+	}
+	for len(data) > 0 {
+		packet, rest, err := protocol.Split(data)
+		if err != nil {
+			return kicked("Malformed packet: %v", err)
+		}
+		t := protocol.Type(packet[0])
+		p, ok := s.packets[t]
+		if !ok || t == protocol.TypeConnectAuth {
+			return kicked("%s is not sent by clients", t)
+		}
+		if err := protocol.DecodeInto(packet, p); err != nil {
+			return kicked("Malformed %s: %v", t, err)
+		}
+		if !s.limiter.allow(now) {
+			return kicked("Sent more than %.0f packets per second", s.gateway.config.Rate)
+		}
+		if input, ok := p.(*protocol.PlayerInput); ok && s.analysis != nil {
+			s.analysis.input(now, input)
+		}
+		data = rest
+	}
+	return nil
+}
+
+// forward passes frames both ways until the connection ends. Once one side
+// has closed, the other has closeTimeout to follow.
+func (s *session) forward(server *websocket.Conn) string {
+	ended := make(chan struct{}, 2)
+	go func() { s.forwardClient(server); ended <- struct{}{} }()
+	go func() { s.forwardServer(server); ended <- struct{}{} }()
+
+	<-ended
+	select {
+	case <-ended:
+	case <-time.After(closeTimeout):
+		s.close()
+		<-ended
+	}
+	return s.reason
+}
+
+// forwardClient checks each client frame and passes it on to the instance
+func (s *session) forwardClient(server *websocket.Conn) {
+	for {
+		messageType, data, err := s.readFrame(s.gateway.config.IdleTimeout)
+		var rejected *rejection
+		if errors.As(err, &rejected) {
+			s.end("client: " + rejected.Message)
+			s.reject(rejected, websocket.ClosePolicyViolation)
+			server.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(writeTimeout))
+			return
+		}
+		if err != nil {
+			s.end("client: " + err.Error())
+			server.WriteControl(websocket.CloseMessage, closeMessage(err), time.Now().Add(writeTimeout))
+			return
+		}
+		s.fromClient.Add(1)
+
+		if rejected := s.check(messageType, data, time.Now()); rejected != nil {
+			s.end("client: " + rejected.Message)
+			s.reject(rejected, websocket.ClosePolicyViolation)
+			server.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(writeTimeout))
+			return
+		}
+
+		server.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := server.WriteMessage(messageType, data); err != nil {
+			s.end("server: " + err.Error())
+			s.close()
+			return
+		}
+	}
+}
+
+// forwardServer passes the instance's frames to the client unchanged, after
+// the analysis has seen them
+func (s *session) forwardServer(server *websocket.Conn) {
+	for {
+		messageType, data, err := server.ReadMessage()
+		if err != nil {
+			s.end("server: " + err.Error())
+			s.client.WriteControl(websocket.CloseMessage, closeMessage(err), time.Now().Add(writeTimeout))
+			return
+		}
+		s.fromServer.Add(1)
+		if s.analysis != nil && messageType == websocket.BinaryMessage {
+			s.analysis.serverFrame(time.Now(), data)
+		}
+
+		s.clientMu.Lock()
+		s.client.SetWriteDeadline(time.Now().Add(writeTimeout))
+		err = s.client.WriteMessage(messageType, data)
+		s.clientMu.Unlock()
+		if err != nil {
+			s.end("client: " + err.Error())
+			s.close()
+			return
+		}
+	}
+}
+
+// closeMessage passes on a close frame as it was sent
+func closeMessage(readErr error) []byte {
+	var closeErr *websocket.CloseError
+	if errors.As(readErr, &closeErr) && closeErr.Code != websocket.CloseNoStatusReceived &&
+		closeErr.Code != websocket.CloseAbnormalClosure {
+		return websocket.FormatCloseMessage(closeErr.Code, closeErr.Text)
+	}
+	return websocket.FormatCloseMessage(websocket.CloseGoingAway, "")
+}
+
+// fail ends a session whose CONNECT_AUTH could not be read
+func (s *session) fail(err error) string {
+	var rejected *rejection
+	if errors.As(err, &rejected) {
+		s.reject(rejected, websocket.ClosePolicyViolation)
+		return rejected.Error()
+	}
+	return "client: " + err.Error()
+}
+
+// reject sends the client a DISCONNECT saying why it is being turned away,
+// then closes the connection with code
+func (s *session) reject(rejected *rejection, code int) {
+	packet, err := protocol.Encode(&protocol.Disconnect{
+		Reason:      rejected.Reason,
+		TimestampMs: uint32(time.Now().UnixMilli()),
+	})
+	if err != nil {
+		return
+	}
+
+	deadline := time.Now().Add(writeTimeout)
+	s.clientMu.Lock()
+	s.client.SetWriteDeadline(deadline)
+	s.client.WriteMessage(websocket.BinaryMessage, packet)
+	s.clientMu.Unlock()
+	s.client.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, rejected.Message), deadline)
+}
+
+// end records why the session ended, if it is the first reason
+func (s *session) end(reason string) {
+	s.endOnce.Do(func() { s.reason = reason })
+}
+
+// setServer attaches the instance connection, unless the session has
+// already been closed
+func (s *session) setServer(server *websocket.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.server = server
+	return true
+}
+
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.client.Close()
+	if s.server != nil {
+		s.server.Close()
+	}
+}
+
+func (s *session) summary() string {
+	return fmt.Sprintf("%d frames from client, %d from server", s.fromClient.Load(), s.fromServer.Load())
+}
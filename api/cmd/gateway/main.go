@@ -0,0 +1,131 @@
+// Command gateway is the WebSocket edge in front of a region's game servers.
+// Clients connect to it instead of to a Godot instance, and it only forwards
+// players the API has let in, sending traffic that is well formed.
+//
+// The first packet on a connection must be CONNECT_AUTH, within
+// -auth-timeout. The gateway checks it with the API's verify endpoint, which
+// checks the protocol version, the token, bans and the character, and says
+// which instance the player holds a slot on (or was playing on, if they are
+// reconnecting). A rejected player gets a DISCONNECT with the API's reason,
+// as an instance would send. An accepted one is connected to the instance's
+// internal address, which receives the original CONNECT_AUTH and verifies it
+// again as usual, so instances work the same with or without a gateway.
+//
+// Every frame a client sends afterwards must be packets the client is meant
+// to send (PLAYER_INPUT, HEARTBEAT, DISCONNECT and STATE_ACK) that decode,
+// and no larger than -max-packet. Clients get -rate packets per second with
+// bursts of up to -burst. A client breaking any of these is disconnected
+// with DISCONNECT reason KICKED before the instance sees the frame. Frames
+// from the instance are forwarded unchanged.
+//
+// The gateway also scores each player for cheating with pkg/anticheat, from
+// their PLAYER_INPUT and the instance's ACTION_CONFIRM and GAME_EVENT
+// packets. The scores are reported to the API every -report-interval while
+// they change and when the player leaves, where moderators review them; the
+// gateway never acts on them itself.
+//
+// Instances behind a gateway register the gateway's public URL as their
+// address, which is what clients are sent to, and their own URL as their
+// internal_address. The gateway needs the same GAME_SERVER_API_KEY as they
+// do.
+//
+// Usage:
+//
+//	GAME_SERVER_API_KEY=... go run ./cmd/gateway -region europe -listen :9001 -api http://localhost:8080
+//	go run ./cmd/gateway -region asia -rate 60 -burst 120 -max-packet 4096
+//	go run ./cmd/gateway -region europe -report-interval 30s
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+// Config holds the gateway's command line options
+type Config struct {
+	Listen         string
+	API            string
+	ServerKey      string
+	Region         string
+	MaxPacketSize  int
+	Rate           float64
+	Burst          int
+	AuthTimeout    time.Duration
+	IdleTimeout    time.Duration
+	ReportInterval time.Duration
+}
+
+func main() {
+	config := &Config{}
+	flag.StringVar(&config.Listen, "listen", ":9001", "address clients connect to")
+	flag.StringVar(&config.API, "api", "http://localhost:8080", "API base URL")
+	flag.StringVar(&config.ServerKey, "server-key", os.Getenv("GAME_SERVER_API_KEY"), "key for the API's internal endpoints (default $GAME_SERVER_API_KEY)")
+	flag.StringVar(&config.Region, "region", "", "region whose game servers this gateway fronts")
+	flag.IntVar(&config.MaxPacketSize, "max-packet", protocol.MaxPacketSize, "largest frame a client may send, in bytes")
+	flag.Float64Var(&config.Rate, "rate", 60, "packets per second a client may send")
+	flag.IntVar(&config.Burst, "burst", 120, "packets a client may send at once, above -rate")
+	flag.DurationVar(&config.AuthTimeout, "auth-timeout", 5*time.Second, "how long a client has to send CONNECT_AUTH")
+	flag.DurationVar(&config.IdleTimeout, "idle-timeout", 30*time.Second, "disconnect clients that send nothing for this long")
+	flag.DurationVar(&config.ReportInterval, "report-interval", time.Minute, "how often to report players' anti-cheat scores to the API (0 disables the analysis)")
+	flag.Parse()
+
+	if err := config.validate(); err != nil {
+		logf("%v", err)
+		os.Exit(2)
+	}
+
+	g := newGateway(config, newAPIClient(config.API, config.ServerKey))
+	server := &http.Server{Addr: config.Listen, Handler: g}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	logf("Gateway for %s listening on %s (API %s, %.0f packets/s, burst %d, max packet %d bytes)",
+		config.Region, config.Listen, config.API, config.Rate, config.Burst, config.MaxPacketSize)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logf("Server error: %v", err)
+		os.Exit(1)
+	}
+
+	// Hijacked connections outlive the server
+	g.closeAll()
+}
+
+func (c *Config) validate() error {
+	if c.Region == "" {
+		return errors.New("-region is required")
+	}
+	if c.ServerKey == "" {
+		return errors.New("-server-key or GAME_SERVER_API_KEY is required")
+	}
+	if c.MaxPacketSize < protocol.HeaderSize || c.MaxPacketSize > protocol.MaxPacketSize {
+		return fmt.Errorf("-max-packet must be between %d and %d", protocol.HeaderSize, protocol.MaxPacketSize)
+	}
+	if !(c.Rate > 0) || c.Burst < 1 {
+		return errors.New("-rate and -burst must be positive")
+	}
+	if c.AuthTimeout <= 0 || c.IdleTimeout <= 0 {
+		return errors.New("-auth-timeout and -idle-timeout must be positive")
+	}
+	if c.ReportInterval < 0 {
+		return errors.New("-report-interval must not be negative")
+	}
+	return nil
+}
+
+func logf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "[Gateway] "+format+"\n", args...)
+}
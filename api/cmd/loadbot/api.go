@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// apiClient is the slice of the REST API a bot needs to get into a game
+type apiClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+func newAPIClient(baseURL string) *apiClient {
+	return &apiClient{baseURL: baseURL, http: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// apiError is a non-2xx response from the API
+type apiError struct {
+	Path   string
+	Status int
+	Msg    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s returned %d: %s", e.Path, e.Status, e.Msg)
+}
+
+// call sends body as JSON and decodes a successful response into out. It
+// returns the response status.
+func (c *apiClient) call(ctx context.Context, method, path, token string, body, out any) (int, error) {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return 0, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, &payload)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return resp.StatusCode, &apiError{Path: path, Status: resp.StatusCode, Msg: errResp.Error}
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode %s response: %w", path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+type authResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// login returns an access token, registering the account first if it does
+// not exist and register is set
+func (c *apiClient) login(ctx context.Context, username, password, region string, register bool) (string, error) {
+	var auth authResponse
+	_, err := c.call(ctx, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"username": username,
+		"password": password,
+	}, &auth)
+	if apiErr, ok := err.(*apiError); ok && apiErr.Status == http.StatusUnauthorized && register {
+		_, err = c.call(ctx, http.MethodPost, "/api/auth/register", "", map[string]string{
+			"username": username,
+			"email":    username + "@loadbot.invalid",
+			"password": password,
+			"region":   region,
+		}, &auth)
+	}
+	if err != nil {
+		return "", err
+	}
+	return auth.AccessToken, nil
+}
+
+// character returns the account's character ID, creating one named name if
+// the account has none and create is set
+func (c *apiClient) character(ctx context.Context, token, name string, create bool) (int, error) {
+	var character struct {
+		ID int `json:"id"`
+	}
+	status, err := c.call(ctx, http.MethodGet, "/api/character/me", token, nil, &character)
+	if status == http.StatusNotFound && create {
+		_, err = c.call(ctx, http.MethodPost, "/api/character/create", token, map[string]string{"name": name}, &character)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return character.ID, nil
+}
+
+// assignment is a reserved slot on a game server, from SelectRegion or the
+// region queue
+type assignment struct {
+	Status       string `json:"status"`
+	InstanceID   string `json:"instance_id"`
+	WebSocketURL string `json:"websocket_url"`
+}
+
+// selectRegion reserves a slot in region, waiting in the region queue if it
+// is full
+func (c *apiClient) selectRegion(ctx context.Context, token, region string) (*assignment, error) {
+	var slot assignment
+	_, err := c.call(ctx, http.MethodPost, "/api/regions/select", token, map[string]string{"region_id": region}, &slot)
+	if err != nil {
+		return nil, err
+	}
+
+	// Queued players long-poll until they are promoted
+	for slot.Status == "queued" {
+		slot = assignment{}
+		_, err = c.call(ctx, http.MethodGet, "/api/queue/status?wait=10", token, nil, &slot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &slot, nil
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/omega-realm/api/pkg/protocol"
+	"github.com/omega-realm/api/pkg/recording"
+)
+
+const (
+	inputInterval     = 100 * time.Millisecond // PLAYER_INPUT at 10Hz
+	heartbeatInterval = time.Second
+	writeTimeout      = 5 * time.Second
+)
+
+// epoch is the zero point of the millisecond timestamps bots put in packets
+var epoch = time.Now()
+
+func nowMs() uint32 {
+	return uint32(time.Since(epoch).Milliseconds())
+}
+
+// bot is one simulated player: it logs in, joins a game server, and plays a
+// scripted route until its context ends
+type bot struct {
+	id     int
+	config *Config
+	api    *apiClient
+	stats  *Stats
+	record *recording.Writer
+	rng    *rand.Rand
+
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	out     []byte
+
+	connected    atomic.Bool
+	stateUpdates atomic.Int64
+	bytesIn      atomic.Int64
+	bytesOut     atomic.Int64
+
+	// Send times of recent inputs, by sequence number
+	inputSent [256]atomic.Int64
+
+	mu         sync.Mutex
+	heartbeats map[uint32]time.Time
+	position   protocol.Vector2
+	disconnect *protocol.DisconnectReason
+}
+
+func newBot(id int, config *Config, api *apiClient, stats *Stats, record *recording.Writer) *bot {
+	return &bot{
+		id:         id,
+		config:     config,
+		api:        api,
+		stats:      stats,
+		record:     record,
+		rng:        rand.New(rand.NewPCG(config.Seed, uint64(id))),
+		heartbeats: make(map[uint32]time.Time),
+	}
+}
+
+func (b *bot) name() string {
+	return fmt.Sprintf("%s%04d", b.config.Prefix, b.id)
+}
+
+// Run plays one session and records how it ended
+func (b *bot) Run(ctx context.Context) {
+	reason, err := b.play(ctx)
+	if err != nil && ctx.Err() == nil {
+		logf("bot %d: %v", b.id, err)
+	}
+	b.connected.Store(false)
+	b.stats.RecordDisconnect(reason)
+	if b.record != nil && b.conn != nil {
+		b.record.CloseConnection(uint32(b.id), time.Now(), reason)
+	}
+}
+
+// play returns the disconnect reason for the stats
+func (b *bot) play(ctx context.Context) (string, error) {
+	token, characterID := "loadbot", strconv.Itoa(b.id)
+	url := b.config.ServerURL
+
+	if !b.config.Offline {
+		var err error
+		token, err = b.api.login(ctx, b.name(), b.config.Password, b.config.Region, b.config.Register)
+		if err != nil {
+			return "api: login failed", err
+		}
+		id, err := b.api.character(ctx, token, b.name(), b.config.Register)
+		if err != nil {
+			return "api: no character", err
+		}
+		characterID = strconv.Itoa(id)
+
+		slot, err := b.api.selectRegion(ctx, token, b.config.Region)
+		if err != nil {
+			return "api: select region failed", err
+		}
+		if url == "" {
+			url = slot.WebSocketURL
+		}
+	}
+
+	dialStart := time.Now()
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	conn, _, err := dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return "dial failed", err
+	}
+	b.conn = conn
+	if b.record != nil {
+		b.record.OpenConnection(uint32(b.id), dialStart, b.name()+" "+url)
+	}
+	defer conn.Close()
+
+	region, _ := protocol.RegionCodeFromID(b.config.Region)
+	if err := b.send(&protocol.ConnectAuth{
+		Token:           token,
+		CharacterID:     characterID,
+		Region:          region,
+		ProtocolVersion: protocol.Version,
+		Capabilities:    protocol.SupportedCapabilities,
+	}); err != nil {
+		return "write failed", err
+	}
+
+	readErr := make(chan error, 1)
+	go func() { readErr <- b.readLoop(dialStart) }()
+
+	err = b.writeLoop(ctx, readErr)
+	if ctx.Err() != nil {
+		// Run over: leave cleanly
+		b.send(&protocol.Disconnect{Reason: protocol.DisconnectUserQuit, TimestampMs: nowMs()})
+		b.writeMu.Lock()
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeTimeout))
+		b.writeMu.Unlock()
+		return "completed", nil
+	}
+
+	b.mu.Lock()
+	reason := b.disconnect
+	b.mu.Unlock()
+	if reason != nil {
+		return "server: " + reason.String(), nil
+	}
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return fmt.Sprintf("closed: %d", closeErr.Code), err
+	}
+	return "connection lost", err
+}
+
+// writeLoop sends inputs and heartbeats until ctx ends or reading fails
+func (b *bot) writeLoop(ctx context.Context, readErr <-chan error) error {
+	inputs := time.NewTicker(inputInterval)
+	defer inputs.Stop()
+	heartbeats := time.NewTicker(heartbeatInterval)
+	defer heartbeats.Stop()
+
+	route := newRoute(b.rng)
+	b.mu.Lock()
+	b.position = route.center
+	b.mu.Unlock()
+
+	var sequence uint8
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			return err
+		case <-heartbeats.C:
+			ts := nowMs()
+			b.mu.Lock()
+			for sentTs, sent := range b.heartbeats {
+				if time.Since(sent) > 30*time.Second {
+					delete(b.heartbeats, sentTs)
+				}
+			}
+			b.heartbeats[ts] = time.Now()
+			b.mu.Unlock()
+			if err := b.send(&protocol.Heartbeat{TimestampMs: ts}); err != nil {
+				return err
+			}
+		case <-inputs.C:
+			b.mu.Lock()
+			input := route.next(&b.position, inputInterval, sequence)
+			b.mu.Unlock()
+
+			b.inputSent[sequence].Store(time.Now().UnixNano())
+			if err := b.send(input); err != nil {
+				return err
+			}
+			sequence++
+		}
+	}
+}
+
+// readLoop handles server packets until the connection fails
+func (b *bot) readLoop(dialStart time.Time) error {
+	var (
+		state     protocol.StateUpdate
+		delta     protocol.StateDelta
+		snapshots = protocol.NewDeltaDecoder()
+		heartbeat protocol.Heartbeat
+		confirm   protocol.ActionConfirm
+		event     protocol.GameEvent
+		bye       protocol.Disconnect
+		joined    bool
+	)
+
+	for {
+		_, message, err := b.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		b.bytesIn.Add(int64(len(message)))
+
+		// A message may carry several packets back to back
+		for len(message) > 0 {
+			packet, rest, err := protocol.Split(message)
+			if err != nil {
+				b.stats.RecordDecodeError()
+				break
+			}
+			message = rest
+			if b.record != nil {
+				b.record.WritePacket(uint32(b.id), recording.ServerToClient, time.Now(), packet)
+			}
+
+			switch protocol.Type(packet[0]) {
+			case protocol.TypeStateUpdate:
+				if protocol.DecodeInto(packet, &state) != nil {
+					b.stats.RecordDecodeError()
+					continue
+				}
+				b.stateUpdates.Add(1)
+				if !joined {
+					joined = true
+					b.connected.Store(true)
+					b.stats.RecordJoin(time.Since(dialStart))
+				}
+
+			case protocol.TypeStateDelta:
+				if protocol.DecodeInto(packet, &delta) != nil {
+					b.stats.RecordDecodeError()
+					continue
+				}
+				_, complete, err := snapshots.Apply(&delta)
+				if err != nil {
+					b.stats.RecordDecodeError()
+					continue
+				}
+				if !complete {
+					continue
+				}
+				if err := b.send(&protocol.StateAck{ServerTick: delta.ServerTick}); err != nil {
+					return err
+				}
+				b.stateUpdates.Add(1)
+				if !joined {
+					joined = true
+					b.connected.Store(true)
+					b.stats.RecordJoin(time.Since(dialStart))
+				}
+
+			case protocol.TypeHeartbeat:
+				if protocol.DecodeInto(packet, &heartbeat) != nil {
+					b.stats.RecordDecodeError()
+					continue
+				}
+				b.mu.Lock()
+				sent, ours := b.heartbeats[heartbeat.TimestampMs]
+				delete(b.heartbeats, heartbeat.TimestampMs)
+				b.mu.Unlock()
+				if ours {
+					b.stats.RecordRTT(time.Since(sent))
+				} else if err := b.send(&heartbeat); err != nil {
+					// The server's own keep-alive: echo it back
+					return err
+				}
+
+			case protocol.TypeActionConfirm:
+				if protocol.DecodeInto(packet, &confirm) != nil {
+					b.stats.RecordDecodeError()
+					continue
+				}
+				if sent := b.inputSent[confirm.SequenceNumber].Swap(0); sent != 0 {
+					b.stats.RecordConfirm(time.Since(time.Unix(0, sent)))
+				}
+				// Rejected moves snap back to the server's position
+				if confirm.ActionType == protocol.ActionMove && confirm.ResultCode != protocol.ResultSuccess {
+					b.mu.Lock()
+					b.position = confirm.CorrectedPosition
+					b.mu.Unlock()
+				}
+
+			case protocol.TypeGameEvent:
+				if protocol.DecodeInto(packet, &event) != nil {
+					b.stats.RecordDecodeError()
+				}
+
+			case protocol.TypeDisconnect:
+				if protocol.DecodeInto(packet, &bye) != nil {
+					b.stats.RecordDecodeError()
+					continue
+				}
+				b.mu.Lock()
+				reason := bye.Reason
+				b.disconnect = &reason
+				b.mu.Unlock()
+
+			default:
+				b.stats.RecordDecodeError()
+			}
+		}
+	}
+}
+
+// send encodes p into the bot's buffer and writes it as one binary message
+func (b *bot) send(p protocol.Packet) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	var err error
+	b.out, err = protocol.AppendPacket(b.out[:0], p)
+	if err != nil {
+		return err
+	}
+	b.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := b.conn.WriteMessage(websocket.BinaryMessage, b.out); err != nil {
+		return err
+	}
+	b.bytesOut.Add(int64(len(b.out)))
+	if b.record != nil {
+		b.record.WritePacket(uint32(b.id), recording.ClientToServer, time.Now(), b.out)
+	}
+	return nil
+}
+
+// route is a bot's scripted behaviour: it circles a point, firing in
+// bursts and aiming slightly ahead of where it is going
+type route struct {
+	center    protocol.Vector2
+	radius    float64
+	speed     float64
+	angle     float64
+	clockwise bool
+	burstTick int
+}
+
+func newRoute(rng *rand.Rand) *route {
+	return &route{
+		center:    protocol.Vector2{X: float32(rng.Float64()*200 - 100), Y: float32(rng.Float64()*200 - 100)},
+		radius:    5 + rng.Float64()*15,
+		speed:     3 + rng.Float64()*3,
+		angle:     rng.Float64() * 2 * math.Pi,
+		clockwise: rng.IntN(2) == 0,
+		burstTick: rng.IntN(10),
+	}
+}
+
+// next advances position by one input interval and returns the input
+func (r *route) next(position *protocol.Vector2, dt time.Duration, sequence uint8) *protocol.PlayerInput {
+	step := r.speed * dt.Seconds() / r.radius
+	if r.clockwise {
+		step = -step
+	}
+	r.angle += step
+
+	target := protocol.Vector2{
+		X: r.center.X + float32(r.radius*math.Cos(r.angle)),
+		Y: r.center.Y + float32(r.radius*math.Sin(r.angle)),
+	}
+	seconds := float32(dt.Seconds())
+	velocity := protocol.Vector2{X: (target.X - position.X) / seconds, Y: (target.Y - position.Y) / seconds}
+	*position = target
+
+	var flags protocol.InputFlags
+	switch {
+	case velocity.X > 0.1:
+		flags |= protocol.InputMoveRight
+	case velocity.X < -0.1:
+		flags |= protocol.InputMoveLeft
+	}
+	switch {
+	case velocity.Y > 0.1:
+		flags |= protocol.InputMoveDown
+	case velocity.Y < -0.1:
+		flags |= protocol.InputMoveUp
+	}
+	// Three shots every second
+	r.burstTick = (r.burstTick + 1) % 10
+	if r.burstTick < 3 {
+		flags |= protocol.InputShoot
+	}
+
+	return &protocol.PlayerInput{
+		Position:       target,
+		Velocity:       velocity,
+		InputFlags:     flags,
+		AimAngle:       math.Atan2(float64(velocity.Y), float64(velocity.X)) + 0.2,
+		SequenceNumber: sequence,
+	}
+}
@@ -0,0 +1,172 @@
+// Command loadbot runs a swarm of headless bots against a game server to
+// measure how it degrades as players are added.
+//
+// Each bot logs in through the API (registering an account and character
+// if -register is set), reserves a slot with SelectRegion, connects to the
+// assigned game server and sends CONNECT_AUTH. It then plays at 10Hz,
+// circling a random point and firing in bursts, echoes the server's
+// heartbeats and measures its own. Bots join -ramp apart, so the periodic
+// report lines trace latency and update rate against the player count.
+// With -record the bots' traffic is saved for cmd/replay.
+//
+// Usage:
+//
+//	go run ./cmd/loadbot -bots 200 -ramp 250ms -duration 5m -register
+//	go run ./cmd/loadbot -offline -server ws://localhost:9001 -bots 50
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/omega-realm/api/pkg/protocol"
+	"github.com/omega-realm/api/pkg/recording"
+)
+
+// Config holds the loadbot's command line options
+type Config struct {
+	APIURL         string
+	ServerURL      string
+	Region         string
+	Bots           int
+	Ramp           time.Duration
+	Duration       time.Duration
+	ReportInterval time.Duration
+	Prefix         string
+	Password       string
+	Register       bool
+	Offline        bool
+	Seed           uint64
+	RecordPath     string
+}
+
+func parseFlags() *Config {
+	config := &Config{}
+	flag.StringVar(&config.APIURL, "api", "http://localhost:8080", "API base URL")
+	flag.StringVar(&config.ServerURL, "server", "", "game server WebSocket URL; overrides the one assigned by SelectRegion")
+	flag.StringVar(&config.Region, "region", "asia", "region to join")
+	flag.IntVar(&config.Bots, "bots", 10, "number of bots")
+	flag.DurationVar(&config.Ramp, "ramp", 100*time.Millisecond, "delay between starting bots")
+	flag.DurationVar(&config.Duration, "duration", time.Minute, "how long to run once every bot has started")
+	flag.DurationVar(&config.ReportInterval, "report", 5*time.Second, "interval between report lines")
+	flag.StringVar(&config.Prefix, "prefix", "loadbot", "account and character name prefix; bots are <prefix>0001, <prefix>0002, ...")
+	flag.StringVar(&config.Password, "password", "loadbot-password", "password of every bot account")
+	flag.BoolVar(&config.Register, "register", false, "register missing accounts and characters")
+	flag.BoolVar(&config.Offline, "offline", false, "skip the API and connect straight to -server with placeholder credentials")
+	flag.Uint64Var(&config.Seed, "seed", 1, "random seed for bot routes")
+	flag.StringVar(&config.RecordPath, "record", "", "record every bot's packets to this file for cmd/replay")
+	flag.Parse()
+	return config
+}
+
+func logf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "[LoadBot] "+format+"\n", args...)
+}
+
+func main() {
+	config := parseFlags()
+	if config.Offline && config.ServerURL == "" {
+		logf("-offline requires -server")
+		os.Exit(2)
+	}
+	if _, ok := protocol.RegionCodeFromID(config.Region); !ok {
+		logf("region %q has no CONNECT_AUTH region code", config.Region)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	api := newAPIClient(config.APIURL)
+	stats := NewStats()
+
+	var record *recording.Writer
+	if config.RecordPath != "" {
+		var err error
+		record, err = recording.Create(config.RecordPath)
+		if err != nil {
+			logf("Failed to create recording: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	// Bot IDs double as recording connection IDs
+	bots := make([]*bot, config.Bots)
+	for i := range bots {
+		bots[i] = newBot(i+1, config, api, stats, record)
+	}
+
+	// The run ends Duration after the last bot starts, or on Ctrl-C
+	runCtx, cancel := context.WithTimeout(ctx, time.Duration(config.Bots)*config.Ramp+config.Duration)
+	defer cancel()
+
+	logf("Starting %d bots in %s, %s apart", config.Bots, config.Region, config.Ramp)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, b := range bots {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				b.Run(runCtx)
+			}()
+
+			select {
+			case <-runCtx.Done():
+				return
+			case <-time.After(config.Ramp):
+			}
+		}
+	}()
+
+	report(runCtx, config, stats, bots, start)
+
+	// Every bot disconnects before the final report
+	wg.Wait()
+	if record != nil {
+		if err := record.Close(); err != nil {
+			logf("Failed to write recording: %v", err)
+		}
+	}
+	stats.WriteReport(os.Stdout)
+}
+
+// report writes a line per interval until ctx ends
+func report(ctx context.Context, config *Config, stats *Stats, bots []*bot, start time.Time) {
+	ticker := time.NewTicker(config.ReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			connected := sampleRates(stats, bots, config.ReportInterval)
+			stats.WriteWindow(os.Stdout, time.Since(start), connected, len(bots))
+		}
+	}
+}
+
+// sampleRates records every in-game bot's traffic since the last sample and
+// returns how many bots are in game
+func sampleRates(stats *Stats, bots []*bot, elapsed time.Duration) int {
+	connected := 0
+	for _, b := range bots {
+		updates, in, out := b.stateUpdates.Swap(0), b.bytesIn.Swap(0), b.bytesOut.Swap(0)
+		if !b.connected.Load() {
+			continue
+		}
+		connected++
+		stats.RecordRates(updates, in, out, elapsed)
+	}
+	return connected
+}
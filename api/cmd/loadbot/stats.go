@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// series collects samples for the current report window and for the whole run
+type series struct {
+	window []float64
+	total  []float64
+}
+
+func (s *series) add(v float64) {
+	s.window = append(s.window, v)
+	s.total = append(s.total, v)
+}
+
+// summary is the distribution of a set of samples
+type summary struct {
+	Count              int
+	P50, P90, P99, Max float64
+}
+
+func summarize(samples []float64) summary {
+	if len(samples) == 0 {
+		return summary{}
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	return summary{
+		Count: len(sorted),
+		P50:   percentile(sorted, 50),
+		P90:   percentile(sorted, 90),
+		P99:   percentile(sorted, 99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// percentile uses the nearest-rank method on sorted samples
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
+
+// Stats aggregates measurements from every bot
+type Stats struct {
+	mu sync.Mutex
+
+	// Latencies in milliseconds
+	rtt     series // HEARTBEAT round trip
+	confirm series // PLAYER_INPUT to its ACTION_CONFIRM
+	join    series // dialing the game server to the first STATE_UPDATE
+
+	// Per-bot rates over each report window
+	stateRate series // STATE_UPDATE packets per second
+	bytesIn   series // bytes received per second
+	bytesOut  series // bytes sent per second
+
+	decodeErrors int
+	disconnects  map[string]int
+}
+
+func NewStats() *Stats {
+	return &Stats{disconnects: make(map[string]int)}
+}
+
+func (s *Stats) addLatency(target *series, d time.Duration) {
+	s.mu.Lock()
+	target.add(float64(d.Microseconds()) / 1000)
+	s.mu.Unlock()
+}
+
+func (s *Stats) RecordRTT(d time.Duration)     { s.addLatency(&s.rtt, d) }
+func (s *Stats) RecordConfirm(d time.Duration) { s.addLatency(&s.confirm, d) }
+func (s *Stats) RecordJoin(d time.Duration)    { s.addLatency(&s.join, d) }
+
+// RecordRates adds one bot's traffic over a report window of length elapsed
+func (s *Stats) RecordRates(stateUpdates, bytesIn, bytesOut int64, elapsed time.Duration) {
+	seconds := elapsed.Seconds()
+	s.mu.Lock()
+	s.stateRate.add(float64(stateUpdates) / seconds)
+	s.bytesIn.add(float64(bytesIn) / seconds)
+	s.bytesOut.add(float64(bytesOut) / seconds)
+	s.mu.Unlock()
+}
+
+func (s *Stats) RecordDecodeError() {
+	s.mu.Lock()
+	s.decodeErrors++
+	s.mu.Unlock()
+}
+
+// RecordDisconnect counts why a bot's session ended
+func (s *Stats) RecordDisconnect(reason string) {
+	s.mu.Lock()
+	s.disconnects[reason]++
+	s.mu.Unlock()
+}
+
+// WriteWindow writes a one-line summary of the current report window and
+// starts a new one
+func (s *Stats) WriteWindow(w io.Writer, elapsed time.Duration, connected, total int) {
+	s.mu.Lock()
+	rtt := summarize(s.rtt.window)
+	confirm := summarize(s.confirm.window)
+	state := summarize(s.stateRate.window)
+	in := summarize(s.bytesIn.window)
+	out := summarize(s.bytesOut.window)
+	for _, series := range []*series{&s.rtt, &s.confirm, &s.join, &s.stateRate, &s.bytesIn, &s.bytesOut} {
+		series.window = series.window[:0]
+	}
+	s.mu.Unlock()
+
+	fmt.Fprintf(w, "[LoadBot] %6s bots=%d/%d rtt p50=%.1fms p99=%.1fms confirm p50=%.1fms p99=%.1fms state p50=%.1fHz in p50=%s/s out p50=%s/s\n",
+		elapsed.Truncate(time.Second), connected, total,
+		rtt.P50, rtt.P99, confirm.P50, confirm.P99, state.P50, formatBytes(in.P50), formatBytes(out.P50))
+}
+
+// WriteReport writes the distributions over the whole run
+func (s *Stats) WriteReport(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(w, "\n%-22s %8s %10s %10s %10s %10s\n", "metric", "samples", "p50", "p90", "p99", "max")
+	writeRow := func(name string, samples []float64, format func(float64) string) {
+		sum := summarize(samples)
+		fmt.Fprintf(w, "%-22s %8d %10s %10s %10s %10s\n", name, sum.Count,
+			format(sum.P50), format(sum.P90), format(sum.P99), format(sum.Max))
+	}
+	ms := func(v float64) string { return fmt.Sprintf("%.1fms", v) }
+	hz := func(v float64) string { return fmt.Sprintf("%.1fHz", v) }
+	rate := func(v float64) string { return formatBytes(v) + "/s" }
+
+	writeRow("heartbeat rtt", s.rtt.total, ms)
+	writeRow("input confirm latency", s.confirm.total, ms)
+	writeRow("join time", s.join.total, ms)
+	writeRow("state update rate", s.stateRate.total, hz)
+	writeRow("bytes in per bot", s.bytesIn.total, rate)
+	writeRow("bytes out per bot", s.bytesOut.total, rate)
+
+	if s.decodeErrors > 0 {
+		fmt.Fprintf(w, "\nundecodable packets: %d\n", s.decodeErrors)
+	}
+
+	fmt.Fprintf(w, "\n%-30s %8s\n", "disconnect reason", "bots")
+	reasons := make([]string, 0, len(s.disconnects))
+	for reason := range s.disconnects {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(w, "%-30s %8d\n", reason, s.disconnects[reason])
+	}
+}
+
+func formatBytes(v float64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", v), ".0") + units[i]
+}
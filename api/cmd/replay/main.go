@@ -0,0 +1,51 @@
+// Command replay inspects packet recordings and replays them against a game
+// server.
+//
+// Usage:
+//
+//	replay info session.orec
+//	replay timeline [-type STATE_UPDATE,GAME_EVENT] [-entity 42] [-conn 3] [-from 10s] [-to 20s] session.orec
+//	replay send -server ws://localhost:9001 [-conn 3] [-speed 2] [-token <jwt>] session.orec
+//
+// Recordings are written by the -record flags of cmd/loadbot and cmd/wsproxy,
+// and by anything else using pkg/recording.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `usage: replay <command> [flags] <recording>
+
+commands:
+  info      summarize connections and packet counts
+  timeline  print decoded packets in order
+  send      re-send client traffic to a game server
+
+Run "replay <command> -h" for the command's flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "info":
+		err = runInfo(os.Args[2:])
+	case "timeline":
+		err = runTimeline(os.Args[2:])
+	case "send":
+		err = runSend(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Replay] %v\n", err)
+		os.Exit(1)
+	}
+}
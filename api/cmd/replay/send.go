@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/omega-realm/api/pkg/protocol"
+	"github.com/omega-realm/api/pkg/recording"
+)
+
+type timedPacket struct {
+	offset time.Duration
+	data   []byte
+}
+
+// replayResult is what happened to one replayed connection
+type replayResult struct {
+	connection uint32
+	sent       int
+	received   map[protocol.Type]int
+	ended      string
+}
+
+func runSend(args []string) error {
+	flags := flag.NewFlagSet("send", flag.ExitOnError)
+	f := filter{entity: -1, direction: "client"}
+	serverURL := flags.String("server", "", "game server WebSocket URL (required)")
+	flags.IntVar(&f.connection, "conn", -1, "only replay this connection")
+	flags.DurationVar(&f.from, "from", 0, "skip packets before this offset")
+	flags.DurationVar(&f.to, "to", 0, "stop at this offset (0 for the end)")
+	speed := flags.Float64("speed", 1, "playback speed; 2 replays twice as fast")
+	token := flags.String("token", "", "replace the token in recorded CONNECT_AUTH packets, which have usually expired")
+	wait := flags.Duration("wait", 2*time.Second, "how long to keep listening after the last packet")
+	verbose := flags.Bool("v", false, "print every packet the server sends back")
+	recordPath := flags.String("record", "", "record the replayed session to this file")
+	flags.Parse(args)
+	if flags.NArg() != 1 || *serverURL == "" || *speed <= 0 {
+		return errors.New("send needs -server, a positive -speed and one recording")
+	}
+
+	// Client packets grouped by connection, in recorded order
+	streams := make(map[uint32][]timedPacket)
+	first := time.Duration(-1)
+	err := f.each(flags.Arg(0), func(record *recording.Record, p protocol.Packet, decodeErr error) error {
+		if record.Kind != recording.KindPacket {
+			return nil
+		}
+		data := record.Data
+		if auth, ok := p.(*protocol.ConnectAuth); ok && *token != "" {
+			auth.Token = *token
+			encoded, err := protocol.Encode(auth)
+			if err != nil {
+				return err
+			}
+			data = encoded
+		}
+		if first < 0 {
+			first = record.Offset
+		}
+		streams[record.Connection] = append(streams[record.Connection], timedPacket{offset: record.Offset - first, data: data})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 {
+		return errors.New("no client packets to replay")
+	}
+
+	var recorder *recording.Writer
+	if *recordPath != "" {
+		recorder, err = recording.Create(*recordPath)
+		if err != nil {
+			return err
+		}
+		defer recorder.Close()
+	}
+
+	start := time.Now()
+	results := make(chan *replayResult, len(streams))
+	var wg sync.WaitGroup
+	for connection, packets := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- replayConnection(*serverURL, connection, packets, start, *speed, *wait, *verbose, recorder)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var all []*replayResult
+	for result := range results {
+		all = append(all, result)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].connection < all[j].connection })
+	fmt.Printf("\n%-6s %6s  %-60s %s\n", "conn", "sent", "received", "ended")
+	for _, result := range all {
+		var received []string
+		for t := protocol.TypePlayerInput; t <= protocol.TypeDisconnect; t++ {
+			if n := result.received[t]; n > 0 {
+				received = append(received, fmt.Sprintf("%s=%d", t, n))
+			}
+		}
+		fmt.Printf("%-6d %6d  %-60s %s\n", result.connection, result.sent, strings.Join(received, " "), result.ended)
+	}
+	return nil
+}
+
+// replayConnection sends one connection's packets with their recorded
+// spacing, scaled by speed
+func replayConnection(serverURL string, connection uint32, packets []timedPacket, start time.Time, speed float64, wait time.Duration, verbose bool, recorder *recording.Writer) *replayResult {
+	result := &replayResult{connection: connection, received: make(map[protocol.Type]int)}
+
+	conn, _, err := websocket.DefaultDialer.Dial(serverURL, nil)
+	if err != nil {
+		result.ended = "dial failed: " + err.Error()
+		return result
+	}
+	if recorder != nil {
+		recorder.OpenConnection(connection, time.Now(), fmt.Sprintf("replay of conn %d", connection))
+	}
+
+	var mu sync.Mutex
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				mu.Lock()
+				if result.ended == "" {
+					result.ended = "connection lost: " + err.Error()
+				}
+				mu.Unlock()
+				return
+			}
+			at := time.Now()
+			for len(message) > 0 {
+				packet, rest, err := protocol.Split(message)
+				if err != nil {
+					break
+				}
+				message = rest
+				if recorder != nil {
+					recorder.WritePacket(connection, recording.ServerToClient, at, packet)
+				}
+
+				p, err := protocol.Decode(packet)
+				mu.Lock()
+				if err == nil {
+					result.received[p.Type()]++
+					if bye, ok := p.(*protocol.Disconnect); ok {
+						result.ended = "server: " + bye.Reason.String()
+					}
+				}
+				mu.Unlock()
+				if verbose {
+					record := &recording.Record{Kind: recording.KindPacket, Direction: recording.ServerToClient, Data: packet}
+					fmt.Printf("%10.3fs conn=%-4d %s\n", at.Sub(start).Seconds(), connection, describeRecord(record, p, err))
+				}
+			}
+		}
+	}()
+	// The reader must stop before result is handed back
+	defer func() {
+		conn.Close()
+		<-done
+	}()
+
+	for _, packet := range packets {
+		due := start.Add(time.Duration(float64(packet.offset) / speed))
+		select {
+		case <-done:
+			return result
+		case <-time.After(time.Until(due)):
+		}
+		if err := conn.WriteMessage(websocket.BinaryMessage, packet.data); err != nil {
+			mu.Lock()
+			result.ended = "write failed: " + err.Error()
+			mu.Unlock()
+			return result
+		}
+		if recorder != nil {
+			recorder.WritePacket(connection, recording.ClientToServer, time.Now(), packet.data)
+		}
+		result.sent++
+	}
+
+	select {
+	case <-done:
+	case <-time.After(wait):
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		mu.Lock()
+		if result.ended == "" {
+			result.ended = "completed"
+		}
+		mu.Unlock()
+	}
+	if recorder != nil {
+		mu.Lock()
+		recorder.CloseConnection(connection, time.Now(), result.ended)
+		mu.Unlock()
+	}
+	return result
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omega-realm/api/pkg/protocol"
+	"github.com/omega-realm/api/pkg/recording"
+)
+
+// filter selects records for the timeline and for sending
+type filter struct {
+	types      map[protocol.Type]bool
+	entity     int
+	connection int
+	direction  string
+	from, to   time.Duration
+}
+
+func (f *filter) register(flags *flag.FlagSet) {
+	flags.Func("type", "comma-separated packet types to show, e.g. STATE_UPDATE,GAME_EVENT", func(value string) error {
+		f.types = make(map[protocol.Type]bool)
+		for _, name := range strings.Split(value, ",") {
+			t, ok := protocol.ParseType(strings.TrimSpace(name))
+			if !ok {
+				return fmt.Errorf("unknown packet type %q", name)
+			}
+			f.types[t] = true
+		}
+		return nil
+	})
+	flags.IntVar(&f.entity, "entity", -1, "only packets mentioning this entity ID")
+	flags.IntVar(&f.connection, "conn", -1, "only this connection")
+	flags.StringVar(&f.direction, "dir", "", "only this direction: client or server")
+	flags.DurationVar(&f.from, "from", 0, "skip records before this offset")
+	flags.DurationVar(&f.to, "to", 0, "stop at this offset (0 for the end)")
+}
+
+// matchRecord applies every filter except the entity one
+func (f *filter) matchRecord(record *recording.Record) bool {
+	if f.connection >= 0 && record.Connection != uint32(f.connection) {
+		return false
+	}
+	if record.Kind != recording.KindPacket {
+		return f.types == nil && f.entity < 0
+	}
+	switch f.direction {
+	case "client":
+		if record.Direction != recording.ClientToServer {
+			return false
+		}
+	case "server":
+		if record.Direction != recording.ServerToClient {
+			return false
+		}
+	}
+	if f.types != nil && (len(record.Data) == 0 || !f.types[protocol.Type(record.Data[0])]) {
+		return false
+	}
+	return true
+}
+
+// matchEntity reports whether p mentions the filtered entity. State updates
+// and deltas are trimmed down to it.
+func (f *filter) matchEntity(p protocol.Packet) bool {
+	if f.entity < 0 {
+		return true
+	}
+	id := uint16(f.entity)
+	switch p := p.(type) {
+	case *protocol.StateUpdate:
+		kept := p.Entities[:0]
+		for _, entity := range p.Entities {
+			if entity.EntityID == id {
+				kept = append(kept, entity)
+			}
+		}
+		p.Entities = kept
+		return len(kept) > 0
+	case *protocol.StateDelta:
+		kept := p.Entities[:0]
+		for _, entity := range p.Entities {
+			if entity.EntityID == id {
+				kept = append(kept, entity)
+			}
+		}
+		p.Entities = kept
+		removed := slices.Contains(p.Removed, id)
+		if removed {
+			p.Removed = []uint16{id}
+		} else {
+			p.Removed = p.Removed[:0]
+		}
+		return len(kept) > 0 || removed
+	case *protocol.GameEvent:
+		return p.SourceID == id || p.TargetID == id
+	default:
+		return false
+	}
+}
+
+// each calls fn for every record passing the filter, in order. p is nil for
+// connection records and undecodable packets.
+func (f *filter) each(path string, fn func(record *recording.Record, p protocol.Packet, decodeErr error) error) error {
+	reader, err := recording.Open(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if f.from > 0 {
+		if err := reader.Seek(f.from); err != nil {
+			return err
+		}
+	}
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if f.to > 0 && record.Offset > f.to {
+			return nil
+		}
+		if !f.matchRecord(record) {
+			continue
+		}
+
+		var p protocol.Packet
+		var decodeErr error
+		if record.Kind == recording.KindPacket {
+			p, decodeErr = protocol.Decode(record.Data)
+			if decodeErr == nil && !f.matchEntity(p) {
+				continue
+			}
+			if decodeErr != nil && f.entity >= 0 {
+				continue
+			}
+		}
+		if err := fn(record, p, decodeErr); err != nil {
+			return err
+		}
+	}
+}
+
+func runTimeline(args []string) error {
+	flags := flag.NewFlagSet("timeline", flag.ExitOnError)
+	var f filter
+	f.register(flags)
+	asJSON := flags.Bool("json", false, "print one JSON object per record")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("timeline needs one recording")
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	out.SetEscapeHTML(false)
+	return f.each(flags.Arg(0), func(record *recording.Record, p protocol.Packet, decodeErr error) error {
+		if *asJSON {
+			return out.Encode(jsonRecord(record, p, decodeErr))
+		}
+		fmt.Printf("%10.3fs conn=%-4d %s\n", record.Offset.Seconds(), record.Connection, describeRecord(record, p, decodeErr))
+		return nil
+	})
+}
+
+func jsonRecord(record *recording.Record, p protocol.Packet, decodeErr error) map[string]any {
+	line := map[string]any{
+		"offset_ms":  float64(record.Offset.Microseconds()) / 1000,
+		"connection": record.Connection,
+		"kind":       record.Kind.String(),
+	}
+	switch {
+	case record.Kind != recording.KindPacket:
+		line["detail"] = string(record.Data)
+	case decodeErr != nil:
+		line["direction"] = record.Direction.String()
+		line["error"] = decodeErr.Error()
+		line["bytes"] = record.Data
+	default:
+		line["direction"] = record.Direction.String()
+		line["type"] = p.Type().String()
+		line["packet"] = p
+	}
+	return line
+}
+
+func describeRecord(record *recording.Record, p protocol.Packet, decodeErr error) string {
+	switch {
+	case record.Kind == recording.KindOpen:
+		return "OPEN  " + string(record.Data)
+	case record.Kind == recording.KindClose:
+		return "CLOSE " + string(record.Data)
+	case decodeErr != nil:
+		return fmt.Sprintf("%s undecodable (%v): % x", record.Direction, decodeErr, record.Data)
+	default:
+		return fmt.Sprintf("%s %-14s %s", record.Direction, p.Type(), protocol.Describe(p))
+	}
+}
+
+func runInfo(args []string) error {
+	flags := flag.NewFlagSet("info", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("info needs one recording")
+	}
+
+	reader, err := recording.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	type connectionInfo struct {
+		label, closed  string
+		opened, last   time.Duration
+		packets, bytes [2]int
+	}
+	connections := make(map[uint32]*connectionInfo)
+	types := make(map[string]int)
+	var duration time.Duration
+	undecodable := 0
+
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		duration = record.Offset
+
+		c := connections[record.Connection]
+		if c == nil {
+			c = &connectionInfo{opened: record.Offset}
+			connections[record.Connection] = c
+		}
+		c.last = record.Offset
+		switch record.Kind {
+		case recording.KindOpen:
+			c.label = string(record.Data)
+		case recording.KindClose:
+			c.closed = string(record.Data)
+		case recording.KindPacket:
+			dir := min(int(record.Direction), 1)
+			c.packets[dir]++
+			c.bytes[dir] += len(record.Data)
+			header, err := protocol.ReadHeader(record.Data)
+			if err != nil || !header.Type.Valid() {
+				undecodable++
+				continue
+			}
+			types[record.Direction.String()+" "+header.Type.String()]++
+		}
+	}
+
+	fmt.Printf("started:     %s\n", reader.Start().UTC().Format(time.RFC3339Nano))
+	fmt.Printf("duration:    %s\n", duration)
+	fmt.Printf("connections: %d\n", len(connections))
+	fmt.Printf("index:       %d entries\n\n", len(reader.Index()))
+
+	ids := make([]uint32, 0, len(connections))
+	for id := range connections {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	fmt.Printf("%-6s %-24s %10s %10s %16s %16s  %s\n", "conn", "label", "from", "to", "C->S", "S->C", "closed")
+	for _, id := range ids {
+		c := connections[id]
+		fmt.Printf("%-6d %-24s %9.1fs %9.1fs %7d/%7dB %7d/%7dB  %s\n", id, c.label,
+			c.opened.Seconds(), c.last.Seconds(), c.packets[0], c.bytes[0], c.packets[1], c.bytes[1], c.closed)
+	}
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Printf("\n%-24s %8s\n", "packets", "count")
+	for _, name := range names {
+		fmt.Printf("%-24s %8d\n", name, types[name])
+	}
+	if undecodable > 0 {
+		fmt.Printf("%-24s %8d\n", "undecodable", undecodable)
+	}
+	return nil
+}
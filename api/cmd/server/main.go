@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -8,10 +9,16 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/omega-realm/api/internal/chat"
 	"github.com/omega-realm/api/internal/database"
 	"github.com/omega-realm/api/internal/handlers"
+	"github.com/omega-realm/api/internal/matchmaking"
 	"github.com/omega-realm/api/internal/middleware"
+	"github.com/omega-realm/api/internal/namepolicy"
 	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/regions"
+	"github.com/omega-realm/api/internal/websocket"
+	"github.com/omega-realm/api/internal/workers"
 )
 
 func main() {
@@ -58,11 +65,75 @@ func main() {
 
 	log.Println("[API] Redis connected successfully")
 
+	// Load the region registry
+	log.Println("[API] Loading regions...")
+	registry := regions.NewRegistry(db)
+	if err := registry.Load(context.Background()); err != nil {
+		log.Fatalf("[API] Failed to load regions: %v", err)
+	}
+
+	// Load game-server registry configuration
+	gameServerConfig := redisClient.LoadGameServerConfigFromEnv()
+	if gameServerConfig.APIKey == "" {
+		log.Println("[API] GAME_SERVER_API_KEY is not set, game servers will not be able to register")
+	}
+
+	// Load party configuration
+	partyConfig := redisClient.LoadPartyConfigFromEnv()
+
+	// Load the name policy (denylist, reserved names)
+	namePolicy, err := namepolicy.LoadFromEnv(db.NameTaken)
+	if err != nil {
+		log.Fatalf("[API] Failed to load name policy: %v", err)
+	}
+
+	// Start background workers
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Start the real-time event hub (fans out Redis pub/sub to WebSocket clients)
+	events := websocket.NewPublisher(redis)
+	hub := websocket.NewHub(redis, websocket.NewTopicAuthorizer(redis, registry))
+	go hub.Run(ctx)
+
+	workerConfig := workers.LoadConfigFromEnv()
+	accountDeleter := workers.NewAccountDeleter(db, redis, workerConfig.AccountDeletionInterval)
+	go accountDeleter.Run(ctx)
+	characterPurger := workers.NewCharacterPurger(db, workerConfig.CharacterRestoreWindow, workerConfig.CharacterPurgeInterval)
+	go characterPurger.Run(ctx)
+	go registry.Run(ctx, workerConfig.RegionRefreshInterval)
+	queuePromoter := workers.NewQueuePromoter(redis, registry, events, gameServerConfig.SlotReservationTTL, workerConfig.QueueHeartbeatTimeout, workerConfig.QueuePromotionInterval)
+	go queuePromoter.Run(ctx)
+	chatPurger := workers.NewChatPurger(db, workerConfig.ChatHistoryRetention, workerConfig.ChatPurgeInterval)
+	go chatPurger.Run(ctx)
+	sessionReaper := workers.NewSessionReaper(db, redis, workerConfig.SessionReapInterval)
+	go sessionReaper.Run(ctx)
+
+	// Start the PvP matchmaker
+	matchmakingConfig := matchmaking.LoadConfigFromEnv()
+	matchmaker := matchmaking.NewService(db, redis, events, matchmaking.SystemClock{}, matchmakingConfig, gameServerConfig.SlotReservationTTL)
+	go matchmaker.Run(ctx)
+
+	// Chat is sent over HTTP or as a "chat" action on the event stream
+	chatService := chat.NewService(db, redis, namePolicy, events, registry, chat.LoadConfigFromEnv())
+	hub.Handle("chat", chatService.HandleAction)
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db)
-	characterHandler := handlers.NewCharacterHandler(db)
+	authMiddleware := middleware.NewAuth(redis)
+	authHandler := handlers.NewAuthHandler(db, redis, namePolicy, registry)
+	characterHandler := handlers.NewCharacterHandler(db, redis, namePolicy, workerConfig.CharacterRestoreWindow)
 	leaderboardHandler := handlers.NewLeaderboardHandler(db)
-	regionHandler := handlers.NewRegionHandler(redis)
+	regionHandler := handlers.NewRegionHandler(db, redis, registry, gameServerConfig.SlotReservationTTL)
+	accountHandler := handlers.NewAccountHandler(db, workerConfig.AccountDeletionDelay)
+	queueHandler := handlers.NewQueueHandler(redis)
+	partyHandler := handlers.NewPartyHandler(db, redis, partyConfig, events)
+	gameServerHandler := handlers.NewGameServerHandler(db, redis, registry, gameServerConfig)
+	matchmakingHandler := handlers.NewMatchmakingHandler(db, redis, registry, matchmaker, events)
+	friendHandler := handlers.NewFriendHandler(db, redis, events)
+	chatHandler := handlers.NewChatHandler(chatService)
+	moderationHandler := handlers.NewModerationHandler(db, redis, events)
+	reportHandler := handlers.NewReportHandler(db, redis, events)
+	antiCheatHandler := handlers.NewAntiCheatHandler(db)
 
 	// Setup HTTP routes
 	mux := http.NewServeMux()
@@ -76,14 +147,25 @@ func main() {
 		})
 	})
 
+	// Real-time events (WebSocket, authenticated with the access token)
+	mux.HandleFunc("/api/events", hub.ServeWS)
+
 	// Auth routes
 	mux.HandleFunc("/api/auth/register", authHandler.Register)
 	mux.HandleFunc("/api/auth/login", authHandler.Login)
 	mux.HandleFunc("/api/auth/refresh", authHandler.RefreshToken)
 
 	// Character routes (protected with JWT auth)
-	mux.HandleFunc("/api/character/me", middleware.RequireAuth(characterHandler.GetCharacter))
-	mux.HandleFunc("/api/character/create", middleware.RequireAuth(characterHandler.CreateCharacter))
+	mux.HandleFunc("/api/character/me", authMiddleware.RequireAuth(characterHandler.GetCharacter))
+	mux.HandleFunc("/api/character/create", authMiddleware.RequireAuth(characterHandler.CreateCharacter))
+	mux.HandleFunc("/api/character/rename", authMiddleware.RequireAuth(characterHandler.RenameCharacter))
+	mux.HandleFunc("/api/character/delete", authMiddleware.RequireAuth(characterHandler.DeleteCharacter))
+	mux.HandleFunc("/api/character/restore", authMiddleware.RequireAuth(characterHandler.RestoreCharacter))
+
+	// Account routes (protected with JWT auth)
+	mux.HandleFunc("/api/account/export", authMiddleware.RequireAuth(accountHandler.ExportAccount))
+	mux.HandleFunc("/api/account/delete", authMiddleware.RequireAuth(accountHandler.DeleteAccount))
+	mux.HandleFunc("/api/account/delete/cancel", authMiddleware.RequireAuth(accountHandler.CancelAccountDeletion))
 
 	// Leaderboard routes
 	mux.HandleFunc("/api/leaderboard", leaderboardHandler.GetLeaderboard)
@@ -91,7 +173,75 @@ func main() {
 
 	// Region routes
 	mux.HandleFunc("/api/regions", regionHandler.GetRegions)
-	mux.HandleFunc("/api/regions/select", middleware.RequireAuth(regionHandler.SelectRegion))
+	mux.HandleFunc("/api/regions/select", authMiddleware.RequireAuth(regionHandler.SelectRegion))
+
+	// Queue routes (protected with JWT auth)
+	mux.HandleFunc("/api/queue/status", authMiddleware.RequireAuth(queueHandler.GetQueueStatus))
+	mux.HandleFunc("/api/queue/leave", authMiddleware.RequireAuth(queueHandler.LeaveQueue))
+
+	// Party routes (protected with JWT auth)
+	mux.HandleFunc("/api/party", authMiddleware.RequireAuth(partyHandler.GetParty))
+	mux.HandleFunc("/api/party/create", authMiddleware.RequireAuth(partyHandler.CreateParty))
+	mux.HandleFunc("/api/party/invite", authMiddleware.RequireAuth(partyHandler.InvitePlayer))
+	mux.HandleFunc("/api/party/invites", authMiddleware.RequireAuth(partyHandler.GetInvites))
+	mux.HandleFunc("/api/party/accept", authMiddleware.RequireAuth(partyHandler.AcceptInvite))
+	mux.HandleFunc("/api/party/decline", authMiddleware.RequireAuth(partyHandler.DeclineInvite))
+	mux.HandleFunc("/api/party/leave", authMiddleware.RequireAuth(partyHandler.LeaveParty))
+	mux.HandleFunc("/api/party/kick", authMiddleware.RequireAuth(partyHandler.KickMember))
+
+	// Friends and presence routes (protected with JWT auth)
+	mux.HandleFunc("/api/friends", authMiddleware.RequireAuth(friendHandler.GetFriends))
+	mux.HandleFunc("/api/friends/requests", authMiddleware.RequireAuth(friendHandler.GetFriendRequests))
+	mux.HandleFunc("/api/friends/request", authMiddleware.RequireAuth(friendHandler.SendFriendRequest))
+	mux.HandleFunc("/api/friends/accept", authMiddleware.RequireAuth(friendHandler.AcceptFriendRequest))
+	mux.HandleFunc("/api/friends/decline", authMiddleware.RequireAuth(friendHandler.DeclineFriendRequest))
+	mux.HandleFunc("/api/friends/remove", authMiddleware.RequireAuth(friendHandler.RemoveFriend))
+	mux.HandleFunc("/api/friends/block", authMiddleware.RequireAuth(friendHandler.BlockUser))
+	mux.HandleFunc("/api/friends/unblock", authMiddleware.RequireAuth(friendHandler.UnblockUser))
+	mux.HandleFunc("/api/friends/blocked", authMiddleware.RequireAuth(friendHandler.GetBlockedUsers))
+	mux.HandleFunc("/api/presence", authMiddleware.RequireAuth(friendHandler.GetPresence))
+
+	// Chat routes (protected with JWT auth)
+	mux.HandleFunc("/api/chat/send", authMiddleware.RequireAuth(chatHandler.SendChatMessage))
+
+	// Player report routes (protected with JWT auth)
+	mux.HandleFunc("/api/reports", authMiddleware.RequireAuth(reportHandler.FileReport))
+	mux.HandleFunc("/api/reports/mine", authMiddleware.RequireAuth(reportHandler.GetMyReports))
+
+	// PvP matchmaking routes (protected with JWT auth)
+	mux.HandleFunc("/api/matchmaking/join", authMiddleware.RequireAuth(matchmakingHandler.JoinMatchmaking))
+	mux.HandleFunc("/api/matchmaking/status", authMiddleware.RequireAuth(matchmakingHandler.GetMatchmakingStatus))
+	mux.HandleFunc("/api/matchmaking/cancel", authMiddleware.RequireAuth(matchmakingHandler.CancelMatchmaking))
+
+	// Game server routes (protected with the shared server key)
+	mux.HandleFunc("/api/internal/servers/register", middleware.RequireServerKey(gameServerConfig.APIKey, gameServerHandler.RegisterGameServer))
+	mux.HandleFunc("/api/internal/servers/heartbeat", middleware.RequireServerKey(gameServerConfig.APIKey, gameServerHandler.Heartbeat))
+	mux.HandleFunc("/api/internal/servers/deregister", middleware.RequireServerKey(gameServerConfig.APIKey, gameServerHandler.DeregisterGameServer))
+	mux.HandleFunc("/api/internal/servers/claim", middleware.RequireServerKey(gameServerConfig.APIKey, gameServerHandler.ClaimReservation))
+	mux.HandleFunc("/api/internal/servers/verify", middleware.RequireServerKey(gameServerConfig.APIKey, gameServerHandler.VerifyConnection))
+	mux.HandleFunc("/api/internal/sessions/open", middleware.RequireServerKey(gameServerConfig.APIKey, gameServerHandler.OpenSession))
+	mux.HandleFunc("/api/internal/sessions/close", middleware.RequireServerKey(gameServerConfig.APIKey, gameServerHandler.CloseSession))
+	mux.HandleFunc("/api/internal/matches/kill", middleware.RequireServerKey(gameServerConfig.APIKey, matchmakingHandler.RecordKill))
+	mux.HandleFunc("/api/internal/anticheat/scores", middleware.RequireServerKey(gameServerConfig.APIKey, antiCheatHandler.ReportScores))
+
+	// Admin routes (protected with JWT auth and admin role)
+	mux.HandleFunc("/api/admin/regions", authMiddleware.RequireAdmin(regionHandler.SaveRegion))
+	mux.HandleFunc("/api/admin/regions/status", authMiddleware.RequireAdmin(regionHandler.SetRegionStatus))
+	mux.HandleFunc("/api/admin/servers", authMiddleware.RequireAdmin(gameServerHandler.ListGameServers))
+	mux.HandleFunc("/api/admin/sessions", authMiddleware.RequireAdmin(gameServerHandler.GetSessionStats))
+
+	// Moderation routes (protected with JWT auth and moderator or admin role)
+	mux.HandleFunc("/api/admin/chat/messages", authMiddleware.RequireModerator(moderationHandler.GetChatMessages))
+	mux.HandleFunc("/api/admin/sanctions", authMiddleware.RequireModerator(moderationHandler.GetSanctions))
+	mux.HandleFunc("/api/admin/sanctions/issue", authMiddleware.RequireModerator(moderationHandler.IssueSanction))
+	mux.HandleFunc("/api/admin/sanctions/revoke", authMiddleware.RequireModerator(moderationHandler.RevokeSanction))
+	mux.HandleFunc("/api/admin/reports", authMiddleware.RequireModerator(reportHandler.ListReports))
+	mux.HandleFunc("/api/admin/reports/detail", authMiddleware.RequireModerator(reportHandler.GetReport))
+	mux.HandleFunc("/api/admin/reports/assign", authMiddleware.RequireModerator(reportHandler.AssignReport))
+	mux.HandleFunc("/api/admin/reports/status", authMiddleware.RequireModerator(reportHandler.UpdateReportStatus))
+	mux.HandleFunc("/api/admin/reports/sanction", authMiddleware.RequireModerator(reportHandler.SanctionFromReport))
+	mux.HandleFunc("/api/admin/anticheat/suspects", authMiddleware.RequireModerator(antiCheatHandler.ListSuspects))
+	mux.HandleFunc("/api/admin/anticheat/suspects/detail", authMiddleware.RequireModerator(antiCheatHandler.GetSuspect))
 
 	// CORS middleware
 	handler := corsMiddleware(mux)
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/omega-realm/api/pkg/protocol"
+	"github.com/omega-realm/api/pkg/recording"
+)
+
+// maxTextLength caps how much of a text frame is logged
+const maxTextLength = 200
+
+// packetLog streams one line per packet, shared by every connection
+type packetLog struct {
+	mu     sync.Mutex
+	out    io.Writer
+	json   *json.Encoder
+	format string
+	types  map[protocol.Type]bool
+}
+
+func newPacketLog(out io.Writer, format string, types map[protocol.Type]bool) *packetLog {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	return &packetLog{out: out, json: encoder, format: format, types: types}
+}
+
+func (l *packetLog) open(at time.Time, connection uint32, label string) {
+	l.event(at, connection, "open", label)
+}
+
+func (l *packetLog) close(at time.Time, connection uint32, reason string) {
+	l.event(at, connection, "close", reason)
+}
+
+func (l *packetLog) event(at time.Time, connection uint32, kind, detail string) {
+	if l.format == "none" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.format == "json" {
+		l.json.Encode(map[string]any{
+			"time":       at.Format(time.RFC3339Nano),
+			"connection": connection,
+			"kind":       kind,
+			"detail":     detail,
+		})
+		return
+	}
+	fmt.Fprintf(l.out, "%s conn=%-4d %-5s %s\n", at.Format("15:04:05.000"), connection, strings.ToUpper(kind), detail)
+}
+
+// frame logs every packet in a frame read at the given time, with the delay
+// it was given or whether it was dropped
+func (l *packetLog) frame(at time.Time, connection uint32, direction recording.Direction, messageType int, data []byte, delay time.Duration, dropped bool) {
+	if l.format == "none" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fate := ""
+	switch {
+	case dropped:
+		fate = " [dropped]"
+	case delay > 0:
+		fate = fmt.Sprintf(" [+%dms]", delay.Milliseconds())
+	}
+
+	if messageType != websocket.BinaryMessage {
+		if l.types != nil {
+			return
+		}
+		text := string(data)
+		if len(text) > maxTextLength {
+			text = text[:maxTextLength] + "..."
+		}
+		if l.format == "json" {
+			l.json.Encode(l.jsonLine(at, connection, direction, delay, dropped, map[string]any{"text": text}))
+			return
+		}
+		fmt.Fprintf(l.out, "%s conn=%-4d %s %-14s %q%s\n", at.Format("15:04:05.000"), connection, direction, "text", text, fate)
+		return
+	}
+
+	// A frame may carry several packets back to back
+	for len(data) > 0 {
+		packet, rest, err := protocol.Split(data)
+		if err != nil {
+			l.undecodable(at, connection, direction, data, err, delay, dropped, fate)
+			return
+		}
+		data = rest
+
+		if l.types != nil && !l.types[protocol.Type(packet[0])] {
+			continue
+		}
+		p, err := protocol.Decode(packet)
+		if err != nil {
+			l.undecodable(at, connection, direction, packet, err, delay, dropped, fate)
+			continue
+		}
+		if l.format == "json" {
+			l.json.Encode(l.jsonLine(at, connection, direction, delay, dropped, map[string]any{
+				"type":   p.Type().String(),
+				"packet": p,
+			}))
+			continue
+		}
+		fmt.Fprintf(l.out, "%s conn=%-4d %s %-14s %s%s\n", at.Format("15:04:05.000"), connection, direction, p.Type(), protocol.Describe(p), fate)
+	}
+}
+
+func (l *packetLog) undecodable(at time.Time, connection uint32, direction recording.Direction, data []byte, err error, delay time.Duration, dropped bool, fate string) {
+	if l.types != nil {
+		return
+	}
+	if l.format == "json" {
+		l.json.Encode(l.jsonLine(at, connection, direction, delay, dropped, map[string]any{
+			"error": err.Error(),
+			"bytes": data,
+		}))
+		return
+	}
+	fmt.Fprintf(l.out, "%s conn=%-4d %s undecodable (%v): % x%s\n", at.Format("15:04:05.000"), connection, direction, err, data, fate)
+}
+
+func (l *packetLog) jsonLine(at time.Time, connection uint32, direction recording.Direction, delay time.Duration, dropped bool, fields map[string]any) map[string]any {
+	fields["time"] = at.Format(time.RFC3339Nano)
+	fields["connection"] = connection
+	fields["direction"] = direction.String()
+	fields["delay_ms"] = float64(delay.Microseconds()) / 1000
+	fields["dropped"] = dropped
+	return fields
+}
+
+func formatSummary(frames, dropped, bytes int64) string {
+	return fmt.Sprintf("%d frames (%d dropped), %d bytes", frames, dropped, bytes)
+}
@@ -0,0 +1,146 @@
+// Command wsproxy sits between a game client and a game server, forwarding
+// WebSocket frames unchanged while logging the packets they carry.
+//
+// Point the client at -listen instead of the game server. Every binary frame
+// is split into packets, decoded with pkg/protocol and printed as one line
+// per packet (-format text) or one JSON object per packet (-format json).
+//
+// -latency, -jitter and -loss impair delivery so client prediction and
+// ACTION_CONFIRM reconciliation can be tested against a server on the same
+// machine. Latency is one-way and applies to each direction. Jitter varies
+// it, but frames are never reordered, as on a real WebSocket. Loss drops
+// whole frames, except ones carrying CONNECT_AUTH or DISCONNECT, which would
+// end the session rather than test it. -impair limits all three to frames
+// sent by the client or by the server.
+//
+// Usage:
+//
+//	go run ./cmd/wsproxy -upstream ws://localhost:9001 -listen :9101
+//	go run ./cmd/wsproxy -upstream ws://localhost:9001 -latency 80ms -jitter 20ms -loss 0.02
+//	go run ./cmd/wsproxy -upstream ws://localhost:9001 -types PLAYER_INPUT,ACTION_CONFIRM -format json
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/omega-realm/api/pkg/protocol"
+	"github.com/omega-realm/api/pkg/recording"
+)
+
+// Config holds the proxy's command line options
+type Config struct {
+	Listen     string
+	Upstream   string
+	Format     string
+	Types      map[protocol.Type]bool
+	Latency    time.Duration
+	Jitter     time.Duration
+	Loss       float64
+	Impair     string
+	RecordPath string
+	Seed       uint64
+}
+
+func main() {
+	config := &Config{}
+	flag.StringVar(&config.Listen, "listen", ":9101", "address clients connect to")
+	flag.StringVar(&config.Upstream, "upstream", "ws://localhost:9001", "game server to forward to")
+	flag.StringVar(&config.Format, "format", "text", "packet log format: text, json or none")
+	flag.Func("types", "comma-separated packet types to log, e.g. PLAYER_INPUT,ACTION_CONFIRM (default all)", func(value string) error {
+		config.Types = make(map[protocol.Type]bool)
+		for _, name := range strings.Split(value, ",") {
+			t, ok := protocol.ParseType(strings.TrimSpace(name))
+			if !ok {
+				return fmt.Errorf("unknown packet type %q", name)
+			}
+			config.Types[t] = true
+		}
+		return nil
+	})
+	flag.DurationVar(&config.Latency, "latency", 0, "one-way delay added to every frame")
+	flag.DurationVar(&config.Jitter, "jitter", 0, "random variation of the delay, up to this much either way")
+	flag.Float64Var(&config.Loss, "loss", 0, "fraction of frames to drop, 0 to 1")
+	flag.StringVar(&config.Impair, "impair", "both", "frames to impair: both, client (sent by the client) or server")
+	flag.StringVar(&config.RecordPath, "record", "", "record delivered packets to this file for cmd/replay")
+	flag.Uint64Var(&config.Seed, "seed", 1, "random seed for jitter and loss")
+	flag.Parse()
+
+	if err := config.validate(); err != nil {
+		logf("%v", err)
+		os.Exit(2)
+	}
+	upstream, err := url.Parse(config.Upstream)
+	if err != nil {
+		logf("Invalid upstream: %v", err)
+		os.Exit(2)
+	}
+
+	var record *recording.Writer
+	if config.RecordPath != "" {
+		record, err = recording.Create(config.RecordPath)
+		if err != nil {
+			logf("Failed to create recording: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	p := newProxy(config, upstream, newPacketLog(os.Stdout, config.Format, config.Types), record)
+	server := &http.Server{Addr: config.Listen, Handler: p}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	logf("Forwarding %s to %s (latency %s, jitter %s, loss %.1f%%, impair %s)",
+		config.Listen, config.Upstream, config.Latency, config.Jitter, config.Loss*100, config.Impair)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logf("Server error: %v", err)
+		os.Exit(1)
+	}
+
+	// Hijacked connections outlive the server, so close them before the
+	// recording is finished
+	p.closeAll()
+	if record != nil {
+		if err := record.Close(); err != nil {
+			logf("Failed to write recording: %v", err)
+		}
+	}
+}
+
+func (c *Config) validate() error {
+	switch c.Format {
+	case "text", "json", "none":
+	default:
+		return fmt.Errorf("unknown -format %q", c.Format)
+	}
+	switch c.Impair {
+	case "both", "client", "server":
+	default:
+		return fmt.Errorf("unknown -impair %q", c.Impair)
+	}
+	if c.Loss < 0 || c.Loss > 1 {
+		return errors.New("-loss must be between 0 and 1")
+	}
+	if c.Latency < 0 || c.Jitter < 0 {
+		return errors.New("-latency and -jitter cannot be negative")
+	}
+	return nil
+}
+
+func logf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "[WSProxy] "+format+"\n", args...)
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/omega-realm/api/pkg/protocol"
+	"github.com/omega-realm/api/pkg/recording"
+)
+
+const (
+	// queueSize bounds the frames in flight per direction; a full queue
+	// stops reading, pushing back on the sender like a full TCP window
+	queueSize = 4096
+
+	// closeTimeout is how long the other side gets to answer a close
+	closeTimeout = 5 * time.Second
+
+	writeTimeout = 10 * time.Second
+)
+
+// proxy accepts client connections and pairs each with one to the upstream
+// game server
+type proxy struct {
+	config   *Config
+	upstream *url.URL
+	log      *packetLog
+	record   *recording.Writer
+	upgrader websocket.Upgrader
+	nextID   atomic.Uint32
+
+	mu       sync.Mutex
+	sessions map[uint32]*session
+}
+
+func newProxy(config *Config, upstream *url.URL, log *packetLog, record *recording.Writer) *proxy {
+	return &proxy{
+		config:   config,
+		upstream: upstream,
+		log:      log,
+		record:   record,
+		upgrader: websocket.Upgrader{
+			// Godot sends no Origin, and this is a local debugging tool
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		sessions: make(map[uint32]*session),
+	}
+}
+
+// upstreamURL keeps the client's path and query, so the proxy is transparent
+// to servers that route on them
+func (p *proxy) upstreamURL(r *http.Request) string {
+	u := *p.upstream
+	if r.URL.Path != "" && r.URL.Path != "/" {
+		u.Path = strings.TrimSuffix(u.Path, "/") + r.URL.Path
+	}
+	if r.URL.RawQuery != "" {
+		u.RawQuery = r.URL.RawQuery
+	}
+	return u.String()
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := p.nextID.Add(1)
+	target := p.upstreamURL(r)
+
+	// Dial first so a client is refused, not upgraded and dropped, when the
+	// server is down
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 10 * time.Second,
+		Subprotocols:     websocket.Subprotocols(r),
+	}
+	server, _, err := dialer.DialContext(r.Context(), target, nil)
+	if err != nil {
+		logf("conn %d: upstream %s: %v", id, target, err)
+		http.Error(w, "Upstream unavailable", http.StatusBadGateway)
+		return
+	}
+
+	header := http.Header{}
+	if server.Subprotocol() != "" {
+		header.Set("Sec-WebSocket-Protocol", server.Subprotocol())
+	}
+	client, err := p.upgrader.Upgrade(w, r, header)
+	if err != nil {
+		server.Close()
+		logf("conn %d: upgrade failed: %v", id, err)
+		return
+	}
+
+	label := r.RemoteAddr + " -> " + target
+	s := &session{id: id, client: client, server: server, done: make(chan struct{})}
+	s.up = p.newLink(s, recording.ClientToServer, client, server, p.config.Impair != "server")
+	s.down = p.newLink(s, recording.ServerToClient, server, client, p.config.Impair != "client")
+
+	p.mu.Lock()
+	p.sessions[id] = s
+	p.mu.Unlock()
+
+	now := time.Now()
+	p.log.open(now, id, label)
+	if p.record != nil {
+		p.record.OpenConnection(id, now, label)
+	}
+	logf("conn %d: %s", id, label)
+
+	reason := s.run()
+
+	now = time.Now()
+	p.log.close(now, id, reason)
+	if p.record != nil {
+		p.record.CloseConnection(id, now, reason)
+	}
+	logf("conn %d closed (%s): C->S %s, S->C %s", id, reason, s.up.summary(), s.down.summary())
+
+	p.mu.Lock()
+	delete(p.sessions, id)
+	p.mu.Unlock()
+}
+
+// closeAll drops every open session
+func (p *proxy) closeAll() {
+	p.mu.Lock()
+	sessions := make([]*session, 0, len(p.sessions))
+	for _, s := range p.sessions {
+		sessions = append(sessions, s)
+	}
+	p.mu.Unlock()
+
+	for _, s := range sessions {
+		s.close()
+		<-s.done
+	}
+}
+
+func (p *proxy) newLink(s *session, direction recording.Direction, from, to *websocket.Conn, impaired bool) *link {
+	l := &link{
+		proxy:     p,
+		session:   s,
+		direction: direction,
+		from:      from,
+		to:        to,
+		queue:     make(chan delivery, queueSize),
+	}
+	if impaired {
+		l.latency = p.config.Latency
+		l.jitter = p.config.Jitter
+		l.loss = p.config.Loss
+		l.rng = rand.New(rand.NewPCG(p.config.Seed, uint64(s.id)<<1|uint64(direction)))
+	}
+	return l
+}
+
+// session is one client connection and its upstream connection
+type session struct {
+	id             uint32
+	client, server *websocket.Conn
+	up, down       *link
+	closeOnce      sync.Once
+	done           chan struct{}
+
+	// reason is why the first side to stop reading stopped
+	endOnce sync.Once
+	reason  string
+}
+
+// run forwards both directions until the connection ends and returns why it
+// ended. Once one side has closed, the other has closeTimeout to follow.
+func (s *session) run() string {
+	defer close(s.done)
+
+	ended := make(chan struct{}, 2)
+	go func() { s.up.run(); ended <- struct{}{} }()
+	go func() { s.down.run(); ended <- struct{}{} }()
+
+	<-ended
+	select {
+	case <-ended:
+	case <-time.After(closeTimeout):
+		s.close()
+		<-ended
+	}
+	s.close()
+	return s.reason
+}
+
+// end records the read error that ended the session, if it is the first
+func (s *session) end(l *link, err error) {
+	s.endOnce.Do(func() {
+		side := "client"
+		if l == s.down {
+			side = "server"
+		}
+		s.reason = side + ": " + err.Error()
+	})
+}
+
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		s.client.Close()
+		s.server.Close()
+	})
+}
+
+// delivery is a frame waiting out its delay
+type delivery struct {
+	messageType int
+	data        []byte
+	at          time.Time
+}
+
+// link forwards frames in one direction, delaying and dropping them as
+// configured
+type link struct {
+	proxy     *proxy
+	session   *session
+	direction recording.Direction
+	from, to  *websocket.Conn
+	queue     chan delivery
+
+	latency, jitter time.Duration
+	loss            float64
+	rng             *rand.Rand
+	last            time.Time
+
+	// readErr is set before queue is closed, so the writer can pass the
+	// close on
+	readErr error
+
+	frames, dropped, bytes atomic.Int64
+}
+
+func (l *link) run() {
+	done := make(chan struct{})
+	go func() {
+		l.writeLoop()
+		close(done)
+	}()
+	l.readLoop()
+	<-done
+}
+
+func (l *link) readLoop() {
+	defer close(l.queue)
+	for {
+		messageType, data, err := l.from.ReadMessage()
+		if err != nil {
+			l.readErr = err
+			l.session.end(l, err)
+			return
+		}
+		now := time.Now()
+		l.frames.Add(1)
+		l.bytes.Add(int64(len(data)))
+
+		dropped := messageType == websocket.BinaryMessage && l.drop(data)
+		at := l.schedule(now)
+		l.proxy.log.frame(now, l.session.id, l.direction, messageType, data, at.Sub(now), dropped)
+		if dropped {
+			l.dropped.Add(1)
+			continue
+		}
+		l.queue <- delivery{messageType: messageType, data: data, at: at}
+	}
+}
+
+// drop decides whether a frame is lost. Frames that open or end the session
+// are always delivered.
+func (l *link) drop(data []byte) bool {
+	if l.loss == 0 || len(data) == 0 {
+		return false
+	}
+	switch protocol.Type(data[0]) {
+	case protocol.TypeConnectAuth, protocol.TypeDisconnect:
+		return false
+	}
+	return l.rng.Float64() < l.loss
+}
+
+// schedule picks when a frame received at now is delivered. A frame is never
+// delivered before the one ahead of it.
+func (l *link) schedule(now time.Time) time.Time {
+	delay := l.latency
+	if l.jitter > 0 {
+		delay += time.Duration((l.rng.Float64()*2 - 1) * float64(l.jitter))
+	}
+	at := now.Add(max(delay, 0))
+	if at.Before(l.last) {
+		at = l.last
+	}
+	l.last = at
+	return at
+}
+
+func (l *link) writeLoop() {
+	failed := false
+	for d := range l.queue {
+		// Keep draining after a failed write so the reader never blocks;
+		// closing the session makes it stop
+		if failed {
+			continue
+		}
+		if wait := time.Until(d.at); wait > 0 {
+			time.Sleep(wait)
+		}
+		l.to.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := l.to.WriteMessage(d.messageType, d.data); err != nil {
+			failed = true
+			l.session.close()
+			continue
+		}
+		if l.proxy.record != nil && d.messageType == websocket.BinaryMessage {
+			l.recordFrame(d.data)
+		}
+	}
+	if failed {
+		return
+	}
+
+	// Pass a close frame on as it was sent, after everything queued before it
+	message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "")
+	var closeErr *websocket.CloseError
+	if errors.As(l.readErr, &closeErr) && closeErr.Code != websocket.CloseNoStatusReceived &&
+		closeErr.Code != websocket.CloseAbnormalClosure {
+		message = websocket.FormatCloseMessage(closeErr.Code, closeErr.Text)
+	}
+	l.to.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeTimeout))
+}
+
+// recordFrame records each packet in a delivered frame
+func (l *link) recordFrame(data []byte) {
+	now := time.Now()
+	for len(data) > 0 {
+		packet, rest, err := protocol.Split(data)
+		if err != nil {
+			l.proxy.record.WritePacket(l.session.id, l.direction, now, data)
+			return
+		}
+		l.proxy.record.WritePacket(l.session.id, l.direction, now, packet)
+		data = rest
+	}
+}
+
+func (l *link) summary() string {
+	return formatSummary(l.frames.Load(), l.dropped.Load(), l.bytes.Load())
+}
@@ -10,13 +10,41 @@
 -- TABLES
 -- ============================================================================
 
+-- Regions table - Game server regions (single source of truth for valid regions)
+CREATE TABLE IF NOT EXISTS regions (
+    id VARCHAR(20) PRIMARY KEY,
+    display_name VARCHAR(50) NOT NULL,
+    websocket_url VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'online' CHECK (status IN ('online', 'offline', 'maintenance')),
+    max_players INTEGER NOT NULL DEFAULT 200 CHECK (max_players > 0),
+    latency_estimate VARCHAR(20) NOT NULL DEFAULT '',
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    min_protocol_version INTEGER NOT NULL DEFAULT 0 CHECK (min_protocol_version >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT region_id_format CHECK (id ~ '^[a-z0-9-]+$')
+);
+
+COMMENT ON TABLE regions IS 'Game server regions, cached in memory by the API and managed via admin endpoints';
+COMMENT ON COLUMN regions.sort_order IS 'Display order; the first region is the registration default';
+COMMENT ON COLUMN regions.min_protocol_version IS 'Oldest client protocol version accepted in CONNECT_AUTH; 0 also accepts unversioned clients';
+
+-- Launch regions
+INSERT INTO regions (id, display_name, websocket_url, max_players, latency_estimate, sort_order) VALUES
+    ('asia', 'Asia', 'ws://asia.omegagame.io:9001', 200, '< 50ms', 1),
+    ('europe', 'Europe', 'ws://europe.omegagame.io:9001', 200, '< 80ms', 2),
+    ('us-west', 'US West', 'ws://us-west.omegagame.io:9001', 200, '< 100ms', 3)
+ON CONFLICT (id) DO NOTHING;
+
 -- Users table - Stores player account information
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     username VARCHAR(50) UNIQUE NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
-    region VARCHAR(20) DEFAULT 'Asia' CHECK (region IN ('Asia', 'Europe', 'US-West')),
+    region VARCHAR(20) DEFAULT 'asia' REFERENCES regions(id),
+    role VARCHAR(20) NOT NULL DEFAULT 'player' CHECK (role IN ('player', 'moderator', 'admin')),
+    name_lookalike TEXT NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 
     CONSTRAINT username_length CHECK (char_length(username) >= 3 AND char_length(username) <= 50),
@@ -24,20 +52,33 @@ CREATE TABLE IF NOT EXISTS users (
 );
 
 COMMENT ON TABLE users IS 'Player account information and authentication data';
-COMMENT ON COLUMN users.region IS 'Preferred game server region: Asia, Europe, or US-West';
+COMMENT ON COLUMN users.region IS 'Preferred game server region (regions.id)';
+COMMENT ON COLUMN users.role IS 'Access level: player, moderator, or admin';
+COMMENT ON COLUMN users.name_lookalike IS 'namepolicy.LookAlikeKey of the username; unique, so look-alike usernames collide';
 
 -- Characters table - Single character slot per player
 CREATE TABLE IF NOT EXISTS characters (
     id SERIAL PRIMARY KEY,
     user_id INTEGER UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     name VARCHAR(50) UNIQUE NOT NULL,
+    name_canonical TEXT NOT NULL,
+    name_lookalike TEXT NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
 
-    CONSTRAINT character_name_length CHECK (char_length(name) >= 3 AND char_length(name) <= 50)
+    -- Must match namepolicy.ValidateCharacterName (lengths are in characters, not bytes)
+    CONSTRAINT character_name_length CHECK (char_length(name) >= 3 AND char_length(name) <= 50),
+    CONSTRAINT character_name_nfkc CHECK (name IS NFKC NORMALIZED),
+    CONSTRAINT character_name_trimmed CHECK (name = btrim(name)),
+    CONSTRAINT character_name_no_control CHECK (name !~ '[[:cntrl:]]')
 );
 
 COMMENT ON TABLE characters IS 'Player characters - limited to one character per user';
 COMMENT ON COLUMN characters.user_id IS 'UNIQUE constraint enforces single character per user';
+COMMENT ON COLUMN characters.name IS 'Display name, stored NFKC normalized';
+COMMENT ON COLUMN characters.name_canonical IS 'NFKC case-folded name; carries the uniqueness guarantee';
+COMMENT ON COLUMN characters.name_lookalike IS 'namepolicy.LookAlikeKey of the name; unique, so look-alike names collide';
+COMMENT ON COLUMN characters.deleted_at IS 'Soft-deletion time; the row (and its name) is purged after the restore window';
 
 -- Leaderboards table - PvP and monster kill statistics
 CREATE TABLE IF NOT EXISTS leaderboards (
@@ -58,15 +99,191 @@ COMMENT ON COLUMN leaderboards.pvp_kills IS 'Number of player kills for PvP lead
 CREATE TABLE IF NOT EXISTS sessions (
     id SERIAL PRIMARY KEY,
     character_id INTEGER NOT NULL REFERENCES characters(id) ON DELETE CASCADE,
-    server_region VARCHAR(20) NOT NULL CHECK (server_region IN ('Asia', 'Europe', 'US-West')),
+    server_region VARCHAR(20) NOT NULL REFERENCES regions(id),
+    instance_id VARCHAR(64),
     started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     ended_at TIMESTAMP,
+    end_reason VARCHAR(30),
 
     CONSTRAINT valid_session_time CHECK (ended_at IS NULL OR ended_at >= started_at)
 );
 
 COMMENT ON TABLE sessions IS 'Game session tracking for analytics and connection management';
+COMMENT ON COLUMN sessions.instance_id IS 'Game server instance the session is on';
 COMMENT ON COLUMN sessions.ended_at IS 'NULL indicates active session';
+COMMENT ON COLUMN sessions.end_reason IS 'Why the session ended: a DisconnectReason name, or orphaned';
+
+-- Audit events table - Security and account lifecycle log
+CREATE TABLE IF NOT EXISTS audit_events (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    event_type VARCHAR(50) NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE audit_events IS 'Append-only log of account and security events';
+COMMENT ON COLUMN audit_events.user_id IS 'NULL once the account has been deleted';
+
+-- Account deletions table - Delayed, cancellable account deletion requests
+CREATE TABLE IF NOT EXISTS account_deletions (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    requested_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    scheduled_for TIMESTAMP NOT NULL,
+
+    CONSTRAINT valid_deletion_time CHECK (scheduled_for >= requested_at)
+);
+
+COMMENT ON TABLE account_deletions IS 'Pending account deletions, executed by the API once scheduled_for passes';
+
+-- Party invites table - Invitations to join a party
+CREATE TABLE IF NOT EXISTS party_invites (
+    id SERIAL PRIMARY KEY,
+    party_id VARCHAR(32) NOT NULL,
+    inviter_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invitee_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    responded_at TIMESTAMP,
+
+    CONSTRAINT valid_invite_status CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled')),
+    CONSTRAINT no_self_invite CHECK (inviter_id <> invitee_id)
+);
+
+COMMENT ON TABLE party_invites IS 'Party invitations; party membership itself is kept in Redis';
+COMMENT ON COLUMN party_invites.party_id IS 'Redis party ID, which disappears when the party disbands';
+
+-- Friendships table - Friend requests and accepted friendships
+CREATE TABLE IF NOT EXISTS friendships (
+    id SERIAL PRIMARY KEY,
+    requester_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    addressee_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP,
+
+    CONSTRAINT valid_friendship_status CHECK (status IN ('pending', 'accepted', 'declined')),
+    CONSTRAINT no_self_friendship CHECK (requester_id <> addressee_id)
+);
+
+COMMENT ON TABLE friendships IS 'Friend requests; an accepted row is a friendship in both directions';
+
+-- User blocks table - Players who blocked each other
+CREATE TABLE IF NOT EXISTS user_blocks (
+    blocker_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (blocker_id, blocked_id),
+    CONSTRAINT no_self_block CHECK (blocker_id <> blocked_id)
+);
+
+COMMENT ON TABLE user_blocks IS 'A block in either direction hides both users from each other';
+
+-- Character ratings table - Hidden PvP matchmaking ratings
+CREATE TABLE IF NOT EXISTS character_ratings (
+    character_id INTEGER PRIMARY KEY REFERENCES characters(id) ON DELETE CASCADE,
+    rating DOUBLE PRECISION NOT NULL DEFAULT 1500,
+    deviation DOUBLE PRECISION NOT NULL DEFAULT 350,
+    games_played INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE character_ratings IS 'Glicko matchmaking ratings updated from PvP kills; never shown to players';
+COMMENT ON COLUMN character_ratings.deviation IS 'Rating uncertainty; shrinks as the character plays more';
+
+-- Reports table - Player reports awaiting moderator triage
+CREATE TABLE IF NOT EXISTS reports (
+    id SERIAL PRIMARY KEY,
+    reporter_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reported_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reported_character_id INTEGER REFERENCES characters(id) ON DELETE SET NULL,
+    reported_character_name VARCHAR(50) NOT NULL,
+    category VARCHAR(20) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    match_id VARCHAR(64),
+    session_id INTEGER REFERENCES sessions(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    assigned_to INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    resolution TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP,
+
+    CONSTRAINT valid_report_category CHECK (category IN ('cheating', 'abusive_name', 'harassment', 'spam', 'other')),
+    CONSTRAINT valid_report_status CHECK (status IN ('open', 'in_review', 'resolved', 'dismissed')),
+    CONSTRAINT no_self_report CHECK (reporter_id <> reported_user_id)
+);
+
+COMMENT ON TABLE reports IS 'Player reports against a character, triaged by moderators';
+COMMENT ON COLUMN reports.reported_character_name IS 'Character name when the report was filed, kept if the character is renamed';
+
+-- Sanctions table - Moderation actions against users
+CREATE TABLE IF NOT EXISTS sanctions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    report_id INTEGER REFERENCES reports(id) ON DELETE SET NULL,
+    issued_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    revoked_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+
+    CONSTRAINT valid_sanction_type CHECK (type IN ('mute', 'ban'))
+);
+
+COMMENT ON TABLE sanctions IS 'Mutes and bans; active until expires_at unless revoked';
+COMMENT ON COLUMN sanctions.report_id IS 'The player report the sanction was issued from, if any';
+COMMENT ON COLUMN sanctions.expires_at IS 'NULL for a permanent sanction';
+
+-- Chat messages table - Recent chat history for moderation review
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id BIGSERIAL PRIMARY KEY,
+    sender_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL,
+    channel_key VARCHAR(32),
+    recipient_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    original_message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_chat_channel CHECK (channel IN ('global', 'region', 'party', 'whisper'))
+);
+
+COMMENT ON TABLE chat_messages IS 'Chat history for moderation review; purged after CHAT_HISTORY_DAYS';
+COMMENT ON COLUMN chat_messages.channel_key IS 'Region ID for region chat, party ID for party chat';
+COMMENT ON COLUMN chat_messages.original_message IS 'Text as typed, stored only when the profanity filter changed it';
+
+-- Suspect scores table - Anti-cheat scores per analysed game connection
+CREATE TABLE IF NOT EXISTS suspect_scores (
+    id SERIAL PRIMARY KEY,
+    connection_id VARCHAR(64) UNIQUE NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    character_id INTEGER REFERENCES characters(id) ON DELETE SET NULL,
+    instance_id VARCHAR(64) NOT NULL DEFAULT '',
+    region VARCHAR(50) NOT NULL DEFAULT '',
+    score INTEGER NOT NULL DEFAULT 0,
+    peak_score INTEGER NOT NULL DEFAULT 0,
+    speed_score INTEGER NOT NULL DEFAULT 0,
+    correction_score INTEGER NOT NULL DEFAULT 0,
+    aim_snap_score INTEGER NOT NULL DEFAULT 0,
+    shot_rate_score INTEGER NOT NULL DEFAULT 0,
+    speed_violations INTEGER NOT NULL DEFAULT 0,
+    corrections INTEGER NOT NULL DEFAULT 0,
+    aim_snaps INTEGER NOT NULL DEFAULT 0,
+    shot_rate_violations INTEGER NOT NULL DEFAULT 0,
+    inputs INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE suspect_scores IS 'Anti-cheat scores reported by gateways, one row per game connection';
+COMMENT ON COLUMN suspect_scores.connection_id IS 'Random ID the gateway gives the connection; repeated reports update the row';
+COMMENT ON COLUMN suspect_scores.score IS 'Combined score (0-100) in the latest report; scores fade as behaviour improves';
+COMMENT ON COLUMN suspect_scores.peak_score IS 'Highest combined score reported for the connection';
 
 -- ============================================================================
 -- INDEXES
@@ -75,13 +292,17 @@ COMMENT ON COLUMN sessions.ended_at IS 'NULL indicates active session';
 -- Users indexes
 CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE UNIQUE INDEX IF NOT EXISTS users_name_lookalike_key ON users(name_lookalike);
 CREATE INDEX IF NOT EXISTS idx_users_region ON users(region);
 CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at DESC);
 
 -- Characters indexes
 CREATE INDEX IF NOT EXISTS idx_characters_user_id ON characters(user_id);
 CREATE INDEX IF NOT EXISTS idx_characters_name ON characters(name);
+CREATE UNIQUE INDEX IF NOT EXISTS characters_name_canonical_key ON characters(name_canonical);
+CREATE UNIQUE INDEX IF NOT EXISTS characters_name_lookalike_key ON characters(name_lookalike);
 CREATE INDEX IF NOT EXISTS idx_characters_created_at ON characters(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_characters_deleted_at ON characters(deleted_at) WHERE deleted_at IS NOT NULL;
 
 -- Leaderboards indexes
 CREATE INDEX IF NOT EXISTS idx_leaderboards_character_id ON leaderboards(character_id);
@@ -94,6 +315,42 @@ CREATE INDEX IF NOT EXISTS idx_sessions_character_id ON sessions(character_id);
 CREATE INDEX IF NOT EXISTS idx_sessions_server_region ON sessions(server_region);
 CREATE INDEX IF NOT EXISTS idx_sessions_started_at ON sessions(started_at DESC);
 CREATE INDEX IF NOT EXISTS idx_sessions_active ON sessions(character_id, ended_at) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_sessions_open_instance ON sessions(instance_id) WHERE ended_at IS NULL;
+
+-- Audit events indexes
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id, created_at DESC);
+
+-- Account deletions indexes
+CREATE INDEX IF NOT EXISTS idx_account_deletions_scheduled_for ON account_deletions(scheduled_for);
+
+-- Party invites indexes
+CREATE INDEX IF NOT EXISTS idx_party_invites_invitee ON party_invites(invitee_id, status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_party_invites_pending ON party_invites(party_id, invitee_id) WHERE status = 'pending';
+
+-- Friendships indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_friendships_pair ON friendships(LEAST(requester_id, addressee_id), GREATEST(requester_id, addressee_id)) WHERE status IN ('pending', 'accepted');
+CREATE INDEX IF NOT EXISTS idx_friendships_addressee ON friendships(addressee_id, status);
+CREATE INDEX IF NOT EXISTS idx_friendships_requester ON friendships(requester_id, status);
+
+-- User blocks indexes
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked ON user_blocks(blocked_id);
+
+-- Reports indexes
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_reports_reported_user ON reports(reported_user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_reports_assigned_to ON reports(assigned_to, status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_pair ON reports(reporter_id, reported_user_id, category) WHERE status IN ('open', 'in_review');
+
+-- Sanctions indexes
+CREATE INDEX IF NOT EXISTS idx_sanctions_user ON sanctions(user_id, type);
+
+-- Chat messages indexes
+CREATE INDEX IF NOT EXISTS idx_chat_messages_created_at ON chat_messages(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_sender ON chat_messages(sender_id, created_at DESC);
+
+-- Suspect scores indexes
+CREATE INDEX IF NOT EXISTS idx_suspect_scores_user ON suspect_scores(user_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_suspect_scores_peak ON suspect_scores(peak_score DESC, updated_at DESC);
 
 -- ============================================================================
 -- TRIGGERS
@@ -152,6 +409,7 @@ SELECT
 FROM leaderboards l
 JOIN characters c ON l.character_id = c.id
 JOIN users u ON c.user_id = u.id
+WHERE c.deleted_at IS NULL
 ORDER BY l.pvp_kills DESC, l.updated_at DESC
 LIMIT 100;
 
@@ -164,6 +422,7 @@ SELECT
     c.name AS character_name,
     u.username,
     s.server_region,
+    s.instance_id,
     s.started_at,
     EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - s.started_at)) / 60 AS duration_minutes
 FROM sessions s
@@ -188,7 +447,7 @@ COMMENT ON VIEW v_active_sessions IS 'Currently active game sessions';
 -- WHERE c.user_id = $1;
 
 -- Get active sessions by region
--- SELECT * FROM v_active_sessions WHERE server_region = 'Asia';
+-- SELECT * FROM v_active_sessions WHERE server_region = 'asia';
 
 -- Update player kills (after a kill event)
 -- UPDATE leaderboards
@@ -197,7 +456,7 @@ COMMENT ON VIEW v_active_sessions IS 'Currently active game sessions';
 
 -- End a session
 -- UPDATE sessions
--- SET ended_at = CURRENT_TIMESTAMP
+-- SET ended_at = CURRENT_TIMESTAMP, end_reason = 'user_quit'
 -- WHERE id = $1 AND ended_at IS NULL;
 
 -- ============================================================================
@@ -17,22 +17,31 @@ var (
 	RefreshTokenDuration = 7 * 24 * time.Hour   // Refresh token valid for 7 days
 )
 
+// User roles
+const (
+	RolePlayer    = "player"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 // CustomClaims represents the JWT claims structure
 type CustomClaims struct {
 	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Region   string `json:"region"`
+	Role     string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // GenerateAccessToken creates a new access token for a user
-func GenerateAccessToken(userID int, username, email, region string) (string, error) {
+func GenerateAccessToken(userID int, username, email, region, role string) (string, error) {
 	claims := CustomClaims{
 		UserID:   userID,
 		Username: username,
 		Email:    email,
 		Region:   region,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
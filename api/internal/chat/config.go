@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config holds chat configuration
+type Config struct {
+	MaxLength  int
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// LoadConfigFromEnv loads chat configuration from environment variables
+func LoadConfigFromEnv() *Config {
+	return &Config{
+		MaxLength:  getEnvAsInt("CHAT_MAX_LENGTH", 200),
+		RateLimit:  getEnvAsInt("CHAT_RATE_LIMIT", 5),
+		RateWindow: getEnvAsDuration("CHAT_RATE_WINDOW", 10*time.Second),
+	}
+}
+
+func getEnvAsInt(key string, defaultValue int) int {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	// A length or rate limit of zero or less would refuse every message
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value <= 0 {
+		log.Printf("[Chat] Invalid integer value for %s: %s, using default: %d", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	// A rate window of zero or less would expire the counter at once and
+	// silently disable the rate limit
+	value, err := time.ParseDuration(valueStr)
+	if err != nil || value <= 0 {
+		log.Printf("[Chat] Invalid duration value for %s: %s, using default: %s", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
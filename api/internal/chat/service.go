@@ -0,0 +1,311 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/omega-realm/api/internal/database"
+	"github.com/omega-realm/api/internal/models"
+	"github.com/omega-realm/api/internal/namepolicy"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/regions"
+	"github.com/omega-realm/api/internal/websocket"
+)
+
+// Chat channels
+const (
+	ChannelGlobal  = "global"
+	ChannelRegion  = "region"
+	ChannelParty   = "party"
+	ChannelWhisper = "whisper"
+)
+
+// Errors returned by Send. Their messages are safe to show to the sender.
+var (
+	ErrInvalidChannel    = errors.New("channel must be global, region, party or whisper")
+	ErrEmptyMessage      = errors.New("message is empty")
+	ErrMessageTooLong    = errors.New("message is too long")
+	ErrInvalidRegion     = errors.New("invalid region")
+	ErrNotInParty        = errors.New("you are not in a party")
+	ErrRecipientNotFound = errors.New("recipient not found")
+	ErrCannotWhisper     = errors.New("you cannot whisper this user")
+	ErrBanned            = errors.New("you are banned")
+)
+
+// MutedError is returned while the sender has an active mute
+type MutedError struct {
+	ExpiresAt *time.Time
+}
+
+func (e *MutedError) Error() string {
+	if e.ExpiresAt == nil {
+		return "you are muted"
+	}
+	return fmt.Sprintf("you are muted until %s", e.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+// RateLimitError is returned when the sender is over the message rate limit
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("you are sending messages too quickly, try again in %ds", int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// SendRequest is a chat message from a player. RegionID defaults to the
+// region the sender is playing in; Recipient is a username and only used for
+// whispers.
+type SendRequest struct {
+	Channel   string `json:"channel"`
+	RegionID  string `json:"region_id,omitempty"`
+	Recipient string `json:"recipient,omitempty"`
+	Message   string `json:"message"`
+}
+
+// Message is a chat message as delivered to clients in a chat_message event
+type Message struct {
+	ID            int64     `json:"id"`
+	Channel       string    `json:"channel"`
+	RegionID      string    `json:"region_id,omitempty"`
+	PartyID       string    `json:"party_id,omitempty"`
+	SenderID      int       `json:"sender_id"`
+	SenderName    string    `json:"sender_name"`
+	RecipientID   int       `json:"recipient_id,omitempty"`
+	RecipientName string    `json:"recipient_name,omitempty"`
+	Text          string    `json:"text"`
+	SentAt        time.Time `json:"sent_at"`
+}
+
+func rateKey(userID int) string {
+	return fmt.Sprintf("chat_rate:%d", userID)
+}
+
+// Service relays chat between players on every API replica. Messages are
+// filtered, stored for moderation and then published through the real-time
+// hub: channel messages to a topic, whispers to both users.
+//
+// Channel messages reach everyone subscribed to the topic; clients hide
+// messages from users they have blocked.
+type Service struct {
+	db       *database.DB
+	redis    *redisClient.Client
+	policy   *namepolicy.Policy
+	events   *websocket.Publisher
+	registry *regions.Registry
+	config   *Config
+}
+
+// NewService creates a chat service
+func NewService(db *database.DB, redis *redisClient.Client, policy *namepolicy.Policy, events *websocket.Publisher, registry *regions.Registry, config *Config) *Service {
+	return &Service{
+		db:       db,
+		redis:    redis,
+		policy:   policy,
+		events:   events,
+		registry: registry,
+		config:   config,
+	}
+}
+
+// Send delivers a chat message from senderID and returns it as delivered
+func (s *Service) Send(ctx context.Context, senderID int, req SendRequest) (*Message, error) {
+	switch req.Channel {
+	case ChannelGlobal, ChannelRegion, ChannelParty, ChannelWhisper:
+	default:
+		return nil, ErrInvalidChannel
+	}
+
+	text := strings.TrimSpace(req.Message)
+	if text == "" {
+		return nil, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(text) > s.config.MaxLength {
+		return nil, ErrMessageTooLong
+	}
+
+	// Bans end the sender's sessions, but a connection opened before the ban
+	// may not have been closed yet
+	ban, err := s.db.ActiveSanction(ctx, senderID, models.SanctionBan)
+	if err != nil {
+		return nil, err
+	}
+	if ban != nil {
+		return nil, ErrBanned
+	}
+
+	mute, err := s.db.ActiveSanction(ctx, senderID, models.SanctionMute)
+	if err != nil {
+		return nil, err
+	}
+	if mute != nil {
+		return nil, &MutedError{ExpiresAt: mute.ExpiresAt}
+	}
+
+	allowed, retryAfter, err := s.redis.AllowRate(ctx, rateKey(senderID), s.config.RateLimit, s.config.RateWindow)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, &RateLimitError{RetryAfter: retryAfter}
+	}
+
+	msg := &Message{Channel: req.Channel, SenderID: senderID}
+	err = s.db.QueryRowContext(ctx, `SELECT username FROM users WHERE id = $1`, senderID).Scan(&msg.SenderName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up chat sender %d: %w", senderID, err)
+	}
+
+	var channelKey string
+	var recipientID *int
+	switch req.Channel {
+	case ChannelRegion:
+		regionID, err := s.resolveRegion(ctx, senderID, req.RegionID)
+		if err != nil {
+			return nil, err
+		}
+		msg.RegionID = regionID
+		channelKey = regionID
+	case ChannelParty:
+		party, err := s.redis.GetUserParty(ctx, senderID)
+		if errors.Is(err, redisClient.ErrNotInParty) {
+			return nil, ErrNotInParty
+		}
+		if err != nil {
+			return nil, err
+		}
+		msg.PartyID = party.ID
+		channelKey = party.ID
+	case ChannelWhisper:
+		if err := s.resolveRecipient(ctx, senderID, req.Recipient, msg); err != nil {
+			return nil, err
+		}
+		recipientID = &msg.RecipientID
+	}
+
+	filtered, censored := s.policy.CensorText(text)
+	msg.Text = filtered
+	var original *string
+	if censored {
+		original = &text
+	}
+
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO chat_messages (sender_id, channel, channel_key, recipient_id, message, original_message)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+		RETURNING id, created_at
+	`, senderID, msg.Channel, channelKey, recipientID, msg.Text, original).Scan(&msg.ID, &msg.SentAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store chat message: %w", err)
+	}
+
+	if err := s.publish(ctx, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// HandleAction is the hub action handler for chat sent over the WebSocket.
+// Internal failures are logged and replaced with a generic error, since the
+// hub shows the error to the sender.
+func (s *Service) HandleAction(ctx context.Context, userID int, data json.RawMessage) error {
+	var req SendRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return errors.New("invalid chat message")
+	}
+
+	_, err := s.Send(ctx, userID, req)
+	if err != nil && !IsUserError(err) {
+		log.Printf("[Chat] %v", err)
+		return errors.New("failed to send message")
+	}
+	return err
+}
+
+// IsUserError reports whether err was caused by the sender's request rather
+// than a failure on our side
+func IsUserError(err error) bool {
+	var muted *MutedError
+	var limited *RateLimitError
+	switch {
+	case errors.As(err, &muted), errors.As(err, &limited):
+		return true
+	case errors.Is(err, ErrInvalidChannel), errors.Is(err, ErrEmptyMessage),
+		errors.Is(err, ErrMessageTooLong), errors.Is(err, ErrInvalidRegion),
+		errors.Is(err, ErrNotInParty), errors.Is(err, ErrRecipientNotFound),
+		errors.Is(err, ErrCannotWhisper), errors.Is(err, ErrBanned):
+		return true
+	default:
+		return false
+	}
+}
+
+// resolveRegion picks the region for region chat: the requested one, or the
+// region the sender is playing in, or their home region
+func (s *Service) resolveRegion(ctx context.Context, senderID int, requested string) (string, error) {
+	regionID := regions.NormalizeRegionID(requested)
+	if regionID == "" {
+		if _, session, err := s.redis.GetUserSession(ctx, senderID); err == nil {
+			regionID = session.ServerRegion
+			if regionID == "" {
+				regionID = session.Region
+			}
+		}
+	}
+	if !s.registry.IsValidRegion(regionID) {
+		return "", ErrInvalidRegion
+	}
+	return regionID, nil
+}
+
+// resolveRecipient fills in the whisper recipient, who must exist and not
+// have a block with the sender in either direction
+func (s *Service) resolveRecipient(ctx context.Context, senderID int, username string, msg *Message) error {
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, username FROM users WHERE username = $1`, username,
+	).Scan(&msg.RecipientID, &msg.RecipientName)
+	if err == sql.ErrNoRows {
+		return ErrRecipientNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up whisper recipient %q: %w", username, err)
+	}
+	if msg.RecipientID == senderID {
+		return ErrCannotWhisper
+	}
+
+	blocked, err := s.db.IsBlocked(ctx, senderID, msg.RecipientID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrCannotWhisper
+	}
+	return nil
+}
+
+// publish hands the message to the hub. Whispers are echoed to the sender so
+// their other connections see the conversation too.
+func (s *Service) publish(ctx context.Context, msg *Message) error {
+	switch msg.Channel {
+	case ChannelGlobal:
+		return s.events.PublishTopic(ctx, websocket.TopicGlobal, websocket.EventChatMessage, msg)
+	case ChannelRegion:
+		return s.events.PublishTopic(ctx, websocket.RegionTopic(msg.RegionID), websocket.EventChatMessage, msg)
+	case ChannelParty:
+		return s.events.PublishTopic(ctx, websocket.PartyTopic(msg.PartyID), websocket.EventChatMessage, msg)
+	default:
+		if err := s.events.PublishUser(ctx, msg.RecipientID, websocket.EventChatMessage, msg); err != nil {
+			return err
+		}
+		return s.events.PublishUser(ctx, msg.SenderID, websocket.EventChatMessage, msg)
+	}
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/omega-realm/api/internal/models"
+)
+
+// Audit event types
+const (
+	AuditUserRegistered           = "user.registered"
+	AuditUserLogin                = "user.login"
+	AuditAccountExported          = "account.exported"
+	AuditAccountDeletionRequested = "account.deletion_requested"
+	AuditAccountDeletionCancelled = "account.deletion_cancelled"
+	AuditAccountDeleted           = "account.deleted"
+	AuditCharacterDeleted         = "character.deleted"
+	AuditCharacterRestored        = "character.restored"
+	AuditCharacterPurged          = "character.purged"
+	AuditRegionSaved              = "region.saved"
+	AuditRegionStatusChanged      = "region.status_changed"
+	AuditSanctionIssued           = "sanction.issued"
+	AuditSanctionRevoked          = "sanction.revoked"
+	AuditReportFiled              = "report.filed"
+	AuditReportUpdated            = "report.updated"
+)
+
+// RecordAuditEvent appends an entry to the audit log. userID may be 0 for
+// events that no longer have an owning account.
+func (db *DB) RecordAuditEvent(ctx context.Context, userID int, eventType string, details map[string]any) error {
+	if details == nil {
+		details = map[string]any{}
+	}
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	query := `
+		INSERT INTO audit_events (user_id, event_type, details)
+		VALUES (NULLIF($1, 0), $2, $3)
+	`
+	if _, err := db.ExecContext(ctx, query, userID, eventType, detailsJSON); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// RecentAuditEvents returns the user's latest audit events, newest first
+func (db *DB) RecentAuditEvents(ctx context.Context, userID, limit int) ([]models.AuditEvent, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, user_id, event_type, details, created_at
+		FROM audit_events WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		if err := rows.Scan(&event.ID, &event.UserID, &event.EventType, &event.Details, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// IsBlocked reports whether either user has blocked the other
+func (db *DB) IsBlocked(ctx context.Context, userID, otherID int) (bool, error) {
+	var blocked bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1)
+		)
+	`, userID, otherID).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check blocks: %w", err)
+	}
+	return blocked, nil
+}
+
+// BlockedAmong returns which of userIDs have blocked, or been blocked by, userID
+func (db *DB) BlockedAmong(ctx context.Context, userID int, userIDs []int) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT blocked_id FROM user_blocks WHERE blocker_id = $1 AND blocked_id = ANY($2)
+		UNION
+		SELECT blocker_id FROM user_blocks WHERE blocked_id = $1 AND blocker_id = ANY($2)
+	`, userID, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check blocks: %w", err)
+	}
+	defer rows.Close()
+
+	blocked := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan block: %w", err)
+		}
+		blocked[id] = true
+	}
+	return blocked, rows.Err()
+}
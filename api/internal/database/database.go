@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -112,13 +113,35 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 // InitSchema creates database tables if they don't exist
 func (db *DB) InitSchema() error {
 	schema := `
+	-- Regions table (single source of truth for valid regions)
+	CREATE TABLE IF NOT EXISTS regions (
+		id VARCHAR(20) PRIMARY KEY,
+		display_name VARCHAR(50) NOT NULL,
+		websocket_url VARCHAR(255) NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'online' CHECK (status IN ('online', 'offline', 'maintenance')),
+		max_players INTEGER NOT NULL DEFAULT 200 CHECK (max_players > 0),
+		latency_estimate VARCHAR(20) NOT NULL DEFAULT '',
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		min_protocol_version INTEGER NOT NULL DEFAULT 0 CHECK (min_protocol_version >= 0),
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Seed the launch regions; admins manage them from then on
+	INSERT INTO regions (id, display_name, websocket_url, max_players, latency_estimate, sort_order) VALUES
+		('asia', 'Asia', 'ws://asia.omegagame.io:9001', 200, '< 50ms', 1),
+		('europe', 'Europe', 'ws://europe.omegagame.io:9001', 200, '< 80ms', 2),
+		('us-west', 'US West', 'ws://us-west.omegagame.io:9001', 200, '< 100ms', 3)
+	ON CONFLICT (id) DO NOTHING;
+
 	-- Users table
 	CREATE TABLE IF NOT EXISTS users (
 		id SERIAL PRIMARY KEY,
 		username VARCHAR(50) UNIQUE NOT NULL,
 		email VARCHAR(255) UNIQUE NOT NULL,
 		password_hash VARCHAR(255) NOT NULL,
-		region VARCHAR(20) DEFAULT 'Asia',
+		region VARCHAR(20) DEFAULT 'asia' REFERENCES regions(id),
+		role VARCHAR(20) NOT NULL DEFAULT 'player' CHECK (role IN ('player', 'moderator', 'admin')),
+		name_lookalike TEXT,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
 
@@ -127,7 +150,10 @@ func (db *DB) InitSchema() error {
 		id SERIAL PRIMARY KEY,
 		user_id INTEGER UNIQUE REFERENCES users(id) ON DELETE CASCADE,
 		name VARCHAR(50) UNIQUE NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		name_canonical TEXT,
+		name_lookalike TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		deleted_at TIMESTAMP
 	);
 
 	-- Leaderboards table
@@ -144,9 +170,139 @@ func (db *DB) InitSchema() error {
 	CREATE TABLE IF NOT EXISTS sessions (
 		id SERIAL PRIMARY KEY,
 		character_id INTEGER REFERENCES characters(id) ON DELETE CASCADE,
-		server_region VARCHAR(20),
+		server_region VARCHAR(20) REFERENCES regions(id),
+		instance_id VARCHAR(64),
 		started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		ended_at TIMESTAMP
+		ended_at TIMESTAMP,
+		end_reason VARCHAR(30)
+	);
+
+	-- Audit events table
+	CREATE TABLE IF NOT EXISTS audit_events (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		event_type VARCHAR(50) NOT NULL,
+		details JSONB NOT NULL DEFAULT '{}',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Pending account deletions (one per user, cancellable until scheduled_for)
+	CREATE TABLE IF NOT EXISTS account_deletions (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		requested_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		scheduled_for TIMESTAMP NOT NULL
+	);
+
+	-- Party invites (party membership itself lives in Redis)
+	CREATE TABLE IF NOT EXISTS party_invites (
+		id SERIAL PRIMARY KEY,
+		party_id VARCHAR(32) NOT NULL,
+		inviter_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		invitee_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled')),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		responded_at TIMESTAMP,
+		CONSTRAINT no_self_invite CHECK (inviter_id <> invitee_id)
+	);
+
+	-- Friend requests and friendships (one live row per pair of users)
+	CREATE TABLE IF NOT EXISTS friendships (
+		id SERIAL PRIMARY KEY,
+		requester_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		addressee_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined')),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		responded_at TIMESTAMP,
+		CONSTRAINT no_self_friendship CHECK (requester_id <> addressee_id)
+	);
+
+	-- User blocks (one-directional; either direction hides presence both ways)
+	CREATE TABLE IF NOT EXISTS user_blocks (
+		blocker_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		blocked_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (blocker_id, blocked_id),
+		CONSTRAINT no_self_block CHECK (blocker_id <> blocked_id)
+	);
+
+	-- Hidden PvP matchmaking ratings (Glicko), one row per character once they have a recorded kill
+	CREATE TABLE IF NOT EXISTS character_ratings (
+		character_id INTEGER PRIMARY KEY REFERENCES characters(id) ON DELETE CASCADE,
+		rating DOUBLE PRECISION NOT NULL DEFAULT 1500,
+		deviation DOUBLE PRECISION NOT NULL DEFAULT 350,
+		games_played INTEGER NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Player reports awaiting moderator triage
+	CREATE TABLE IF NOT EXISTS reports (
+		id SERIAL PRIMARY KEY,
+		reporter_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		reported_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		reported_character_id INTEGER REFERENCES characters(id) ON DELETE SET NULL,
+		reported_character_name VARCHAR(50) NOT NULL,
+		category VARCHAR(20) NOT NULL CHECK (category IN ('cheating', 'abusive_name', 'harassment', 'spam', 'other')),
+		description TEXT NOT NULL DEFAULT '',
+		match_id VARCHAR(64),
+		session_id INTEGER REFERENCES sessions(id) ON DELETE SET NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'in_review', 'resolved', 'dismissed')),
+		assigned_to INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		resolution TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		resolved_at TIMESTAMP,
+		CONSTRAINT no_self_report CHECK (reporter_id <> reported_user_id)
+	);
+
+	-- Moderation sanctions (expires_at NULL means permanent)
+	CREATE TABLE IF NOT EXISTS sanctions (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		type VARCHAR(20) NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		report_id INTEGER REFERENCES reports(id) ON DELETE SET NULL,
+		issued_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP,
+		revoked_at TIMESTAMP,
+		revoked_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		CONSTRAINT valid_sanction_type CHECK (type IN ('mute', 'ban'))
+	);
+
+	-- Recent chat history, kept for moderation review
+	CREATE TABLE IF NOT EXISTS chat_messages (
+		id BIGSERIAL PRIMARY KEY,
+		sender_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		channel VARCHAR(20) NOT NULL CHECK (channel IN ('global', 'region', 'party', 'whisper')),
+		channel_key VARCHAR(32),
+		recipient_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		message TEXT NOT NULL,
+		original_message TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Anti-cheat suspect scores, one row per analysed game connection
+	CREATE TABLE IF NOT EXISTS suspect_scores (
+		id SERIAL PRIMARY KEY,
+		connection_id VARCHAR(64) UNIQUE NOT NULL,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		character_id INTEGER REFERENCES characters(id) ON DELETE SET NULL,
+		instance_id VARCHAR(64) NOT NULL DEFAULT '',
+		region VARCHAR(50) NOT NULL DEFAULT '',
+		score INTEGER NOT NULL DEFAULT 0,
+		peak_score INTEGER NOT NULL DEFAULT 0,
+		speed_score INTEGER NOT NULL DEFAULT 0,
+		correction_score INTEGER NOT NULL DEFAULT 0,
+		aim_snap_score INTEGER NOT NULL DEFAULT 0,
+		shot_rate_score INTEGER NOT NULL DEFAULT 0,
+		speed_violations INTEGER NOT NULL DEFAULT 0,
+		corrections INTEGER NOT NULL DEFAULT 0,
+		aim_snaps INTEGER NOT NULL DEFAULT 0,
+		shot_rate_violations INTEGER NOT NULL DEFAULT 0,
+		inputs INTEGER NOT NULL DEFAULT 0,
+		started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
 
 	-- Create indexes for performance
@@ -158,6 +314,25 @@ func (db *DB) InitSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_leaderboards_pvp_kills ON leaderboards(pvp_kills DESC);
 	CREATE INDEX IF NOT EXISTS idx_sessions_character_id ON sessions(character_id);
 	CREATE INDEX IF NOT EXISTS idx_sessions_started_at ON sessions(started_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_sessions_active ON sessions(character_id, ended_at) WHERE ended_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_sessions_open_instance ON sessions(instance_id) WHERE ended_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id, created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_account_deletions_scheduled_for ON account_deletions(scheduled_for);
+	CREATE INDEX IF NOT EXISTS idx_party_invites_invitee ON party_invites(invitee_id, status);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_party_invites_pending ON party_invites(party_id, invitee_id) WHERE status = 'pending';
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_friendships_pair ON friendships(LEAST(requester_id, addressee_id), GREATEST(requester_id, addressee_id)) WHERE status IN ('pending', 'accepted');
+	CREATE INDEX IF NOT EXISTS idx_friendships_addressee ON friendships(addressee_id, status);
+	CREATE INDEX IF NOT EXISTS idx_friendships_requester ON friendships(requester_id, status);
+	CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked ON user_blocks(blocked_id);
+	CREATE INDEX IF NOT EXISTS idx_sanctions_user ON sanctions(user_id, type);
+	CREATE INDEX IF NOT EXISTS idx_reports_status ON reports(status, created_at);
+	CREATE INDEX IF NOT EXISTS idx_reports_reported_user ON reports(reported_user_id, created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_reports_assigned_to ON reports(assigned_to, status);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_pair ON reports(reporter_id, reported_user_id, category) WHERE status IN ('open', 'in_review');
+	CREATE INDEX IF NOT EXISTS idx_chat_messages_created_at ON chat_messages(created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_chat_messages_sender ON chat_messages(sender_id, created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_suspect_scores_user ON suspect_scores(user_id, updated_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_suspect_scores_peak ON suspect_scores(peak_score DESC, updated_at DESC);
 	`
 
 	_, err := db.Exec(schema)
@@ -165,6 +340,11 @@ func (db *DB) InitSchema() error {
 		return fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	// Bring databases created by earlier versions up to date
+	if err := db.migrateSchema(); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
 	// Initialize triggers and functions
 	if err := db.initTriggers(); err != nil {
 		return fmt.Errorf("failed to initialize triggers: %w", err)
@@ -174,6 +354,104 @@ func (db *DB) InitSchema() error {
 	return nil
 }
 
+// migrateSchema applies idempotent changes to tables that already exist
+func (db *DB) migrateSchema() error {
+	migrations := `
+	-- Canonical (NFKC + case folded) character names carry the unique index.
+	-- They are filled in from Go by backfillNames; case folding can expand a
+	-- name, so the column is unbounded.
+	ALTER TABLE characters ADD COLUMN IF NOT EXISTS name_canonical TEXT;
+	ALTER TABLE characters ALTER COLUMN name_canonical TYPE TEXT;
+
+	-- Soft deletion of characters
+	ALTER TABLE characters ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+	CREATE INDEX IF NOT EXISTS idx_characters_deleted_at ON characters(deleted_at) WHERE deleted_at IS NOT NULL;
+
+	-- Regions moved from hard-coded CHECK lists to the regions table, keyed
+	-- by lowercase region ID
+	ALTER TABLE users DROP CONSTRAINT IF EXISTS users_region_check;
+	ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_server_region_check;
+	UPDATE users SET region = LOWER(region) WHERE region <> LOWER(region);
+	UPDATE sessions SET server_region = LOWER(server_region) WHERE server_region <> LOWER(server_region);
+	ALTER TABLE users ALTER COLUMN region SET DEFAULT 'asia';
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_region_fkey') THEN
+			ALTER TABLE users ADD CONSTRAINT users_region_fkey FOREIGN KEY (region) REFERENCES regions(id);
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'sessions_server_region_fkey') THEN
+			ALTER TABLE sessions ADD CONSTRAINT sessions_server_region_fkey FOREIGN KEY (server_region) REFERENCES regions(id);
+		END IF;
+	END $$;
+
+	-- User roles for admin endpoints
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'player';
+
+	-- Constraints database/schema.sql declares that older tables lack
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_role_check') THEN
+			ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('player', 'moderator', 'admin'));
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'region_id_format') THEN
+			ALTER TABLE regions ADD CONSTRAINT region_id_format CHECK (id ~ '^[a-z0-9-]+$');
+		END IF;
+	END $$;
+
+	-- Bans, and sanctions issued from player reports
+	ALTER TABLE sanctions DROP CONSTRAINT IF EXISTS valid_sanction_type;
+	ALTER TABLE sanctions ADD CONSTRAINT valid_sanction_type CHECK (type IN ('mute', 'ban'));
+	ALTER TABLE sanctions ADD COLUMN IF NOT EXISTS report_id INTEGER REFERENCES reports(id) ON DELETE SET NULL;
+
+	-- Sessions are opened and closed by game servers
+	ALTER TABLE sessions ADD COLUMN IF NOT EXISTS instance_id VARCHAR(64);
+	ALTER TABLE sessions ADD COLUMN IF NOT EXISTS end_reason VARCHAR(30);
+
+	-- Oldest client protocol version each region accepts
+	ALTER TABLE regions ADD COLUMN IF NOT EXISTS min_protocol_version INTEGER NOT NULL DEFAULT 0 CHECK (min_protocol_version >= 0);
+
+	-- Look-alike keys of names, also filled in from Go by backfillNames
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS name_lookalike TEXT;
+	ALTER TABLE characters ADD COLUMN IF NOT EXISTS name_lookalike TEXT;
+	`
+	if _, err := db.Exec(migrations); err != nil {
+		return err
+	}
+
+	if err := db.backfillNames(context.Background()); err != nil {
+		return err
+	}
+
+	// Constraints that hold only once every row has been backfilled
+	constraints := `
+	ALTER TABLE users ALTER COLUMN name_lookalike SET NOT NULL;
+	ALTER TABLE characters ALTER COLUMN name_canonical SET NOT NULL;
+	ALTER TABLE characters ALTER COLUMN name_lookalike SET NOT NULL;
+	CREATE UNIQUE INDEX IF NOT EXISTS users_name_lookalike_key ON users(name_lookalike);
+	CREATE UNIQUE INDEX IF NOT EXISTS characters_name_canonical_key ON characters(name_canonical);
+	CREATE UNIQUE INDEX IF NOT EXISTS characters_name_lookalike_key ON characters(name_lookalike);
+
+	-- Must match namepolicy.ValidateCharacterName, as in database/schema.sql
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'character_name_length') THEN
+			ALTER TABLE characters ADD CONSTRAINT character_name_length CHECK (char_length(name) >= 3 AND char_length(name) <= 50);
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'character_name_nfkc') THEN
+			ALTER TABLE characters ADD CONSTRAINT character_name_nfkc CHECK (name IS NFKC NORMALIZED);
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'character_name_trimmed') THEN
+			ALTER TABLE characters ADD CONSTRAINT character_name_trimmed CHECK (name = btrim(name));
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'character_name_no_control') THEN
+			ALTER TABLE characters ADD CONSTRAINT character_name_no_control CHECK (name !~ '[[:cntrl:]]');
+		END IF;
+	END $$;
+	`
+	_, err := db.Exec(constraints)
+	return err
+}
+
 // initTriggers creates database triggers for automation
 func (db *DB) initTriggers() error {
 	triggers := `
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/omega-realm/api/internal/namepolicy"
+)
+
+// NameTaken reports whether a username or character name is already in use
+// by a look-alike name. Names are compared by their namepolicy look-alike
+// key, so case, accents and letters from other scripts that look like Latin
+// ones are ignored, and soft-deleted
+// characters keep their name reserved until they are purged. Rows owned by
+// ownerID are skipped so a user can change the capitalization of their own
+// name. It satisfies namepolicy.Lookup.
+func (db *DB) NameTaken(ctx context.Context, kind namepolicy.Kind, name string, ownerID int) (bool, error) {
+	var query string
+	switch kind {
+	case namepolicy.KindUsername:
+		query = `SELECT EXISTS(SELECT 1 FROM users WHERE name_lookalike = $1 AND id <> $2)`
+	case namepolicy.KindCharacter:
+		query = `SELECT EXISTS(SELECT 1 FROM characters WHERE name_lookalike = $1 AND user_id <> $2)`
+	default:
+		return false, fmt.Errorf("unknown name kind: %s", kind)
+	}
+
+	var taken bool
+	if err := db.QueryRowContext(ctx, query, namepolicy.LookAlikeKey(name), ownerID).Scan(&taken); err != nil {
+		return false, err
+	}
+	return taken, nil
+}
+
+// backfillNames fills in the look-alike keys of usernames, and the canonical
+// forms and look-alike keys of character names, stored before those columns existed or
+// before they were computed in Go, with the same functions new names go
+// through. Where existing names collide, the oldest keeps its key and the
+// others get theirs suffixed with their ID, which no name produces: they keep
+// working, and the oldest still blocks new look-alikes.
+func (db *DB) backfillNames(ctx context.Context) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start name backfill: %w", err)
+	}
+	defer tx.Rollback()
+
+	users, err := pendingNames(ctx, tx, `SELECT id, username FROM users WHERE name_lookalike IS NULL ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		lookalike, err := uniqueKey(ctx, tx, "users", "name_lookalike", namepolicy.LookAlikeKey(user.name), user.id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET name_lookalike = $2 WHERE id = $1`, user.id, lookalike); err != nil {
+			return fmt.Errorf("failed to backfill username look-alike key: %w", err)
+		}
+	}
+
+	// Characters without a look-alike key may have a canonical name from the SQL
+	// backfill, which lower-cased rather than case folded, so both are
+	// recomputed. Their old canonical names are cleared first so that they
+	// cannot collide with the recomputed ones.
+	characters, err := pendingNames(ctx, tx, `SELECT id, name FROM characters WHERE name_lookalike IS NULL ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	if len(characters) > 0 {
+		_, err := tx.ExecContext(ctx, `
+			ALTER TABLE characters ALTER COLUMN name_canonical DROP NOT NULL;
+			DROP INDEX IF EXISTS characters_name_canonical_key;
+			UPDATE characters SET name_canonical = NULL WHERE name_lookalike IS NULL;
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare character name backfill: %w", err)
+		}
+	}
+	for _, character := range characters {
+		name, err := storableName(ctx, tx, character)
+		if err != nil {
+			return err
+		}
+		canonical, err := uniqueKey(ctx, tx, "characters", "name_canonical", namepolicy.CanonicalName(name), character.id)
+		if err != nil {
+			return err
+		}
+		lookalike, err := uniqueKey(ctx, tx, "characters", "name_lookalike", namepolicy.LookAlikeKey(name), character.id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE characters SET name = $2, name_canonical = $3, name_lookalike = $4 WHERE id = $1`,
+			character.id, name, canonical, lookalike)
+		if err != nil {
+			return fmt.Errorf("failed to backfill character name: %w", err)
+		}
+	}
+
+	if len(users) > 0 || len(characters) > 0 {
+		log.Printf("[Database] Backfilled names of %d users and %d characters", len(users), len(characters))
+	}
+	return tx.Commit()
+}
+
+// storedName is a name read for the backfill
+type storedName struct {
+	id   int
+	name string
+}
+
+func pendingNames(ctx context.Context, tx *sql.Tx, query string) ([]storedName, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query names to backfill: %w", err)
+	}
+	defer rows.Close()
+
+	var names []storedName
+	for rows.Next() {
+		var n storedName
+		if err := rows.Scan(&n.id, &n.name); err != nil {
+			return nil, fmt.Errorf("failed to scan name to backfill: %w", err)
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
+// storableName returns a character's name in the form the character_name
+// CHECK constraints require: NFKC normalized and trimmed, without control
+// characters. A name that no longer fits, or that now clashes with another
+// character's, is replaced with one made from the character's ID.
+func storableName(ctx context.Context, tx *sql.Tx, character storedName) (string, error) {
+	name := namepolicy.NormalizeCharacterName(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, character.name))
+	if name == character.name {
+		return name, nil
+	}
+
+	length := utf8.RuneCountInString(name)
+	fits := length >= namepolicy.MinCharacterNameRunes && length <= namepolicy.MaxCharacterNameRunes
+	if fits {
+		var taken bool
+		err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM characters WHERE name = $1 AND id <> $2)`, name, character.id,
+		).Scan(&taken)
+		if err != nil {
+			return "", fmt.Errorf("failed to check character name: %w", err)
+		}
+		fits = !taken
+	}
+	if !fits {
+		name = fmt.Sprintf("Character%d", character.id)
+	}
+	log.Printf("[Database] Character %d renamed from %q to %q to satisfy name constraints", character.id, character.name, name)
+	return name, nil
+}
+
+// uniqueKey returns key, or key suffixed with id when another row of table
+// already holds it in column
+func uniqueKey(ctx context.Context, tx *sql.Tx, table, column, key string, id int) (string, error) {
+	var taken bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE `+column+` = $1 AND id <> $2)`, key, id,
+	).Scan(&taken)
+	if err != nil {
+		return "", fmt.Errorf("failed to check %s.%s: %w", table, column, err)
+	}
+	if !taken {
+		return key, nil
+	}
+	log.Printf("[Database] %s %d collides with an older row on %s %q; grandfathered", table, id, column, key)
+	return fmt.Sprintf("%s#%d", key, id), nil
+}
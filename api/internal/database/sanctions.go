@@ -0,0 +1,30 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/omega-realm/api/internal/models"
+)
+
+// ActiveSanction returns the user's longest-running active sanction of the
+// given type, or nil when there is none
+func (db *DB) ActiveSanction(ctx context.Context, userID int, sanctionType string) (*models.Sanction, error) {
+	var s models.Sanction
+	err := db.QueryRowContext(ctx, `
+		SELECT id, user_id, type, reason, issued_by, created_at, expires_at
+		FROM sanctions
+		WHERE user_id = $1 AND type = $2 AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		ORDER BY expires_at DESC NULLS FIRST
+		LIMIT 1
+	`, userID, sanctionType).Scan(&s.ID, &s.UserID, &s.Type, &s.Reason, &s.IssuedBy, &s.CreatedAt, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s sanctions: %w", sanctionType, err)
+	}
+	return &s, nil
+}
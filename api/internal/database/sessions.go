@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// CloseInstanceSessions ends every open session on a game server instance and
+// returns the users they belonged to. An empty instanceID closes the open
+// sessions that were never tied to an instance.
+func (db *DB) CloseInstanceSessions(ctx context.Context, instanceID, endReason string) ([]int, error) {
+	rows, err := db.QueryContext(ctx, `
+		UPDATE sessions s
+		SET ended_at = CURRENT_TIMESTAMP, end_reason = $2
+		FROM characters c
+		WHERE c.id = s.character_id
+		  AND s.ended_at IS NULL
+		  AND COALESCE(s.instance_id, '') = $1
+		RETURNING c.user_id
+	`, instanceID, endReason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close sessions on %q: %w", instanceID, err)
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan closed session: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
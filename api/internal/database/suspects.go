@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omega-realm/api/internal/models"
+)
+
+// suspectScoreColumns is selected by every suspect score query, in the order
+// scanSuspectScore reads them
+const suspectScoreColumns = `
+	id, connection_id, user_id, character_id, instance_id, region, score, peak_score,
+	speed_score, correction_score, aim_snap_score, shot_rate_score,
+	speed_violations, corrections, aim_snaps, shot_rate_violations, inputs, started_at, updated_at
+`
+
+// RecentSuspectScores returns the anti-cheat scores of the user's latest
+// connections, newest first
+func (db *DB) RecentSuspectScores(ctx context.Context, userID, limit int) ([]models.SuspectScore, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+suspectScoreColumns+`
+		FROM suspect_scores WHERE user_id = $1
+		ORDER BY updated_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suspect scores: %w", err)
+	}
+	defer rows.Close()
+
+	scores := []models.SuspectScore{}
+	for rows.Next() {
+		var s models.SuspectScore
+		if err := rows.Scan(&s.ID, &s.ConnectionID, &s.UserID, &s.CharacterID, &s.InstanceID, &s.Region,
+			&s.Score, &s.PeakScore, &s.SpeedScore, &s.CorrectionScore, &s.AimSnapScore, &s.ShotRateScore,
+			&s.SpeedViolations, &s.Corrections, &s.AimSnaps, &s.ShotRateViolations, &s.Inputs,
+			&s.StartedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan suspect score: %w", err)
+		}
+		scores = append(scores, s)
+	}
+	return scores, rows.Err()
+}
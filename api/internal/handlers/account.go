@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+	"github.com/omega-realm/api/internal/middleware"
+	"github.com/omega-realm/api/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type AccountHandler struct {
+	db            *database.DB
+	deletionDelay time.Duration
+}
+
+func NewAccountHandler(db *database.DB, deletionDelay time.Duration) *AccountHandler {
+	return &AccountHandler{db: db, deletionDelay: deletionDelay}
+}
+
+// DeleteAccountRequest represents the request body for account deletion
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// AccountDeletionResponse describes the state of an account deletion request
+type AccountDeletionResponse struct {
+	Message  string                  `json:"message"`
+	Deletion *models.AccountDeletion `json:"deletion,omitempty"`
+}
+
+// AccountExport is the personal-data archive returned by ExportAccount
+type AccountExport struct {
+	ExportedAt      time.Time               `json:"exported_at"`
+	User            *models.User            `json:"user"`
+	Character       *models.Character       `json:"character"`
+	Leaderboard     *models.Leaderboard     `json:"leaderboard"`
+	Sessions        []models.Session        `json:"sessions"`
+	AuditEvents     []models.AuditEvent     `json:"audit_events"`
+	Friendships     []models.FriendRequest  `json:"friendships"`
+	BlockedUsers    []models.BlockedUser    `json:"blocked_users"`
+	PendingDeletion *models.AccountDeletion `json:"pending_deletion"`
+}
+
+// ExportAccount returns a JSON archive of everything stored about the user
+func (h *AccountHandler) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	export, err := h.buildExport(r, claims.UserID)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Account] Failed to export account %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to export account"})
+		return
+	}
+
+	if err := h.db.RecordAuditEvent(r.Context(), claims.UserID, database.AuditAccountExported, nil); err != nil {
+		log.Printf("[Account] %v", err)
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="omega-realm-account-%d.json"`, claims.UserID))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(export)
+}
+
+// buildExport gathers the user's rows from every table that references them
+func (h *AccountHandler) buildExport(r *http.Request, userID int) (*AccountExport, error) {
+	ctx := r.Context()
+	export := &AccountExport{
+		ExportedAt:   time.Now().UTC(),
+		Sessions:     []models.Session{},
+		AuditEvents:  []models.AuditEvent{},
+		Friendships:  []models.FriendRequest{},
+		BlockedUsers: []models.BlockedUser{},
+	}
+
+	var user models.User
+	err := h.db.QueryRowContext(ctx,
+		`SELECT id, username, email, region, role, created_at FROM users WHERE id = $1`, userID,
+	).Scan(&user.ID, &user.Username, &user.Email, &user.Region, &user.Role, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	export.User = &user
+
+	var character models.Character
+	err = h.db.QueryRowContext(ctx,
+		`SELECT id, user_id, name, created_at, deleted_at FROM characters WHERE user_id = $1`, userID,
+	).Scan(&character.ID, &character.UserID, &character.Name, &character.CreatedAt, &character.DeletedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if err == nil {
+		export.Character = &character
+
+		var stats models.Leaderboard
+		err = h.db.QueryRowContext(ctx, `
+			SELECT id, character_id, pvp_kills, monster_kills, deaths, updated_at
+			FROM leaderboards WHERE character_id = $1
+		`, character.ID).Scan(&stats.ID, &stats.CharacterID, &stats.PvPKills, &stats.MonsterKills, &stats.Deaths, &stats.UpdatedAt)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if err == nil {
+			export.Leaderboard = &stats
+		}
+
+		rows, err := h.db.QueryContext(ctx, `
+			SELECT id, character_id, server_region, instance_id, started_at, ended_at, end_reason
+			FROM sessions WHERE character_id = $1
+			ORDER BY started_at DESC
+		`, character.ID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var session models.Session
+			if err := rows.Scan(&session.ID, &session.CharacterID, &session.ServerRegion, &session.InstanceID, &session.StartedAt, &session.EndedAt, &session.EndReason); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			export.Sessions = append(export.Sessions, session)
+		}
+		rows.Close()
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT id, user_id, event_type, details, created_at
+		FROM audit_events WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var event models.AuditEvent
+		if err := rows.Scan(&event.ID, &event.UserID, &event.EventType, &event.Details, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		export.AuditEvents = append(export.AuditEvents, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	friendRows, err := h.db.QueryContext(ctx, `
+		SELECT f.id, f.requester_id, req.username, f.addressee_id, addr.username, f.status, f.created_at, f.responded_at
+		FROM friendships f
+		JOIN users req ON req.id = f.requester_id
+		JOIN users addr ON addr.id = f.addressee_id
+		WHERE f.requester_id = $1 OR f.addressee_id = $1
+		ORDER BY f.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer friendRows.Close()
+	for friendRows.Next() {
+		var friendship models.FriendRequest
+		if err := friendRows.Scan(&friendship.ID, &friendship.RequesterID, &friendship.RequesterName, &friendship.AddresseeID, &friendship.AddresseeName, &friendship.Status, &friendship.CreatedAt, &friendship.RespondedAt); err != nil {
+			return nil, err
+		}
+		export.Friendships = append(export.Friendships, friendship)
+	}
+	if err := friendRows.Err(); err != nil {
+		return nil, err
+	}
+
+	blockRows, err := h.db.QueryContext(ctx, `
+		SELECT u.id, u.username, b.created_at
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer blockRows.Close()
+	for blockRows.Next() {
+		var blocked models.BlockedUser
+		if err := blockRows.Scan(&blocked.UserID, &blocked.Username, &blocked.CreatedAt); err != nil {
+			return nil, err
+		}
+		export.BlockedUsers = append(export.BlockedUsers, blocked)
+	}
+	if err := blockRows.Err(); err != nil {
+		return nil, err
+	}
+
+	var deletion models.AccountDeletion
+	err = h.db.QueryRowContext(ctx,
+		`SELECT user_id, requested_at, scheduled_for FROM account_deletions WHERE user_id = $1`, userID,
+	).Scan(&deletion.UserID, &deletion.RequestedAt, &deletion.ScheduledFor)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil {
+		export.PendingDeletion = &deletion
+	}
+
+	return export, nil
+}
+
+// DeleteAccount schedules the authenticated user's account for deletion.
+// The account stays usable, and the request cancellable, until the grace
+// period ends and the account deletion worker removes it.
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	// Require the password again so a stolen access token cannot delete the account
+	var passwordHash string
+	err := h.db.QueryRow(`SELECT password_hash FROM users WHERE id = $1`, claims.UserID).Scan(&passwordHash)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Account] Failed to fetch user %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)) != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid password"})
+		return
+	}
+
+	var deletion models.AccountDeletion
+	query := `
+		INSERT INTO account_deletions (user_id, scheduled_for)
+		VALUES ($1, CURRENT_TIMESTAMP + make_interval(secs => $2))
+		ON CONFLICT (user_id) DO UPDATE SET user_id = EXCLUDED.user_id
+		RETURNING user_id, requested_at, scheduled_for
+	`
+	err = h.db.QueryRow(query, claims.UserID, h.deletionDelay.Seconds()).Scan(
+		&deletion.UserID,
+		&deletion.RequestedAt,
+		&deletion.ScheduledFor,
+	)
+	if err != nil {
+		log.Printf("[Account] Failed to schedule deletion for user %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to schedule account deletion"})
+		return
+	}
+
+	if err := h.db.RecordAuditEvent(r.Context(), claims.UserID, database.AuditAccountDeletionRequested, map[string]any{
+		"scheduled_for": deletion.ScheduledFor,
+	}); err != nil {
+		log.Printf("[Account] %v", err)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(AccountDeletionResponse{
+		Message:  "Account scheduled for deletion",
+		Deletion: &deletion,
+	})
+
+	log.Printf("[Account] Deletion scheduled for user %d at %s", claims.UserID, deletion.ScheduledFor.Format(time.RFC3339))
+}
+
+// CancelAccountDeletion cancels a pending account deletion
+func (h *AccountHandler) CancelAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	result, err := h.db.Exec(`DELETE FROM account_deletions WHERE user_id = $1`, claims.UserID)
+	if err != nil {
+		log.Printf("[Account] Failed to cancel deletion for user %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to cancel account deletion"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "No pending account deletion"})
+		return
+	}
+
+	if err := h.db.RecordAuditEvent(r.Context(), claims.UserID, database.AuditAccountDeletionCancelled, nil); err != nil {
+		log.Printf("[Account] %v", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(AccountDeletionResponse{Message: "Account deletion cancelled"})
+}
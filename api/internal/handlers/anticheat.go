@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+	"github.com/omega-realm/api/internal/models"
+	"github.com/omega-realm/api/pkg/anticheat"
+)
+
+const (
+	maxConnectionIDLength = 64
+	// suspectLogScore is the combined score at which a report is logged as
+	// well as stored
+	suspectLogScore     = 80
+	defaultSuspectScore = 50
+	defaultSuspectDays  = 7
+	maxSuspectDays      = 90
+	defaultSuspectLimit = 50
+	maxSuspectLimit     = 200
+	// suspectHistory is how many connections and audit events are shown
+	// with a suspect
+	suspectHistory = 50
+)
+
+type AntiCheatHandler struct {
+	db *database.DB
+}
+
+func NewAntiCheatHandler(db *database.DB) *AntiCheatHandler {
+	return &AntiCheatHandler{db: db}
+}
+
+// ReportScoresRequest is a gateway's latest analysis of one connection.
+// Gateways report each connection repeatedly while it is open, under the
+// same ConnectionID.
+type ReportScoresRequest struct {
+	ConnectionID string    `json:"connection_id"`
+	UserID       int       `json:"user_id"`
+	CharacterID  int       `json:"character_id"`
+	InstanceID   string    `json:"instance_id"`
+	Region       string    `json:"region"`
+	StartedAt    time.Time `json:"started_at"`
+	anticheat.Report
+}
+
+// Suspect is a player whose connections scored at least the requested
+// score, with what moderators already know about them
+type Suspect struct {
+	UserID          int       `json:"user_id"`
+	Username        string    `json:"username"`
+	PeakScore       int       `json:"peak_score"`
+	Connections     int       `json:"connections"`
+	LastSeen        time.Time `json:"last_seen"`
+	CheatingReports int       `json:"cheating_reports"`
+	Banned          bool      `json:"banned"`
+}
+
+// SuspectDetailResponse is a player's recent anti-cheat scores with the
+// context a moderator needs to judge them
+type SuspectDetailResponse struct {
+	UserID          int                   `json:"user_id"`
+	Username        string                `json:"username"`
+	Scores          []models.SuspectScore `json:"scores"`
+	CheatingReports int                   `json:"cheating_reports"`
+	AuditHistory    []models.AuditEvent   `json:"audit_history"`
+	Banned          bool                  `json:"banned"`
+}
+
+// ReportScores stores a gateway's scores for a connection. The connection's
+// peak score is kept when later reports score lower.
+func (h *AntiCheatHandler) ReportScores(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ReportScoresRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	if req.ConnectionID == "" || len(req.ConnectionID) > maxConnectionIDLength || req.UserID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "connection_id and user_id are required"})
+		return
+	}
+	if !validSuspectReport(req.Report) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Scores must be between 0 and 100 and counts must not be negative"})
+		return
+	}
+	if req.StartedAt.IsZero() {
+		req.StartedAt = time.Now()
+	}
+
+	// A character that has since been purged, or is not the user's, is
+	// left out rather than failing the report
+	result, err := h.db.ExecContext(r.Context(), `
+		INSERT INTO suspect_scores (
+			connection_id, user_id, character_id, instance_id, region, score, peak_score,
+			speed_score, correction_score, aim_snap_score, shot_rate_score,
+			speed_violations, corrections, aim_snaps, shot_rate_violations, inputs, started_at
+		)
+		SELECT $1, u.id, (SELECT c.id FROM characters c WHERE c.id = $3 AND c.user_id = u.id), $4, $5, $6, $6,
+		       $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
+		FROM users u WHERE u.id = $2
+		ON CONFLICT (connection_id) DO UPDATE SET
+			score = EXCLUDED.score,
+			peak_score = GREATEST(suspect_scores.peak_score, EXCLUDED.score),
+			speed_score = EXCLUDED.speed_score,
+			correction_score = EXCLUDED.correction_score,
+			aim_snap_score = EXCLUDED.aim_snap_score,
+			shot_rate_score = EXCLUDED.shot_rate_score,
+			speed_violations = EXCLUDED.speed_violations,
+			corrections = EXCLUDED.corrections,
+			aim_snaps = EXCLUDED.aim_snaps,
+			shot_rate_violations = EXCLUDED.shot_rate_violations,
+			inputs = EXCLUDED.inputs,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE suspect_scores.user_id = EXCLUDED.user_id
+	`, req.ConnectionID, req.UserID, req.CharacterID, req.InstanceID, req.Region, req.Score,
+		req.SpeedScore, req.CorrectionScore, req.AimSnapScore, req.ShotRateScore,
+		req.SpeedViolations, req.Corrections, req.AimSnaps, req.ShotRateViolations, req.Inputs, req.StartedAt)
+	if err != nil {
+		log.Printf("[AntiCheat] Failed to store scores for connection %s: %v", req.ConnectionID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to store scores"})
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		// Either the user is gone or the connection ID belongs to someone else
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	if req.Score >= suspectLogScore {
+		log.Printf("[AntiCheat] User %d scored %d on connection %s (speed %d, corrections %d, aim snaps %d, shot rate %d)",
+			req.UserID, req.Score, req.ConnectionID, req.SpeedScore, req.CorrectionScore, req.AimSnapScore, req.ShotRateScore)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Scores recorded"})
+}
+
+// ListSuspects lists players whose connections peaked at or above min_score
+// (default 50) within the last days (default 7), highest first. Also
+// accepts limit.
+func (h *AntiCheatHandler) ListSuspects(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	minScore, ok := intParam(w, r, "min_score", defaultSuspectScore)
+	if !ok {
+		return
+	}
+	days, ok := intParam(w, r, "days", defaultSuspectDays)
+	if !ok {
+		return
+	}
+	limit, ok := intParam(w, r, "limit", defaultSuspectLimit)
+	if !ok {
+		return
+	}
+	minScore = min(max(minScore, 0), 100)
+	days = min(max(days, 1), maxSuspectDays)
+	limit = min(max(limit, 1), maxSuspectLimit)
+
+	rows, err := h.db.QueryContext(r.Context(), `
+		SELECT s.user_id, u.username, MAX(s.peak_score), COUNT(*), MAX(s.updated_at),
+		       (SELECT COUNT(*) FROM reports rp WHERE rp.reported_user_id = s.user_id AND rp.category = $1),
+		       EXISTS (SELECT 1 FROM sanctions sn
+		               WHERE sn.user_id = s.user_id AND sn.type = $2 AND sn.revoked_at IS NULL
+		                 AND (sn.expires_at IS NULL OR sn.expires_at > CURRENT_TIMESTAMP))
+		FROM suspect_scores s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.updated_at > CURRENT_TIMESTAMP - make_interval(days => $3)
+		GROUP BY s.user_id, u.username
+		HAVING MAX(s.peak_score) >= $4
+		ORDER BY MAX(s.peak_score) DESC, MAX(s.updated_at) DESC
+		LIMIT $5
+	`, models.ReportCheating, models.SanctionBan, days, minScore, limit)
+	if err != nil {
+		log.Printf("[AntiCheat] Failed to query suspects: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load suspects"})
+		return
+	}
+	defer rows.Close()
+
+	suspects := []Suspect{}
+	for rows.Next() {
+		var s Suspect
+		if err := rows.Scan(&s.UserID, &s.Username, &s.PeakScore, &s.Connections, &s.LastSeen, &s.CheatingReports, &s.Banned); err != nil {
+			log.Printf("[AntiCheat] Failed to scan suspect: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load suspects"})
+			return
+		}
+		suspects = append(suspects, s)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("[AntiCheat] Failed to read suspects: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load suspects"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"suspects": suspects})
+}
+
+// GetSuspect returns a player's latest anti-cheat scores, how often they
+// have been reported for cheating and their recent audit history
+func (h *AntiCheatHandler) GetSuspect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := intParam(w, r, "user_id", 0)
+	if !ok {
+		return
+	}
+	if userID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "user_id is required"})
+		return
+	}
+
+	ctx := r.Context()
+	response := SuspectDetailResponse{UserID: userID}
+	err := h.db.QueryRowContext(ctx, `
+		SELECT u.username,
+		       (SELECT COUNT(*) FROM reports rp WHERE rp.reported_user_id = u.id AND rp.category = $2)
+		FROM users u WHERE u.id = $1
+	`, userID, models.ReportCheating).Scan(&response.Username, &response.CheatingReports)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[AntiCheat] Failed to look up user %d: %v", userID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load suspect"})
+		return
+	}
+
+	response.Scores, err = h.db.RecentSuspectScores(ctx, userID, suspectHistory)
+	if err != nil {
+		log.Printf("[AntiCheat] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load suspect"})
+		return
+	}
+	response.AuditHistory, err = h.db.RecentAuditEvents(ctx, userID, suspectHistory)
+	if err != nil {
+		log.Printf("[AntiCheat] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load suspect"})
+		return
+	}
+	ban, err := h.db.ActiveSanction(ctx, userID, models.SanctionBan)
+	if err != nil {
+		log.Printf("[AntiCheat] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load suspect"})
+		return
+	}
+	response.Banned = ban != nil
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func validSuspectReport(report anticheat.Report) bool {
+	for _, score := range []int{report.Score, report.SpeedScore, report.CorrectionScore, report.AimSnapScore, report.ShotRateScore} {
+		if score < 0 || score > 100 {
+			return false
+		}
+	}
+	for _, count := range []int{report.SpeedViolations, report.Corrections, report.AimSnaps, report.ShotRateViolations, report.Inputs} {
+		if count < 0 {
+			return false
+		}
+	}
+	return true
+}
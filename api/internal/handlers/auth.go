@@ -6,19 +6,26 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/omega-realm/api/internal/auth"
 	"github.com/omega-realm/api/internal/database"
 	"github.com/omega-realm/api/internal/models"
+	"github.com/omega-realm/api/internal/namepolicy"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/regions"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler struct {
-	db *database.DB
+	db         *database.DB
+	redis      *redisClient.Client
+	namePolicy *namepolicy.Policy
+	registry   *regions.Registry
 }
 
-func NewAuthHandler(db *database.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db *database.DB, redis *redisClient.Client, namePolicy *namepolicy.Policy, registry *regions.Registry) *AuthHandler {
+	return &AuthHandler{db: db, redis: redis, namePolicy: namePolicy, registry: registry}
 }
 
 // RegisterRequest represents the registration request body
@@ -67,12 +74,18 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate input
-	if err := validateRegisterRequest(&req); err != nil {
+	req.Region = regions.NormalizeRegionID(req.Region)
+	if err := validateRegisterRequest(&req, h.registry); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	// Apply the name policy (profanity, reserved names, duplicates)
+	if !enforceNamePolicy(w, r, h.namePolicy, namepolicy.KindUsername, "username", req.Username, 0) {
+		return
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -84,17 +97,17 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	// Set default region if not provided
 	if req.Region == "" {
-		req.Region = "Asia"
+		req.Region = h.registry.DefaultRegion()
 	}
 
 	// Insert user into database
 	var userID int
 	query := `
-		INSERT INTO users (username, email, password_hash, region)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO users (username, email, password_hash, region, name_lookalike)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id
 	`
-	err = h.db.QueryRow(query, req.Username, req.Email, string(hashedPassword), req.Region).Scan(&userID)
+	err = h.db.QueryRow(query, req.Username, req.Email, string(hashedPassword), req.Region, namepolicy.LookAlikeKey(req.Username)).Scan(&userID)
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate key") {
 			w.WriteHeader(http.StatusConflict)
@@ -108,7 +121,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate tokens
-	accessToken, err := auth.GenerateAccessToken(userID, req.Username, req.Email, req.Region)
+	accessToken, err := auth.GenerateAccessToken(userID, req.Username, req.Email, req.Region, auth.RolePlayer)
 	if err != nil {
 		log.Printf("[Auth] Failed to generate access token: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -129,6 +142,11 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		Username: req.Username,
 		Email:    req.Email,
 		Region:   req.Region,
+		Role:     auth.RolePlayer,
+	}
+
+	if !h.startSession(w, r, accessToken, user) {
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -139,6 +157,12 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		User:         user,
 	})
 
+	if err := h.db.RecordAuditEvent(r.Context(), userID, database.AuditUserRegistered, map[string]any{
+		"region": req.Region,
+	}); err != nil {
+		log.Printf("[Auth] %v", err)
+	}
+
 	log.Printf("[Auth] User registered successfully: %s (ID: %d)", req.Username, userID)
 }
 
@@ -166,7 +190,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Fetch user from database
 	var user models.User
 	query := `
-		SELECT id, username, email, password_hash, region, created_at
+		SELECT id, username, email, password_hash, region, role, created_at
 		FROM users
 		WHERE username = $1
 	`
@@ -176,6 +200,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		&user.Email,
 		&user.PasswordHash,
 		&user.Region,
+		&user.Role,
 		&user.CreatedAt,
 	)
 	if err != nil {
@@ -198,8 +223,12 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.rejectBanned(w, r, user.ID) {
+		return
+	}
+
 	// Generate tokens
-	accessToken, err := auth.GenerateAccessToken(user.ID, user.Username, user.Email, user.Region)
+	accessToken, err := auth.GenerateAccessToken(user.ID, user.Username, user.Email, user.Region, user.Role)
 	if err != nil {
 		log.Printf("[Auth] Failed to generate access token: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -218,6 +247,10 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Clear password hash before sending
 	user.PasswordHash = ""
 
+	if !h.startSession(w, r, accessToken, &user) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(AuthResponse{
 		AccessToken:  accessToken,
@@ -225,6 +258,12 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		User:         &user,
 	})
 
+	if err := h.db.RecordAuditEvent(r.Context(), user.ID, database.AuditUserLogin, map[string]any{
+		"remote_addr": r.RemoteAddr,
+	}); err != nil {
+		log.Printf("[Auth] %v", err)
+	}
+
 	log.Printf("[Auth] User logged in successfully: %s (ID: %d)", user.Username, user.ID)
 }
 
@@ -253,7 +292,7 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	// Fetch user from database using subject (username)
 	var user models.User
 	query := `
-		SELECT id, username, email, region
+		SELECT id, username, email, region, role
 		FROM users
 		WHERE username = $1
 	`
@@ -262,6 +301,7 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		&user.Username,
 		&user.Email,
 		&user.Region,
+		&user.Role,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -275,8 +315,12 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.rejectBanned(w, r, user.ID) {
+		return
+	}
+
 	// Generate new tokens
-	accessToken, err := auth.GenerateAccessToken(user.ID, user.Username, user.Email, user.Region)
+	accessToken, err := auth.GenerateAccessToken(user.ID, user.Username, user.Email, user.Region, user.Role)
 	if err != nil {
 		log.Printf("[Auth] Failed to generate access token: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -292,6 +336,10 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.startSession(w, r, accessToken, &user) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(AuthResponse{
 		AccessToken:  accessToken,
@@ -302,8 +350,79 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[Auth] Token refreshed for user: %s (ID: %d)", user.Username, user.ID)
 }
 
+// BannedResponse is returned instead of tokens to a banned user
+type BannedResponse struct {
+	Error     string     `json:"error"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// rejectBanned replies with 403 and returns true if the user has an active
+// ban. If the check itself fails it replies with 500 and also returns true.
+func (h *AuthHandler) rejectBanned(w http.ResponseWriter, r *http.Request, userID int) bool {
+	ban, err := h.db.ActiveSanction(r.Context(), userID, models.SanctionBan)
+	if err != nil {
+		log.Printf("[Auth] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return true
+	}
+	if ban == nil {
+		return false
+	}
+
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(BannedResponse{
+		Error:     "Account is banned",
+		Reason:    ban.Reason,
+		ExpiresAt: ban.ExpiresAt,
+	})
+	return true
+}
+
+// startSession records the new access token as the user's current Redis
+// session. Authenticated requests are checked against it, and the user's
+// presence is derived from it. The game server the user is on carries over
+// from the session it replaces. If the session cannot be stored, or the user
+// was banned after the caller checked, it replies and returns false.
+func (h *AuthHandler) startSession(w http.ResponseWriter, r *http.Request, accessToken string, user *models.User) bool {
+	now := time.Now().UTC()
+	session := &redisClient.SessionData{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Region:    user.Region,
+		CreatedAt: now,
+		ExpiresAt: now.Add(auth.AccessTokenDuration),
+	}
+
+	ctx := r.Context()
+	if _, previous, err := h.redis.GetUserSession(ctx, user.ID); err == nil {
+		session.CharacterID = previous.CharacterID
+		session.ServerRegion = previous.ServerRegion
+		session.InstanceID = previous.InstanceID
+	}
+
+	if err := h.redis.SetSession(ctx, accessToken, session, auth.AccessTokenDuration); err != nil {
+		log.Printf("[Auth] Failed to store session for user %d: %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to start session"})
+		return false
+	}
+
+	// A ban issued since the caller checked ended the user's sessions before
+	// this one existed, so it would outlive the ban
+	if h.rejectBanned(w, r, user.ID) {
+		if err := h.redis.DeleteSession(ctx, accessToken); err != nil {
+			log.Printf("[Auth] Failed to end session of banned user %d: %v", user.ID, err)
+		}
+		return false
+	}
+	return true
+}
+
 // validateRegisterRequest validates the registration request
-func validateRegisterRequest(req *RegisterRequest) error {
+func validateRegisterRequest(req *RegisterRequest, registry *regions.Registry) error {
 	if req.Username == "" {
 		return &ValidationError{Field: "username", Message: "Username is required"}
 	}
@@ -322,21 +441,13 @@ func validateRegisterRequest(req *RegisterRequest) error {
 	if len(req.Password) < 6 {
 		return &ValidationError{Field: "password", Message: "Password must be at least 6 characters"}
 	}
-	if req.Region != "" && !isValidRegion(req.Region) {
-		return &ValidationError{Field: "region", Message: "Invalid region. Must be Asia, Europe, or US-West"}
-	}
-	return nil
-}
-
-// isValidRegion checks if the region is valid
-func isValidRegion(region string) bool {
-	validRegions := []string{"Asia", "Europe", "US-West"}
-	for _, r := range validRegions {
-		if r == region {
-			return true
+	if req.Region != "" && !registry.IsValidRegion(req.Region) {
+		return &ValidationError{
+			Field:   "region",
+			Message: "Invalid region. Must be one of: " + strings.Join(registry.RegionIDs(), ", "),
 		}
 	}
-	return false
+	return nil
 }
 
 // ValidationError represents a validation error
@@ -4,20 +4,27 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/omega-realm/api/internal/database"
 	"github.com/omega-realm/api/internal/middleware"
 	"github.com/omega-realm/api/internal/models"
+	"github.com/omega-realm/api/internal/namepolicy"
+	redisClient "github.com/omega-realm/api/internal/redis"
 )
 
 type CharacterHandler struct {
-	db *database.DB
+	db            *database.DB
+	redis         *redisClient.Client
+	namePolicy    *namepolicy.Policy
+	restoreWindow time.Duration
 }
 
-func NewCharacterHandler(db *database.DB) *CharacterHandler {
-	return &CharacterHandler{db: db}
+func NewCharacterHandler(db *database.DB, redis *redisClient.Client, namePolicy *namepolicy.Policy, restoreWindow time.Duration) *CharacterHandler {
+	return &CharacterHandler{db: db, redis: redis, namePolicy: namePolicy, restoreWindow: restoreWindow}
 }
 
 // CreateCharacterRequest represents the request body for character creation
@@ -25,6 +32,11 @@ type CreateCharacterRequest struct {
 	Name string `json:"name"`
 }
 
+// RenameCharacterRequest represents the request body for renaming a character
+type RenameCharacterRequest struct {
+	Name string `json:"name"`
+}
+
 // CharacterSuccessResponse represents a success response with character data
 type CharacterSuccessResponse struct {
 	Message   string            `json:"message"`
@@ -43,9 +55,9 @@ func (h *CharacterHandler) GetCharacter(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Query character by user_id
+	// Query character by user_id (soft-deleted characters are hidden)
 	var character models.Character
-	query := `SELECT id, user_id, name, created_at FROM characters WHERE user_id = $1`
+	query := `SELECT id, user_id, name, created_at FROM characters WHERE user_id = $1 AND deleted_at IS NULL`
 	err := h.db.QueryRow(query, claims.UserID).Scan(
 		&character.ID,
 		&character.UserID,
@@ -94,21 +106,32 @@ func (h *CharacterHandler) CreateCharacter(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Validate character name
-	if err := validateCharacterName(req.Name); err != nil {
+	// Normalize (NFKC) and validate character name
+	req.Name = namepolicy.NormalizeCharacterName(req.Name)
+	if err := namepolicy.ValidateCharacterName(req.Name); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	// Apply the name policy (profanity, reserved names, duplicates)
+	if !enforceNamePolicy(w, r, h.namePolicy, namepolicy.KindCharacter, "name", req.Name, claims.UserID) {
+		return
+	}
+
 	// Check if user already has a character
 	var existingCharacterID int
-	checkQuery := `SELECT id FROM characters WHERE user_id = $1`
-	err := h.db.QueryRow(checkQuery, claims.UserID).Scan(&existingCharacterID)
+	var existingDeletedAt *time.Time
+	checkQuery := `SELECT id, deleted_at FROM characters WHERE user_id = $1`
+	err := h.db.QueryRow(checkQuery, claims.UserID).Scan(&existingCharacterID, &existingDeletedAt)
 
 	if err == nil {
-		// Character exists
+		// Character exists; a soft-deleted one still occupies the slot until purged
 		w.WriteHeader(http.StatusConflict)
+		if existingDeletedAt != nil {
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "User has a deleted character that can still be restored"})
+			return
+		}
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "User already has a character"})
 		return
 	} else if err != sql.ErrNoRows {
@@ -121,11 +144,11 @@ func (h *CharacterHandler) CreateCharacter(w http.ResponseWriter, r *http.Reques
 	// Insert new character
 	var character models.Character
 	insertQuery := `
-		INSERT INTO characters (user_id, name)
-		VALUES ($1, $2)
+		INSERT INTO characters (user_id, name, name_canonical, name_lookalike)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, user_id, name, created_at
 	`
-	err = h.db.QueryRow(insertQuery, claims.UserID, req.Name).Scan(
+	err = h.db.QueryRow(insertQuery, claims.UserID, req.Name, namepolicy.CanonicalName(req.Name), namepolicy.LookAlikeKey(req.Name)).Scan(
 		&character.ID,
 		&character.UserID,
 		&character.Name,
@@ -135,7 +158,9 @@ func (h *CharacterHandler) CreateCharacter(w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		// Check if it's a unique constraint violation
 		if strings.Contains(err.Error(), "duplicate key") {
-			if strings.Contains(err.Error(), "characters_name_key") {
+			if strings.Contains(err.Error(), "characters_name_key") ||
+				strings.Contains(err.Error(), "characters_name_canonical_key") ||
+				strings.Contains(err.Error(), "characters_name_lookalike_key") {
 				w.WriteHeader(http.StatusConflict)
 				json.NewEncoder(w).Encode(ErrorResponse{Error: "Character name already taken"})
 				return
@@ -159,40 +184,218 @@ func (h *CharacterHandler) CreateCharacter(w http.ResponseWriter, r *http.Reques
 	})
 }
 
-// validateCharacterName validates the character name
-func validateCharacterName(name string) error {
-	// Trim whitespace
-	name = strings.TrimSpace(name)
+// RenameCharacter changes the name of the authenticated user's character
+func (h *CharacterHandler) RenameCharacter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	// Parse request body
+	var req RenameCharacterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
 
-	// Check length
-	if len(name) < 3 {
-		return fmt.Errorf("character name must be at least 3 characters long")
+	// Normalize (NFKC) and validate character name
+	req.Name = namepolicy.NormalizeCharacterName(req.Name)
+	if err := namepolicy.ValidateCharacterName(req.Name); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
 	}
-	if len(name) > 50 {
-		return fmt.Errorf("character name must not exceed 50 characters")
+
+	// Apply the name policy (profanity, reserved names, duplicates)
+	if !enforceNamePolicy(w, r, h.namePolicy, namepolicy.KindCharacter, "name", req.Name, claims.UserID) {
+		return
 	}
 
-	// Check for valid characters (alphanumeric, spaces, underscores, hyphens)
-	for _, char := range name {
-		if !isValidCharacterNameChar(char) {
-			return fmt.Errorf("character name contains invalid characters. Only letters, numbers, spaces, underscores, and hyphens are allowed")
+	// Update the character name
+	var character models.Character
+	updateQuery := `
+		UPDATE characters
+		SET name = $2, name_canonical = $3, name_lookalike = $4
+		WHERE user_id = $1 AND deleted_at IS NULL
+		RETURNING id, user_id, name, created_at
+	`
+	err := h.db.QueryRow(updateQuery, claims.UserID, req.Name, namepolicy.CanonicalName(req.Name), namepolicy.LookAlikeKey(req.Name)).Scan(
+		&character.ID,
+		&character.UserID,
+		&character.Name,
+		&character.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "No character found for this user"})
+		return
+	}
+
+	if err != nil {
+		if strings.Contains(err.Error(), "characters_name_key") ||
+			strings.Contains(err.Error(), "characters_name_canonical_key") ||
+			strings.Contains(err.Error(), "characters_name_lookalike_key") {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Character name already taken"})
+			return
 		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to rename character"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(CharacterSuccessResponse{
+		Message:   "Character renamed successfully",
+		Character: &character,
+	})
+}
+
+// DeleteCharacter soft-deletes the authenticated user's character. The
+// character disappears from lookups and leaderboards but keeps its name and
+// stats until the purger removes it after the restore window.
+func (h *CharacterHandler) DeleteCharacter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var character models.Character
+	query := `
+		UPDATE characters
+		SET deleted_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND deleted_at IS NULL
+		RETURNING id, user_id, name, created_at, deleted_at
+	`
+	err := h.db.QueryRow(query, claims.UserID).Scan(
+		&character.ID,
+		&character.UserID,
+		&character.Name,
+		&character.CreatedAt,
+		&character.DeletedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "No character found for this user"})
+		return
+	}
+
+	if err != nil {
+		log.Printf("[Character] Failed to delete character for user %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to delete character"})
+		return
+	}
+
+	// Drop the character from the Redis leaderboards; restore re-seeds them
+	if err := h.redis.RemovePlayer(r.Context(), character.ID); err != nil {
+		log.Printf("[Character] %v", err)
 	}
 
-	// Check that it doesn't start or end with whitespace
-	if name != strings.TrimSpace(name) {
-		return fmt.Errorf("character name cannot start or end with whitespace")
+	if err := h.db.RecordAuditEvent(r.Context(), claims.UserID, database.AuditCharacterDeleted, map[string]any{
+		"character_id": character.ID,
+	}); err != nil {
+		log.Printf("[Character] %v", err)
 	}
 
-	return nil
+	restoreUntil := character.DeletedAt.Add(h.restoreWindow)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(CharacterSuccessResponse{
+		Message:   fmt.Sprintf("Character deleted. It can be restored until %s", restoreUntil.Format(time.RFC3339)),
+		Character: &character,
+	})
 }
 
-// isValidCharacterNameChar checks if a character is valid for character names
-func isValidCharacterNameChar(char rune) bool {
-	return (char >= 'a' && char <= 'z') ||
-		(char >= 'A' && char <= 'Z') ||
-		(char >= '0' && char <= '9') ||
-		char == ' ' ||
-		char == '_' ||
-		char == '-'
+// RestoreCharacter undoes a soft delete if the restore window has not passed
+func (h *CharacterHandler) RestoreCharacter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var character models.Character
+	query := `
+		UPDATE characters
+		SET deleted_at = NULL
+		WHERE user_id = $1
+		  AND deleted_at IS NOT NULL
+		  AND deleted_at > CURRENT_TIMESTAMP - make_interval(secs => $2)
+		RETURNING id, user_id, name, created_at
+	`
+	err := h.db.QueryRow(query, claims.UserID, h.restoreWindow.Seconds()).Scan(
+		&character.ID,
+		&character.UserID,
+		&character.Name,
+		&character.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "No restorable character found for this user"})
+		return
+	}
+
+	if err != nil {
+		log.Printf("[Character] Failed to restore character for user %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to restore character"})
+		return
+	}
+
+	// Put the character back on the Redis leaderboards from the persisted stats
+	var pvpKills, monsterKills, deaths int
+	err = h.db.QueryRow(
+		`SELECT pvp_kills, monster_kills, deaths FROM leaderboards WHERE character_id = $1`, character.ID,
+	).Scan(&pvpKills, &monsterKills, &deaths)
+	if err == nil {
+		err = h.redis.SetPlayerStats(r.Context(), character.ID, pvpKills, monsterKills, deaths)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("[Character] Failed to re-seed leaderboard for character %d: %v", character.ID, err)
+	}
+
+	if err := h.db.RecordAuditEvent(r.Context(), claims.UserID, database.AuditCharacterRestored, map[string]any{
+		"character_id": character.ID,
+	}); err != nil {
+		log.Printf("[Character] %v", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(CharacterSuccessResponse{
+		Message:   "Character restored successfully",
+		Character: &character,
+	})
 }
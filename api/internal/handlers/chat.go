@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/omega-realm/api/internal/chat"
+	"github.com/omega-realm/api/internal/middleware"
+)
+
+type ChatHandler struct {
+	service *chat.Service
+}
+
+func NewChatHandler(service *chat.Service) *ChatHandler {
+	return &ChatHandler{service: service}
+}
+
+// SendChatMessage sends a chat message over HTTP, for clients that are not
+// connected to the event stream. Connected clients can send the same request
+// as a "chat" action instead.
+func (h *ChatHandler) SendChatMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req chat.SendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	msg, err := h.service.Send(r.Context(), claims.UserID, req)
+	if err != nil {
+		var muted *chat.MutedError
+		var limited *chat.RateLimitError
+		switch {
+		case errors.As(err, &limited):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+		case errors.As(err, &muted), errors.Is(err, chat.ErrCannotWhisper), errors.Is(err, chat.ErrBanned):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, chat.ErrNotInParty), errors.Is(err, chat.ErrRecipientNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case chat.IsUserError(err):
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			log.Printf("[Chat] %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to send message"})
+			return
+		}
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(msg)
+}
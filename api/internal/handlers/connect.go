@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/omega-realm/api/internal/auth"
+	"github.com/omega-realm/api/internal/models"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/regions"
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+// VerifyConnectionRequest carries a player's CONNECT_AUTH as received by a
+// game server, or by a gateway, which sends its region instead of an instance
+type VerifyConnectionRequest struct {
+	InstanceID      string                `json:"instance_id"`
+	Region          string                `json:"region"`
+	Token           string                `json:"token"`
+	CharacterID     int                   `json:"character_id"`
+	ProtocolVersion uint16                `json:"protocol_version"`
+	Capabilities    protocol.Capabilities `json:"capabilities"`
+}
+
+// VerifyConnectionResponse tells the game server who connected and which
+// capabilities it may use with them. A gateway is also told which instance to
+// forward the player to.
+type VerifyConnectionResponse struct {
+	InstanceID      string                `json:"instance_id"`
+	Address         string                `json:"address,omitempty"`
+	UserID          int                   `json:"user_id"`
+	Username        string                `json:"username"`
+	CharacterID     int                   `json:"character_id"`
+	CharacterName   string                `json:"character_name"`
+	ProtocolVersion uint16                `json:"protocol_version"`
+	Capabilities    protocol.Capabilities `json:"capabilities"`
+}
+
+// ConnectionRejectedResponse is returned for a connection the game server
+// must refuse, with the reason to send in its DISCONNECT
+type ConnectionRejectedResponse struct {
+	Error              string                    `json:"error"`
+	DisconnectReason   protocol.DisconnectReason `json:"disconnect_reason"`
+	MinProtocolVersion int                       `json:"min_protocol_version,omitempty"`
+	ProtocolVersion    uint16                    `json:"protocol_version,omitempty"`
+}
+
+// VerifyConnection checks a player's CONNECT_AUTH before the game server
+// admits them: the client's protocol version against the instance region's
+// minimum, the token against a live session, and the character against the
+// token's account, which must not be banned. The capabilities in the
+// response are the ones both the client and this protocol version support.
+//
+// A gateway verifies players before any instance sees them, so it sends the
+// region it serves rather than an instance ID. The player is routed to the
+// instance they hold a slot on, or the one they were playing on if they are
+// reconnecting.
+func (h *GameServerHandler) VerifyConnection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req VerifyConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	ctx := r.Context()
+	var instance *redisClient.GameServerInstance
+	var region *models.Region
+	if req.InstanceID != "" {
+		var err error
+		instance, err = h.redis.GetGameServer(ctx, req.InstanceID)
+		if errors.Is(err, redisClient.ErrGameServerNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Game server not registered"})
+			return
+		}
+		if err != nil {
+			log.Printf("[GameServer] %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+			return
+		}
+
+		region = h.registry.GetRegionDetails(instance.Region)
+		if region == nil {
+			log.Printf("[GameServer] Instance %s is in unknown region %s", instance.InstanceID, instance.Region)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+			return
+		}
+	} else {
+		region = h.registry.GetRegionDetails(regions.NormalizeRegionID(req.Region))
+		if region == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Either instance_id or a valid region is required"})
+			return
+		}
+	}
+
+	// The version is checked first, so an outdated client is told to update
+	// rather than that its token is bad
+	if int(req.ProtocolVersion) < region.MinProtocolVersion || req.ProtocolVersion > protocol.Version {
+		w.WriteHeader(http.StatusUpgradeRequired)
+		json.NewEncoder(w).Encode(ConnectionRejectedResponse{
+			Error: fmt.Sprintf("Protocol version %d is not supported in %s, which accepts %d to %d",
+				req.ProtocolVersion, region.ID, region.MinProtocolVersion, protocol.Version),
+			DisconnectReason:   protocol.DisconnectVersionMismatch,
+			MinProtocolVersion: region.MinProtocolVersion,
+			ProtocolVersion:    protocol.Version,
+		})
+		return
+	}
+
+	claims, err := auth.ValidateToken(req.Token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ConnectionRejectedResponse{
+			Error:            "Invalid or expired token",
+			DisconnectReason: protocol.DisconnectInvalidAuth,
+		})
+		return
+	}
+
+	// Logging out and bans end the session before the token expires
+	session, err := h.redis.GetSession(ctx, req.Token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ConnectionRejectedResponse{
+			Error:            "Session has ended",
+			DisconnectReason: protocol.DisconnectInvalidAuth,
+		})
+		return
+	}
+
+	// A ban issued while the session was being replaced could miss it, so
+	// the ban itself is checked too
+	ban, err := h.db.ActiveSanction(ctx, claims.UserID, models.SanctionBan)
+	if err != nil {
+		log.Printf("[GameServer] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+	if ban != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ConnectionRejectedResponse{
+			Error:            "Account is banned",
+			DisconnectReason: protocol.DisconnectKicked,
+		})
+		return
+	}
+
+	var ownerID int
+	var characterName string
+	err = h.db.QueryRowContext(ctx,
+		`SELECT user_id, name FROM characters WHERE id = $1 AND deleted_at IS NULL`, req.CharacterID,
+	).Scan(&ownerID, &characterName)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("[GameServer] Failed to look up character %d: %v", req.CharacterID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+	if err == sql.ErrNoRows || ownerID != claims.UserID {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ConnectionRejectedResponse{
+			Error:            "Character does not belong to this account",
+			DisconnectReason: protocol.DisconnectInvalidAuth,
+		})
+		return
+	}
+
+	address := ""
+	if instance == nil {
+		instance, err = h.assignedInstance(ctx, claims.UserID, session, region.ID)
+		if err != nil {
+			log.Printf("[GameServer] %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+			return
+		}
+		if instance == nil {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(ConnectionRejectedResponse{
+				Error:            fmt.Sprintf("No game server in %s is expecting this player", region.ID),
+				DisconnectReason: protocol.DisconnectInvalidAuth,
+			})
+			return
+		}
+		address = instance.DialAddress()
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(VerifyConnectionResponse{
+		InstanceID:      instance.InstanceID,
+		Address:         address,
+		UserID:          claims.UserID,
+		Username:        claims.Username,
+		CharacterID:     req.CharacterID,
+		CharacterName:   characterName,
+		ProtocolVersion: req.ProtocolVersion,
+		Capabilities:    protocol.Negotiate(req.Capabilities, protocol.SupportedCapabilities),
+	})
+}
+
+// assignedInstance returns the live instance in the region that a player
+// holds a slot on or, once the slot is claimed, the one their session says
+// they are playing on. It returns nil if there is neither.
+func (h *GameServerHandler) assignedInstance(ctx context.Context, userID int, session *redisClient.SessionData, regionID string) (*redisClient.GameServerInstance, error) {
+	instanceID, err := h.redis.GetUserReservation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if instanceID == "" {
+		instanceID = session.InstanceID
+	}
+	if instanceID == "" {
+		return nil, nil
+	}
+
+	instance, err := h.redis.GetGameServer(ctx, instanceID)
+	if errors.Is(err, redisClient.ErrGameServerNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if instance.Region != regionID {
+		return nil, nil
+	}
+	return instance, nil
+}
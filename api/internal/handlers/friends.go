@@ -0,0 +1,665 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+	"github.com/omega-realm/api/internal/middleware"
+	"github.com/omega-realm/api/internal/models"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/websocket"
+)
+
+// maxPresenceLookup caps how many users one presence request may ask about
+const maxPresenceLookup = 100
+
+type FriendHandler struct {
+	db     *database.DB
+	redis  *redisClient.Client
+	events *websocket.Publisher
+}
+
+func NewFriendHandler(db *database.DB, redis *redisClient.Client, events *websocket.Publisher) *FriendHandler {
+	return &FriendHandler{db: db, redis: redis, events: events}
+}
+
+// Friend is an accepted friend with their current presence
+type Friend struct {
+	UserID        int                   `json:"user_id"`
+	Username      string                `json:"username"`
+	CharacterName *string               `json:"character_name"`
+	FriendsSince  time.Time             `json:"friends_since"`
+	Presence      *redisClient.Presence `json:"presence"`
+}
+
+// FriendUsernameRequest names another player by username
+type FriendUsernameRequest struct {
+	Username string `json:"username"`
+}
+
+// FriendUserRequest names another player by user ID
+type FriendUserRequest struct {
+	UserID int `json:"user_id"`
+}
+
+// FriendRequestResponseRequest represents the request body for accepting or declining a friend request
+type FriendRequestResponseRequest struct {
+	RequestID int `json:"request_id"`
+}
+
+// GetFriends returns the user's friends and where they are playing
+func (h *FriendHandler) GetFriends(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	ctx := r.Context()
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT u.id, u.username, c.name, COALESCE(f.responded_at, f.created_at)
+		FROM friendships f
+		JOIN users u ON u.id = CASE WHEN f.requester_id = $1 THEN f.addressee_id ELSE f.requester_id END
+		LEFT JOIN characters c ON c.user_id = u.id AND c.deleted_at IS NULL
+		WHERE (f.requester_id = $1 OR f.addressee_id = $1) AND f.status = 'accepted'
+		ORDER BY u.username
+	`, claims.UserID)
+	if err != nil {
+		log.Printf("[Friends] Failed to fetch friends for user %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to fetch friends"})
+		return
+	}
+	defer rows.Close()
+
+	friends := []Friend{}
+	for rows.Next() {
+		var friend Friend
+		if err := rows.Scan(&friend.UserID, &friend.Username, &friend.CharacterName, &friend.FriendsSince); err != nil {
+			log.Printf("[Friends] Failed to scan friend: %v", err)
+			continue
+		}
+		friends = append(friends, friend)
+	}
+
+	friendIDs := make([]int, len(friends))
+	for i, friend := range friends {
+		friendIDs[i] = friend.UserID
+	}
+	presence, err := h.visiblePresence(r, claims.UserID, friendIDs)
+	if err != nil {
+		log.Printf("[Friends] %v", err)
+	}
+	for i := range friends {
+		friends[i].Presence = presence[friends[i].UserID]
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"friends": friends,
+	})
+}
+
+// GetFriendRequests returns the user's pending incoming and outgoing friend requests
+func (h *FriendHandler) GetFriendRequests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	rows, err := h.db.QueryContext(r.Context(), `
+		SELECT f.id, f.requester_id, req.username, f.addressee_id, addr.username, f.status, f.created_at
+		FROM friendships f
+		JOIN users req ON req.id = f.requester_id
+		JOIN users addr ON addr.id = f.addressee_id
+		WHERE (f.requester_id = $1 OR f.addressee_id = $1) AND f.status = 'pending'
+		ORDER BY f.created_at DESC
+	`, claims.UserID)
+	if err != nil {
+		log.Printf("[Friends] Failed to fetch friend requests for user %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to fetch friend requests"})
+		return
+	}
+	defer rows.Close()
+
+	incoming := []models.FriendRequest{}
+	outgoing := []models.FriendRequest{}
+	for rows.Next() {
+		var request models.FriendRequest
+		if err := rows.Scan(&request.ID, &request.RequesterID, &request.RequesterName, &request.AddresseeID, &request.AddresseeName, &request.Status, &request.CreatedAt); err != nil {
+			log.Printf("[Friends] Failed to scan friend request: %v", err)
+			continue
+		}
+		if request.AddresseeID == claims.UserID {
+			incoming = append(incoming, request)
+		} else {
+			outgoing = append(outgoing, request)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"incoming": incoming,
+		"outgoing": outgoing,
+	})
+}
+
+// SendFriendRequest sends a friend request to another player. If they had
+// already sent one to the user, it is accepted instead.
+func (h *FriendHandler) SendFriendRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req FriendUsernameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	targetID, ok := h.lookupUser(w, r, req.Username, claims.UserID)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	blocked, err := h.db.IsBlocked(ctx, claims.UserID, targetID)
+	if err != nil {
+		log.Printf("[Friends] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+	if blocked {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You cannot send a friend request to this user"})
+		return
+	}
+
+	// A pending request in the other direction means both want to be friends
+	var request models.FriendRequest
+	err = h.db.QueryRowContext(ctx, `
+		UPDATE friendships SET status = 'accepted', responded_at = CURRENT_TIMESTAMP
+		WHERE requester_id = $1 AND addressee_id = $2 AND status = 'pending'
+		RETURNING id, requester_id, addressee_id, status, created_at, responded_at
+	`, targetID, claims.UserID).Scan(
+		&request.ID,
+		&request.RequesterID,
+		&request.AddresseeID,
+		&request.Status,
+		&request.CreatedAt,
+		&request.RespondedAt,
+	)
+	if err == nil {
+		log.Printf("[Friends] Users %d and %d are now friends", targetID, claims.UserID)
+		if err := h.events.PublishUser(ctx, targetID, websocket.EventFriendAccepted, request); err != nil {
+			log.Printf("[Friends] %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": "Friend request accepted",
+			"request": request,
+		})
+		return
+	}
+	if err != sql.ErrNoRows {
+		log.Printf("[Friends] Failed to accept reverse friend request: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to send friend request"})
+		return
+	}
+
+	err = h.db.QueryRowContext(ctx, `
+		INSERT INTO friendships (requester_id, addressee_id)
+		VALUES ($1, $2)
+		ON CONFLICT (LEAST(requester_id, addressee_id), GREATEST(requester_id, addressee_id))
+			WHERE status IN ('pending', 'accepted') DO NOTHING
+		RETURNING id, requester_id, addressee_id, status, created_at
+	`, claims.UserID, targetID).Scan(
+		&request.ID,
+		&request.RequesterID,
+		&request.AddresseeID,
+		&request.Status,
+		&request.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You are already friends or a request is pending"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Friends] Failed to create friend request: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to send friend request"})
+		return
+	}
+
+	request.RequesterName = claims.Username
+	if err := h.events.PublishUser(ctx, targetID, websocket.EventFriendRequest, request); err != nil {
+		log.Printf("[Friends] %v", err)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Friend request sent",
+		"request": request,
+	})
+}
+
+// AcceptFriendRequest accepts a pending friend request sent to the user
+func (h *FriendHandler) AcceptFriendRequest(w http.ResponseWriter, r *http.Request) {
+	h.respondToRequest(w, r, models.FriendshipAccepted)
+}
+
+// DeclineFriendRequest declines a pending friend request sent to the user
+func (h *FriendHandler) DeclineFriendRequest(w http.ResponseWriter, r *http.Request) {
+	h.respondToRequest(w, r, models.FriendshipDeclined)
+}
+
+func (h *FriendHandler) respondToRequest(w http.ResponseWriter, r *http.Request, status string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req FriendRequestResponseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	var request models.FriendRequest
+	err := h.db.QueryRowContext(r.Context(), `
+		UPDATE friendships SET status = $3, responded_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND addressee_id = $2 AND status = 'pending'
+		RETURNING id, requester_id, addressee_id, status, created_at, responded_at
+	`, req.RequestID, claims.UserID, status).Scan(
+		&request.ID,
+		&request.RequesterID,
+		&request.AddresseeID,
+		&request.Status,
+		&request.CreatedAt,
+		&request.RespondedAt,
+	)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Friend request not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Friends] Failed to set friend request %d to %s: %v", req.RequestID, status, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to respond to friend request"})
+		return
+	}
+	message := "Friend request declined"
+	if status == models.FriendshipAccepted {
+		message = "Friend request accepted"
+		request.AddresseeName = claims.Username
+		if err := h.events.PublishUser(r.Context(), request.RequesterID, websocket.EventFriendAccepted, request); err != nil {
+			log.Printf("[Friends] %v", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// RemoveFriend removes a friend, or withdraws a pending request to or from them
+func (h *FriendHandler) RemoveFriend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req FriendUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	result, err := h.db.ExecContext(r.Context(), `
+		DELETE FROM friendships
+		WHERE ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+			AND status IN ('pending', 'accepted')
+	`, claims.UserID, req.UserID)
+	if err != nil {
+		log.Printf("[Friends] Failed to remove friend %d for user %d: %v", req.UserID, claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to remove friend"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Friend not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Friend removed"})
+}
+
+// BlockUser blocks another player. Any friendship or pending request between
+// them is removed, and they stop seeing each other's presence.
+func (h *FriendHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req FriendUsernameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	targetID, ok := h.lookupUser(w, r, req.Username, claims.UserID)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[Friends] Failed to begin transaction: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to block user"})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+	`, claims.UserID, targetID); err != nil {
+		log.Printf("[Friends] Failed to block user %d for user %d: %v", targetID, claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to block user"})
+		return
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM friendships
+		WHERE ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+			AND status IN ('pending', 'accepted')
+	`, claims.UserID, targetID); err != nil {
+		log.Printf("[Friends] Failed to remove friendship with blocked user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to block user"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("[Friends] Failed to commit block: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to block user"})
+		return
+	}
+
+	log.Printf("[Friends] User %d blocked user %d", claims.UserID, targetID)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "User blocked"})
+}
+
+// UnblockUser lifts a block the user placed
+func (h *FriendHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req FriendUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	result, err := h.db.ExecContext(r.Context(),
+		`DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`, claims.UserID, req.UserID,
+	)
+	if err != nil {
+		log.Printf("[Friends] Failed to unblock user %d for user %d: %v", req.UserID, claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to unblock user"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User is not blocked"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "User unblocked"})
+}
+
+// GetBlockedUsers returns the players the user has blocked
+func (h *FriendHandler) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	rows, err := h.db.QueryContext(r.Context(), `
+		SELECT u.id, u.username, b.created_at
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC
+	`, claims.UserID)
+	if err != nil {
+		log.Printf("[Friends] Failed to fetch blocked users for user %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to fetch blocked users"})
+		return
+	}
+	defer rows.Close()
+
+	blocked := []models.BlockedUser{}
+	for rows.Next() {
+		var user models.BlockedUser
+		if err := rows.Scan(&user.UserID, &user.Username, &user.CreatedAt); err != nil {
+			log.Printf("[Friends] Failed to scan blocked user: %v", err)
+			continue
+		}
+		blocked = append(blocked, user)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"blocked": blocked,
+	})
+}
+
+// GetPresence returns the presence of the users in ?user_ids=1,2,3. Users who
+// blocked the caller, or whom the caller blocked, always appear offline.
+func (h *FriendHandler) GetPresence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var userIDs []int
+	for _, field := range strings.Split(r.URL.Query().Get("user_ids"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		userID, err := strconv.Atoi(field)
+		if err != nil || userID <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "user_ids must be a comma-separated list of user IDs"})
+			return
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if len(userIDs) == 0 || len(userIDs) > maxPresenceLookup {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Provide between 1 and " + strconv.Itoa(maxPresenceLookup) + " user_ids"})
+		return
+	}
+
+	presence, err := h.visiblePresence(r, claims.UserID, userIDs)
+	if err != nil {
+		log.Printf("[Friends] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get presence"})
+		return
+	}
+
+	result := make([]*redisClient.Presence, 0, len(userIDs))
+	for _, userID := range userIDs {
+		result = append(result, presence[userID])
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"presence": result,
+	})
+}
+
+// visiblePresence returns the presence of userIDs as seen by viewerID. Blocked
+// users in either direction are reported offline. On error every user is
+// reported offline alongside it.
+func (h *FriendHandler) visiblePresence(r *http.Request, viewerID int, userIDs []int) (map[int]*redisClient.Presence, error) {
+	offline := func() map[int]*redisClient.Presence {
+		presence := make(map[int]*redisClient.Presence, len(userIDs))
+		for _, userID := range userIDs {
+			presence[userID] = &redisClient.Presence{UserID: userID, Status: redisClient.PresenceOffline}
+		}
+		return presence
+	}
+	if len(userIDs) == 0 {
+		return offline(), nil
+	}
+
+	ctx := r.Context()
+	blocked, err := h.db.BlockedAmong(ctx, viewerID, userIDs)
+	if err != nil {
+		return offline(), err
+	}
+	presence, err := h.redis.GetPresence(ctx, userIDs)
+	if err != nil {
+		return offline(), err
+	}
+
+	for userID := range blocked {
+		presence[userID] = &redisClient.Presence{UserID: userID, Status: redisClient.PresenceOffline}
+	}
+	return presence, nil
+}
+
+// lookupUser resolves a username to a user ID other than the caller's
+func (h *FriendHandler) lookupUser(w http.ResponseWriter, r *http.Request, username string, callerID int) (int, bool) {
+	var userID int
+	err := h.db.QueryRowContext(r.Context(), `SELECT id FROM users WHERE username = $1`, username).Scan(&userID)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not found"})
+		return 0, false
+	}
+	if err != nil {
+		log.Printf("[Friends] Failed to look up user %q: %v", username, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return 0, false
+	}
+	if userID == callerID {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You cannot do that to yourself"})
+		return 0, false
+	}
+	return userID, true
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+	"github.com/omega-realm/api/internal/models"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/regions"
+)
+
+type GameServerHandler struct {
+	db       *database.DB
+	redis    *redisClient.Client
+	registry *regions.Registry
+	config   *redisClient.GameServerConfig
+}
+
+func NewGameServerHandler(db *database.DB, redis *redisClient.Client, registry *regions.Registry, config *redisClient.GameServerConfig) *GameServerHandler {
+	return &GameServerHandler{db: db, redis: redis, registry: registry, config: config}
+}
+
+// RegisterGameServerRequest represents the request body a game server sends on startup
+type RegisterGameServerRequest struct {
+	InstanceID      string `json:"instance_id"`
+	Region          string `json:"region"`
+	Address         string `json:"address"`
+	InternalAddress string `json:"internal_address"`
+	Capacity        int    `json:"capacity"`
+	PlayerCount     int    `json:"player_count"`
+}
+
+// GameServerHeartbeatRequest represents the periodic heartbeat from a game server
+type GameServerHeartbeatRequest struct {
+	InstanceID  string                  `json:"instance_id"`
+	PlayerCount int                     `json:"player_count"`
+	Metrics     redisClient.TickMetrics `json:"metrics"`
+}
+
+// DeregisterGameServerRequest represents the request body sent on clean shutdown
+type DeregisterGameServerRequest struct {
+	InstanceID string `json:"instance_id"`
+}
+
+// ClaimReservationRequest is sent by a game server when an assigned player connects
+type ClaimReservationRequest struct {
+	InstanceID  string `json:"instance_id"`
+	CharacterID int    `json:"character_id"`
+}
+
+// ClaimReservationResponse reports whether the player held a slot on the instance
+type ClaimReservationResponse struct {
+	Reserved bool `json:"reserved"`
+}
+
+// GameServerResponse is returned from register and heartbeat calls
+type GameServerResponse struct {
+	Instance                 *redisClient.GameServerInstance `json:"instance"`
+	HeartbeatIntervalSeconds int                             `json:"heartbeat_interval_seconds"`
+}
+
+// RegisterGameServer registers (or re-registers) a game server instance
+func (h *GameServerHandler) RegisterGameServer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req RegisterGameServerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	req.Region = regions.NormalizeRegionID(req.Region)
+	if err := h.validateRegisterRequest(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if req.InstanceID == "" {
+		id, err := randomHexID()
+		if err != nil {
+			log.Printf("[GameServer] Failed to generate instance ID: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+			return
+		}
+		req.InstanceID = id
+	}
+
+	now := time.Now().UTC()
+	instance := &redisClient.GameServerInstance{
+		InstanceID:      req.InstanceID,
+		Region:          req.Region,
+		Address:         req.Address,
+		InternalAddress: req.InternalAddress,
+		Capacity:        req.Capacity,
+		PlayerCount:     req.PlayerCount,
+		RegisteredAt:    now,
+		LastHeartbeat:   now,
+	}
+
+	if err := h.redis.RegisterGameServer(r.Context(), instance, h.config.TTL); err != nil {
+		log.Printf("[GameServer] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to register game server"})
+		return
+	}
+
+	log.Printf("[GameServer] Registered %s in %s at %s (capacity %d)", instance.InstanceID, instance.Region, instance.Address, instance.Capacity)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(GameServerResponse{
+		Instance:                 instance,
+		HeartbeatIntervalSeconds: int(h.config.HeartbeatInterval.Seconds()),
+	})
+}
+
+// Heartbeat refreshes an instance's TTL, player count and tick metrics. A 404
+// tells the game server its registration expired and it must register again.
+func (h *GameServerHandler) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req GameServerHeartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	if req.InstanceID == "" || req.PlayerCount < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Instance ID and a non-negative player count are required"})
+		return
+	}
+
+	instance, err := h.redis.SetGameServerHeartbeat(r.Context(), req.InstanceID, req.PlayerCount, req.Metrics, h.config.TTL)
+	if errors.Is(err, redisClient.ErrGameServerNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Game server not registered"})
+		return
+	}
+	if err != nil {
+		log.Printf("[GameServer] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to record heartbeat"})
+		return
+	}
+
+	// Game servers refresh their player count on their own schedule, so only
+	// a claim one interval older than this heartbeat is surely in its count
+	claimedBefore := time.Now().Add(-h.config.HeartbeatInterval)
+	if err := h.redis.ReleaseClaims(r.Context(), req.InstanceID, claimedBefore, h.config.TTL); err != nil {
+		log.Printf("[GameServer] %v", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(GameServerResponse{
+		Instance:                 instance,
+		HeartbeatIntervalSeconds: int(h.config.HeartbeatInterval.Seconds()),
+	})
+}
+
+// DeregisterGameServer removes an instance on clean shutdown
+func (h *GameServerHandler) DeregisterGameServer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req DeregisterGameServerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	err := h.redis.DeregisterGameServer(r.Context(), req.InstanceID)
+	if errors.Is(err, redisClient.ErrGameServerNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Game server not registered"})
+		return
+	}
+	if err != nil {
+		log.Printf("[GameServer] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to deregister game server"})
+		return
+	}
+
+	// Players still connected were dropped by the shutdown
+	userIDs, err := h.db.CloseInstanceSessions(r.Context(), req.InstanceID, models.SessionEndServerShutdown)
+	if err != nil {
+		log.Printf("[GameServer] %v", err)
+	}
+	for _, userID := range userIDs {
+		if err := h.redis.ClearUserSessionGameServer(r.Context(), userID, req.InstanceID); err != nil {
+			log.Printf("[GameServer] Failed to update presence for user %d: %v", userID, err)
+		}
+	}
+
+	log.Printf("[GameServer] Deregistered %s, closed %d sessions", req.InstanceID, len(userIDs))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Game server deregistered"})
+}
+
+// ClaimReservation consumes the slot reserved for a player by SelectRegion.
+// Unclaimed reservations lapse on their own, freeing the slot. A claimed slot
+// stays counted until a heartbeat includes the player, or for the instance's
+// TTL if heartbeats stop.
+func (h *GameServerHandler) ClaimReservation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ClaimReservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	var userID int
+	err := h.db.QueryRowContext(r.Context(),
+		`SELECT user_id FROM characters WHERE id = $1 AND deleted_at IS NULL`, req.CharacterID,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Character not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[GameServer] Failed to look up character %d: %v", req.CharacterID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+
+	reserved, err := h.redis.ClaimReservation(r.Context(), req.InstanceID, userID, h.config.TTL)
+	if err != nil {
+		log.Printf("[GameServer] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to claim reservation"})
+		return
+	}
+	if err := h.redis.ClearPromotion(r.Context(), userID); err != nil {
+		log.Printf("[GameServer] %v", err)
+	}
+
+	// The player is now in game on this instance, which friends can see. A
+	// claim without a reservation says nothing about where they are.
+	if !reserved {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(ClaimReservationResponse{Reserved: false})
+		return
+	}
+	if instance, err := h.redis.GetGameServer(r.Context(), req.InstanceID); err == nil {
+		if err := h.redis.UpdateUserSessionGameServer(r.Context(), userID, req.CharacterID, instance.Region, instance.InstanceID); err != nil {
+			log.Printf("[GameServer] Failed to update presence for user %d: %v", userID, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ClaimReservationResponse{Reserved: reserved})
+}
+
+// ListGameServers returns the live instances of every region (admin only)
+func (h *GameServerHandler) ListGameServers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	servers := make(map[string][]*redisClient.GameServerInstance)
+	for _, regionID := range h.registry.RegionIDs() {
+		instances, err := h.redis.ListGameServers(r.Context(), regionID)
+		if err != nil {
+			log.Printf("[GameServer] %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to list game servers"})
+			return
+		}
+		if instances == nil {
+			instances = []*redisClient.GameServerInstance{}
+		}
+		servers[regionID] = instances
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"servers": servers,
+	})
+}
+
+// validateRegisterRequest validates a game server registration
+func (h *GameServerHandler) validateRegisterRequest(req *RegisterGameServerRequest) error {
+	if len(req.InstanceID) > 64 {
+		return &ValidationError{Field: "instance_id", Message: "Instance ID must not exceed 64 characters"}
+	}
+	if !h.registry.IsValidRegion(req.Region) {
+		return &ValidationError{
+			Field:   "region",
+			Message: "Invalid region. Valid regions are: " + strings.Join(h.registry.RegionIDs(), ", "),
+		}
+	}
+	if !strings.HasPrefix(req.Address, "ws://") && !strings.HasPrefix(req.Address, "wss://") {
+		return &ValidationError{Field: "address", Message: "Address must start with ws:// or wss://"}
+	}
+	if req.InternalAddress != "" && !strings.HasPrefix(req.InternalAddress, "ws://") && !strings.HasPrefix(req.InternalAddress, "wss://") {
+		return &ValidationError{Field: "internal_address", Message: "Internal address must start with ws:// or wss://"}
+	}
+	if req.Capacity <= 0 {
+		return &ValidationError{Field: "capacity", Message: "Capacity must be positive"}
+	}
+	if req.PlayerCount < 0 {
+		return &ValidationError{Field: "player_count", Message: "Player count must not be negative"}
+	}
+	return nil
+}
+
+// randomHexID returns a random 16-character ID for instances and parties
+func randomHexID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
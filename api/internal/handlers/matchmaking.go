@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+	"github.com/omega-realm/api/internal/matchmaking"
+	"github.com/omega-realm/api/internal/middleware"
+	"github.com/omega-realm/api/internal/models"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/regions"
+	"github.com/omega-realm/api/internal/websocket"
+)
+
+// Matchmaking statuses
+const (
+	MatchStatusSearching = "searching"
+	MatchStatusMatched   = "matched"
+)
+
+type MatchmakingHandler struct {
+	db       *database.DB
+	redis    *redisClient.Client
+	registry *regions.Registry
+	service  *matchmaking.Service
+	events   *websocket.Publisher
+}
+
+func NewMatchmakingHandler(db *database.DB, redis *redisClient.Client, registry *regions.Registry, service *matchmaking.Service, events *websocket.Publisher) *MatchmakingHandler {
+	return &MatchmakingHandler{
+		db:       db,
+		redis:    redis,
+		registry: registry,
+		service:  service,
+		events:   events,
+	}
+}
+
+type JoinMatchmakingRequest struct {
+	RegionID string `json:"region_id"`
+}
+
+// MatchmakingStatusResponse describes a PvP matchmaking ticket. The player's
+// rating is deliberately left out; once a match forms the status becomes
+// "matched" and the arena's connection details are filled in.
+type MatchmakingStatusResponse struct {
+	Status               string     `json:"status"`
+	Region               string     `json:"region"`
+	WaitSeconds          int        `json:"wait_seconds,omitempty"`
+	MatchID              string     `json:"match_id,omitempty"`
+	PlayerCount          int        `json:"player_count,omitempty"`
+	InstanceID           string     `json:"instance_id,omitempty"`
+	WebSocketURL         string     `json:"websocket_url,omitempty"`
+	ReservationExpiresAt *time.Time `json:"reservation_expires_at,omitempty"`
+}
+
+type RecordKillRequest struct {
+	KillerCharacterID int `json:"killer_character_id"`
+	VictimCharacterID int `json:"victim_character_id"`
+}
+
+// JoinMatchmaking puts the user's character into the PvP matchmaking pool
+// for a region. Joining again restarts the search.
+func (h *MatchmakingHandler) JoinMatchmaking(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req JoinMatchmakingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	req.RegionID = regions.NormalizeRegionID(req.RegionID)
+	if !h.registry.IsValidRegion(req.RegionID) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error: "Invalid region. Valid regions are: " + strings.Join(h.registry.RegionIDs(), ", "),
+		})
+		return
+	}
+	region := h.registry.GetRegionDetails(req.RegionID)
+	if region == nil || region.Status != models.RegionStatusOnline {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Selected region is currently unavailable"})
+		return
+	}
+
+	ctx := r.Context()
+	var characterID int
+	err := h.db.QueryRowContext(ctx,
+		`SELECT id FROM characters WHERE user_id = $1 AND deleted_at IS NULL`, claims.UserID,
+	).Scan(&characterID)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Character not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Matchmaking] Failed to look up character for user %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+
+	// Arena matches are solo; drop any open-world queue spot the user holds
+	if err := h.redis.LeaveQueue(ctx, claims.UserID); err != nil {
+		log.Printf("[Matchmaking] %v", err)
+	}
+
+	ticket, err := h.service.Enqueue(ctx, claims.UserID, characterID, req.RegionID)
+	if err != nil {
+		log.Printf("[Matchmaking] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to join matchmaking"})
+		return
+	}
+
+	log.Printf("[Matchmaking] User %d searching in %s", claims.UserID, req.RegionID)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(MatchmakingStatusResponse{
+		Status: MatchStatusSearching,
+		Region: ticket.Region,
+	})
+}
+
+// GetMatchmakingStatus returns the user's search or match. Clients should
+// poll it; tickets that are not polled expire.
+func (h *MatchmakingHandler) GetMatchmakingStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	ticket, match, err := h.service.Status(r.Context(), claims.UserID)
+	if errors.Is(err, matchmaking.ErrNoTicket) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Not in matchmaking"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Matchmaking] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get matchmaking status"})
+		return
+	}
+
+	var response MatchmakingStatusResponse
+	if match != nil {
+		response = MatchmakingStatusResponse{
+			Status:               MatchStatusMatched,
+			Region:               match.Region,
+			MatchID:              match.ID,
+			PlayerCount:          len(match.UserIDs),
+			InstanceID:           match.InstanceID,
+			WebSocketURL:         match.WebSocketURL,
+			ReservationExpiresAt: &match.ExpiresAt,
+		}
+	} else {
+		response = MatchmakingStatusResponse{
+			Status:      MatchStatusSearching,
+			Region:      ticket.Region,
+			WaitSeconds: int(ticket.LastSeen.Sub(ticket.CreatedAt).Seconds()),
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CancelMatchmaking stops the user's search, or gives up a match they have
+// not joined yet
+func (h *MatchmakingHandler) CancelMatchmaking(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	if err := h.service.Cancel(r.Context(), claims.UserID); err != nil {
+		log.Printf("[Matchmaking] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to cancel matchmaking"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Matchmaking cancelled"})
+}
+
+// RecordKill is called by game servers when one character kills another in
+// PvP. It updates both hidden ratings and the kill leaderboards.
+func (h *MatchmakingHandler) RecordKill(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req RecordKillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	if req.KillerCharacterID <= 0 || req.VictimCharacterID <= 0 || req.KillerCharacterID == req.VictimCharacterID {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "killer_character_id and victim_character_id must be two different characters"})
+		return
+	}
+
+	ctx := r.Context()
+	var found, killerUserID int
+	err := h.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(MAX(user_id) FILTER (WHERE id = $1), 0)
+		FROM characters WHERE id IN ($1, $2) AND deleted_at IS NULL
+	`, req.KillerCharacterID, req.VictimCharacterID).Scan(&found, &killerUserID)
+	if err != nil {
+		log.Printf("[Matchmaking] Failed to look up characters: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+	if found != 2 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Character not found"})
+		return
+	}
+
+	if _, _, err := h.service.Ratings().RecordKill(ctx, req.KillerCharacterID, req.VictimCharacterID); err != nil {
+		log.Printf("[Matchmaking] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to record kill"})
+		return
+	}
+
+	_, err = h.db.ExecContext(ctx, `
+		UPDATE leaderboards
+		SET pvp_kills = pvp_kills + CASE WHEN character_id = $1 THEN 1 ELSE 0 END,
+		    deaths = deaths + CASE WHEN character_id = $2 THEN 1 ELSE 0 END
+		WHERE character_id IN ($1, $2)
+	`, req.KillerCharacterID, req.VictimCharacterID)
+	if err != nil {
+		log.Printf("[Matchmaking] Failed to update leaderboards: %v", err)
+	}
+
+	// A missing rank means the killer was not on the leaderboard yet
+	previousRank, _ := h.redis.GetPlayerPvPRank(ctx, req.KillerCharacterID)
+	if err := h.redis.RecordKill(ctx, req.KillerCharacterID, req.VictimCharacterID, true); err != nil {
+		log.Printf("[Matchmaking] %v", err)
+	} else if rank, err := h.redis.GetPlayerPvPRank(ctx, req.KillerCharacterID); err == nil && rank != previousRank {
+		err := h.events.PublishUser(ctx, killerUserID, websocket.EventRankChanged, map[string]any{
+			"character_id":  req.KillerCharacterID,
+			"leaderboard":   "pvp",
+			"rank":          rank,
+			"previous_rank": previousRank,
+		})
+		if err != nil {
+			log.Printf("[Matchmaking] %v", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Kill recorded"})
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+	"github.com/omega-realm/api/internal/middleware"
+	"github.com/omega-realm/api/internal/models"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/websocket"
+)
+
+const (
+	defaultChatReviewLimit = 100
+	maxChatReviewLimit     = 500
+)
+
+// sanctionTypes are the sanctions moderators may issue
+var sanctionTypes = map[string]bool{
+	models.SanctionMute: true,
+	models.SanctionBan:  true,
+}
+
+type ModerationHandler struct {
+	db     *database.DB
+	redis  *redisClient.Client
+	events *websocket.Publisher
+}
+
+func NewModerationHandler(db *database.DB, redis *redisClient.Client, events *websocket.Publisher) *ModerationHandler {
+	return &ModerationHandler{db: db, redis: redis, events: events}
+}
+
+type IssueSanctionRequest struct {
+	UserID          int    `json:"user_id"`
+	Type            string `json:"type"`
+	Reason          string `json:"reason"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+type RevokeSanctionRequest struct {
+	SanctionID int `json:"sanction_id"`
+}
+
+// GetChatMessages returns stored chat, newest first, for moderation review.
+// Filters: user_id (sent or whispered to), channel, channel_key, before_id
+// (for paging) and limit.
+func (h *ModerationHandler) GetChatMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	userID, ok := intParam(w, r, "user_id", 0)
+	if !ok {
+		return
+	}
+	beforeID, ok := intParam(w, r, "before_id", 0)
+	if !ok {
+		return
+	}
+	limit, ok := intParam(w, r, "limit", defaultChatReviewLimit)
+	if !ok {
+		return
+	}
+	limit = min(max(limit, 1), maxChatReviewLimit)
+
+	rows, err := h.db.QueryContext(r.Context(), `
+		SELECT m.id, m.sender_id, s.username, m.channel, COALESCE(m.channel_key, ''),
+		       m.recipient_id, COALESCE(rcpt.username, ''), m.message, m.original_message, m.created_at
+		FROM chat_messages m
+		JOIN users s ON s.id = m.sender_id
+		LEFT JOIN users rcpt ON rcpt.id = m.recipient_id
+		WHERE ($1 = 0 OR m.sender_id = $1 OR m.recipient_id = $1)
+		  AND ($2 = '' OR m.channel = $2)
+		  AND ($3 = '' OR m.channel_key = $3)
+		  AND ($4 = 0 OR m.id < $4)
+		ORDER BY m.id DESC
+		LIMIT $5
+	`, userID, query.Get("channel"), query.Get("channel_key"), beforeID, limit)
+	if err != nil {
+		log.Printf("[Moderation] Failed to query chat messages: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load chat messages"})
+		return
+	}
+	defer rows.Close()
+
+	messages := []models.ChatMessage{}
+	for rows.Next() {
+		var m models.ChatMessage
+		if err := rows.Scan(&m.ID, &m.SenderID, &m.SenderName, &m.Channel, &m.ChannelKey,
+			&m.RecipientID, &m.RecipientName, &m.Message, &m.Original, &m.CreatedAt); err != nil {
+			log.Printf("[Moderation] Failed to scan chat message: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load chat messages"})
+			return
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("[Moderation] Failed to read chat messages: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load chat messages"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"messages": messages})
+}
+
+// GetSanctions lists every sanction issued against a user, newest first
+func (h *ModerationHandler) GetSanctions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := intParam(w, r, "user_id", 0)
+	if !ok {
+		return
+	}
+	if userID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "user_id is required"})
+		return
+	}
+
+	rows, err := h.db.QueryContext(r.Context(), `
+		SELECT id, user_id, type, reason, report_id, issued_by, created_at, expires_at, revoked_at, revoked_by
+		FROM sanctions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("[Moderation] Failed to query sanctions for user %d: %v", userID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load sanctions"})
+		return
+	}
+	defer rows.Close()
+
+	sanctions := []models.Sanction{}
+	for rows.Next() {
+		var s models.Sanction
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Type, &s.Reason, &s.ReportID, &s.IssuedBy, &s.CreatedAt, &s.ExpiresAt, &s.RevokedAt, &s.RevokedBy); err != nil {
+			log.Printf("[Moderation] Failed to scan sanction: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load sanctions"})
+			return
+		}
+		sanctions = append(sanctions, s)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("[Moderation] Failed to read sanctions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load sanctions"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"sanctions": sanctions})
+}
+
+// IssueSanction mutes or bans a user. A duration of 0 makes the sanction
+// permanent.
+func (h *ModerationHandler) IssueSanction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req IssueSanctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	if msg := validateSanctionRequest(&req, claims.UserID); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
+	}
+
+	sanction := newSanction(req, claims.UserID)
+	err := issueSanction(r.Context(), h.db, h.redis, h.events, sanction)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Moderation] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to issue sanction"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sanction)
+}
+
+// RevokeSanction lifts an active sanction early
+func (h *ModerationHandler) RevokeSanction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req RevokeSanctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	ctx := r.Context()
+	var userID int
+	var sanctionType string
+	err := h.db.QueryRowContext(ctx, `
+		UPDATE sanctions
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_by = $2
+		WHERE id = $1 AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		RETURNING user_id, type
+	`, req.SanctionID, claims.UserID).Scan(&userID, &sanctionType)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Active sanction not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Moderation] Failed to revoke sanction %d: %v", req.SanctionID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to revoke sanction"})
+		return
+	}
+
+	if err := h.db.RecordAuditEvent(ctx, userID, database.AuditSanctionRevoked, map[string]any{
+		"sanction_id": req.SanctionID,
+		"type":        sanctionType,
+		"revoked_by":  claims.UserID,
+	}); err != nil {
+		log.Printf("[Moderation] %v", err)
+	}
+
+	log.Printf("[Moderation] User %d revoked %s #%d for user %d", claims.UserID, sanctionType, req.SanctionID, userID)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Sanction revoked"})
+}
+
+// validateSanctionRequest trims the request and returns why it is invalid,
+// or "" if it is acceptable
+func validateSanctionRequest(req *IssueSanctionRequest, moderatorID int) string {
+	req.Reason = strings.TrimSpace(req.Reason)
+	switch {
+	case req.UserID <= 0:
+		return "user_id is required"
+	case !sanctionTypes[req.Type]:
+		return "Invalid sanction type"
+	case req.Reason == "":
+		return "A reason is required"
+	case req.DurationMinutes < 0:
+		return "duration_minutes must not be negative"
+	case req.UserID == moderatorID:
+		return "You cannot sanction yourself"
+	}
+	return ""
+}
+
+// newSanction builds the sanction a request describes. A duration of 0 makes
+// it permanent.
+func newSanction(req IssueSanctionRequest, moderatorID int) *models.Sanction {
+	sanction := &models.Sanction{
+		UserID:   req.UserID,
+		Type:     req.Type,
+		Reason:   req.Reason,
+		IssuedBy: &moderatorID,
+	}
+	if req.DurationMinutes > 0 {
+		expiresAt := time.Now().UTC().Add(time.Duration(req.DurationMinutes) * time.Minute)
+		sanction.ExpiresAt = &expiresAt
+	}
+	return sanction
+}
+
+// issueSanction stores a sanction and puts it into effect. A muted user is
+// told so; a banned user loses their sessions and queue spot and is
+// disconnected from the event stream. Returns sql.ErrNoRows if the user does
+// not exist.
+func issueSanction(ctx context.Context, db *database.DB, redis *redisClient.Client, events *websocket.Publisher, sanction *models.Sanction) error {
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO sanctions (user_id, type, reason, report_id, issued_by, expires_at)
+		SELECT id, $2, $3, $4, $5, $6 FROM users WHERE id = $1
+		RETURNING id, created_at
+	`, sanction.UserID, sanction.Type, sanction.Reason, sanction.ReportID, sanction.IssuedBy, sanction.ExpiresAt,
+	).Scan(&sanction.ID, &sanction.CreatedAt)
+	if err == sql.ErrNoRows {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to issue %s to user %d: %w", sanction.Type, sanction.UserID, err)
+	}
+
+	if err := db.RecordAuditEvent(ctx, sanction.UserID, database.AuditSanctionIssued, map[string]any{
+		"sanction_id": sanction.ID,
+		"type":        sanction.Type,
+		"reason":      sanction.Reason,
+		"report_id":   sanction.ReportID,
+		"issued_by":   sanction.IssuedBy,
+		"expires_at":  sanction.ExpiresAt,
+	}); err != nil {
+		log.Printf("[Moderation] %v", err)
+	}
+
+	notice := map[string]any{
+		"reason":     sanction.Reason,
+		"expires_at": sanction.ExpiresAt,
+	}
+	switch sanction.Type {
+	case models.SanctionMute:
+		if err := events.PublishUser(ctx, sanction.UserID, websocket.EventMuted, notice); err != nil {
+			log.Printf("[Moderation] %v", err)
+		}
+	case models.SanctionBan:
+		if err := redis.InvalidateUserSessions(ctx, sanction.UserID); err != nil {
+			log.Printf("[Moderation] Failed to end sessions of banned user %d: %v", sanction.UserID, err)
+		}
+		if err := redis.LeaveQueue(ctx, sanction.UserID); err != nil {
+			log.Printf("[Moderation] %v", err)
+		}
+		// The hub closes the user's connections after delivering this
+		if err := events.PublishUser(ctx, sanction.UserID, websocket.EventBanned, notice); err != nil {
+			log.Printf("[Moderation] %v", err)
+		}
+	}
+
+	log.Printf("[Moderation] User %d issued %s #%d to user %d", *sanction.IssuedBy, sanction.Type, sanction.ID, sanction.UserID)
+	return nil
+}
+
+// intParam reads an optional integer query parameter, replying with 400 if it
+// is malformed
+func intParam(w http.ResponseWriter, r *http.Request, name string, defaultValue int) (int, bool) {
+	valueStr := r.URL.Query().Get(name)
+	if valueStr == "" {
+		return defaultValue, true
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: name + " must be a non-negative integer"})
+		return 0, false
+	}
+	return value, true
+}
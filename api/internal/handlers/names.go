@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/omega-realm/api/internal/namepolicy"
+)
+
+// NamePolicyErrorResponse is returned when a name fails the name policy
+type NamePolicyErrorResponse struct {
+	Error   string                 `json:"error"`
+	Field   string                 `json:"field"`
+	Reasons []namepolicy.Violation `json:"reasons"`
+}
+
+// enforceNamePolicy runs the name policy and writes an error response if the
+// name is rejected. It returns false when the caller should stop handling the
+// request. ownerID is 0 for names that do not belong to anyone yet.
+func enforceNamePolicy(w http.ResponseWriter, r *http.Request, policy *namepolicy.Policy, kind namepolicy.Kind, field, name string, ownerID int) bool {
+	violations, err := policy.Check(r.Context(), kind, name, ownerID)
+	if err != nil {
+		log.Printf("[NamePolicy] Failed to check %s %q: %v", kind, name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to validate name"})
+		return false
+	}
+	if len(violations) == 0 {
+		return true
+	}
+
+	// A name that is only taken is a conflict; anything else is invalid input
+	status := http.StatusBadRequest
+	if len(violations) == 1 && violations[0].Code == namepolicy.ReasonDuplicate {
+		status = http.StatusConflict
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(NamePolicyErrorResponse{
+		Error:   violations[0].Message,
+		Field:   field,
+		Reasons: violations,
+	})
+	return false
+}
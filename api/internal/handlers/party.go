@@ -0,0 +1,594 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/lib/pq"
+	"github.com/omega-realm/api/internal/database"
+	"github.com/omega-realm/api/internal/middleware"
+	"github.com/omega-realm/api/internal/models"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/websocket"
+)
+
+type PartyHandler struct {
+	db     *database.DB
+	redis  *redisClient.Client
+	config *redisClient.PartyConfig
+	events *websocket.Publisher
+}
+
+func NewPartyHandler(db *database.DB, redis *redisClient.Client, config *redisClient.PartyConfig, events *websocket.Publisher) *PartyHandler {
+	return &PartyHandler{db: db, redis: redis, config: config, events: events}
+}
+
+// PartyMember is a party member with display details
+type PartyMember struct {
+	UserID        int     `json:"user_id"`
+	Username      string  `json:"username"`
+	CharacterName *string `json:"character_name"`
+	IsLeader      bool    `json:"is_leader"`
+}
+
+// PartyResponse represents a party and its members
+type PartyResponse struct {
+	Party   *redisClient.Party `json:"party"`
+	Members []PartyMember      `json:"members"`
+}
+
+// PartyInviteRequest represents the request body for inviting a player
+type PartyInviteRequest struct {
+	Username string `json:"username"`
+}
+
+// PartyInviteResponseRequest represents the request body for accepting or declining an invite
+type PartyInviteResponseRequest struct {
+	InviteID int `json:"invite_id"`
+}
+
+// PartyKickRequest represents the request body for removing a member
+type PartyKickRequest struct {
+	UserID int `json:"user_id"`
+}
+
+// GetParty returns the user's current party
+func (h *PartyHandler) GetParty(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	party, ok := h.requireParty(w, r, claims.UserID)
+	if !ok {
+		return
+	}
+
+	h.writeParty(w, r, http.StatusOK, party)
+}
+
+// CreateParty creates a new party led by the user
+func (h *PartyHandler) CreateParty(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	partyID, err := randomHexID()
+	if err != nil {
+		log.Printf("[Party] Failed to generate party ID: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+
+	party, err := h.redis.CreateParty(r.Context(), partyID, claims.UserID, h.config.MaxSize)
+	if errors.Is(err, redisClient.ErrAlreadyInParty) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You are already in a party"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Party] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to create party"})
+		return
+	}
+
+	log.Printf("[Party] User %d created party %s", claims.UserID, party.ID)
+	h.writeParty(w, r, http.StatusCreated, party)
+}
+
+// InvitePlayer invites another player to the user's party (leader only)
+func (h *PartyHandler) InvitePlayer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req PartyInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	party, ok := h.requireLeader(w, r, claims.UserID)
+	if !ok {
+		return
+	}
+	if len(party.Members) >= party.MaxSize {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Party is full"})
+		return
+	}
+
+	var inviteeID int
+	err := h.db.QueryRow(`SELECT id FROM users WHERE username = $1`, req.Username).Scan(&inviteeID)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Party] Failed to look up user %q: %v", req.Username, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+	if inviteeID == claims.UserID {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You cannot invite yourself"})
+		return
+	}
+	if party.HasMember(inviteeID) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User is already in your party"})
+		return
+	}
+	blocked, err := h.db.IsBlocked(r.Context(), claims.UserID, inviteeID)
+	if err != nil {
+		log.Printf("[Party] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+	if blocked {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You cannot invite this user"})
+		return
+	}
+
+	// Expired invites no longer block a fresh one
+	_, err = h.db.Exec(`
+		UPDATE party_invites SET status = 'cancelled', responded_at = CURRENT_TIMESTAMP
+		WHERE party_id = $1 AND invitee_id = $2 AND status = 'pending' AND expires_at <= CURRENT_TIMESTAMP
+	`, party.ID, inviteeID)
+	if err != nil {
+		log.Printf("[Party] Failed to expire old invites: %v", err)
+	}
+
+	var invite models.PartyInvite
+	query := `
+		INSERT INTO party_invites (party_id, inviter_id, invitee_id, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))
+		ON CONFLICT (party_id, invitee_id) WHERE status = 'pending' DO NOTHING
+		RETURNING id, party_id, inviter_id, invitee_id, status, created_at, expires_at
+	`
+	err = h.db.QueryRow(query, party.ID, claims.UserID, inviteeID, h.config.InviteTTL.Seconds()).Scan(
+		&invite.ID,
+		&invite.PartyID,
+		&invite.InviterID,
+		&invite.InviteeID,
+		&invite.Status,
+		&invite.CreatedAt,
+		&invite.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User already has a pending invite to your party"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Party] Failed to create invite: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to create invite"})
+		return
+	}
+
+	invite.InviterName = claims.Username
+	if err := h.events.PublishUser(r.Context(), inviteeID, websocket.EventPartyInvite, invite); err != nil {
+		log.Printf("[Party] %v", err)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Invite sent",
+		"invite":  invite,
+	})
+}
+
+// GetInvites returns the user's pending, unexpired party invites
+func (h *PartyHandler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT i.id, i.party_id, i.inviter_id, u.username, i.invitee_id, i.status, i.created_at, i.expires_at
+		FROM party_invites i
+		JOIN users u ON u.id = i.inviter_id
+		WHERE i.invitee_id = $1 AND i.status = 'pending' AND i.expires_at > CURRENT_TIMESTAMP
+		ORDER BY i.created_at DESC
+	`, claims.UserID)
+	if err != nil {
+		log.Printf("[Party] Failed to fetch invites for user %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to fetch invites"})
+		return
+	}
+	defer rows.Close()
+
+	invites := []models.PartyInvite{}
+	for rows.Next() {
+		var invite models.PartyInvite
+		if err := rows.Scan(&invite.ID, &invite.PartyID, &invite.InviterID, &invite.InviterName, &invite.InviteeID, &invite.Status, &invite.CreatedAt, &invite.ExpiresAt); err != nil {
+			log.Printf("[Party] Failed to scan invite: %v", err)
+			continue
+		}
+		invites = append(invites, invite)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"invites": invites,
+	})
+}
+
+// AcceptInvite joins the party the user was invited to
+func (h *PartyHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req PartyInviteResponseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	var partyID string
+	err := h.db.QueryRow(`
+		SELECT party_id FROM party_invites
+		WHERE id = $1 AND invitee_id = $2 AND status = 'pending' AND expires_at > CURRENT_TIMESTAMP
+	`, req.InviteID, claims.UserID).Scan(&partyID)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invite not found or expired"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Party] Failed to fetch invite %d: %v", req.InviteID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+
+	party, err := h.redis.JoinParty(r.Context(), partyID, claims.UserID)
+	switch {
+	case errors.Is(err, redisClient.ErrPartyNotFound):
+		h.setInviteStatus(req.InviteID, models.PartyInviteCancelled)
+		w.WriteHeader(http.StatusGone)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Party no longer exists"})
+		return
+	case errors.Is(err, redisClient.ErrPartyFull):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Party is full"})
+		return
+	case errors.Is(err, redisClient.ErrAlreadyInParty):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Leave your current party first"})
+		return
+	case err != nil:
+		log.Printf("[Party] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to join party"})
+		return
+	}
+
+	h.setInviteStatus(req.InviteID, models.PartyInviteAccepted)
+
+	log.Printf("[Party] User %d joined party %s", claims.UserID, party.ID)
+	h.writeParty(w, r, http.StatusOK, party)
+}
+
+// DeclineInvite declines a pending party invite
+func (h *PartyHandler) DeclineInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req PartyInviteResponseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	result, err := h.db.Exec(`
+		UPDATE party_invites SET status = 'declined', responded_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND invitee_id = $2 AND status = 'pending'
+	`, req.InviteID, claims.UserID)
+	if err != nil {
+		log.Printf("[Party] Failed to decline invite %d: %v", req.InviteID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to decline invite"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invite not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invite declined"})
+}
+
+// LeaveParty removes the user from their party. Leadership passes to the
+// longest-standing member; the last member leaving disbands the party.
+func (h *PartyHandler) LeaveParty(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	party, ok := h.requireParty(w, r, claims.UserID)
+	if !ok {
+		return
+	}
+
+	remaining, err := h.redis.RemovePartyMember(r.Context(), party.ID, claims.UserID)
+	if errors.Is(err, redisClient.ErrPartyNotFound) || errors.Is(err, redisClient.ErrNotInParty) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You are not in a party"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Party] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to leave party"})
+		return
+	}
+
+	if remaining == nil {
+		h.cancelPartyInvites(party.ID)
+		log.Printf("[Party] Party %s disbanded", party.ID)
+	}
+	left := websocket.PartyLeft{PartyID: party.ID}
+	if err := h.events.PublishUser(r.Context(), claims.UserID, websocket.EventPartyLeft, left); err != nil {
+		log.Printf("[Party] %v", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Left party"})
+}
+
+// KickMember removes another member from the party (leader only)
+func (h *PartyHandler) KickMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req PartyKickRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	party, ok := h.requireLeader(w, r, claims.UserID)
+	if !ok {
+		return
+	}
+	if req.UserID == claims.UserID {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Use leave to remove yourself"})
+		return
+	}
+
+	party, err := h.redis.RemovePartyMember(r.Context(), party.ID, req.UserID)
+	if errors.Is(err, redisClient.ErrNotInParty) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User is not in your party"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Party] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to kick member"})
+		return
+	}
+
+	log.Printf("[Party] User %d kicked user %d from party %s", claims.UserID, req.UserID, party.ID)
+	left := websocket.PartyLeft{PartyID: party.ID, Kicked: true}
+	if err := h.events.PublishUser(r.Context(), req.UserID, websocket.EventPartyLeft, left); err != nil {
+		log.Printf("[Party] %v", err)
+	}
+	h.writeParty(w, r, http.StatusOK, party)
+}
+
+// requireParty loads the user's party, writing a 404 if they have none
+func (h *PartyHandler) requireParty(w http.ResponseWriter, r *http.Request, userID int) (*redisClient.Party, bool) {
+	party, err := h.redis.GetUserParty(r.Context(), userID)
+	if errors.Is(err, redisClient.ErrNotInParty) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You are not in a party"})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("[Party] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to fetch party"})
+		return nil, false
+	}
+	return party, true
+}
+
+// requireLeader loads the user's party, writing a 403 unless they lead it
+func (h *PartyHandler) requireLeader(w http.ResponseWriter, r *http.Request, userID int) (*redisClient.Party, bool) {
+	party, ok := h.requireParty(w, r, userID)
+	if !ok {
+		return nil, false
+	}
+	if party.LeaderID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Only the party leader can do that"})
+		return nil, false
+	}
+	return party, true
+}
+
+// writeParty responds with the party and its members' usernames and characters
+func (h *PartyHandler) writeParty(w http.ResponseWriter, r *http.Request, status int, party *redisClient.Party) {
+	members := make([]PartyMember, 0, len(party.Members))
+
+	rows, err := h.db.QueryContext(r.Context(), `
+		SELECT u.id, u.username, c.name
+		FROM users u
+		LEFT JOIN characters c ON c.user_id = u.id AND c.deleted_at IS NULL
+		WHERE u.id = ANY($1)
+	`, pq.Array(party.Members))
+	if err != nil {
+		log.Printf("[Party] Failed to fetch members of party %s: %v", party.ID, err)
+	} else {
+		byID := make(map[int]PartyMember, len(party.Members))
+		for rows.Next() {
+			var member PartyMember
+			if err := rows.Scan(&member.UserID, &member.Username, &member.CharacterName); err != nil {
+				log.Printf("[Party] Failed to scan party member: %v", err)
+				continue
+			}
+			byID[member.UserID] = member
+		}
+		rows.Close()
+
+		// Keep join order
+		for _, userID := range party.Members {
+			member, ok := byID[userID]
+			if !ok {
+				member = PartyMember{UserID: userID}
+			}
+			member.IsLeader = userID == party.LeaderID
+			members = append(members, member)
+		}
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(PartyResponse{
+		Party:   party,
+		Members: members,
+	})
+}
+
+// setInviteStatus records the outcome of an invite
+func (h *PartyHandler) setInviteStatus(inviteID int, status string) {
+	_, err := h.db.Exec(`
+		UPDATE party_invites SET status = $2, responded_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'
+	`, inviteID, status)
+	if err != nil {
+		log.Printf("[Party] Failed to set invite %d to %s: %v", inviteID, status, err)
+	}
+}
+
+// cancelPartyInvites cancels the pending invites of a disbanded party
+func (h *PartyHandler) cancelPartyInvites(partyID string) {
+	_, err := h.db.Exec(`
+		UPDATE party_invites SET status = 'cancelled', responded_at = CURRENT_TIMESTAMP
+		WHERE party_id = $1 AND status = 'pending'
+	`, partyID)
+	if err != nil {
+		log.Printf("[Party] Failed to cancel invites for party %s: %v", partyID, err)
+	}
+}
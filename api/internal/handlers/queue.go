@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/omega-realm/api/internal/middleware"
+	redisClient "github.com/omega-realm/api/internal/redis"
+)
+
+// maxQueueWait caps long-poll requests below the server's write timeout
+const maxQueueWait = 10 * time.Second
+
+// queuePollInterval is how often a long-poll re-checks the queue
+const queuePollInterval = time.Second
+
+// Queue statuses
+const (
+	QueueStatusQueued = "queued"
+	QueueStatusReady  = "ready"
+)
+
+type QueueHandler struct {
+	redis *redisClient.Client
+}
+
+func NewQueueHandler(redis *redisClient.Client) *QueueHandler {
+	return &QueueHandler{redis: redis}
+}
+
+// QueueStatusResponse describes a player's place in a region's waiting queue.
+// Once the player is promoted the status becomes "ready" and the connection
+// details of the reserved game server are filled in.
+type QueueStatusResponse struct {
+	Status               string     `json:"status"`
+	Region               string     `json:"region"`
+	Position             int64      `json:"position,omitempty"`
+	QueueLength          int64      `json:"queue_length,omitempty"`
+	EstimatedWaitSeconds *int       `json:"estimated_wait_seconds,omitempty"`
+	InstanceID           string     `json:"instance_id,omitempty"`
+	WebSocketURL         string     `json:"websocket_url,omitempty"`
+	ReservationExpiresAt *time.Time `json:"reservation_expires_at,omitempty"`
+}
+
+// GetQueueStatus returns the user's queue position. Polling counts as the
+// queue heartbeat. With ?wait=<seconds> the request blocks until the position
+// changes, the user is promoted, or the wait (max 10s) runs out.
+func (h *QueueHandler) GetQueueStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	wait := time.Duration(0)
+	if waitStr := r.URL.Query().Get("wait"); waitStr != "" {
+		seconds, err := strconv.Atoi(waitStr)
+		if err != nil || seconds < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "wait must be a non-negative number of seconds"})
+			return
+		}
+		wait = min(time.Duration(seconds)*time.Second, maxQueueWait)
+	}
+
+	ctx := r.Context()
+	status, err := queueStatus(ctx, h.redis, claims.UserID)
+	if err == nil && status.Status == QueueStatusQueued && wait > 0 {
+		status, err = h.waitForChange(ctx, claims.UserID, status, wait)
+	}
+	if errors.Is(err, redisClient.ErrNotQueued) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Not in a queue"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Queue] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get queue status"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// waitForChange polls until the user's position or status differs from last
+func (h *QueueHandler) waitForChange(ctx context.Context, userID int, last *QueueStatusResponse, wait time.Duration) (*QueueStatusResponse, error) {
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(queuePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return last, nil
+		case <-deadline.C:
+			return last, nil
+		case <-ticker.C:
+			status, err := queueStatus(ctx, h.redis, userID)
+			if err != nil {
+				return nil, err
+			}
+			if status.Status != last.Status || status.Position != last.Position {
+				return status, nil
+			}
+		}
+	}
+}
+
+// LeaveQueue removes the user from their region's queue
+func (h *QueueHandler) LeaveQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	if err := h.redis.LeaveQueue(r.Context(), claims.UserID); err != nil {
+		log.Printf("[Queue] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to leave queue"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Left queue"})
+}
+
+// queueStatus returns the user's promotion if they have one, otherwise their
+// queue position. Reading the position also records a queue heartbeat.
+func queueStatus(ctx context.Context, redis *redisClient.Client, userID int) (*QueueStatusResponse, error) {
+	reservation, err := redis.GetPromotion(ctx, userID)
+	if err == nil {
+		return &QueueStatusResponse{
+			Status:               QueueStatusReady,
+			Region:               reservation.Instance.Region,
+			InstanceID:           reservation.Instance.InstanceID,
+			WebSocketURL:         reservation.Instance.Address,
+			ReservationExpiresAt: &reservation.ExpiresAt,
+		}, nil
+	}
+	if !errors.Is(err, redisClient.ErrNotQueued) {
+		return nil, err
+	}
+
+	entry, err := redis.GetQueueEntry(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := redis.TouchQueue(ctx, entry.Region, userID); err != nil {
+		return nil, err
+	}
+
+	status := &QueueStatusResponse{
+		Status:      QueueStatusQueued,
+		Region:      entry.Region,
+		Position:    entry.Position,
+		QueueLength: entry.Length,
+	}
+	if entry.EstimatedWait != nil {
+		seconds := int(entry.EstimatedWait.Seconds())
+		status.EstimatedWaitSeconds = &seconds
+	}
+	return status, nil
+}
@@ -2,21 +2,32 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/omega-realm/api/internal/database"
 	"github.com/omega-realm/api/internal/middleware"
 	"github.com/omega-realm/api/internal/models"
 	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/regions"
+	"github.com/omega-realm/api/pkg/protocol"
 )
 
 type RegionHandler struct {
-	redis *redisClient.Client
+	db             *database.DB
+	redis          *redisClient.Client
+	registry       *regions.Registry
+	reservationTTL time.Duration
 }
 
-func NewRegionHandler(redis *redisClient.Client) *RegionHandler {
-	return &RegionHandler{redis: redis}
+func NewRegionHandler(db *database.DB, redis *redisClient.Client, registry *regions.Registry, reservationTTL time.Duration) *RegionHandler {
+	return &RegionHandler{db: db, redis: redis, registry: registry, reservationTTL: reservationTTL}
 }
 
 // SelectRegionRequest represents the request body for region selection
@@ -24,14 +35,21 @@ type SelectRegionRequest struct {
 	RegionID string `json:"region_id"`
 }
 
-// SelectRegionResponse represents the response after selecting a region
+// SelectRegionResponse represents the response after selecting a region.
+// WebSocketURL points at the assigned game server instance, which holds a
+// slot for the player until ReservationExpiresAt.
 type SelectRegionResponse struct {
-	Message      string         `json:"message"`
-	Region       *models.Region `json:"region"`
-	WebSocketURL string         `json:"websocket_url"`
+	Message              string         `json:"message"`
+	Region               *models.Region `json:"region"`
+	PartyID              string         `json:"party_id,omitempty"`
+	InstanceID           string         `json:"instance_id"`
+	WebSocketURL         string         `json:"websocket_url"`
+	ReservationExpiresAt time.Time      `json:"reservation_expires_at"`
 }
 
-// GetRegions returns all available regions with their current player counts
+// GetRegions returns all available regions with their current player counts.
+// Clients compare protocol_version and each region's min_protocol_version
+// against their own version before connecting.
 func (h *RegionHandler) GetRegions(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -41,26 +59,27 @@ func (h *RegionHandler) GetRegions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Get all regions
-	regions := models.GetAllRegions()
+	allRegions := h.registry.GetAllRegions()
 
-	// Populate active player counts from Redis
-	ctx := context.Background()
-	for _, region := range regions {
-		count, err := h.redis.GetActiveUsersByRegion(ctx, region.ID)
-		if err != nil {
-			// If Redis fails, default to 0 active players
-			count = 0
+	// Populate live player counts and capacity from the registered game servers
+	ctx := r.Context()
+	for _, region := range allRegions {
+		if err := h.applyLiveCapacity(ctx, region); err != nil {
+			// If Redis fails, report the region as having no live capacity
+			log.Printf("[Region] %v", err)
 		}
-		region.ActivePlayers = count
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]any{
-		"regions": regions,
+		"regions":          allRegions,
+		"protocol_version": protocol.Version,
 	})
 }
 
-// SelectRegion allows an authenticated user to select their game region
+// SelectRegion allows an authenticated user to select their game region. If
+// the region is full, or others are already waiting, the user is queued and
+// gets 202 with their position instead.
 func (h *RegionHandler) SelectRegion(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -70,7 +89,7 @@ func (h *RegionHandler) SelectRegion(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Get user claims from context (verify authentication)
-	_, ok := middleware.GetUserClaims(r)
+	claims, ok := middleware.GetUserClaims(r)
 	if !ok {
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
@@ -86,19 +105,19 @@ func (h *RegionHandler) SelectRegion(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Normalize region ID to lowercase
-	req.RegionID = strings.ToLower(strings.TrimSpace(req.RegionID))
+	req.RegionID = regions.NormalizeRegionID(req.RegionID)
 
 	// Validate region ID
-	if !models.IsValidRegion(req.RegionID) {
+	if !h.registry.IsValidRegion(req.RegionID) {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(ErrorResponse{
-			Error: "Invalid region. Valid regions are: asia, europe, us-west",
+			Error: "Invalid region. Valid regions are: " + strings.Join(h.registry.RegionIDs(), ", "),
 		})
 		return
 	}
 
 	// Get region details
-	region := models.GetRegionDetails(req.RegionID)
+	region := h.registry.GetRegionDetails(req.RegionID)
 	if region == nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get region details"})
@@ -114,20 +133,63 @@ func (h *RegionHandler) SelectRegion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get active player count
-	ctx := context.Background()
-	activeCount, err := h.redis.GetActiveUsersByRegion(ctx, req.RegionID)
-	if err == nil {
-		region.ActivePlayers = activeCount
+	// Check live capacity of the region's game servers
+	ctx := r.Context()
+	if err := h.applyLiveCapacity(ctx, region); err != nil {
+		log.Printf("[Region] %v", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Selected region is currently unavailable"})
+		return
+	}
+	if region.Instances == 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error: "No game servers are running in the selected region",
+		})
+		return
+	}
+	// Parties are placed together by their leader
+	party, err := h.redis.GetUserParty(ctx, claims.UserID)
+	if err != nil && !errors.Is(err, redisClient.ErrNotInParty) {
+		log.Printf("[Region] %v", err)
+	}
+	if err == nil && len(party.Members) > 1 {
+		h.selectRegionForParty(w, r, region, party, claims.UserID)
+		return
+	}
+
+	// Hand out a slot the queue already promoted this user to
+	reservation, err := h.redis.GetPromotion(ctx, claims.UserID)
+	if err == nil && reservation.Instance.Region == req.RegionID {
+		if err := h.redis.ClearPromotion(ctx, claims.UserID); err != nil {
+			log.Printf("[Region] %v", err)
+		}
+	} else {
+		// Keep the queue fair: nobody skips players who are already waiting
+		queued, err := h.redis.QueueLength(ctx, req.RegionID)
+		if err != nil {
+			log.Printf("[Region] %v", err)
+		}
+		if queued > 0 || region.ActivePlayers >= int64(region.MaxPlayers) {
+			h.enqueue(w, r, req.RegionID, claims.UserID)
+			return
+		}
 
-		// Check if region is full
-		if activeCount >= int64(region.MaxPlayers) {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			json.NewEncoder(w).Encode(ErrorResponse{
-				Error: "Selected region is currently full. Please try another region.",
-			})
+		// Reserve a slot on the best instance in the region
+		reservation, err = h.redis.ReserveSlots(ctx, req.RegionID, []int{claims.UserID}, h.reservationTTL)
+		if errors.Is(err, redisClient.ErrRegionFull) {
+			h.enqueue(w, r, req.RegionID, claims.UserID)
 			return
 		}
+		if err != nil {
+			writeReserveError(w, req.RegionID, err)
+			return
+		}
+
+		// A user who got straight in no longer needs a place in another region's queue
+		if err := h.redis.LeaveQueue(ctx, claims.UserID); err != nil {
+			log.Printf("[Region] %v", err)
+		}
 	}
 
 	// Extract token from Authorization header
@@ -151,8 +213,298 @@ func (h *RegionHandler) SelectRegion(w http.ResponseWriter, r *http.Request) {
 	// Return success response with region details and WebSocket URL
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(SelectRegionResponse{
-		Message:      "Region selected successfully",
-		Region:       region,
-		WebSocketURL: region.WebSocketURL,
+		Message:              "Region selected successfully",
+		Region:               region,
+		InstanceID:           reservation.Instance.InstanceID,
+		WebSocketURL:         reservation.Instance.Address,
+		ReservationExpiresAt: reservation.ExpiresAt,
+	})
+
+	log.Printf("[Region] User %d assigned to %s in %s", claims.UserID, reservation.Instance.InstanceID, req.RegionID)
+}
+
+// selectRegionForParty reserves slots for every party member on one instance.
+// Parties do not queue: either everyone gets a slot on the same server or the
+// request fails and nothing is reserved. Members pick up the assignment from
+// GET /api/party.
+func (h *RegionHandler) selectRegionForParty(w http.ResponseWriter, r *http.Request, region *models.Region, party *redisClient.Party, userID int) {
+	ctx := r.Context()
+
+	if party.LeaderID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Only the party leader can select a region"})
+		return
+	}
+
+	queued, err := h.redis.QueueLength(ctx, region.ID)
+	if err != nil {
+		log.Printf("[Region] %v", err)
+	}
+	if queued > 0 || region.ActivePlayers+int64(len(party.Members)) > int64(region.MaxPlayers) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error: "Selected region does not have room for your whole party. Please try another region.",
+		})
+		return
+	}
+
+	reservation, err := h.redis.ReserveSlots(ctx, region.ID, party.Members, h.reservationTTL)
+	if errors.Is(err, redisClient.ErrRegionFull) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error: "No game server in the selected region has room for your whole party. Please try another region.",
+		})
+		return
+	}
+	if err != nil {
+		writeReserveError(w, region.ID, err)
+		return
+	}
+
+	if err := h.redis.SetPartyAssignment(ctx, party.ID, reservation); err != nil {
+		log.Printf("[Region] Failed to record assignment for party %s: %v", party.ID, err)
+		for _, member := range party.Members {
+			if err := h.redis.ReleaseReservation(ctx, member); err != nil {
+				log.Printf("[Region] %v", err)
+			}
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to assign a game server"})
+		return
+	}
+
+	for _, member := range party.Members {
+		if err := h.redis.LeaveQueue(ctx, member); err != nil {
+			log.Printf("[Region] %v", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(SelectRegionResponse{
+		Message:              "Region selected successfully",
+		Region:               region,
+		PartyID:              party.ID,
+		InstanceID:           reservation.Instance.InstanceID,
+		WebSocketURL:         reservation.Instance.Address,
+		ReservationExpiresAt: reservation.ExpiresAt,
+	})
+
+	log.Printf("[Region] Party %s (%d members) assigned to %s in %s", party.ID, len(party.Members), reservation.Instance.InstanceID, region.ID)
+}
+
+// enqueue puts the user in the region's waiting queue and responds with
+// 202 and their position
+func (h *RegionHandler) enqueue(w http.ResponseWriter, r *http.Request, regionID string, userID int) {
+	ctx := r.Context()
+	if _, err := h.redis.JoinQueue(ctx, regionID, userID); err != nil {
+		log.Printf("[Region] Failed to queue user %d for %s: %v", userID, regionID, err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error: "Selected region is currently full. Please try another region.",
+		})
+		return
+	}
+
+	status, err := queueStatus(ctx, h.redis, userID)
+	if err != nil {
+		log.Printf("[Region] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get queue status"})
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(status)
+}
+
+// writeReserveError maps a ReserveSlots failure to an error response
+func writeReserveError(w http.ResponseWriter, regionID string, err error) {
+	switch {
+	case errors.Is(err, redisClient.ErrNoGameServers):
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "No game servers are running in the selected region"})
+	case errors.Is(err, redisClient.ErrRegionFull):
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error: "Selected region is currently full. Please try another region.",
+		})
+	default:
+		log.Printf("[Region] Failed to reserve slots in %s: %v", regionID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to assign a game server"})
+	}
+}
+
+// applyLiveCapacity overwrites a region's player count and capacity with the
+// totals of its live game server instances. Reserved slots count as players.
+// Capacity is capped by the region's configured max_players.
+func (h *RegionHandler) applyLiveCapacity(ctx context.Context, region *models.Region) error {
+	region.ActivePlayers = 0
+	region.Instances = 0
+
+	capacity, err := h.redis.GetRegionCapacity(ctx, region.ID)
+	if err != nil {
+		region.MaxPlayers = 0
+		return err
+	}
+
+	region.Instances = capacity.Instances
+	region.ActivePlayers = capacity.PlayerCount + capacity.Reserved
+	region.MaxPlayers = min(region.MaxPlayers, capacity.Capacity)
+	return nil
+}
+
+// SetRegionStatusRequest represents the request body for changing a region's status
+type SetRegionStatusRequest struct {
+	RegionID string `json:"region_id"`
+	Status   string `json:"status"`
+}
+
+// SaveRegion creates or updates a region (admin only)
+func (h *RegionHandler) SaveRegion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var region models.Region
+	if err := json.NewDecoder(r.Body).Decode(&region); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	region.ID = regions.NormalizeRegionID(region.ID)
+	if region.Status == "" {
+		region.Status = models.RegionStatusOnline
+	}
+	if err := validateRegion(&region); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.registry.SaveRegion(r.Context(), &region); err != nil {
+		log.Printf("[Region] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to save region"})
+		return
+	}
+
+	if err := h.db.RecordAuditEvent(r.Context(), claims.UserID, database.AuditRegionSaved, map[string]any{
+		"region_id":            region.ID,
+		"websocket_url":        region.WebSocketURL,
+		"status":               region.Status,
+		"max_players":          region.MaxPlayers,
+		"min_protocol_version": region.MinProtocolVersion,
+		"sort_order":           region.SortOrder,
+	}); err != nil {
+		log.Printf("[Region] %v", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Region saved successfully",
+		"region":  h.registry.GetRegionDetails(region.ID),
+	})
+}
+
+// SetRegionStatus flips a region between online, maintenance and offline (admin only)
+func (h *RegionHandler) SetRegionStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req SetRegionStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	req.RegionID = regions.NormalizeRegionID(req.RegionID)
+	if !regions.IsValidStatus(req.Status) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid status. Must be online, maintenance, or offline"})
+		return
+	}
+
+	err := h.registry.SetStatus(r.Context(), req.RegionID, req.Status)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Region not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Region] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to update region status"})
+		return
+	}
+
+	if err := h.db.RecordAuditEvent(r.Context(), claims.UserID, database.AuditRegionStatusChanged, map[string]any{
+		"region_id": req.RegionID,
+		"status":    req.Status,
+	}); err != nil {
+		log.Printf("[Region] %v", err)
+	}
+
+	log.Printf("[Region] Region %s set to %s by user %d", req.RegionID, req.Status, claims.UserID)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Region status updated",
+		"region":  h.registry.GetRegionDetails(req.RegionID),
 	})
 }
+
+// validateRegion validates an admin region definition
+func validateRegion(region *models.Region) error {
+	if region.ID == "" || len(region.ID) > 20 {
+		return &ValidationError{Field: "id", Message: "Region ID must be between 1 and 20 characters"}
+	}
+	for _, char := range region.ID {
+		if !(char >= 'a' && char <= 'z') && !(char >= '0' && char <= '9') && char != '-' {
+			return &ValidationError{Field: "id", Message: "Region ID may only contain lowercase letters, numbers, and hyphens"}
+		}
+	}
+	if region.DisplayName == "" {
+		return &ValidationError{Field: "display_name", Message: "Display name is required"}
+	}
+	if !strings.HasPrefix(region.WebSocketURL, "ws://") && !strings.HasPrefix(region.WebSocketURL, "wss://") {
+		return &ValidationError{Field: "websocket_url", Message: "WebSocket URL must start with ws:// or wss://"}
+	}
+	if region.MaxPlayers <= 0 {
+		return &ValidationError{Field: "max_players", Message: "Max players must be positive"}
+	}
+	if !regions.IsValidStatus(region.Status) {
+		return &ValidationError{Field: "status", Message: "Invalid status. Must be online, maintenance, or offline"}
+	}
+	// A minimum newer than this build would turn every client away
+	if region.MinProtocolVersion < 0 || region.MinProtocolVersion > int(protocol.Version) {
+		return &ValidationError{
+			Field:   "min_protocol_version",
+			Message: fmt.Sprintf("Min protocol version must be between 0 and %d", protocol.Version),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,737 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+	"github.com/omega-realm/api/internal/database"
+	"github.com/omega-realm/api/internal/middleware"
+	"github.com/omega-realm/api/internal/models"
+	"github.com/omega-realm/api/internal/namepolicy"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/websocket"
+)
+
+const (
+	maxReportDescription = 1000
+	maxReportMatchID     = 64
+	// reportRateLimit reports per reportRateWindow keep one player from
+	// flooding the queue
+	reportRateLimit  = 10
+	reportRateWindow = time.Hour
+	// reportAuditHistory is how many of the reported player's audit events
+	// are shown with a report
+	reportAuditHistory = 50
+	defaultReportLimit = 50
+	maxReportLimit     = 200
+)
+
+// reportCategories are the categories a player may file a report under
+var reportCategories = map[string]bool{
+	models.ReportCheating:    true,
+	models.ReportAbusiveName: true,
+	models.ReportHarassment:  true,
+	models.ReportSpam:        true,
+	models.ReportOther:       true,
+}
+
+// reportTransitions lists, for each status, the statuses a report may move
+// to it from. Closed reports can be reopened.
+var reportTransitions = map[string][]string{
+	models.ReportOpen:      {models.ReportInReview, models.ReportResolved, models.ReportDismissed},
+	models.ReportInReview:  {models.ReportOpen},
+	models.ReportResolved:  {models.ReportOpen, models.ReportInReview},
+	models.ReportDismissed: {models.ReportOpen, models.ReportInReview},
+}
+
+// reportColumns is selected by every report query, to be read by scanReport
+const reportColumns = `
+	r.id, r.reporter_id, COALESCE(rep.username, ''), r.reported_user_id, r.reported_character_id,
+	r.reported_character_name, r.category, r.description, r.match_id, r.session_id, r.status,
+	r.assigned_to, r.resolution, r.created_at, r.updated_at, r.resolved_at
+`
+
+type ReportHandler struct {
+	db     *database.DB
+	redis  *redisClient.Client
+	events *websocket.Publisher
+}
+
+func NewReportHandler(db *database.DB, redis *redisClient.Client, events *websocket.Publisher) *ReportHandler {
+	return &ReportHandler{db: db, redis: redis, events: events}
+}
+
+// FileReportRequest names the reported character by ID or by name. MatchID
+// and SessionID optionally tie the report to where it happened.
+type FileReportRequest struct {
+	CharacterID   int    `json:"character_id"`
+	CharacterName string `json:"character_name"`
+	Category      string `json:"category"`
+	Description   string `json:"description"`
+	MatchID       string `json:"match_id"`
+	SessionID     int    `json:"session_id"`
+}
+
+type AssignReportRequest struct {
+	ReportID   int `json:"report_id"`
+	AssigneeID int `json:"assignee_id"`
+}
+
+type UpdateReportStatusRequest struct {
+	ReportID   int    `json:"report_id"`
+	Status     string `json:"status"`
+	Resolution string `json:"resolution"`
+}
+
+type ReportSanctionRequest struct {
+	ReportID        int    `json:"report_id"`
+	Type            string `json:"type"`
+	Reason          string `json:"reason"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+// ReportDetailResponse is a report with the context a moderator needs to
+// judge it
+type ReportDetailResponse struct {
+	Report        models.Report         `json:"report"`
+	ReportCount   int                   `json:"report_count"`
+	AuditHistory  []models.AuditEvent   `json:"audit_history"`
+	Sanctions     []models.Sanction     `json:"sanctions"`
+	SuspectScores []models.SuspectScore `json:"suspect_scores"`
+}
+
+// FileReport reports a character for moderator review
+func (h *ReportHandler) FileReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req FileReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	req.Description = strings.TrimSpace(req.Description)
+	req.MatchID = strings.TrimSpace(req.MatchID)
+	switch {
+	case req.CharacterID <= 0 && strings.TrimSpace(req.CharacterName) == "":
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "character_id or character_name is required"})
+		return
+	case !reportCategories[req.Category]:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid report category"})
+		return
+	case utf8.RuneCountInString(req.Description) > maxReportDescription:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Description is too long"})
+		return
+	case len(req.MatchID) > maxReportMatchID:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid match_id"})
+		return
+	}
+
+	ctx := r.Context()
+	var characterID, reportedUserID int
+	var characterName string
+	err := h.db.QueryRowContext(ctx, `
+		SELECT id, user_id, name FROM characters
+		WHERE CASE WHEN $1 > 0 THEN id = $1 ELSE name_canonical = $2 END
+		  AND deleted_at IS NULL
+	`, req.CharacterID, namepolicy.CanonicalName(req.CharacterName)).Scan(&characterID, &reportedUserID, &characterName)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Character not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Reports] Failed to look up reported character: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+	if reportedUserID == claims.UserID {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You cannot report yourself"})
+		return
+	}
+
+	var sessionID *int
+	if req.SessionID > 0 {
+		var exists bool
+		err := h.db.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM sessions WHERE id = $1 AND character_id = $2)`, req.SessionID, characterID,
+		).Scan(&exists)
+		if err != nil {
+			log.Printf("[Reports] Failed to look up session %d: %v", req.SessionID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+			return
+		}
+		if !exists {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "session_id is not a session of the reported character"})
+			return
+		}
+		sessionID = &req.SessionID
+	}
+
+	allowed, _, err := h.redis.AllowRate(ctx, reportRateKey(claims.UserID), reportRateLimit, reportRateWindow)
+	if err != nil {
+		log.Printf("[Reports] %v", err)
+	} else if !allowed {
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You have filed too many reports, please try again later"})
+		return
+	}
+
+	var report models.Report
+	err = h.db.QueryRowContext(ctx, `
+		INSERT INTO reports (reporter_id, reported_user_id, reported_character_id, reported_character_name,
+		                     category, description, match_id, session_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
+		ON CONFLICT (reporter_id, reported_user_id, category) WHERE status IN ('open', 'in_review') DO NOTHING
+		RETURNING id, status, created_at
+	`, claims.UserID, reportedUserID, characterID, characterName, req.Category, req.Description, req.MatchID, sessionID,
+	).Scan(&report.ID, &report.Status, &report.CreatedAt)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You already have an open report against this player for that reason"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Reports] Failed to file report: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to file report"})
+		return
+	}
+
+	if err := h.db.RecordAuditEvent(ctx, claims.UserID, database.AuditReportFiled, map[string]any{
+		"report_id":        report.ID,
+		"reported_user_id": reportedUserID,
+		"category":         req.Category,
+	}); err != nil {
+		log.Printf("[Reports] %v", err)
+	}
+
+	log.Printf("[Reports] User %d reported character %d (%s)", claims.UserID, characterID, req.Category)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":         report.ID,
+		"status":     report.Status,
+		"created_at": report.CreatedAt,
+	})
+}
+
+// GetMyReports lists the reports the caller has filed and how far each got.
+// Moderator notes are not included.
+func (h *ReportHandler) GetMyReports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	rows, err := h.db.QueryContext(r.Context(), `
+		SELECT id, reported_character_name, category, status, created_at, resolved_at
+		FROM reports WHERE reporter_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, claims.UserID, defaultReportLimit)
+	if err != nil {
+		log.Printf("[Reports] Failed to query reports of user %d: %v", claims.UserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load reports"})
+		return
+	}
+	defer rows.Close()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		var report models.Report
+		if err := rows.Scan(&report.ID, &report.ReportedCharacterName, &report.Category, &report.Status, &report.CreatedAt, &report.ResolvedAt); err != nil {
+			log.Printf("[Reports] Failed to scan report: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load reports"})
+			return
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("[Reports] Failed to read reports: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load reports"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"reports": reports})
+}
+
+// ListReports is the moderation queue, oldest first. Filters: status
+// (default open and in_review), category, assigned_to, reported_user_id and
+// limit.
+func (h *ReportHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	statuses := []string{models.ReportOpen, models.ReportInReview}
+	if status := query.Get("status"); status != "" {
+		if _, ok := reportTransitions[status]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid status"})
+			return
+		}
+		statuses = []string{status}
+	}
+	assignedTo, ok := intParam(w, r, "assigned_to", 0)
+	if !ok {
+		return
+	}
+	reportedUserID, ok := intParam(w, r, "reported_user_id", 0)
+	if !ok {
+		return
+	}
+	limit, ok := intParam(w, r, "limit", defaultReportLimit)
+	if !ok {
+		return
+	}
+	limit = min(max(limit, 1), maxReportLimit)
+
+	rows, err := h.db.QueryContext(r.Context(), `
+		SELECT `+reportColumns+`
+		FROM reports r
+		LEFT JOIN users rep ON rep.id = r.reporter_id
+		WHERE r.status = ANY($1)
+		  AND ($2 = '' OR r.category = $2)
+		  AND ($3 = 0 OR r.assigned_to = $3)
+		  AND ($4 = 0 OR r.reported_user_id = $4)
+		ORDER BY r.created_at ASC
+		LIMIT $5
+	`, pq.Array(statuses), query.Get("category"), assignedTo, reportedUserID, limit)
+	if err != nil {
+		log.Printf("[Reports] Failed to query report queue: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load reports"})
+		return
+	}
+	defer rows.Close()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			log.Printf("[Reports] Failed to scan report: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load reports"})
+			return
+		}
+		reports = append(reports, *report)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("[Reports] Failed to read report queue: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load reports"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"reports": reports})
+}
+
+// GetReport returns one report with the reported player's recent audit
+// history and anti-cheat scores, their sanctions and how often they have
+// been reported
+func (h *ReportHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	reportID, ok := intParam(w, r, "id", 0)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	report, ok := h.loadReport(w, r, reportID)
+	if !ok {
+		return
+	}
+
+	response := ReportDetailResponse{Report: *report, Sanctions: []models.Sanction{}}
+	err := h.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM reports WHERE reported_user_id = $1`, report.ReportedUserID,
+	).Scan(&response.ReportCount)
+	if err != nil {
+		log.Printf("[Reports] Failed to count reports against user %d: %v", report.ReportedUserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load report"})
+		return
+	}
+
+	response.AuditHistory, err = h.db.RecentAuditEvents(ctx, report.ReportedUserID, reportAuditHistory)
+	if err != nil {
+		log.Printf("[Reports] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load report"})
+		return
+	}
+
+	response.SuspectScores, err = h.db.RecentSuspectScores(ctx, report.ReportedUserID, reportAuditHistory)
+	if err != nil {
+		log.Printf("[Reports] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load report"})
+		return
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT id, user_id, type, reason, report_id, issued_by, created_at, expires_at, revoked_at, revoked_by
+		FROM sanctions WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, report.ReportedUserID)
+	if err != nil {
+		log.Printf("[Reports] Failed to query sanctions for user %d: %v", report.ReportedUserID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load report"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s models.Sanction
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Type, &s.Reason, &s.ReportID, &s.IssuedBy, &s.CreatedAt, &s.ExpiresAt, &s.RevokedAt, &s.RevokedBy); err != nil {
+			log.Printf("[Reports] Failed to scan sanction: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load report"})
+			return
+		}
+		response.Sanctions = append(response.Sanctions, s)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("[Reports] Failed to read sanctions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load report"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// AssignReport assigns a report to a moderator, the caller by default, and
+// moves an open report into review
+func (h *ReportHandler) AssignReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req AssignReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	if req.AssigneeID == 0 {
+		req.AssigneeID = claims.UserID
+	}
+
+	ctx := r.Context()
+	var isModerator bool
+	err := h.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND role IN ('moderator', 'admin'))`, req.AssigneeID,
+	).Scan(&isModerator)
+	if err != nil {
+		log.Printf("[Reports] Failed to look up assignee %d: %v", req.AssigneeID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+	if !isModerator {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Reports can only be assigned to moderators"})
+		return
+	}
+
+	result, err := h.db.ExecContext(ctx, `
+		UPDATE reports
+		SET assigned_to = $2,
+		    status = CASE WHEN status = 'open' THEN 'in_review' ELSE status END,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status IN ('open', 'in_review')
+	`, req.ReportID, req.AssigneeID)
+	if err != nil {
+		log.Printf("[Reports] Failed to assign report %d: %v", req.ReportID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to assign report"})
+		return
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Open report not found"})
+		return
+	}
+
+	h.recordReportUpdate(r, claims.UserID, req.ReportID, map[string]any{"assigned_to": req.AssigneeID})
+
+	report, ok := h.loadReport(w, r, req.ReportID)
+	if !ok {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// UpdateReportStatus moves a report through its workflow. Resolving or
+// dismissing a report records the moderator's resolution note.
+func (h *ReportHandler) UpdateReportStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req UpdateReportStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	if _, ok := reportTransitions[req.Status]; !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid status"})
+		return
+	}
+
+	if !h.setReportStatus(w, r, req.ReportID, req.Status, strings.TrimSpace(req.Resolution)) {
+		return
+	}
+
+	h.recordReportUpdate(r, claims.UserID, req.ReportID, map[string]any{"status": req.Status})
+
+	report, ok := h.loadReport(w, r, req.ReportID)
+	if !ok {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// SanctionFromReport mutes or bans the reported player and resolves the
+// report. The sanction reason doubles as the resolution note.
+func (h *ReportHandler) SanctionFromReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	var req ReportSanctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	report, ok := h.loadReport(w, r, req.ReportID)
+	if !ok {
+		return
+	}
+	if report.Status != models.ReportOpen && report.Status != models.ReportInReview {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Report is already closed"})
+		return
+	}
+
+	sanctionReq := IssueSanctionRequest{
+		UserID:          report.ReportedUserID,
+		Type:            req.Type,
+		Reason:          req.Reason,
+		DurationMinutes: req.DurationMinutes,
+	}
+	if msg := validateSanctionRequest(&sanctionReq, claims.UserID); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
+	}
+
+	sanction := newSanction(sanctionReq, claims.UserID)
+	sanction.ReportID = &report.ID
+	err := issueSanction(r.Context(), h.db, h.redis, h.events, sanction)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Reports] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to issue sanction"})
+		return
+	}
+
+	if !h.setReportStatus(w, r, report.ID, models.ReportResolved, sanctionReq.Reason) {
+		return
+	}
+	h.recordReportUpdate(r, claims.UserID, report.ID, map[string]any{
+		"status":      models.ReportResolved,
+		"sanction_id": sanction.ID,
+	})
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sanction)
+}
+
+// setReportStatus applies a workflow transition, replying with an error and
+// returning false if the report does not exist or cannot move to status
+func (h *ReportHandler) setReportStatus(w http.ResponseWriter, r *http.Request, reportID int, status, resolution string) bool {
+	closing := status == models.ReportResolved || status == models.ReportDismissed
+	result, err := h.db.ExecContext(r.Context(), `
+		UPDATE reports
+		SET status = $2,
+		    resolution = CASE WHEN $4 THEN NULLIF($3, '') ELSE resolution END,
+		    resolved_at = CASE WHEN $4 THEN CURRENT_TIMESTAMP ELSE NULL END,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = ANY($5)
+	`, reportID, status, resolution, closing, pq.Array(reportTransitions[status]))
+	if err != nil {
+		log.Printf("[Reports] Failed to set report %d to %s: %v", reportID, status, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to update report"})
+		return false
+	}
+	if updated, _ := result.RowsAffected(); updated > 0 {
+		return true
+	}
+
+	// Nothing changed: either there is no such report or the move is not allowed
+	if _, ok := h.loadReport(w, r, reportID); ok {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Report cannot move to " + status})
+	}
+	return false
+}
+
+// loadReport fetches a report, replying with an error and returning false if
+// it cannot
+func (h *ReportHandler) loadReport(w http.ResponseWriter, r *http.Request, reportID int) (*models.Report, bool) {
+	report, err := scanReport(h.db.QueryRowContext(r.Context(), `
+		SELECT `+reportColumns+`
+		FROM reports r
+		LEFT JOIN users rep ON rep.id = r.reporter_id
+		WHERE r.id = $1
+	`, reportID))
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Report not found"})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("[Reports] Failed to load report %d: %v", reportID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load report"})
+		return nil, false
+	}
+	return report, true
+}
+
+// recordReportUpdate audits a moderator's change to a report
+func (h *ReportHandler) recordReportUpdate(r *http.Request, moderatorID, reportID int, details map[string]any) {
+	details["report_id"] = reportID
+	if err := h.db.RecordAuditEvent(r.Context(), moderatorID, database.AuditReportUpdated, details); err != nil {
+		log.Printf("[Reports] %v", err)
+	}
+}
+
+// scanReport reads a row selected with reportColumns
+func scanReport(row interface{ Scan(...any) error }) (*models.Report, error) {
+	var report models.Report
+	err := row.Scan(
+		&report.ID,
+		&report.ReporterID,
+		&report.ReporterName,
+		&report.ReportedUserID,
+		&report.ReportedCharacterID,
+		&report.ReportedCharacterName,
+		&report.Category,
+		&report.Description,
+		&report.MatchID,
+		&report.SessionID,
+		&report.Status,
+		&report.AssignedTo,
+		&report.Resolution,
+		&report.CreatedAt,
+		&report.UpdatedAt,
+		&report.ResolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func reportRateKey(userID int) string {
+	return fmt.Sprintf("report_rate:%d", userID)
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/omega-realm/api/internal/models"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+// disconnectReasons maps the DisconnectReason sent in DISCONNECT packets to
+// session end reasons
+var disconnectReasons = map[protocol.DisconnectReason]string{
+	protocol.DisconnectUserQuit:         models.SessionEndUserQuit,
+	protocol.DisconnectTimeout:          models.SessionEndTimeout,
+	protocol.DisconnectKicked:           models.SessionEndKicked,
+	protocol.DisconnectServerShutdown:   models.SessionEndServerShutdown,
+	protocol.DisconnectInvalidAuth:      models.SessionEndInvalidAuth,
+	protocol.DisconnectDuplicateSession: models.SessionEndDuplicateSession,
+}
+
+// OpenSessionRequest is sent by a game server once a player's CONNECT_AUTH
+// has been accepted
+type OpenSessionRequest struct {
+	InstanceID  string `json:"instance_id"`
+	CharacterID int    `json:"character_id"`
+}
+
+// OpenSessionResponse carries the ID the game server closes the session with
+type OpenSessionResponse struct {
+	SessionID int       `json:"session_id"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// CloseSessionRequest is sent by a game server when a player disconnects or
+// times out
+type CloseSessionRequest struct {
+	SessionID int                       `json:"session_id"`
+	Reason    protocol.DisconnectReason `json:"reason"`
+}
+
+// SessionStatsResponse summarizes concurrency and playtime for admins
+type SessionStatsResponse struct {
+	ActiveByRegion     map[string]int `json:"active_by_region"`
+	SessionsLast24h    int            `json:"sessions_last_24h"`
+	PlaytimeHours24h   float64        `json:"playtime_hours_last_24h"`
+	AverageMinutes24h  float64        `json:"average_session_minutes_last_24h"`
+	PeakConcurrent24h  int            `json:"peak_concurrent_last_24h"`
+	OrphanedSessions7d int            `json:"orphaned_sessions_last_7d"`
+}
+
+// OpenSession records the start of a character's session on an instance. An
+// open session the character still has elsewhere is closed first, since the
+// player can only be connected once.
+func (h *GameServerHandler) OpenSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req OpenSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	ctx := r.Context()
+	instance, err := h.redis.GetGameServer(ctx, req.InstanceID)
+	if errors.Is(err, redisClient.ErrGameServerNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Game server not registered"})
+		return
+	}
+	if err != nil {
+		log.Printf("[GameServer] %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[GameServer] Failed to begin transaction: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Locking the character serializes concurrent opens for it
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT TRUE FROM characters WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, req.CharacterID,
+	).Scan(&exists)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Character not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[GameServer] Failed to look up character %d: %v", req.CharacterID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal server error"})
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE sessions SET ended_at = CURRENT_TIMESTAMP, end_reason = $2
+		WHERE character_id = $1 AND ended_at IS NULL
+	`, req.CharacterID, models.SessionEndDuplicateSession)
+	if err != nil {
+		log.Printf("[GameServer] Failed to close previous sessions of character %d: %v", req.CharacterID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to open session"})
+		return
+	}
+
+	var response OpenSessionResponse
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO sessions (character_id, server_region, instance_id)
+		VALUES ($1, $2, $3)
+		RETURNING id, started_at
+	`, req.CharacterID, instance.Region, instance.InstanceID).Scan(&response.SessionID, &response.StartedAt)
+	if err != nil {
+		log.Printf("[GameServer] Failed to open session for character %d: %v", req.CharacterID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to open session"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[GameServer] Failed to commit session: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to open session"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CloseSession records the end of a session and takes the player out of
+// game in their presence
+func (h *GameServerHandler) CloseSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req CloseSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	endReason, ok := disconnectReasons[req.Reason]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unknown disconnect reason"})
+		return
+	}
+
+	ctx := r.Context()
+	var userID int
+	var instanceID sql.NullString
+	var startedAt, endedAt time.Time
+	err := h.db.QueryRowContext(ctx, `
+		UPDATE sessions s
+		SET ended_at = CURRENT_TIMESTAMP, end_reason = $2
+		FROM characters c
+		WHERE s.id = $1 AND s.ended_at IS NULL AND c.id = s.character_id
+		RETURNING c.user_id, s.instance_id, s.started_at, s.ended_at
+	`, req.SessionID, endReason).Scan(&userID, &instanceID, &startedAt, &endedAt)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Open session not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[GameServer] Failed to close session %d: %v", req.SessionID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to close session"})
+		return
+	}
+
+	if err := h.redis.ClearUserSessionGameServer(ctx, userID, instanceID.String); err != nil {
+		log.Printf("[GameServer] Failed to update presence for user %d: %v", userID, err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":          "Session closed",
+		"duration_seconds": int(endedAt.Sub(startedAt).Seconds()),
+	})
+}
+
+// GetSessionStats reports current concurrency per region and playtime over
+// the last day (admin only)
+func (h *GameServerHandler) GetSessionStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx := r.Context()
+	response := SessionStatsResponse{ActiveByRegion: make(map[string]int)}
+	for _, regionID := range h.registry.RegionIDs() {
+		response.ActiveByRegion[regionID] = 0
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT server_region, COUNT(*) FROM sessions
+		WHERE ended_at IS NULL
+		GROUP BY server_region
+	`)
+	if err != nil {
+		log.Printf("[GameServer] Failed to count active sessions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load session stats"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var regionID string
+		var count int
+		if err := rows.Scan(&regionID, &count); err != nil {
+			log.Printf("[GameServer] Failed to scan session count: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load session stats"})
+			return
+		}
+		response.ActiveByRegion[regionID] = count
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("[GameServer] Failed to read session counts: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load session stats"})
+		return
+	}
+
+	// Playtime counts the part of each session inside the window; peak
+	// concurrency is the most sessions open at any session start
+	err = h.db.QueryRowContext(ctx, `
+		WITH window_sessions AS (
+			SELECT GREATEST(started_at, CURRENT_TIMESTAMP - INTERVAL '24 hours') AS from_at,
+			       COALESCE(ended_at, CURRENT_TIMESTAMP) AS to_at
+			FROM sessions
+			WHERE COALESCE(ended_at, CURRENT_TIMESTAMP) > CURRENT_TIMESTAMP - INTERVAL '24 hours'
+		)
+		SELECT COUNT(*),
+		       COALESCE(SUM(EXTRACT(EPOCH FROM (to_at - from_at))), 0) / 3600,
+		       COALESCE(AVG(EXTRACT(EPOCH FROM (to_at - from_at))), 0) / 60,
+		       COALESCE((
+		           SELECT MAX(open) FROM (
+		               SELECT COUNT(*) AS open
+		               FROM window_sessions a
+		               JOIN window_sessions b ON b.from_at <= a.from_at AND b.to_at > a.from_at
+		               GROUP BY a.from_at
+		           ) concurrent
+		       ), 0),
+		       (SELECT COUNT(*) FROM sessions
+		        WHERE end_reason = $1 AND ended_at > CURRENT_TIMESTAMP - INTERVAL '7 days')
+		FROM window_sessions
+	`, models.SessionEndOrphaned).Scan(
+		&response.SessionsLast24h,
+		&response.PlaytimeHours24h,
+		&response.AverageMinutes24h,
+		&response.PeakConcurrent24h,
+		&response.OrphanedSessions7d,
+	)
+	if err != nil {
+		log.Printf("[GameServer] Failed to summarize sessions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to load session stats"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,70 @@
+package matchmaking
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config holds matchmaking configuration
+type Config struct {
+	MatchSize          int
+	InitialTolerance   float64
+	TolerancePerSecond float64
+	MaxTolerance       float64
+	BucketWidth        float64
+	TicketTimeout      time.Duration
+	Interval           time.Duration
+}
+
+// LoadConfigFromEnv loads matchmaking configuration from environment variables
+func LoadConfigFromEnv() *Config {
+	return &Config{
+		MatchSize:          getEnvAsInt("MATCH_SIZE", 4),
+		InitialTolerance:   float64(getEnvAsInt("MATCH_TOLERANCE_INITIAL", 100)),
+		TolerancePerSecond: float64(getEnvAsInt("MATCH_TOLERANCE_PER_SECOND", 10)),
+		MaxTolerance:       float64(getEnvAsInt("MATCH_TOLERANCE_MAX", 600)),
+		BucketWidth:        float64(getEnvAsPositiveInt("MATCH_BUCKET_WIDTH", 100)),
+		TicketTimeout:      getEnvAsDuration("MATCH_TICKET_TIMEOUT", time.Minute),
+		Interval:           getEnvAsDuration("MATCHMAKING_INTERVAL", time.Second),
+	}
+}
+
+func getEnvAsInt(key string, defaultValue int) int {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		log.Printf("[Matchmaking] Invalid integer value for %s: %s, using default: %d", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvAsPositiveInt is getEnvAsInt for values the matcher divides by
+func getEnvAsPositiveInt(key string, defaultValue int) int {
+	value := getEnvAsInt(key, defaultValue)
+	if value <= 0 {
+		log.Printf("[Matchmaking] Non-positive value for %s: %d, using default: %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	// Neither a ticket timeout nor an interval can be zero or less, and the
+	// matchmaker's ticker panics on a non-positive interval
+	value, err := time.ParseDuration(valueStr)
+	if err != nil || value <= 0 {
+		log.Printf("[Matchmaking] Invalid duration value for %s: %s, using default: %s", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,128 @@
+package matchmaking
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Ticket is a player waiting for a PvP match
+type Ticket struct {
+	UserID      int       `json:"user_id"`
+	CharacterID int       `json:"character_id"`
+	Region      string    `json:"region"`
+	Rating      float64   `json:"rating"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// Matcher groups tickets into matches. It holds no state and does not read
+// the clock, so the same tickets and time always produce the same matches.
+type Matcher struct {
+	config *Config
+}
+
+// NewMatcher creates a matcher
+func NewMatcher(config *Config) *Matcher {
+	return &Matcher{config: config}
+}
+
+// Tolerance is how far from its own rating a ticket accepts opponents after
+// waiting for wait. It starts at InitialTolerance and widens linearly up to
+// MaxTolerance.
+func (m *Matcher) Tolerance(wait time.Duration) float64 {
+	if wait < 0 {
+		wait = 0
+	}
+	return math.Min(m.config.InitialTolerance+m.config.TolerancePerSecond*wait.Seconds(), m.config.MaxTolerance)
+}
+
+func (m *Matcher) bucket(rating float64) int {
+	return int(math.Floor(rating / m.config.BucketWidth))
+}
+
+// Match forms as many matches as it can. Tickets are bucketed by region and
+// rating; the longest-waiting ticket in a region picks the closest-rated
+// opponents within its tolerance window first.
+func (m *Matcher) Match(now time.Time, tickets []*Ticket) [][]*Ticket {
+	byRegion := make(map[string][]*Ticket)
+	for _, ticket := range tickets {
+		byRegion[ticket.Region] = append(byRegion[ticket.Region], ticket)
+	}
+
+	regionIDs := make([]string, 0, len(byRegion))
+	for region := range byRegion {
+		regionIDs = append(regionIDs, region)
+	}
+	sort.Strings(regionIDs)
+
+	var matches [][]*Ticket
+	for _, region := range regionIDs {
+		matches = append(matches, m.matchRegion(now, byRegion[region])...)
+	}
+	return matches
+}
+
+func (m *Matcher) matchRegion(now time.Time, tickets []*Ticket) [][]*Ticket {
+	if m.config.MatchSize < 2 || len(tickets) < m.config.MatchSize {
+		return nil
+	}
+
+	sort.Slice(tickets, func(i, j int) bool {
+		if !tickets[i].CreatedAt.Equal(tickets[j].CreatedAt) {
+			return tickets[i].CreatedAt.Before(tickets[j].CreatedAt)
+		}
+		return tickets[i].UserID < tickets[j].UserID
+	})
+
+	buckets := make(map[int][]*Ticket)
+	for _, ticket := range tickets {
+		b := m.bucket(ticket.Rating)
+		buckets[b] = append(buckets[b], ticket)
+	}
+
+	matched := make(map[int]bool)
+	var matches [][]*Ticket
+
+	for _, anchor := range tickets {
+		if matched[anchor.UserID] {
+			continue
+		}
+
+		tolerance := m.Tolerance(now.Sub(anchor.CreatedAt))
+		var candidates []*Ticket
+		for b := m.bucket(anchor.Rating - tolerance); b <= m.bucket(anchor.Rating+tolerance); b++ {
+			for _, ticket := range buckets[b] {
+				if ticket == anchor || matched[ticket.UserID] {
+					continue
+				}
+				if math.Abs(ticket.Rating-anchor.Rating) <= tolerance {
+					candidates = append(candidates, ticket)
+				}
+			}
+		}
+		if len(candidates) < m.config.MatchSize-1 {
+			continue
+		}
+
+		sort.Slice(candidates, func(i, j int) bool {
+			di := math.Abs(candidates[i].Rating - anchor.Rating)
+			dj := math.Abs(candidates[j].Rating - anchor.Rating)
+			if di != dj {
+				return di < dj
+			}
+			if !candidates[i].CreatedAt.Equal(candidates[j].CreatedAt) {
+				return candidates[i].CreatedAt.Before(candidates[j].CreatedAt)
+			}
+			return candidates[i].UserID < candidates[j].UserID
+		})
+
+		group := append([]*Ticket{anchor}, candidates[:m.config.MatchSize-1]...)
+		for _, ticket := range group {
+			matched[ticket.UserID] = true
+		}
+		matches = append(matches, group)
+	}
+
+	return matches
+}
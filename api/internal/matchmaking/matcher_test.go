@@ -0,0 +1,302 @@
+package matchmaking_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/omega-realm/api/internal/matchmaking"
+)
+
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testConfig(matchSize int) *matchmaking.Config {
+	return &matchmaking.Config{
+		MatchSize:          matchSize,
+		InitialTolerance:   100,
+		TolerancePerSecond: 10,
+		MaxTolerance:       600,
+		BucketWidth:        100,
+		TicketTimeout:      time.Minute,
+		Interval:           time.Second,
+	}
+}
+
+func ticket(userID int, region string, rating float64, createdAt time.Time) *matchmaking.Ticket {
+	return &matchmaking.Ticket{
+		UserID:      userID,
+		CharacterID: userID * 10,
+		Region:      region,
+		Rating:      rating,
+		CreatedAt:   createdAt,
+		LastSeen:    createdAt,
+	}
+}
+
+// userIDs flattens matches to their user IDs, in match order
+func userIDs(matches [][]*matchmaking.Ticket) [][]int {
+	ids := make([][]int, len(matches))
+	for i, match := range matches {
+		for _, ticket := range match {
+			ids[i] = append(ids[i], ticket.UserID)
+		}
+	}
+	return ids
+}
+
+func equalIDs(a, b [][]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestTolerance(t *testing.T) {
+	matcher := matchmaking.NewMatcher(testConfig(2))
+
+	tests := []struct {
+		wait time.Duration
+		want float64
+	}{
+		{-time.Second, 100},
+		{0, 100},
+		{500 * time.Millisecond, 105},
+		{10 * time.Second, 200},
+		{50 * time.Second, 600},
+		{time.Hour, 600},
+	}
+	for _, tt := range tests {
+		if got := matcher.Tolerance(tt.wait); got != tt.want {
+			t.Errorf("Tolerance(%s) = %v, want %v", tt.wait, got, tt.want)
+		}
+	}
+}
+
+func TestMatchWidensToleranceAsTheClockAdvances(t *testing.T) {
+	clock := matchmaking.NewManualClock(start)
+	matcher := matchmaking.NewMatcher(testConfig(2))
+	tickets := []*matchmaking.Ticket{
+		ticket(1, "eu", 1500, clock.Now()),
+		ticket(2, "eu", 1700, clock.Now()),
+	}
+
+	steps := []struct {
+		advance time.Duration
+		matched bool
+	}{
+		{0, false},               // tolerance 100
+		{5 * time.Second, false}, // tolerance 150
+		{4 * time.Second, false}, // tolerance 190
+		{time.Second, true},      // tolerance 200
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		wait := clock.Now().Sub(start)
+		matches := matcher.Match(clock.Now(), tickets)
+		if matched := len(matches) > 0; matched != step.matched {
+			t.Fatalf("after %s: matched = %v, want %v", wait, matched, step.matched)
+		}
+	}
+}
+
+func TestMatchToleranceStopsAtMax(t *testing.T) {
+	clock := matchmaking.NewManualClock(start)
+	matcher := matchmaking.NewMatcher(testConfig(2))
+	tickets := []*matchmaking.Ticket{
+		ticket(1, "eu", 1000, clock.Now()),
+		ticket(2, "eu", 1601, clock.Now()),
+	}
+
+	clock.Advance(time.Hour)
+	if matches := matcher.Match(clock.Now(), tickets); len(matches) != 0 {
+		t.Fatalf("ratings 601 apart matched after an hour: %v", userIDs(matches))
+	}
+}
+
+func TestMatchUsesTheAnchorsWait(t *testing.T) {
+	// The older ticket anchors, so a fresh ticket is matched on the older
+	// ticket's wider window
+	clock := matchmaking.NewManualClock(start)
+	matcher := matchmaking.NewMatcher(testConfig(2))
+	tickets := []*matchmaking.Ticket{ticket(1, "eu", 1500, clock.Now())}
+
+	clock.Advance(20 * time.Second)
+	tickets = append(tickets, ticket(2, "eu", 1790, clock.Now()))
+
+	want := [][]int{{1, 2}}
+	if got := userIDs(matcher.Match(clock.Now(), tickets)); !equalIDs(got, want) {
+		t.Fatalf("Match() = %v, want %v", got, want)
+	}
+}
+
+func TestMatchBucketsByRegion(t *testing.T) {
+	matcher := matchmaking.NewMatcher(testConfig(2))
+
+	tests := []struct {
+		name    string
+		tickets []*matchmaking.Ticket
+		want    [][]int
+	}{
+		{
+			name: "Equal ratings in different regions do not match",
+			tickets: []*matchmaking.Ticket{
+				ticket(1, "eu", 1500, start),
+				ticket(2, "us", 1500, start),
+			},
+			want: [][]int{},
+		},
+		{
+			name: "Each region matches on its own, in region order",
+			tickets: []*matchmaking.Ticket{
+				ticket(1, "us", 1500, start),
+				ticket(2, "eu", 1500, start),
+				ticket(3, "us", 1510, start),
+				ticket(4, "eu", 1520, start),
+				ticket(5, "ap", 1500, start),
+			},
+			want: [][]int{{2, 4}, {1, 3}},
+		},
+		{
+			name: "Ratings either side of a bucket boundary still match",
+			tickets: []*matchmaking.Ticket{
+				ticket(1, "eu", 1499, start),
+				ticket(2, "eu", 1501, start),
+			},
+			want: [][]int{{1, 2}},
+		},
+		{
+			name: "Ratings within tolerance several buckets apart match",
+			tickets: []*matchmaking.Ticket{
+				ticket(1, "eu", 1450, start),
+				ticket(2, "eu", 1549, start),
+			},
+			want: [][]int{{1, 2}},
+		},
+		{
+			name: "Ratings just outside tolerance do not",
+			tickets: []*matchmaking.Ticket{
+				ticket(1, "eu", 1450, start),
+				ticket(2, "eu", 1551, start),
+			},
+			want: [][]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := userIDs(matcher.Match(start, tt.tickets)); !equalIDs(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchBreaksTiesDeterministically(t *testing.T) {
+	tests := []struct {
+		name      string
+		matchSize int
+		tickets   []*matchmaking.Ticket
+		want      [][]int
+	}{
+		{
+			name:      "The longest-waiting ticket anchors",
+			matchSize: 2,
+			tickets: []*matchmaking.Ticket{
+				ticket(1, "eu", 1500, start.Add(2*time.Second)),
+				ticket(2, "eu", 1500, start),
+				ticket(3, "eu", 1500, start.Add(time.Second)),
+			},
+			want: [][]int{{2, 3}},
+		},
+		{
+			name:      "Equal waits anchor on the lowest user ID",
+			matchSize: 2,
+			tickets: []*matchmaking.Ticket{
+				ticket(3, "eu", 1500, start),
+				ticket(2, "eu", 1500, start),
+				ticket(1, "eu", 1500, start),
+			},
+			want: [][]int{{1, 2}},
+		},
+		{
+			name:      "The closest rating is picked first",
+			matchSize: 2,
+			tickets: []*matchmaking.Ticket{
+				ticket(1, "eu", 1500, start),
+				ticket(2, "eu", 1560, start),
+				ticket(3, "eu", 1530, start),
+			},
+			want: [][]int{{1, 3}},
+		},
+		{
+			name:      "Equally close ratings pick the longer wait",
+			matchSize: 2,
+			tickets: []*matchmaking.Ticket{
+				ticket(1, "eu", 1500, start),
+				ticket(2, "eu", 1520, start.Add(2*time.Second)),
+				ticket(3, "eu", 1480, start.Add(time.Second)),
+			},
+			want: [][]int{{1, 3}},
+		},
+		{
+			name:      "Equally close ratings and waits pick the lowest user ID",
+			matchSize: 2,
+			tickets: []*matchmaking.Ticket{
+				ticket(1, "eu", 1500, start),
+				ticket(3, "eu", 1520, start.Add(time.Second)),
+				ticket(2, "eu", 1480, start.Add(time.Second)),
+			},
+			want: [][]int{{1, 2}},
+		},
+		{
+			name:      "Matched tickets are not reused",
+			matchSize: 3,
+			tickets: []*matchmaking.Ticket{
+				ticket(1, "eu", 1500, start),
+				ticket(2, "eu", 1510, start),
+				ticket(3, "eu", 1520, start),
+				ticket(4, "eu", 1530, start),
+				ticket(5, "eu", 1540, start),
+				ticket(6, "eu", 1550, start),
+				ticket(7, "eu", 1560, start),
+			},
+			want: [][]int{{1, 2, 3}, {4, 5, 6}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher := matchmaking.NewMatcher(testConfig(tt.matchSize))
+
+			// Every arrangement of the same tickets must give the same matches
+			for i := range tt.tickets {
+				tickets := append(append([]*matchmaking.Ticket{}, tt.tickets[i:]...), tt.tickets[:i]...)
+				if got := userIDs(matcher.Match(start, tickets)); !equalIDs(got, tt.want) {
+					t.Errorf("Match() rotated by %d = %v, want %v", i, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchNeedsAFullMatch(t *testing.T) {
+	matcher := matchmaking.NewMatcher(testConfig(4))
+	tickets := []*matchmaking.Ticket{
+		ticket(1, "eu", 1500, start),
+		ticket(2, "eu", 1500, start),
+		ticket(3, "eu", 1500, start),
+		ticket(4, "us", 1500, start),
+	}
+	if matches := matcher.Match(start, tickets); len(matches) != 0 {
+		t.Fatalf("Match() = %v, want no matches", userIDs(matches))
+	}
+}
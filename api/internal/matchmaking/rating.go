@@ -0,0 +1,158 @@
+package matchmaking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+)
+
+// Glicko parameters. New characters start at DefaultRating with maximum
+// uncertainty; the deviation shrinks with every recorded kill.
+const (
+	DefaultRating    = 1500.0
+	DefaultDeviation = 350.0
+	MinDeviation     = 50.0
+)
+
+// glickoQ is ln(10)/400, the Glicko scale constant
+var glickoQ = math.Ln10 / 400
+
+// Rating is a character's hidden matchmaking rating
+type Rating struct {
+	CharacterID int       `json:"character_id"`
+	Rating      float64   `json:"rating"`
+	Deviation   float64   `json:"deviation"`
+	GamesPlayed int       `json:"games_played"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NewRating returns the starting rating for a character
+func NewRating(characterID int) Rating {
+	return Rating{CharacterID: characterID, Rating: DefaultRating, Deviation: DefaultDeviation}
+}
+
+// glickoG dampens the impact of an opponent whose rating is uncertain
+func glickoG(deviation float64) float64 {
+	return 1 / math.Sqrt(1+3*glickoQ*glickoQ*deviation*deviation/(math.Pi*math.Pi))
+}
+
+// expectedScore is the probability that a player rated r beats opponent
+func expectedScore(r float64, opponent Rating) float64 {
+	return 1 / (1 + math.Pow(10, -glickoG(opponent.Deviation)*(r-opponent.Rating)/400))
+}
+
+// glickoUpdate applies a single game result (score 1 = win, 0 = loss)
+func glickoUpdate(player, opponent Rating, score float64) Rating {
+	g := glickoG(opponent.Deviation)
+	e := expectedScore(player.Rating, opponent)
+	dSquared := 1 / (glickoQ * glickoQ * g * g * e * (1 - e))
+	denominator := 1/(player.Deviation*player.Deviation) + 1/dSquared
+
+	player.Rating += glickoQ / denominator * g * (score - e)
+	player.Deviation = math.Max(math.Sqrt(1/denominator), MinDeviation)
+	player.GamesPlayed++
+	return player
+}
+
+// UpdateRatings applies the outcome of one PvP kill. Both sides are updated
+// from their pre-game ratings.
+func UpdateRatings(killer, victim Rating) (Rating, Rating) {
+	return glickoUpdate(killer, victim, 1), glickoUpdate(victim, killer, 0)
+}
+
+// RatingStore persists ratings in the character_ratings table
+type RatingStore struct {
+	db *database.DB
+}
+
+// NewRatingStore creates a rating store
+func NewRatingStore(db *database.DB) *RatingStore {
+	return &RatingStore{db: db}
+}
+
+// Get returns a character's rating, or the starting rating if they have none yet
+func (s *RatingStore) Get(ctx context.Context, characterID int) (Rating, error) {
+	rating := Rating{CharacterID: characterID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT rating, deviation, games_played, updated_at
+		FROM character_ratings WHERE character_id = $1
+	`, characterID).Scan(&rating.Rating, &rating.Deviation, &rating.GamesPlayed, &rating.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return NewRating(characterID), nil
+	}
+	if err != nil {
+		return rating, fmt.Errorf("failed to get rating for character %d: %w", characterID, err)
+	}
+	return rating, nil
+}
+
+// RecordKill updates both characters' ratings for a PvP kill in one transaction
+func (s *RatingStore) RecordKill(ctx context.Context, killerID, victimID int) (Rating, Rating, error) {
+	var killer, victim Rating
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return killer, victim, fmt.Errorf("failed to begin rating transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO character_ratings (character_id, rating, deviation)
+		VALUES ($1, $3, $4), ($2, $3, $4)
+		ON CONFLICT (character_id) DO NOTHING
+	`, killerID, victimID, DefaultRating, DefaultDeviation)
+	if err != nil {
+		return killer, victim, fmt.Errorf("failed to initialize ratings: %w", err)
+	}
+
+	// Lock in a fixed order so concurrent kills between the same pair cannot deadlock
+	rows, err := tx.QueryContext(ctx, `
+		SELECT character_id, rating, deviation, games_played
+		FROM character_ratings
+		WHERE character_id IN ($1, $2)
+		ORDER BY character_id
+		FOR UPDATE
+	`, killerID, victimID)
+	if err != nil {
+		return killer, victim, fmt.Errorf("failed to lock ratings: %w", err)
+	}
+	for rows.Next() {
+		var rating Rating
+		if err := rows.Scan(&rating.CharacterID, &rating.Rating, &rating.Deviation, &rating.GamesPlayed); err != nil {
+			rows.Close()
+			return killer, victim, fmt.Errorf("failed to scan rating: %w", err)
+		}
+		if rating.CharacterID == killerID {
+			killer = rating
+		} else {
+			victim = rating
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return killer, victim, fmt.Errorf("failed to read ratings: %w", err)
+	}
+
+	killer, victim = UpdateRatings(killer, victim)
+
+	for _, rating := range []*Rating{&killer, &victim} {
+		err := tx.QueryRowContext(ctx, `
+			UPDATE character_ratings
+			SET rating = $2, deviation = $3, games_played = $4, updated_at = CURRENT_TIMESTAMP
+			WHERE character_id = $1
+			RETURNING updated_at
+		`, rating.CharacterID, rating.Rating, rating.Deviation, rating.GamesPlayed).Scan(&rating.UpdatedAt)
+		if err != nil {
+			return killer, victim, fmt.Errorf("failed to update rating for character %d: %w", rating.CharacterID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return killer, victim, fmt.Errorf("failed to commit ratings: %w", err)
+	}
+	return killer, victim, nil
+}
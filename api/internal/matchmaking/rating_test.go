@@ -0,0 +1,98 @@
+package matchmaking_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/omega-realm/api/internal/matchmaking"
+)
+
+func rating(characterID int, value, deviation float64) matchmaking.Rating {
+	return matchmaking.Rating{CharacterID: characterID, Rating: value, Deviation: deviation}
+}
+
+func TestUpdateRatingsNewPlayers(t *testing.T) {
+	killer, victim := matchmaking.UpdateRatings(matchmaking.NewRating(1), matchmaking.NewRating(2))
+
+	// One game between two unrated players, worked through Glickman's formulas
+	const wantChange, wantDeviation = 162.2, 290.2
+	if math.Abs(killer.Rating-(matchmaking.DefaultRating+wantChange)) > 0.1 {
+		t.Errorf("killer rating = %.1f, want %.1f", killer.Rating, matchmaking.DefaultRating+wantChange)
+	}
+	if math.Abs(victim.Rating-(matchmaking.DefaultRating-wantChange)) > 0.1 {
+		t.Errorf("victim rating = %.1f, want %.1f", victim.Rating, matchmaking.DefaultRating-wantChange)
+	}
+	for _, r := range []matchmaking.Rating{killer, victim} {
+		if math.Abs(r.Deviation-wantDeviation) > 0.1 {
+			t.Errorf("character %d deviation = %.1f, want %.1f", r.CharacterID, r.Deviation, wantDeviation)
+		}
+		if r.GamesPlayed != 1 {
+			t.Errorf("character %d games played = %d, want 1", r.CharacterID, r.GamesPlayed)
+		}
+	}
+	if killer.CharacterID != 1 || victim.CharacterID != 2 {
+		t.Errorf("UpdateRatings() returned characters %d and %d, want 1 and 2", killer.CharacterID, victim.CharacterID)
+	}
+}
+
+func TestUpdateRatings(t *testing.T) {
+	tests := []struct {
+		name           string
+		killer, victim matchmaking.Rating
+		// minGain and maxGain bound how much the killer's rating rises
+		minGain, maxGain float64
+	}{
+		{"Beating an equal", rating(1, 1500, 100), rating(2, 1500, 100), 10, 30},
+		{"Beating a much weaker player gains little", rating(1, 1900, 100), rating(2, 1300, 100), 0, 3},
+		{"An upset gains a lot", rating(1, 1300, 100), rating(2, 1900, 100), 25, 60},
+		{"An uncertain killer moves further", rating(1, 1500, 300), rating(2, 1500, 100), 80, 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			killer, victim := matchmaking.UpdateRatings(tt.killer, tt.victim)
+
+			gain := killer.Rating - tt.killer.Rating
+			if gain < tt.minGain || gain > tt.maxGain {
+				t.Errorf("killer gained %.2f, want %.0f to %.0f", gain, tt.minGain, tt.maxGain)
+			}
+			if victim.Rating >= tt.victim.Rating {
+				t.Errorf("victim rating rose from %.2f to %.2f", tt.victim.Rating, victim.Rating)
+			}
+			if killer.Deviation >= tt.killer.Deviation || victim.Deviation >= tt.victim.Deviation {
+				t.Errorf("deviations did not shrink: killer %.2f -> %.2f, victim %.2f -> %.2f",
+					tt.killer.Deviation, killer.Deviation, tt.victim.Deviation, victim.Deviation)
+			}
+		})
+	}
+}
+
+func TestUpdateRatingsIsSymmetricForEqualPlayers(t *testing.T) {
+	killer, victim := matchmaking.UpdateRatings(rating(1, 1620, 180), rating(2, 1620, 180))
+	gain := killer.Rating - 1620
+	loss := 1620 - victim.Rating
+	if math.Abs(gain-loss) > 1e-9 {
+		t.Errorf("killer gained %v but victim lost %v", gain, loss)
+	}
+	if killer.Deviation != victim.Deviation {
+		t.Errorf("deviations differ: killer %v, victim %v", killer.Deviation, victim.Deviation)
+	}
+}
+
+func TestUpdateRatingsDeviationFloor(t *testing.T) {
+	// Trading kills keeps the two close, so every game is informative
+	a, b := matchmaking.NewRating(1), matchmaking.NewRating(2)
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			a, b = matchmaking.UpdateRatings(a, b)
+		} else {
+			b, a = matchmaking.UpdateRatings(b, a)
+		}
+	}
+	if a.Deviation != matchmaking.MinDeviation || b.Deviation != matchmaking.MinDeviation {
+		t.Errorf("deviations after 100 games = %v and %v, want %v", a.Deviation, b.Deviation, matchmaking.MinDeviation)
+	}
+	if a.GamesPlayed != 100 || b.GamesPlayed != 100 {
+		t.Errorf("games played = %d and %d, want 100", a.GamesPlayed, b.GamesPlayed)
+	}
+}
@@ -0,0 +1,400 @@
+package matchmaking
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/websocket"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// ticketsKey is a hash of user ID → ticket JSON
+	ticketsKey = "mm_tickets"
+	// ticketsSeenKey is a sorted set of user IDs scored by their last status poll (ms)
+	ticketsSeenKey = "mm_tickets_seen"
+	// lockKey elects the one replica that forms matches
+	lockKey = "mm_lock"
+)
+
+// ErrNoTicket is returned when a user is neither searching nor matched
+var ErrNoTicket = errors.New("no matchmaking ticket")
+
+// Match is a formed PvP match and the game server it was placed on
+type Match struct {
+	ID            string    `json:"id"`
+	Region        string    `json:"region"`
+	UserIDs       []int     `json:"user_ids"`
+	AverageRating float64   `json:"average_rating"`
+	InstanceID    string    `json:"instance_id"`
+	WebSocketURL  string    `json:"websocket_url"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func matchKey(userID int) string {
+	return fmt.Sprintf("mm_match:%d", userID)
+}
+
+// matchChannel is where a game server hears about matches placed on it
+func matchChannel(instanceID string) string {
+	return fmt.Sprintf("matches:%s", instanceID)
+}
+
+// renewLockScript extends the lock only if this replica still holds it
+var renewLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Service keeps matchmaking tickets in Redis and periodically groups them
+// into matches. Any replica may accept tickets; only the lock holder forms
+// matches.
+type Service struct {
+	redis          *redisClient.Client
+	events         *websocket.Publisher
+	ratings        *RatingStore
+	matcher        *Matcher
+	clock          Clock
+	config         *Config
+	reservationTTL time.Duration
+	replicaID      string
+}
+
+// NewService creates a matchmaking service
+func NewService(db *database.DB, redis *redisClient.Client, events *websocket.Publisher, clock Clock, config *Config, reservationTTL time.Duration) *Service {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+
+	return &Service{
+		redis:          redis,
+		events:         events,
+		ratings:        NewRatingStore(db),
+		matcher:        NewMatcher(config),
+		clock:          clock,
+		config:         config,
+		reservationTTL: reservationTTL,
+		replicaID:      hex.EncodeToString(idBytes),
+	}
+}
+
+// Ratings returns the service's rating store
+func (s *Service) Ratings() *RatingStore {
+	return s.ratings
+}
+
+// Enqueue creates or replaces the user's ticket for region. Any earlier match
+// the user had not yet joined is dropped.
+func (s *Service) Enqueue(ctx context.Context, userID, characterID int, region string) (*Ticket, error) {
+	rating, err := s.ratings.Get(ctx, characterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.dropMatch(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	ticket := &Ticket{
+		UserID:      userID,
+		CharacterID: characterID,
+		Region:      region,
+		Rating:      rating.Rating,
+		CreatedAt:   now,
+		LastSeen:    now,
+	}
+	ticketJSON, err := json.Marshal(ticket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+
+	member := strconv.Itoa(userID)
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, ticketsKey, member, ticketJSON)
+	pipe.ZAdd(ctx, ticketsSeenKey, redis.Z{Score: float64(now.UnixMilli()), Member: member})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to save ticket: %w", err)
+	}
+	return ticket, nil
+}
+
+// Status returns the user's match if one has formed, otherwise their ticket.
+// Checking a ticket keeps it alive.
+func (s *Service) Status(ctx context.Context, userID int) (*Ticket, *Match, error) {
+	match, err := s.getMatch(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if match != nil {
+		return nil, match, nil
+	}
+
+	ticket, err := s.getTicket(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := s.clock.Now()
+	err = s.redis.ZAddXX(ctx, ticketsSeenKey, redis.Z{Score: float64(now.UnixMilli()), Member: strconv.Itoa(userID)}).Err()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to touch ticket: %w", err)
+	}
+	ticket.LastSeen = now
+	return ticket, nil, nil
+}
+
+// Cancel removes the user's ticket, or backs them out of a match they have
+// not joined yet
+func (s *Service) Cancel(ctx context.Context, userID int) error {
+	member := strconv.Itoa(userID)
+	pipe := s.redis.TxPipeline()
+	pipe.HDel(ctx, ticketsKey, member)
+	pipe.ZRem(ctx, ticketsSeenKey, member)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to cancel ticket: %w", err)
+	}
+	return s.dropMatch(ctx, userID)
+}
+
+func (s *Service) getTicket(ctx context.Context, userID int) (*Ticket, error) {
+	ticketJSON, err := s.redis.HGet(ctx, ticketsKey, strconv.Itoa(userID)).Result()
+	if err == redis.Nil {
+		return nil, ErrNoTicket
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	var ticket Ticket
+	if err := json.Unmarshal([]byte(ticketJSON), &ticket); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ticket: %w", err)
+	}
+	return &ticket, nil
+}
+
+func (s *Service) getMatch(ctx context.Context, userID int) (*Match, error) {
+	matchJSON, err := s.redis.Get(ctx, matchKey(userID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get match: %w", err)
+	}
+
+	var match Match
+	if err := json.Unmarshal([]byte(matchJSON), &match); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal match: %w", err)
+	}
+	return &match, nil
+}
+
+// dropMatch forgets the user's pending match and frees their reserved slot
+func (s *Service) dropMatch(ctx context.Context, userID int) error {
+	deleted, err := s.redis.Del(ctx, matchKey(userID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to drop match: %w", err)
+	}
+	if deleted > 0 {
+		return s.redis.ReleaseReservation(ctx, userID)
+	}
+	return nil
+}
+
+// Run forms matches until ctx is cancelled
+func (s *Service) Run(ctx context.Context) {
+	log.Printf("[Matchmaking] Matchmaker started (match size: %d, interval: %s)", s.config.MatchSize, s.config.Interval)
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			matches, err := s.Tick(ctx)
+			if err != nil {
+				log.Printf("[Matchmaking] %v", err)
+			}
+			if len(matches) > 0 {
+				log.Printf("[Matchmaking] Formed %d matches", len(matches))
+			}
+		}
+	}
+}
+
+// Tick runs one matchmaking pass: it expires idle tickets, groups the rest
+// and places each group on a game server. Groups that cannot be placed keep
+// their tickets and are retried on the next pass. Replicas that do not hold
+// the matchmaker lock do nothing.
+func (s *Service) Tick(ctx context.Context) ([]*Match, error) {
+	leader, err := s.acquireLock(ctx)
+	if err != nil || !leader {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	if err := s.expireTickets(ctx, now); err != nil {
+		return nil, err
+	}
+
+	tickets, err := s.loadTickets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*Match
+	for _, group := range s.matcher.Match(now, tickets) {
+		match, err := s.place(ctx, now, group)
+		if errors.Is(err, redisClient.ErrRegionFull) || errors.Is(err, redisClient.ErrNoGameServers) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[Matchmaking] %v", err)
+			continue
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// acquireLock takes or renews the matchmaker lock. It lapses after a few
+// missed intervals so another replica can take over.
+func (s *Service) acquireLock(ctx context.Context) (bool, error) {
+	ttl := 3 * s.config.Interval
+	ok, err := s.redis.SetNX(ctx, lockKey, s.replicaID, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire matchmaker lock: %w", err)
+	}
+	if ok {
+		return true, nil
+	}
+
+	renewed, err := renewLockScript.Run(ctx, s.redis, []string{lockKey}, s.replicaID, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew matchmaker lock: %w", err)
+	}
+	return renewed == 1, nil
+}
+
+// expireTickets drops tickets whose owner stopped polling
+func (s *Service) expireTickets(ctx context.Context, now time.Time) error {
+	cutoff := strconv.FormatInt(now.Add(-s.config.TicketTimeout).UnixMilli(), 10)
+	stale, err := s.redis.ZRangeByScore(ctx, ticketsSeenKey, &redis.ZRangeBy{Min: "-inf", Max: "(" + cutoff}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list idle tickets: %w", err)
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	members := make([]any, len(stale))
+	for i, member := range stale {
+		members[i] = member
+	}
+	pipe := s.redis.TxPipeline()
+	pipe.HDel(ctx, ticketsKey, stale...)
+	pipe.ZRem(ctx, ticketsSeenKey, members...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to drop idle tickets: %w", err)
+	}
+	log.Printf("[Matchmaking] Dropped %d idle tickets", len(stale))
+	return nil
+}
+
+func (s *Service) loadTickets(ctx context.Context) ([]*Ticket, error) {
+	entries, err := s.redis.HGetAll(ctx, ticketsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tickets: %w", err)
+	}
+
+	tickets := make([]*Ticket, 0, len(entries))
+	for member, ticketJSON := range entries {
+		var ticket Ticket
+		if err := json.Unmarshal([]byte(ticketJSON), &ticket); err != nil {
+			log.Printf("[Matchmaking] Dropping unreadable ticket for user %s: %v", member, err)
+			s.redis.HDel(ctx, ticketsKey, member)
+			continue
+		}
+		tickets = append(tickets, &ticket)
+	}
+	return tickets, nil
+}
+
+// place reserves slots for the whole group on one instance, hands each player
+// the match and tells the instance about it
+func (s *Service) place(ctx context.Context, now time.Time, group []*Ticket) (*Match, error) {
+	region := group[0].Region
+	userIDs := make([]int, len(group))
+	total := 0.0
+	for i, ticket := range group {
+		userIDs[i] = ticket.UserID
+		total += ticket.Rating
+	}
+
+	reservation, err := s.redis.ReserveSlots(ctx, region, userIDs, s.reservationTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	match := &Match{
+		ID:            hex.EncodeToString(idBytes),
+		Region:        region,
+		UserIDs:       userIDs,
+		AverageRating: total / float64(len(group)),
+		InstanceID:    reservation.Instance.InstanceID,
+		WebSocketURL:  reservation.Instance.Address,
+		ExpiresAt:     reservation.ExpiresAt,
+		CreatedAt:     now,
+	}
+	matchJSON, err := json.Marshal(match)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal match: %w", err)
+	}
+
+	pipe := s.redis.TxPipeline()
+	for _, userID := range userIDs {
+		member := strconv.Itoa(userID)
+		pipe.HDel(ctx, ticketsKey, member)
+		pipe.ZRem(ctx, ticketsSeenKey, member)
+		pipe.Set(ctx, matchKey(userID), matchJSON, s.reservationTTL)
+	}
+	pipe.Publish(ctx, matchChannel(match.InstanceID), matchJSON)
+	if _, err := pipe.Exec(ctx); err != nil {
+		for _, userID := range userIDs {
+			s.redis.ReleaseReservation(ctx, userID)
+		}
+		return nil, fmt.Errorf("failed to store match: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		err := s.events.PublishUser(ctx, userID, websocket.EventMatchFound, map[string]any{
+			"match_id":               match.ID,
+			"region":                 match.Region,
+			"player_count":           len(userIDs),
+			"instance_id":            match.InstanceID,
+			"websocket_url":          match.WebSocketURL,
+			"reservation_expires_at": match.ExpiresAt,
+		})
+		if err != nil {
+			log.Printf("[Matchmaking] %v", err)
+		}
+	}
+
+	log.Printf("[Matchmaking] Match %s placed on %s (%d players, avg rating %.0f)", match.ID, match.InstanceID, len(userIDs), match.AverageRating)
+	return match, nil
+}
@@ -2,11 +2,13 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
 	"strings"
 
 	"github.com/omega-realm/api/internal/auth"
+	redisClient "github.com/omega-realm/api/internal/redis"
 )
 
 // contextKey is a custom type for context keys to avoid collisions
@@ -22,8 +24,26 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
-// RequireAuth is a middleware that validates JWT tokens
-func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
+// SessionStore looks up the Redis session behind an access token. It is
+// satisfied by *redis.Client.
+type SessionStore interface {
+	GetSession(ctx context.Context, token string) (*redisClient.SessionData, error)
+}
+
+// Auth holds what the authentication middlewares check tokens against
+type Auth struct {
+	sessions SessionStore
+}
+
+// NewAuth creates the authentication middlewares
+func NewAuth(sessions SessionStore) *Auth {
+	return &Auth{sessions: sessions}
+}
+
+// RequireAuth is a middleware that validates JWT tokens. The token's session
+// must still be live: logging out, bans and account deletion end sessions
+// long before their tokens expire.
+func (a *Auth) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Extract token from Authorization header
 		authHeader := r.Header.Get("Authorization")
@@ -54,6 +74,14 @@ func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		session, err := a.sessions.GetSession(r.Context(), tokenString)
+		if err != nil || session.UserID != claims.UserID {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Session has ended"})
+			return
+		}
+
 		// Add claims to request context
 		ctx := context.WithValue(r.Context(), UserContextKey, claims)
 		r = r.WithContext(ctx)
@@ -68,3 +96,52 @@ func GetUserClaims(r *http.Request) (*auth.CustomClaims, bool) {
 	claims, ok := r.Context().Value(UserContextKey).(*auth.CustomClaims)
 	return claims, ok
 }
+
+// RequireRole is a middleware that validates JWT tokens and only admits users
+// holding one of the given roles
+func (a *Auth) RequireRole(roles []string, next http.HandlerFunc) http.HandlerFunc {
+	return a.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := GetUserClaims(r)
+		if ok {
+			for _, role := range roles {
+				if claims.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Insufficient permissions"})
+	})
+}
+
+// RequireAdmin is a middleware that only admits administrators
+func (a *Auth) RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return a.RequireRole([]string{auth.RoleAdmin}, next)
+}
+
+// RequireModerator is a middleware that admits moderators and administrators
+func (a *Auth) RequireModerator(next http.HandlerFunc) http.HandlerFunc {
+	return a.RequireRole([]string{auth.RoleModerator, auth.RoleAdmin}, next)
+}
+
+// ServerKeyHeader carries the shared secret game servers use for internal endpoints
+const ServerKeyHeader = "X-Server-Key"
+
+// RequireServerKey is a middleware for internal endpoints called by game
+// servers. Requests must present the shared key in the X-Server-Key header.
+func RequireServerKey(key string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provided := r.Header.Get(ServerKeyHeader)
+		if key == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(key)) != 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid server key"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,113 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/omega-realm/api/internal/auth"
+	"github.com/omega-realm/api/internal/middleware"
+	redisClient "github.com/omega-realm/api/internal/redis"
+)
+
+// sessions is an in-memory stand-in for the Redis session store
+type sessions map[string]*redisClient.SessionData
+
+func (s sessions) GetSession(ctx context.Context, token string) (*redisClient.SessionData, error) {
+	session, ok := s[token]
+	if !ok {
+		return nil, errors.New("session not found")
+	}
+	return session, nil
+}
+
+// invalidateUser ends every session of a user, as a ban does
+func (s sessions) invalidateUser(userID int) {
+	for token, session := range s {
+		if session.UserID == userID {
+			delete(s, token)
+		}
+	}
+}
+
+func login(t *testing.T, store sessions, userID int, role string) string {
+	t.Helper()
+	token, err := auth.GenerateAccessToken(userID, "player", "player@example.com", "asia", role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store[token] = &redisClient.SessionData{UserID: userID, Region: "asia"}
+	return token
+}
+
+func serve(handler http.HandlerFunc, header string) int {
+	req := httptest.NewRequest(http.MethodGet, "/api/party", nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec.Code
+}
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	if _, found := middleware.GetUserClaims(r); !found {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func TestRequireAuthRejectsBannedToken(t *testing.T) {
+	store := sessions{}
+	handler := middleware.NewAuth(store).RequireAuth(ok)
+	banned := login(t, store, 1, auth.RolePlayer)
+	other := login(t, store, 2, auth.RolePlayer)
+
+	if code := serve(handler, "Bearer "+banned); code != http.StatusOK {
+		t.Fatalf("before the ban: status %d, want %d", code, http.StatusOK)
+	}
+
+	// The token stays valid for a day, but the ban ended its session
+	store.invalidateUser(1)
+	if code := serve(handler, "Bearer "+banned); code != http.StatusUnauthorized {
+		t.Errorf("after the ban: status %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := serve(handler, "Bearer "+other); code != http.StatusOK {
+		t.Errorf("another user after the ban: status %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	store := sessions{}
+	token := login(t, store, 1, auth.RolePlayer)
+
+	// A token whose session records someone else
+	borrowed, err := auth.GenerateAccessToken(3, "thief", "thief@example.com", "asia", auth.RolePlayer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store[borrowed] = &redisClient.SessionData{UserID: 1}
+
+	a := middleware.NewAuth(store)
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		header  string
+		want    int
+	}{
+		{"Live session", a.RequireAuth(ok), "Bearer " + token, http.StatusOK},
+		{"No header", a.RequireAuth(ok), "", http.StatusUnauthorized},
+		{"Not a bearer token", a.RequireAuth(ok), "Basic " + token, http.StatusUnauthorized},
+		{"Invalid token", a.RequireAuth(ok), "Bearer not-a-token", http.StatusUnauthorized},
+		{"Session of another user", a.RequireAuth(ok), "Bearer " + borrowed, http.StatusUnauthorized},
+		{"Player on a moderator route", a.RequireModerator(ok), "Bearer " + token, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := serve(tt.handler, tt.header); code != tt.want {
+				t.Errorf("status %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // User represents a user account
 type User struct {
@@ -9,15 +12,17 @@ type User struct {
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"`
 	Region       string    `json:"region"`
+	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
 }
 
 // Character represents a player character
 type Character struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // Leaderboard represents leaderboard stats
@@ -35,6 +40,180 @@ type Session struct {
 	ID           int        `json:"id"`
 	CharacterID  int        `json:"character_id"`
 	ServerRegion string     `json:"server_region"`
+	InstanceID   *string    `json:"instance_id,omitempty"`
 	StartedAt    time.Time  `json:"started_at"`
 	EndedAt      *time.Time `json:"ended_at"`
+	EndReason    *string    `json:"end_reason,omitempty"`
+}
+
+// Session end reasons. All but SessionEndOrphaned mirror the client's
+// PacketTypes.DisconnectReason codes.
+const (
+	SessionEndUserQuit         = "user_quit"
+	SessionEndTimeout          = "timeout"
+	SessionEndKicked           = "kicked"
+	SessionEndServerShutdown   = "server_shutdown"
+	SessionEndInvalidAuth      = "invalid_auth"
+	SessionEndDuplicateSession = "duplicate_session"
+	SessionEndOrphaned         = "orphaned"
+)
+
+// AuditEvent represents an entry in the audit log
+type AuditEvent struct {
+	ID        int             `json:"id"`
+	UserID    *int            `json:"user_id"`
+	EventType string          `json:"event_type"`
+	Details   json.RawMessage `json:"details"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AccountDeletion represents a pending account deletion request
+type AccountDeletion struct {
+	UserID       int       `json:"user_id"`
+	RequestedAt  time.Time `json:"requested_at"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+}
+
+// PartyInvite represents an invitation to join a party
+type PartyInvite struct {
+	ID          int        `json:"id"`
+	PartyID     string     `json:"party_id"`
+	InviterID   int        `json:"inviter_id"`
+	InviterName string     `json:"inviter_name,omitempty"`
+	InviteeID   int        `json:"invitee_id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
+
+// PartyInvite status constants
+const (
+	PartyInvitePending   = "pending"
+	PartyInviteAccepted  = "accepted"
+	PartyInviteDeclined  = "declined"
+	PartyInviteCancelled = "cancelled"
+)
+
+// FriendRequest represents a friend request or an accepted friendship
+type FriendRequest struct {
+	ID            int        `json:"id"`
+	RequesterID   int        `json:"requester_id"`
+	RequesterName string     `json:"requester_name,omitempty"`
+	AddresseeID   int        `json:"addressee_id"`
+	AddresseeName string     `json:"addressee_name,omitempty"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RespondedAt   *time.Time `json:"responded_at,omitempty"`
+}
+
+// Friendship status constants
+const (
+	FriendshipPending  = "pending"
+	FriendshipAccepted = "accepted"
+	FriendshipDeclined = "declined"
+)
+
+// BlockedUser represents a user the caller has blocked
+type BlockedUser struct {
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChatMessage is a chat line kept for moderation review. Message is the text
+// as delivered; Original holds what the sender typed when the filter changed it.
+type ChatMessage struct {
+	ID            int64     `json:"id"`
+	SenderID      int       `json:"sender_id"`
+	SenderName    string    `json:"sender_name"`
+	Channel       string    `json:"channel"`
+	ChannelKey    string    `json:"channel_key,omitempty"`
+	RecipientID   *int      `json:"recipient_id,omitempty"`
+	RecipientName string    `json:"recipient_name,omitempty"`
+	Message       string    `json:"message"`
+	Original      *string   `json:"original,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Sanction represents a moderation action against a user
+type Sanction struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	Type      string     `json:"type"`
+	Reason    string     `json:"reason"`
+	ReportID  *int       `json:"report_id,omitempty"`
+	IssuedBy  *int       `json:"issued_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy *int       `json:"revoked_by,omitempty"`
+}
+
+// Sanction type constants
+const (
+	SanctionMute = "mute"
+	SanctionBan  = "ban"
+)
+
+// Report represents a player report against a character
+type Report struct {
+	ID                    int        `json:"id"`
+	ReporterID            *int       `json:"reporter_id"`
+	ReporterName          string     `json:"reporter_name,omitempty"`
+	ReportedUserID        int        `json:"reported_user_id"`
+	ReportedCharacterID   *int       `json:"reported_character_id"`
+	ReportedCharacterName string     `json:"reported_character_name"`
+	Category              string     `json:"category"`
+	Description           string     `json:"description"`
+	MatchID               *string    `json:"match_id,omitempty"`
+	SessionID             *int       `json:"session_id,omitempty"`
+	Status                string     `json:"status"`
+	AssignedTo            *int       `json:"assigned_to,omitempty"`
+	Resolution            *string    `json:"resolution,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	ResolvedAt            *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Report status constants
+const (
+	ReportOpen      = "open"
+	ReportInReview  = "in_review"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+// Report category constants
+const (
+	ReportCheating    = "cheating"
+	ReportAbusiveName = "abusive_name"
+	ReportHarassment  = "harassment"
+	ReportSpam        = "spam"
+	ReportOther       = "other"
+)
+
+// SuspectScore is the anti-cheat analysis of one game connection. Scores run
+// from 0 to 100 and fade as the player behaves; PeakScore is the highest
+// combined score the connection reached.
+type SuspectScore struct {
+	ID                 int       `json:"id"`
+	ConnectionID       string    `json:"connection_id"`
+	UserID             int       `json:"user_id"`
+	CharacterID        *int      `json:"character_id"`
+	InstanceID         string    `json:"instance_id"`
+	Region             string    `json:"region"`
+	Score              int       `json:"score"`
+	PeakScore          int       `json:"peak_score"`
+	SpeedScore         int       `json:"speed_score"`
+	CorrectionScore    int       `json:"correction_score"`
+	AimSnapScore       int       `json:"aim_snap_score"`
+	ShotRateScore      int       `json:"shot_rate_score"`
+	SpeedViolations    int       `json:"speed_violations"`
+	Corrections        int       `json:"corrections"`
+	AimSnaps           int       `json:"aim_snaps"`
+	ShotRateViolations int       `json:"shot_rate_violations"`
+	Inputs             int       `json:"inputs"`
+	StartedAt          time.Time `json:"started_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
 }
@@ -0,0 +1,71 @@
+package namepolicy
+
+// defaultDenylist is always applied. Deployments extend it with the file
+// named in NAME_DENYLIST_PATH rather than editing this list.
+var defaultDenylist = []string{
+	"fuck",
+	"shit",
+	"bitch",
+	"cunt",
+	"nigger",
+	"nigga",
+	"faggot",
+	"retard",
+	"whore",
+	"slut",
+	"nazi",
+	"hitler",
+	"penis",
+	"vagina",
+	"porn",
+	"pussy",
+	"ass",
+}
+
+// defaultReserved names can never be registered, in any spelling
+var defaultReserved = []string{
+	"admin",
+	"administrator",
+	"root",
+	"system",
+	"server",
+	"omega",
+	"omega realm",
+	"omegarealm",
+	"official",
+	"support",
+	"moderator",
+	"staff",
+	"null",
+	"undefined",
+	"everyone",
+}
+
+// staffTitles are terms that imply a name belongs to the game's staff
+var staffTitles = []string{
+	"admin",
+	"moderator",
+	"gamemaster",
+	"developer",
+	"official",
+	"support",
+	"staff",
+	"gm",
+	"mod",
+}
+
+// allowedWords begin or end with a denied term or staff title but are
+// innocent, so they never match one
+var allowedWords = []string{
+	"nazir",
+	"nazim",
+	"officially",
+	"penistone",
+	"pussycat",
+	"pussywillow",
+	"shiitake",
+	"shitake",
+	"staffordshire",
+	"supporter",
+	"supporters",
+}
@@ -0,0 +1,106 @@
+package namepolicy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// leetspeak maps digits and symbols to the letter they usually stand in for
+var leetspeak = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'6': 'g',
+	'7': 't',
+	'8': 'b',
+	'9': 'g',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'i',
+	'+': 't',
+}
+
+// confusables maps look-alike letters from other scripts, and accented
+// Latin letters, to the plain ASCII letter they resemble
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i',
+	'ї': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+	// Latin look-alikes
+	'ı': 'i', 'ɡ': 'g', 'ł': 'l', 'ø': 'o', 'ß': 's',
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a',
+	'ç': 'c', 'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ñ': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ý': 'y', 'ÿ': 'y',
+}
+
+// multiRune collapses letter pairs that render like a single letter
+var multiRune = strings.NewReplacer("rn", "m", "vv", "w")
+
+// LookAlikeKey reduces a name to the form unique in the database: the
+// canonical form with confusable letters mapped to ASCII, so "Аdmin"
+// (Cyrillic A) and "Ädmin" collide with "Admin". It folds nothing a reader
+// can tell apart, so "Ana" and "Anna" or "Player6" and "Player9" stay
+// distinct. Names with the same canonical form always share a key.
+func LookAlikeKey(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	for _, r := range CanonicalName(name) {
+		if mapped, ok := confusables[r]; ok {
+			r = mapped
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Skeleton reduces a name much further, to the form the denylist and the
+// reserved names are matched against: the canonical form, with leetspeak and
+// confusables mapped to ASCII, separators removed and repeated letters
+// collapsed. "Adm1n", "A_D_M_I_N", "ＡＤＭＩＮ" and "Аdmin" (Cyrillic A) all
+// share the skeleton "admin". It is too lossy to make names unique.
+func Skeleton(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+
+	for _, r := range CanonicalName(name) {
+		if isSeparator(r) {
+			continue
+		}
+		if mapped, ok := leetspeak[r]; ok {
+			r = mapped
+		} else if mapped, ok := confusables[r]; ok {
+			r = mapped
+		}
+		// "l" and "i" are indistinguishable in many fonts
+		if r == 'l' {
+			r = 'i'
+		}
+		b.WriteRune(r)
+	}
+
+	// Pairs are folded before repeats collapse, or "vv" would never be seen
+	folded := multiRune.Replace(b.String())
+	b.Reset()
+	var last rune
+	for _, r := range folded {
+		if r != last {
+			b.WriteRune(r)
+			last = r
+		}
+	}
+	return b.String()
+}
+
+// isSeparator reports whether r is dropped when building a skeleton
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || r == '_' || r == '-' || r == '.'
+}
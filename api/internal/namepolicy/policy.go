@@ -0,0 +1,310 @@
+package namepolicy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind identifies which namespace a name belongs to
+type Kind string
+
+const (
+	KindUsername  Kind = "username"
+	KindCharacter Kind = "character"
+)
+
+// Violation reason codes
+const (
+	ReasonProfanity     = "profanity"
+	ReasonReserved      = "reserved"
+	ReasonImpersonation = "impersonation"
+	ReasonDuplicate     = "duplicate"
+)
+
+// Violation describes a single reason a name was rejected
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Match   string `json:"match,omitempty"`
+}
+
+// Lookup reports whether a name is already taken in the given namespace by
+// a name with the same LookAlikeKey. ownerID is the user performing the
+// check so that renaming to a variant of one's own name is not reported as
+// a duplicate.
+type Lookup func(ctx context.Context, kind Kind, name string, ownerID int) (bool, error)
+
+// Policy is the name-policy engine shared by registration, character
+// creation and rename
+type Policy struct {
+	mu       sync.RWMutex
+	denied   []string
+	reserved map[string]bool
+	titles   []string
+	allowed  map[string]bool
+	lookup   Lookup
+
+	// minLength is the shortest spelling of each short denied skeleton, so
+	// chat does not mask ordinary words that collapse to the same skeleton
+	minLength map[string]int
+}
+
+// New creates a policy from denylist and reserved-name terms. Terms are
+// normalized with Skeleton so leetspeak and confusable variants collide.
+func New(denied, reserved []string, lookup Lookup) *Policy {
+	p := &Policy{lookup: lookup}
+	p.set(denied, reserved)
+	return p
+}
+
+// LoadFromEnv builds a policy from the built-in lists, extended by the files
+// named in NAME_DENYLIST_PATH and NAME_RESERVED_PATH when set
+func LoadFromEnv(lookup Lookup) (*Policy, error) {
+	p := New(nil, nil, lookup)
+	if err := p.Reload(os.Getenv("NAME_DENYLIST_PATH"), os.Getenv("NAME_RESERVED_PATH")); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload replaces the denylist and reserved names with the built-in lists
+// plus the terms found in the given files. Empty paths are skipped.
+func (p *Policy) Reload(denylistPath, reservedPath string) error {
+	denied := append([]string{}, defaultDenylist...)
+	reserved := append([]string{}, defaultReserved...)
+
+	if denylistPath != "" {
+		terms, err := readTerms(denylistPath)
+		if err != nil {
+			return fmt.Errorf("failed to load name denylist: %w", err)
+		}
+		denied = append(denied, terms...)
+	}
+	if reservedPath != "" {
+		terms, err := readTerms(reservedPath)
+		if err != nil {
+			return fmt.Errorf("failed to load reserved names: %w", err)
+		}
+		reserved = append(reserved, terms...)
+	}
+
+	p.set(denied, reserved)
+	log.Printf("[NamePolicy] Loaded %d denied terms and %d reserved names", len(denied), len(reserved))
+	return nil
+}
+
+func (p *Policy) set(denied, reserved []string) {
+	deniedSkeletons := make([]string, 0, len(denied))
+	minLength := make(map[string]int)
+	for _, term := range denied {
+		s := Skeleton(term)
+		if s == "" {
+			continue
+		}
+		deniedSkeletons = append(deniedSkeletons, s)
+		n := utf8.RuneCountInString(term)
+		if current, ok := minLength[s]; !ok || n < current {
+			minLength[s] = n
+		}
+	}
+	reservedSkeletons := make(map[string]bool, len(reserved))
+	for _, name := range reserved {
+		if s := Skeleton(name); s != "" {
+			reservedSkeletons[s] = true
+		}
+	}
+	titles := make([]string, 0, len(staffTitles))
+	for _, title := range staffTitles {
+		titles = append(titles, Skeleton(title))
+	}
+	allowed := make(map[string]bool, len(allowedWords))
+	for _, word := range allowedWords {
+		allowed[Skeleton(word)] = true
+	}
+
+	p.mu.Lock()
+	p.denied = deniedSkeletons
+	p.reserved = reservedSkeletons
+	p.titles = titles
+	p.allowed = allowed
+	p.minLength = minLength
+	p.mu.Unlock()
+}
+
+// Check runs every rule against name and returns all violations found. An
+// empty result means the name is acceptable. Only the duplicate check can
+// fail with an error, since it needs the lookup.
+func (p *Policy) Check(ctx context.Context, kind Kind, name string, ownerID int) ([]Violation, error) {
+	violations := p.CheckStatic(name)
+
+	if p.lookup != nil {
+		taken, err := p.lookup(ctx, kind, name, ownerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s uniqueness: %w", kind, err)
+		}
+		if taken {
+			violations = append(violations, Violation{
+				Code:    ReasonDuplicate,
+				Message: fmt.Sprintf("This %s is already taken", kindLabel(kind)),
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+// CheckStatic runs the rules that do not need the lookup: profanity,
+// reserved names and staff impersonation
+func (p *Policy) CheckStatic(name string) []Violation {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var violations []Violation
+	skeleton := Skeleton(name)
+	words := p.words(name)
+
+	for _, term := range p.denied {
+		if matchesTerm(skeleton, words, term) {
+			violations = append(violations, Violation{
+				Code:    ReasonProfanity,
+				Message: "Name contains inappropriate language",
+			})
+			break
+		}
+	}
+
+	if p.reserved[skeleton] {
+		violations = append(violations, Violation{
+			Code:    ReasonReserved,
+			Message: "Name is reserved",
+			Match:   skeleton,
+		})
+	} else if title := p.staffTitle(skeleton, words); title != "" {
+		violations = append(violations, Violation{
+			Code:    ReasonImpersonation,
+			Message: "Name may not imitate staff or official accounts",
+			Match:   title,
+		})
+	}
+
+	return violations
+}
+
+// staffTitle returns the staff title the name imitates, if any
+func (p *Policy) staffTitle(skeleton string, words []string) string {
+	for _, title := range p.titles {
+		if matchesTerm(skeleton, words, title) {
+			return title
+		}
+	}
+	return ""
+}
+
+// matchesTerm reports whether a denylist term or staff title appears in a
+// name at a word boundary. The whole skeleton must equal the term, or one of
+// the words must equal, start with or end with it, so "A_D_M_I_N" and
+// "xXAdminXx" match "admin" but "Badminton" does not. Short terms must be a
+// whole word, and a term inside a word never matches ("Scunthorpe").
+func matchesTerm(skeleton string, words []string, term string) bool {
+	if skeleton == term {
+		return true
+	}
+	for _, word := range words {
+		if word == term {
+			return true
+		}
+		if len(term) >= 4 && (strings.HasPrefix(word, term) || strings.HasSuffix(word, term)) {
+			return true
+		}
+	}
+	return false
+}
+
+// words returns the skeletons of the words in a name, leaving out allowed
+// words. Words are split on separators and brackets, and on changes of case
+// ("TheRealGM" is "The", "Real" and "GM"). Runs of single letters are joined
+// back into one word, so spacing a term out does not hide it.
+func (p *Policy) words(name string) []string {
+	var words []string
+	var run strings.Builder
+	add := func(word string) {
+		if s := Skeleton(word); s != "" && !p.allowed[s] {
+			words = append(words, s)
+		}
+	}
+	flush := func() {
+		if run.Len() > 0 {
+			add(run.String())
+			run.Reset()
+		}
+	}
+
+	for _, field := range strings.FieldsFunc(name, func(r rune) bool {
+		return isSeparator(r) || r == '[' || r == ']' || r == '(' || r == ')'
+	}) {
+		for _, word := range splitCase(field) {
+			if utf8.RuneCountInString(word) == 1 {
+				run.WriteString(word)
+				continue
+			}
+			flush()
+			add(word)
+		}
+	}
+	flush()
+	return words
+}
+
+// splitCase splits a word where a lower case letter is followed by an upper
+// case one, and before the last capital of an upper case run that is
+// followed by lower case ("GMBob" is "GM" and "Bob")
+func splitCase(word string) []string {
+	runes := []rune(word)
+	var parts []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		if unicode.IsLower(runes[i-1]) ||
+			unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
+
+func kindLabel(kind Kind) string {
+	if kind == KindCharacter {
+		return "character name"
+	}
+	return string(kind)
+}
+
+// readTerms reads one term per line, skipping blanks and # comments
+func readTerms(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var terms []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		terms = append(terms, line)
+	}
+	return terms, scanner.Err()
+}
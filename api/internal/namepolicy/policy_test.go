@@ -0,0 +1,136 @@
+package namepolicy
+
+import "testing"
+
+func TestCheckStaticAllowsInnocentNames(t *testing.T) {
+	p := New(defaultDenylist, defaultReserved, nil)
+	for _, name := range []string{
+		"Badminton",
+		"Scunthorpe",
+		"Penistone",
+		"Staffordshire",
+		"Modest",
+		"Gemma",
+		"Moderna",
+		"Cassandra",
+		"Classic",
+		"Nazir",
+		"Shiitake",
+		"Supporter",
+		"Pussycat",
+		"Bad_Minton",
+		"Mike",
+	} {
+		if violations := p.CheckStatic(name); len(violations) > 0 {
+			t.Errorf("CheckStatic(%q) = %+v, want no violations", name, violations)
+		}
+	}
+}
+
+func TestCheckStaticRejects(t *testing.T) {
+	p := New(defaultDenylist, defaultReserved, nil)
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"Admin", ReasonReserved},
+		{"Adm1n", ReasonReserved},
+		{"A_D_M_I_N", ReasonReserved},
+		{"ＡＤＭＩＮ", ReasonReserved},
+		{"Аdmin", ReasonReserved}, // Cyrillic A
+		{"xXAdminXx", ReasonImpersonation},
+		{"TheRealGM", ReasonImpersonation},
+		{"GMBob", ReasonImpersonation},
+		{"Mod_Bob", ReasonImpersonation},
+		{"OfficialBob", ReasonImpersonation},
+		{"BobTheModerator", ReasonImpersonation},
+		{"FuckFace", ReasonProfanity},
+		{"Big_Ass", ReasonProfanity},
+		{"sh1thead", ReasonProfanity},
+	}
+	for _, tt := range tests {
+		violations := p.CheckStatic(tt.name)
+		found := false
+		for _, v := range violations {
+			found = found || v.Code == tt.code
+		}
+		if !found {
+			t.Errorf("CheckStatic(%q) = %+v, want a %s violation", tt.name, violations, tt.code)
+		}
+	}
+}
+
+func TestSkeletonLookAlikes(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"Admin", "Adm1n"},
+		{"mike", "rnike"},
+		{"Pavel", "Раvеl"}, // Cyrillic Р, а and е
+		{"Bob", "ＢＯＢ"},
+		{"Strasse", "Straße"},
+		{"Will", "VViII"},
+	}
+	for _, tt := range tests {
+		if a, b := Skeleton(tt.a), Skeleton(tt.b); a != b {
+			t.Errorf("Skeleton(%q) = %q, Skeleton(%q) = %q, want equal", tt.a, a, tt.b, b)
+		}
+	}
+	if Skeleton("Bob") == Skeleton("Rob") {
+		t.Errorf("Bob and Rob share a skeleton")
+	}
+}
+
+func TestLookAlikeKey(t *testing.T) {
+	same := []struct {
+		a, b string
+	}{
+		{"Admin", "Аdmin"}, // Cyrillic А
+		{"Pavel", "Раvеl"}, // Cyrillic Р, а and е
+		{"Zoe", "Zoë"},
+		{"Bob", "ＢＯＢ"},
+		{"Strasse", "Straße"},
+	}
+	for _, tt := range same {
+		if a, b := LookAlikeKey(tt.a), LookAlikeKey(tt.b); a != b {
+			t.Errorf("LookAlikeKey(%q) = %q, LookAlikeKey(%q) = %q, want equal", tt.a, a, tt.b, b)
+		}
+	}
+
+	// Names a reader can tell apart must stay available to different players
+	distinct := []struct {
+		a, b string
+	}{
+		{"Anna", "Ana"},
+		{"Sniper2", "Sniper22"},
+		{"Player6", "Player9"},
+		{"Admin", "Adm1n"},
+		{"mike", "rnike"},
+		{"Lia", "Iia"},
+		{"Dark Knight", "DarkKnight"},
+		{"Bob", "Rob"},
+	}
+	for _, tt := range distinct {
+		if LookAlikeKey(tt.a) == LookAlikeKey(tt.b) {
+			t.Errorf("%q and %q share the look-alike key %q", tt.a, tt.b, LookAlikeKey(tt.a))
+		}
+	}
+}
+
+func TestCensorText(t *testing.T) {
+	p := New(defaultDenylist, defaultReserved, nil)
+	tests := []struct {
+		text, want string
+	}{
+		{"greetings from Scunthorpe", "greetings from Scunthorpe"},
+		{"as if", "as if"},
+		{"you ass", "you ***"},
+		{"what the fuck", "what the ****"},
+		{"sh1tty  luck", "******  luck"},
+	}
+	for _, tt := range tests {
+		if got, _ := p.CensorText(tt.text); got != tt.want {
+			t.Errorf("CensorText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package namepolicy
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CensorText masks every word of a chat message that contains a denied term,
+// replacing it with asterisks of the same length. It reports whether anything
+// was masked. Whitespace is kept as sent.
+func (p *Policy) CensorText(text string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var b strings.Builder
+	b.Grow(len(text))
+
+	censored := false
+	start := -1
+	flush := func(end int) {
+		word := text[start:end]
+		if p.deniedWord(word) {
+			b.WriteString(strings.Repeat("*", utf8.RuneCountInString(word)))
+			censored = true
+		} else {
+			b.WriteString(word)
+		}
+		start = -1
+	}
+
+	for i, r := range text {
+		if !unicode.IsSpace(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			flush(i)
+		}
+		b.WriteRune(r)
+	}
+	if start >= 0 {
+		flush(len(text))
+	}
+
+	return b.String(), censored
+}
+
+// deniedWord reports whether one word of free text is profane. Unlike names,
+// a short term must also be spelled with at least as many letters as the
+// original, so "as" is not mistaken for "ass".
+func (p *Policy) deniedWord(word string) bool {
+	skeleton := Skeleton(word)
+	words := p.words(word)
+	for _, term := range p.denied {
+		if !matchesTerm(skeleton, words, term) {
+			continue
+		}
+		if len(term) >= 4 || utf8.RuneCountInString(word) >= p.minLength[term] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package namepolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Character name length limits, counted in runes after NFKC normalization.
+// Keep in sync with the character_name_length CHECK in database/schema.sql.
+const (
+	MinCharacterNameRunes = 3
+	MaxCharacterNameRunes = 50
+)
+
+// MaxCombiningMarks is how many combining marks may follow one letter. Thai
+// stacks a vowel and a tone mark; anything taller is Zalgo text.
+const MaxCombiningMarks = 2
+
+// allowedScripts are the scripts a character name may be written in
+var allowedScripts = []*unicode.RangeTable{
+	unicode.Latin,
+	unicode.Greek,
+	unicode.Cyrillic,
+	unicode.Han,
+	unicode.Hiragana,
+	unicode.Katakana,
+	unicode.Hangul,
+	unicode.Thai,
+}
+
+// alphabeticScripts contain look-alike letters, so a name may use at most one
+// of them. CJK and Thai may still be mixed with any one of these.
+var alphabeticScripts = []*unicode.RangeTable{
+	unicode.Latin,
+	unicode.Greek,
+	unicode.Cyrillic,
+}
+
+// extraNameRunes are Common-script characters that belong in names
+var extraNameRunes = map[rune]bool{
+	' ': true,
+	'_': true,
+	'-': true,
+	'ー': true, // KATAKANA-HIRAGANA PROLONGED SOUND MARK
+	'・': true, // KATAKANA MIDDLE DOT
+	'々': true, // IDEOGRAPHIC ITERATION MARK
+}
+
+var foldCase = cases.Fold()
+
+// NormalizeCharacterName returns the NFKC form of name with surrounding
+// whitespace removed. Names are stored in this form.
+func NormalizeCharacterName(name string) string {
+	return strings.TrimSpace(norm.NFKC.String(name))
+}
+
+// CanonicalName returns the case-folded NFKC form used for uniqueness, so
+// that "Ｂｏｂ", "BOB" and "bob" all collide
+func CanonicalName(name string) string {
+	return norm.NFKC.String(foldCase.String(NormalizeCharacterName(name)))
+}
+
+// ValidateCharacterName checks an already normalized name for length, the
+// allowed script set and script mixing
+func ValidateCharacterName(name string) error {
+	if !norm.NFKC.IsNormalString(name) || name != strings.TrimSpace(name) {
+		return errors.New("character name must be normalized before validation")
+	}
+
+	length := utf8.RuneCountInString(name)
+	if length < MinCharacterNameRunes {
+		return fmt.Errorf("character name must be at least %d characters long", MinCharacterNameRunes)
+	}
+	if length > MaxCharacterNameRunes {
+		return fmt.Errorf("character name must not exceed %d characters", MaxCharacterNameRunes)
+	}
+
+	var alphabet *unicode.RangeTable
+	var prev rune
+	marks := 0
+	for i, r := range name {
+		if !unicode.Is(unicode.Mn, r) {
+			marks = 0
+		}
+		switch {
+		case r >= '0' && r <= '9', extraNameRunes[r]:
+		case unicode.Is(unicode.Mn, r):
+			// Combining marks (Thai vowels, tone marks) must follow a letter
+			if i == 0 || !unicode.IsLetter(prev) && !unicode.Is(unicode.Mn, prev) {
+				return fmt.Errorf("character name contains a misplaced combining mark")
+			}
+			if marks++; marks > MaxCombiningMarks {
+				return fmt.Errorf("character name stacks more than %d combining marks on one letter", MaxCombiningMarks)
+			}
+		case unicode.IsLetter(r) && unicode.In(r, allowedScripts...):
+			for _, table := range alphabeticScripts {
+				if !unicode.Is(table, r) {
+					continue
+				}
+				if alphabet != nil && alphabet != table {
+					return fmt.Errorf("character name cannot mix Latin, Greek and Cyrillic letters")
+				}
+				alphabet = table
+			}
+		default:
+			return fmt.Errorf("character name contains invalid characters. Only letters, numbers, spaces, underscores, and hyphens are allowed")
+		}
+		prev = r
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package namepolicy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCharacterName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"Aria", true},
+		{"Dark-Knight_2", true},
+		{"Ελένη", true},
+		{"Дмитрий", true},
+		{"さくら", true},
+		{"สมชาย", true},
+		{"ที่รัก", true},     // a vowel and a tone mark on one letter
+		{"Aq\u0301ua", true}, // a lone mark NFKC cannot compose
+		{"ab", false},
+		{strings.Repeat("a", MaxCharacterNameRunes+1), false},
+		{"P\u0430ypal", false}, // Cyrillic а among Latin
+		{"\u0301Ari", false},
+		{"Ari \u0301", false},
+		{"Zal\u0336\u0337\u0338go", false},
+		{"Z" + strings.Repeat("\u0336", 30) + "algo", false},
+		{"Ari!", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateCharacterName(tt.name)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateCharacterName(%q) = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
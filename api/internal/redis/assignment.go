@@ -0,0 +1,280 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNoGameServers is returned when a region has no live instances
+var ErrNoGameServers = errors.New("no game servers in region")
+
+// ErrRegionFull is returned when no live instance can fit the requested players
+var ErrRegionFull = errors.New("no game server has enough free slots")
+
+// SlotReservation is a held slot on an instance that the player has not connected to yet
+type SlotReservation struct {
+	Instance  *GameServerInstance `json:"instance"`
+	UserIDs   []int               `json:"user_ids"`
+	ExpiresAt time.Time           `json:"expires_at"`
+}
+
+func reservationsKey(instanceID string) string {
+	return fmt.Sprintf("gameserver_reservations:%s", instanceID)
+}
+
+func userReservationKey(userID int) string {
+	return fmt.Sprintf("reservation:user:%d", userID)
+}
+
+// claimedMember is the reservation-set member that holds a claimed slot until
+// the instance's heartbeats count the player
+func claimedMember(userID int) string {
+	return fmt.Sprintf("claimed:%d", userID)
+}
+
+// reserveSlotsScript reserves a slot for every member on one instance, or for
+// none of them. Reservations are sorted-set members scored by their expiry
+// time, so expired ones are dropped before the capacity check.
+//
+// KEYS[1] = gameserver:<id>, KEYS[2] = gameserver_reservations:<id>
+// ARGV[1] = now (ms), ARGV[2] = expiry (ms), ARGV[3] = key TTL (ms), ARGV[4..] = user IDs
+// Returns 1 on success, 0 if the instance is full, -1 if it is gone.
+var reserveSlotsScript = redis.NewScript(`
+local instance = redis.call('GET', KEYS[1])
+if not instance then
+	return -1
+end
+local data = cjson.decode(instance)
+
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+
+local added = 0
+for i = 4, #ARGV do
+	if not redis.call('ZSCORE', KEYS[2], ARGV[i]) then
+		added = added + 1
+	end
+end
+
+local reserved = redis.call('ZCARD', KEYS[2])
+if data.player_count + reserved + added > data.capacity then
+	return 0
+end
+
+for i = 4, #ARGV do
+	redis.call('ZADD', KEYS[2], ARGV[2], ARGV[i])
+end
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+return 1
+`)
+
+// claimReservationScript turns a user's unexpired reservation into a claimed
+// slot, so the player stays counted between connecting and the next
+// heartbeat that includes them.
+//
+// KEYS[1] = gameserver_reservations:<id>
+// ARGV[1] = now (ms), ARGV[2] = user ID, ARGV[3] = claimed member,
+// ARGV[4] = claim expiry (ms), ARGV[5] = claim hold (ms)
+// Returns 1 if the user held a reservation, 0 otherwise.
+var claimReservationScript = redis.NewScript(`
+local expiry = redis.call('ZSCORE', KEYS[1], ARGV[2])
+if not expiry then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[2])
+if tonumber(expiry) <= tonumber(ARGV[1]) then
+	return 0
+end
+
+redis.call('ZADD', KEYS[1], ARGV[4], ARGV[3])
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[5]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[5])
+end
+return 1
+`)
+
+// releaseClaimsScript drops claimed slots whose expiry is at or before a
+// cutoff, leaving pending reservations alone
+//
+// KEYS[1] = gameserver_reservations:<id>, ARGV[1] = cutoff (ms)
+// Returns the number of claims dropped.
+var releaseClaimsScript = redis.NewScript(`
+local dropped = 0
+for _, member in ipairs(redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])) do
+	if string.sub(member, 1, 8) == 'claimed:' then
+		dropped = dropped + redis.call('ZREM', KEYS[1], member)
+	end
+end
+return dropped
+`)
+
+// countReservations returns the number of unexpired reservations per instance
+func (c *Client) countReservations(ctx context.Context, instances []*GameServerInstance) (map[string]int, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	pipe := c.Pipeline()
+	cmds := make([]*redis.IntCmd, len(instances))
+	for i, instance := range instances {
+		cmds[i] = pipe.ZCount(ctx, reservationsKey(instance.InstanceID), "("+now, "+inf")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to count reservations: %w", err)
+	}
+
+	counts := make(map[string]int, len(instances))
+	for i, instance := range instances {
+		counts[instance.InstanceID] = int(cmds[i].Val())
+	}
+	return counts, nil
+}
+
+// ReserveSlots picks an instance in the region for the given users and
+// atomically reserves a slot for each of them on it. All users land on the
+// same instance or none are reserved.
+//
+// Instances are tried most-populated first so that players fill existing
+// arenas before new ones are started. A reservation lapses after ttl unless
+// the game server claims it when the player connects.
+func (c *Client) ReserveSlots(ctx context.Context, region string, userIDs []int, ttl time.Duration) (*SlotReservation, error) {
+	if len(userIDs) == 0 {
+		return nil, errors.New("no users to reserve slots for")
+	}
+
+	instances, err := c.ListGameServers(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, ErrNoGameServers
+	}
+
+	// Drop any reservation the users already hold elsewhere
+	for _, userID := range userIDs {
+		if err := c.ReleaseReservation(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	reserved, err := c.countReservations(ctx, instances)
+	if err != nil {
+		return nil, err
+	}
+
+	load := func(instance *GameServerInstance) int {
+		return instance.PlayerCount + reserved[instance.InstanceID]
+	}
+	sort.SliceStable(instances, func(i, j int) bool {
+		return load(instances[i]) > load(instances[j])
+	})
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	args := []any{now.UnixMilli(), expiresAt.UnixMilli(), ttl.Milliseconds()}
+	for _, userID := range userIDs {
+		args = append(args, userID)
+	}
+
+	for _, instance := range instances {
+		if instance.Capacity-load(instance) < len(userIDs) {
+			continue
+		}
+
+		keys := []string{gameServerKey(instance.InstanceID), reservationsKey(instance.InstanceID)}
+		result, err := reserveSlotsScript.Run(ctx, c, keys, args...).Int()
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve slots on %s: %w", instance.InstanceID, err)
+		}
+		if result != 1 {
+			// Filled up or expired since it was listed, try the next one
+			continue
+		}
+
+		pipe := c.Pipeline()
+		for _, userID := range userIDs {
+			pipe.Set(ctx, userReservationKey(userID), instance.InstanceID, ttl)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to index reservations: %w", err)
+		}
+
+		return &SlotReservation{
+			Instance:  instance,
+			UserIDs:   userIDs,
+			ExpiresAt: expiresAt.UTC(),
+		}, nil
+	}
+
+	return nil, ErrRegionFull
+}
+
+// ClaimReservation consumes a user's reservation once they have connected to
+// the instance. It reports whether the user held an unexpired reservation
+// there.
+//
+// The slot stays counted as claimed for up to hold, until ReleaseClaims sees
+// a heartbeat that includes the player; dropping it at once would let the
+// instance be overbooked in between.
+func (c *Client) ClaimReservation(ctx context.Context, instanceID string, userID int, hold time.Duration) (bool, error) {
+	now := time.Now()
+	args := []any{now.UnixMilli(), userID, claimedMember(userID), now.Add(hold).UnixMilli(), hold.Milliseconds()}
+	claimed, err := claimReservationScript.Run(ctx, c, []string{reservationsKey(instanceID)}, args...).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reservation: %w", err)
+	}
+
+	// Only clear the index if it still points at this instance
+	held, err := c.Get(ctx, userReservationKey(userID)).Result()
+	if err == nil && held == instanceID {
+		c.Del(ctx, userReservationKey(userID))
+	}
+
+	return claimed == 1, nil
+}
+
+// ReleaseClaims stops counting slots claimed on an instance before
+// claimedBefore, once a heartbeat reports a player count taken after them.
+// hold must be the value the claims were made with.
+func (c *Client) ReleaseClaims(ctx context.Context, instanceID string, claimedBefore time.Time, hold time.Duration) error {
+	cutoff := claimedBefore.Add(hold).UnixMilli()
+	if err := releaseClaimsScript.Run(ctx, c, []string{reservationsKey(instanceID)}, cutoff).Err(); err != nil {
+		return fmt.Errorf("failed to release claimed slots: %w", err)
+	}
+	return nil
+}
+
+// GetUserReservation returns the instance a user holds a pending
+// reservation on, or "" if they hold none
+func (c *Client) GetUserReservation(ctx context.Context, userID int) (string, error) {
+	instanceID, err := c.Get(ctx, userReservationKey(userID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get reservation: %w", err)
+	}
+	return instanceID, nil
+}
+
+// ReleaseReservation drops a user's pending reservation, if any
+func (c *Client) ReleaseReservation(ctx context.Context, userID int) error {
+	instanceID, err := c.Get(ctx, userReservationKey(userID)).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get reservation: %w", err)
+	}
+
+	pipe := c.TxPipeline()
+	pipe.ZRem(ctx, reservationsKey(instanceID), strconv.Itoa(userID))
+	pipe.Del(ctx, userReservationKey(userID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to release reservation: %w", err)
+	}
+	return nil
+}
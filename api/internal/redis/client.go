@@ -88,13 +88,26 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+// getEnvAsPositiveInt is getEnvAsInt for sizes and limits, where zero or
+// less would refuse everything
+func getEnvAsPositiveInt(key string, defaultValue int) int {
+	value := getEnvAsInt(key, defaultValue)
+	if value <= 0 {
+		log.Printf("[Redis] Non-positive value for %s: %d, using default: %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
 		return defaultValue
 	}
+	// Every Redis duration is a timeout, TTL or interval: a key with a TTL of
+	// zero or less never expires or expires at once
 	value, err := time.ParseDuration(valueStr)
-	if err != nil {
+	if err != nil || value <= 0 {
 		log.Printf("[Redis] Invalid duration value for %s: %s, using default: %s", key, valueStr, defaultValue)
 		return defaultValue
 	}
@@ -0,0 +1,232 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TickMetrics are the performance numbers a game server reports with each
+// heartbeat (mirrors ServerMain.metrics in the Godot server)
+type TickMetrics struct {
+	TickCount     int64   `json:"tick_count"`
+	TickRate      int     `json:"tick_rate"`
+	AvgTickTimeMs float64 `json:"avg_tick_time_ms"`
+	MaxTickTimeMs float64 `json:"max_tick_time_ms"`
+	EntityCount   int     `json:"entity_count"`
+}
+
+// GameServerInstance is a registered headless Godot server. Address is where
+// clients connect; behind a gateway it is the gateway's, and InternalAddress
+// is where the gateway reaches the instance.
+type GameServerInstance struct {
+	InstanceID      string      `json:"instance_id"`
+	Region          string      `json:"region"`
+	Address         string      `json:"address"`
+	InternalAddress string      `json:"internal_address,omitempty"`
+	Capacity        int         `json:"capacity"`
+	PlayerCount     int         `json:"player_count"`
+	Metrics         TickMetrics `json:"metrics"`
+	RegisteredAt    time.Time   `json:"registered_at"`
+	LastHeartbeat   time.Time   `json:"last_heartbeat"`
+}
+
+// DialAddress returns the address a gateway forwards players to
+func (g *GameServerInstance) DialAddress() string {
+	if g.InternalAddress != "" {
+		return g.InternalAddress
+	}
+	return g.Address
+}
+
+// FreeSlots returns how many more players the instance can take
+func (g *GameServerInstance) FreeSlots() int {
+	if free := g.Capacity - g.PlayerCount; free > 0 {
+		return free
+	}
+	return 0
+}
+
+// GameServerConfig holds game-server registry configuration
+type GameServerConfig struct {
+	APIKey             string
+	TTL                time.Duration
+	HeartbeatInterval  time.Duration
+	SlotReservationTTL time.Duration
+}
+
+// LoadGameServerConfigFromEnv loads game-server registry configuration from
+// environment variables
+func LoadGameServerConfigFromEnv() *GameServerConfig {
+	return &GameServerConfig{
+		APIKey:             getEnv("GAME_SERVER_API_KEY", ""),
+		TTL:                getEnvAsDuration("GAME_SERVER_TTL", 15*time.Second),
+		HeartbeatInterval:  getEnvAsDuration("GAME_SERVER_HEARTBEAT_INTERVAL", 5*time.Second),
+		SlotReservationTTL: getEnvAsDuration("SLOT_RESERVATION_TTL", 30*time.Second),
+	}
+}
+
+// ErrGameServerNotFound is returned when an instance has expired or was never registered
+var ErrGameServerNotFound = errors.New("game server not registered")
+
+func gameServerKey(instanceID string) string {
+	return fmt.Sprintf("gameserver:%s", instanceID)
+}
+
+func gameServerRegionKey(region string) string {
+	return fmt.Sprintf("gameservers:%s", region)
+}
+
+// RegisterGameServer stores an instance with a TTL. The instance disappears
+// unless it is refreshed by SetGameServerHeartbeat before the TTL runs out.
+func (c *Client) RegisterGameServer(ctx context.Context, instance *GameServerInstance, ttl time.Duration) error {
+	// An instance that re-registers in a new region must leave the old index
+	previous, err := c.GetGameServer(ctx, instance.InstanceID)
+	if err == nil && previous.Region != instance.Region {
+		c.SRem(ctx, gameServerRegionKey(previous.Region), instance.InstanceID)
+	}
+
+	instanceJSON, err := json.Marshal(instance)
+	if err != nil {
+		return fmt.Errorf("failed to marshal game server: %w", err)
+	}
+
+	pipe := c.TxPipeline()
+	pipe.Set(ctx, gameServerKey(instance.InstanceID), instanceJSON, ttl)
+	pipe.SAdd(ctx, gameServerRegionKey(instance.Region), instance.InstanceID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to register game server: %w", err)
+	}
+
+	return nil
+}
+
+// GetGameServer returns a live instance
+func (c *Client) GetGameServer(ctx context.Context, instanceID string) (*GameServerInstance, error) {
+	instanceJSON, err := c.Get(ctx, gameServerKey(instanceID)).Result()
+	if err == redis.Nil {
+		return nil, ErrGameServerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game server: %w", err)
+	}
+
+	var instance GameServerInstance
+	if err := json.Unmarshal([]byte(instanceJSON), &instance); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal game server: %w", err)
+	}
+	return &instance, nil
+}
+
+// SetGameServerHeartbeat refreshes an instance's player count, metrics and TTL
+func (c *Client) SetGameServerHeartbeat(ctx context.Context, instanceID string, playerCount int, metrics TickMetrics, ttl time.Duration) (*GameServerInstance, error) {
+	instance, err := c.GetGameServer(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	instance.PlayerCount = playerCount
+	instance.Metrics = metrics
+	instance.LastHeartbeat = time.Now().UTC()
+
+	if err := c.RegisterGameServer(ctx, instance, ttl); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// DeregisterGameServer removes an instance immediately (clean shutdown)
+func (c *Client) DeregisterGameServer(ctx context.Context, instanceID string) error {
+	instance, err := c.GetGameServer(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	pipe := c.TxPipeline()
+	pipe.Del(ctx, gameServerKey(instanceID))
+	pipe.SRem(ctx, gameServerRegionKey(instance.Region), instanceID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to deregister game server: %w", err)
+	}
+	return nil
+}
+
+// ListGameServers returns the live instances in a region. Index entries whose
+// instance key has expired are pruned as a side effect.
+func (c *Client) ListGameServers(ctx context.Context, region string) ([]*GameServerInstance, error) {
+	regionKey := gameServerRegionKey(region)
+
+	instanceIDs, err := c.SMembers(ctx, regionKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game servers for region %s: %w", region, err)
+	}
+	if len(instanceIDs) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(instanceIDs))
+	for i, id := range instanceIDs {
+		keys[i] = gameServerKey(id)
+	}
+
+	values, err := c.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load game servers for region %s: %w", region, err)
+	}
+
+	instances := make([]*GameServerInstance, 0, len(values))
+	var stale []any
+	for i, value := range values {
+		instanceJSON, ok := value.(string)
+		if !ok {
+			stale = append(stale, instanceIDs[i])
+			continue
+		}
+		var instance GameServerInstance
+		if err := json.Unmarshal([]byte(instanceJSON), &instance); err != nil {
+			stale = append(stale, instanceIDs[i])
+			continue
+		}
+		instances = append(instances, &instance)
+	}
+
+	if len(stale) > 0 {
+		c.SRem(ctx, regionKey, stale...)
+	}
+
+	return instances, nil
+}
+
+// RegionCapacity is the live capacity of a region summed over its instances
+type RegionCapacity struct {
+	Instances   int
+	PlayerCount int64
+	Reserved    int64
+	Capacity    int
+}
+
+// GetRegionCapacity aggregates player counts, pending and claimed slots and
+// capacity over a region's live instances
+func (c *Client) GetRegionCapacity(ctx context.Context, region string) (*RegionCapacity, error) {
+	instances, err := c.ListGameServers(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	reserved, err := c.countReservations(ctx, instances)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := &RegionCapacity{Instances: len(instances)}
+	for _, instance := range instances {
+		capacity.PlayerCount += int64(instance.PlayerCount)
+		capacity.Reserved += int64(reserved[instance.InstanceID])
+		capacity.Capacity += instance.Capacity
+	}
+	return capacity, nil
+}
@@ -0,0 +1,280 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// partyTTL bounds how long an abandoned party lingers. Every change refreshes it.
+const partyTTL = 24 * time.Hour
+
+// PartyConfig holds party configuration
+type PartyConfig struct {
+	MaxSize   int
+	InviteTTL time.Duration
+}
+
+// LoadPartyConfigFromEnv loads party configuration from environment variables
+func LoadPartyConfigFromEnv() *PartyConfig {
+	return &PartyConfig{
+		MaxSize:   getEnvAsPositiveInt("PARTY_MAX_SIZE", 4),
+		InviteTTL: getEnvAsDuration("PARTY_INVITE_TTL", 5*time.Minute),
+	}
+}
+
+// maxWatchRetries is how often an optimistic party update is retried on conflict
+const maxWatchRetries = 10
+
+var (
+	// ErrPartyNotFound is returned when a party has disbanded or never existed
+	ErrPartyNotFound = errors.New("party not found")
+	// ErrNotInParty is returned when a user is not a member of any party
+	ErrNotInParty = errors.New("user is not in a party")
+	// ErrAlreadyInParty is returned when a user tries to create or join a second party
+	ErrAlreadyInParty = errors.New("user is already in a party")
+	// ErrPartyFull is returned when a party has reached its max size
+	ErrPartyFull = errors.New("party is full")
+)
+
+// Party is a group of players who join the same game server together.
+// Members are kept in join order; the leader is always a member.
+type Party struct {
+	ID         string           `json:"id"`
+	LeaderID   int              `json:"leader_id"`
+	Members    []int            `json:"members"`
+	MaxSize    int              `json:"max_size"`
+	Assignment *SlotReservation `json:"assignment,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+// HasMember reports whether the user belongs to the party
+func (p *Party) HasMember(userID int) bool {
+	return slices.Contains(p.Members, userID)
+}
+
+func partyKey(partyID string) string {
+	return fmt.Sprintf("party:%s", partyID)
+}
+
+func partyUserKey(userID int) string {
+	return fmt.Sprintf("party_user:%d", userID)
+}
+
+// watchParty runs fn in an optimistic transaction over keys, retrying when
+// another client changed them first
+func (c *Client) watchParty(ctx context.Context, fn func(tx *redis.Tx) error, keys ...string) error {
+	for i := 0; i < maxWatchRetries; i++ {
+		err := c.Watch(ctx, fn, keys...)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return errors.New("party update conflicted too many times")
+}
+
+func readParty(ctx context.Context, cmd redis.Cmdable, partyID string) (*Party, error) {
+	partyJSON, err := cmd.Get(ctx, partyKey(partyID)).Result()
+	if err == redis.Nil {
+		return nil, ErrPartyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get party: %w", err)
+	}
+
+	var party Party
+	if err := json.Unmarshal([]byte(partyJSON), &party); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal party: %w", err)
+	}
+	return &party, nil
+}
+
+// writeParty queues the party and its members' index keys on pipe
+func writeParty(ctx context.Context, pipe redis.Pipeliner, party *Party) error {
+	partyJSON, err := json.Marshal(party)
+	if err != nil {
+		return fmt.Errorf("failed to marshal party: %w", err)
+	}
+
+	pipe.Set(ctx, partyKey(party.ID), partyJSON, partyTTL)
+	for _, member := range party.Members {
+		pipe.Set(ctx, partyUserKey(member), party.ID, partyTTL)
+	}
+	return nil
+}
+
+// CreateParty creates a party led by leaderID
+func (c *Client) CreateParty(ctx context.Context, partyID string, leaderID, maxSize int) (*Party, error) {
+	party := &Party{
+		ID:        partyID,
+		LeaderID:  leaderID,
+		Members:   []int{leaderID},
+		MaxSize:   maxSize,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err := c.watchParty(ctx, func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, partyUserKey(leaderID)).Result()
+		if err != nil {
+			return fmt.Errorf("failed to check party membership: %w", err)
+		}
+		if exists > 0 {
+			return ErrAlreadyInParty
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return writeParty(ctx, pipe, party)
+		})
+		return err
+	}, partyUserKey(leaderID))
+	if err != nil {
+		return nil, err
+	}
+	return party, nil
+}
+
+// GetParty returns a party by ID
+func (c *Client) GetParty(ctx context.Context, partyID string) (*Party, error) {
+	return readParty(ctx, c, partyID)
+}
+
+// GetUserParty returns the party the user belongs to
+func (c *Client) GetUserParty(ctx context.Context, userID int) (*Party, error) {
+	partyID, err := c.Get(ctx, partyUserKey(userID)).Result()
+	if err == redis.Nil {
+		return nil, ErrNotInParty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get party membership: %w", err)
+	}
+
+	party, err := readParty(ctx, c, partyID)
+	if err == ErrPartyNotFound {
+		return nil, ErrNotInParty
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !party.HasMember(userID) {
+		return nil, ErrNotInParty
+	}
+	return party, nil
+}
+
+// JoinParty adds a user to a party if it has room
+func (c *Client) JoinParty(ctx context.Context, partyID string, userID int) (*Party, error) {
+	var party *Party
+	err := c.watchParty(ctx, func(tx *redis.Tx) error {
+		var err error
+		party, err = readParty(ctx, tx, partyID)
+		if err != nil {
+			return err
+		}
+
+		exists, err := tx.Exists(ctx, partyUserKey(userID)).Result()
+		if err != nil {
+			return fmt.Errorf("failed to check party membership: %w", err)
+		}
+		if exists > 0 || party.HasMember(userID) {
+			return ErrAlreadyInParty
+		}
+		if len(party.Members) >= party.MaxSize {
+			return ErrPartyFull
+		}
+
+		party.Members = append(party.Members, userID)
+		// A placement made for the old roster no longer covers everyone
+		party.Assignment = nil
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return writeParty(ctx, pipe, party)
+		})
+		return err
+	}, partyKey(partyID), partyUserKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	return party, nil
+}
+
+// RemovePartyMember removes a user from a party. If the leader leaves, the
+// longest-standing member takes over. It returns nil once the last member
+// has left and the party is disbanded.
+func (c *Client) RemovePartyMember(ctx context.Context, partyID string, userID int) (*Party, error) {
+	var party *Party
+	err := c.watchParty(ctx, func(tx *redis.Tx) error {
+		var err error
+		party, err = readParty(ctx, tx, partyID)
+		if err != nil {
+			return err
+		}
+		if !party.HasMember(userID) {
+			return ErrNotInParty
+		}
+
+		party.Members = slices.DeleteFunc(party.Members, func(member int) bool { return member == userID })
+		party.Assignment = nil
+		if party.LeaderID == userID && len(party.Members) > 0 {
+			party.LeaderID = party.Members[0]
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, partyUserKey(userID))
+			if len(party.Members) == 0 {
+				pipe.Del(ctx, partyKey(partyID))
+				return nil
+			}
+			return writeParty(ctx, pipe, party)
+		})
+		return err
+	}, partyKey(partyID))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(party.Members) == 0 {
+		return nil, nil
+	}
+	return party, nil
+}
+
+// LeaveParty removes a user from whatever party they are in
+func (c *Client) LeaveParty(ctx context.Context, userID int) (*Party, error) {
+	partyID, err := c.Get(ctx, partyUserKey(userID)).Result()
+	if err == redis.Nil {
+		return nil, ErrNotInParty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get party membership: %w", err)
+	}
+
+	party, err := c.RemovePartyMember(ctx, partyID, userID)
+	if err == ErrPartyNotFound {
+		// Stale index entry left behind by an expired party
+		c.Del(ctx, partyUserKey(userID))
+		return nil, ErrNotInParty
+	}
+	return party, err
+}
+
+// SetPartyAssignment records the game server slots reserved for the party so
+// every member can pick up the same connection details
+func (c *Client) SetPartyAssignment(ctx context.Context, partyID string, reservation *SlotReservation) error {
+	return c.watchParty(ctx, func(tx *redis.Tx) error {
+		party, err := readParty(ctx, tx, partyID)
+		if err != nil {
+			return err
+		}
+		party.Assignment = reservation
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return writeParty(ctx, pipe, party)
+		})
+		return err
+	}, partyKey(partyID))
+}
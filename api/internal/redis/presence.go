@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Presence statuses
+const (
+	PresenceOffline = "offline"
+	PresenceOnline  = "online"
+	PresenceInGame  = "in_game"
+)
+
+// Presence is what other players can see about a user's current session
+type Presence struct {
+	UserID     int        `json:"user_id"`
+	Status     string     `json:"status"`
+	Region     string     `json:"region,omitempty"`
+	InstanceID string     `json:"instance_id,omitempty"`
+	Since      *time.Time `json:"since,omitempty"`
+}
+
+// presenceFromSession derives presence from a session; a nil session is offline
+func presenceFromSession(userID int, session *SessionData) *Presence {
+	if session == nil {
+		return &Presence{UserID: userID, Status: PresenceOffline}
+	}
+
+	presence := &Presence{UserID: userID, Status: PresenceOnline, Since: &session.CreatedAt}
+	if session.ServerRegion != "" {
+		presence.Status = PresenceInGame
+		presence.Region = session.ServerRegion
+		presence.InstanceID = session.InstanceID
+	}
+	return presence
+}
+
+// GetPresence returns the presence of each user, keyed by user ID. Users
+// without a live session are offline.
+func (c *Client) GetPresence(ctx context.Context, userIDs []int) (map[int]*Presence, error) {
+	presence := make(map[int]*Presence, len(userIDs))
+	if len(userIDs) == 0 {
+		return presence, nil
+	}
+
+	indexKeys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		indexKeys[i] = userSessionKey(userID)
+	}
+	tokens, err := c.MGet(ctx, indexKeys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user sessions: %w", err)
+	}
+
+	var sessionKeys []string
+	var sessionUsers []int
+	for i, token := range tokens {
+		if token, ok := token.(string); ok {
+			sessionKeys = append(sessionKeys, fmt.Sprintf("session:%s", token))
+			sessionUsers = append(sessionUsers, userIDs[i])
+		} else {
+			presence[userIDs[i]] = presenceFromSession(userIDs[i], nil)
+		}
+	}
+	if len(sessionKeys) == 0 {
+		return presence, nil
+	}
+
+	sessions, err := c.MGet(ctx, sessionKeys...).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	for i, sessionJSON := range sessions {
+		userID := sessionUsers[i]
+		var session *SessionData
+		if sessionJSON, ok := sessionJSON.(string); ok {
+			var data SessionData
+			if err := json.Unmarshal([]byte(sessionJSON), &data); err == nil {
+				session = &data
+			}
+		}
+		presence[userID] = presenceFromSession(userID, session)
+	}
+	return presence, nil
+}
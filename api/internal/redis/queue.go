@@ -0,0 +1,274 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// promotionRateWindow is how far back promotions are counted when estimating waits
+const promotionRateWindow = 5 * time.Minute
+
+// ErrNotQueued is returned when a user is neither queued nor promoted
+var ErrNotQueued = errors.New("user is not in a queue")
+
+// QueueEntry describes a user's place in a region's waiting queue
+type QueueEntry struct {
+	Region   string
+	Position int64
+	Length   int64
+	// EstimatedWait is nil until the region has promoted anyone recently
+	EstimatedWait *time.Duration
+}
+
+func queueKey(region string) string {
+	return fmt.Sprintf("queue:%s", region)
+}
+
+func queueSeqKey(region string) string {
+	return fmt.Sprintf("queue_seq:%s", region)
+}
+
+func queueHeartbeatKey(region string) string {
+	return fmt.Sprintf("queue_heartbeat:%s", region)
+}
+
+func queuePromotionsKey(region string) string {
+	return fmt.Sprintf("queue_promotions:%s", region)
+}
+
+func queueUserKey(userID int) string {
+	return fmt.Sprintf("queue_user:%d", userID)
+}
+
+func queuePromotedKey(userID int) string {
+	return fmt.Sprintf("queue_promoted:%d", userID)
+}
+
+// JoinQueue appends a user to the back of a region's queue. A user already in
+// that queue keeps their place; a user queued elsewhere is moved.
+func (c *Client) JoinQueue(ctx context.Context, region string, userID int) (*QueueEntry, error) {
+	current, err := c.Get(ctx, queueUserKey(userID)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get queue membership: %w", err)
+	}
+	if current != "" && current != region {
+		if err := c.LeaveQueue(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if current != region {
+		// Scores come from a per-region counter so arrival order is strict FIFO
+		seq, err := c.Incr(ctx, queueSeqKey(region)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate queue position: %w", err)
+		}
+
+		pipe := c.TxPipeline()
+		pipe.ZAddNX(ctx, queueKey(region), redis.Z{Score: float64(seq), Member: userID})
+		pipe.ZAdd(ctx, queueHeartbeatKey(region), redis.Z{Score: float64(time.Now().UnixMilli()), Member: userID})
+		pipe.Set(ctx, queueUserKey(userID), region, 0)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to join queue: %w", err)
+		}
+	}
+
+	return c.GetQueueEntry(ctx, userID)
+}
+
+// GetQueueEntry returns a user's current position and estimated wait
+func (c *Client) GetQueueEntry(ctx context.Context, userID int) (*QueueEntry, error) {
+	region, err := c.Get(ctx, queueUserKey(userID)).Result()
+	if err == redis.Nil {
+		return nil, ErrNotQueued
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue membership: %w", err)
+	}
+
+	pipe := c.Pipeline()
+	rankCmd := pipe.ZRank(ctx, queueKey(region), strconv.Itoa(userID))
+	lengthCmd := pipe.ZCard(ctx, queueKey(region))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get queue position: %w", err)
+	}
+
+	rank, err := rankCmd.Result()
+	if err == redis.Nil {
+		// Dropped or promoted while the membership key was being read
+		return nil, ErrNotQueued
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue position: %w", err)
+	}
+
+	entry := &QueueEntry{
+		Region:   region,
+		Position: rank + 1,
+		Length:   lengthCmd.Val(),
+	}
+
+	rate, err := c.promotionRate(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	if rate > 0 {
+		wait := time.Duration(float64(entry.Position) / rate * float64(time.Second))
+		entry.EstimatedWait = &wait
+	}
+
+	return entry, nil
+}
+
+// promotionRate returns promotions per second over the recent window
+func (c *Client) promotionRate(ctx context.Context, region string) (float64, error) {
+	since := time.Now().Add(-promotionRateWindow).UnixMilli()
+	key := queuePromotionsKey(region)
+
+	c.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(since, 10))
+	count, err := c.ZCard(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get promotion rate: %w", err)
+	}
+	return float64(count) / promotionRateWindow.Seconds(), nil
+}
+
+// TouchQueue records a heartbeat for a queued user
+func (c *Client) TouchQueue(ctx context.Context, region string, userID int) error {
+	err := c.ZAddXX(ctx, queueHeartbeatKey(region), redis.Z{Score: float64(time.Now().UnixMilli()), Member: userID}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to record queue heartbeat: %w", err)
+	}
+	return nil
+}
+
+// LeaveQueue removes a user from whichever queue they are in
+func (c *Client) LeaveQueue(ctx context.Context, userID int) error {
+	region, err := c.Get(ctx, queueUserKey(userID)).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get queue membership: %w", err)
+	}
+
+	pipe := c.TxPipeline()
+	pipe.ZRem(ctx, queueKey(region), userID)
+	pipe.ZRem(ctx, queueHeartbeatKey(region), userID)
+	pipe.Del(ctx, queueUserKey(userID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to leave queue: %w", err)
+	}
+	return nil
+}
+
+// QueueLength returns how many users are waiting in a region
+func (c *Client) QueueLength(ctx context.Context, region string) (int64, error) {
+	length, err := c.ZCard(ctx, queueKey(region)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get queue length: %w", err)
+	}
+	return length, nil
+}
+
+// QueueHead returns the user at the front of a region's queue, or 0 if it is empty
+func (c *Client) QueueHead(ctx context.Context, region string) (int, error) {
+	members, err := c.ZRange(ctx, queueKey(region), 0, 0).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get queue head: %w", err)
+	}
+	if len(members) == 0 {
+		return 0, nil
+	}
+	return strconv.Atoi(members[0])
+}
+
+// DropStaleQueued removes users whose last heartbeat is older than timeout
+// and returns their IDs
+func (c *Client) DropStaleQueued(ctx context.Context, region string, timeout time.Duration) ([]int, error) {
+	cutoff := strconv.FormatInt(time.Now().Add(-timeout).UnixMilli(), 10)
+	members, err := c.ZRangeByScore(ctx, queueHeartbeatKey(region), &redis.ZRangeBy{Min: "-inf", Max: "(" + cutoff}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stale queue entries: %w", err)
+	}
+
+	dropped := make([]int, 0, len(members))
+	for _, member := range members {
+		userID, err := strconv.Atoi(member)
+		if err != nil {
+			continue
+		}
+		pipe := c.TxPipeline()
+		pipe.ZRem(ctx, queueKey(region), member)
+		pipe.ZRem(ctx, queueHeartbeatKey(region), member)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return dropped, fmt.Errorf("failed to drop stale queue entry: %w", err)
+		}
+		// Only clear the membership if it still points at this region
+		if current, _ := c.Get(ctx, queueUserKey(userID)).Result(); current == region {
+			c.Del(ctx, queueUserKey(userID))
+		}
+		dropped = append(dropped, userID)
+	}
+	return dropped, nil
+}
+
+// PromoteQueued removes a user from the queue and stores the reservation made
+// for them until they pick it up or it expires. It reports false if the user
+// left the queue in the meantime.
+func (c *Client) PromoteQueued(ctx context.Context, region string, userID int, reservation *SlotReservation, ttl time.Duration) (bool, error) {
+	removed, err := c.ZRem(ctx, queueKey(region), userID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to dequeue user: %w", err)
+	}
+	if removed == 0 {
+		return false, nil
+	}
+
+	reservationJSON, err := json.Marshal(reservation)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal reservation: %w", err)
+	}
+
+	now := time.Now().UnixMilli()
+	pipe := c.TxPipeline()
+	pipe.ZRem(ctx, queueHeartbeatKey(region), userID)
+	pipe.Del(ctx, queueUserKey(userID))
+	pipe.Set(ctx, queuePromotedKey(userID), reservationJSON, ttl)
+	pipe.ZAdd(ctx, queuePromotionsKey(region), redis.Z{Score: float64(now), Member: fmt.Sprintf("%d:%d", userID, now)})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to promote user: %w", err)
+	}
+	return true, nil
+}
+
+// GetPromotion returns the reservation made for a promoted user, if any
+func (c *Client) GetPromotion(ctx context.Context, userID int) (*SlotReservation, error) {
+	reservationJSON, err := c.Get(ctx, queuePromotedKey(userID)).Result()
+	if err == redis.Nil {
+		return nil, ErrNotQueued
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promotion: %w", err)
+	}
+
+	var reservation SlotReservation
+	if err := json.Unmarshal([]byte(reservationJSON), &reservation); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal promotion: %w", err)
+	}
+	return &reservation, nil
+}
+
+// ClearPromotion forgets a user's promotion once it has been handed out
+func (c *Client) ClearPromotion(ctx context.Context, userID int) error {
+	if err := c.Del(ctx, queuePromotedKey(userID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear promotion: %w", err)
+	}
+	return nil
+}
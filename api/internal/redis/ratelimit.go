@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// rateLimitScript counts one event in a fixed window that starts with the
+// first event. The expiry is set in the same call so a counter can never be
+// left without one.
+//
+// KEYS[1] = counter key, ARGV[1] = window (ms)
+// Returns {count, remaining window (ms)}.
+var rateLimitScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {count, redis.call('PTTL', KEYS[1])}
+`)
+
+// AllowRate records one event against key and reports whether it is within
+// limit events per window. When it is not, retryAfter is how long until the
+// window resets.
+func (c *Client) AllowRate(ctx context.Context, key string, limit int, window time.Duration) (allowed bool, retryAfter time.Duration, err error) {
+	result, err := rateLimitScript.Run(ctx, c, []string{key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+
+	if result[0] <= int64(limit) {
+		return true, 0, nil
+	}
+	return false, time.Duration(result[1]) * time.Millisecond, nil
+}
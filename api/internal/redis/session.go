@@ -3,8 +3,11 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 // SessionData represents a user session stored in Redis
@@ -15,11 +18,17 @@ type SessionData struct {
 	Region       string    `json:"region"`
 	CharacterID  int       `json:"character_id,omitempty"`
 	ServerRegion string    `json:"server_region,omitempty"`
+	InstanceID   string    `json:"instance_id,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
 	ExpiresAt    time.Time `json:"expires_at"`
 }
 
-// SetSession stores a user session in Redis with TTL
+func userSessionKey(userID int) string {
+	return fmt.Sprintf("user_session:%d", userID)
+}
+
+// SetSession stores a user session in Redis with TTL. The session also
+// becomes the user's current one, which presence is read from.
 func (c *Client) SetSession(ctx context.Context, token string, session *SessionData, ttl time.Duration) error {
 	sessionKey := fmt.Sprintf("session:%s", token)
 
@@ -34,6 +43,11 @@ func (c *Client) SetSession(ctx context.Context, token string, session *SessionD
 		return fmt.Errorf("failed to set session: %w", err)
 	}
 
+	// Point the user at their newest session
+	if err := c.Set(ctx, userSessionKey(session.UserID), token, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to index session: %w", err)
+	}
+
 	// Add user to active users set
 	activeUsersKey := "active_users"
 	if err := c.SAdd(ctx, activeUsersKey, session.UserID).Err(); err != nil {
@@ -78,6 +92,12 @@ func (c *Client) DeleteSession(ctx context.Context, token string) error {
 		// Remove from active users sets
 		c.SRem(ctx, "active_users", session.UserID)
 		c.SRem(ctx, fmt.Sprintf("active_users:%s", session.Region), session.UserID)
+
+		// Only clear the index if a newer session has not replaced this one
+		current, err := c.Get(ctx, userSessionKey(session.UserID)).Result()
+		if err == nil && current == token {
+			c.Del(ctx, userSessionKey(session.UserID))
+		}
 	}
 
 	// Delete the session key
@@ -120,6 +140,7 @@ func (c *Client) InvalidateUserSessions(ctx context.Context, userID int) error {
 
 	// Remove from active users sets
 	c.SRem(ctx, "active_users", userID)
+	c.Del(ctx, userSessionKey(userID))
 	// Note: We don't know the region, so we'll leave region-specific cleanup to TTL
 
 	return nil
@@ -199,3 +220,58 @@ func (c *Client) UpdateSessionGameServer(ctx context.Context, token string, char
 
 	return nil
 }
+
+// GetUserSession returns the user's current session
+func (c *Client) GetUserSession(ctx context.Context, userID int) (string, *SessionData, error) {
+	token, err := c.Get(ctx, userSessionKey(userID)).Result()
+	if err != nil {
+		return "", nil, fmt.Errorf("session not found: %w", err)
+	}
+
+	session, err := c.GetSession(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// UpdateUserSessionGameServer records which character and game server the
+// user is playing on in their current session. Empty values clear it.
+func (c *Client) UpdateUserSessionGameServer(ctx context.Context, userID, characterID int, serverRegion, instanceID string) error {
+	token, session, err := c.GetUserSession(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	session.CharacterID = characterID
+	session.ServerRegion = serverRegion
+	session.InstanceID = instanceID
+
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session data: %w", err)
+	}
+
+	// KeepTTL preserves the session's remaining lifetime
+	if err := c.Set(ctx, fmt.Sprintf("session:%s", token), sessionJSON, redis.KeepTTL).Err(); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// ClearUserSessionGameServer marks the user as no longer in game, unless they
+// have already moved on to a different instance than instanceID. Users who
+// have logged out have no presence to clear.
+func (c *Client) ClearUserSessionGameServer(ctx context.Context, userID int, instanceID string) error {
+	_, session, err := c.GetUserSession(ctx, userID)
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if session.InstanceID != instanceID {
+		return nil
+	}
+	return c.UpdateUserSessionGameServer(ctx, userID, 0, "", "")
+}
@@ -0,0 +1,216 @@
+package regions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+	"github.com/omega-realm/api/internal/models"
+)
+
+// Registry is the single source of truth for game regions. Regions live in
+// the Postgres regions table and are cached in memory; every replica
+// refreshes its cache periodically so admin changes propagate.
+type Registry struct {
+	db *database.DB
+
+	mu      sync.RWMutex
+	regions map[string]*models.Region
+	order   []string
+}
+
+// NewRegistry creates an empty registry. Call Load before serving requests.
+func NewRegistry(db *database.DB) *Registry {
+	return &Registry{
+		db:      db,
+		regions: make(map[string]*models.Region),
+	}
+}
+
+// Load replaces the in-memory cache with the contents of the regions table
+func (r *Registry) Load(ctx context.Context) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, display_name, websocket_url, status, max_players, latency_estimate, min_protocol_version, sort_order
+		FROM regions
+		ORDER BY sort_order, id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to load regions: %w", err)
+	}
+	defer rows.Close()
+
+	loaded := make(map[string]*models.Region)
+	var order []string
+	for rows.Next() {
+		region := &models.Region{}
+		if err := rows.Scan(
+			&region.ID,
+			&region.DisplayName,
+			&region.WebSocketURL,
+			&region.Status,
+			&region.MaxPlayers,
+			&region.LatencyEstimate,
+			&region.MinProtocolVersion,
+			&region.SortOrder,
+		); err != nil {
+			return fmt.Errorf("failed to scan region: %w", err)
+		}
+		loaded[region.ID] = region
+		order = append(order, region.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load regions: %w", err)
+	}
+
+	r.mu.Lock()
+	r.regions = loaded
+	r.order = order
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Run reloads the cache every interval until ctx is cancelled
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Load(ctx); err != nil {
+				log.Printf("[Regions] %v", err)
+			}
+		}
+	}
+}
+
+// IsValidRegion checks if a region ID exists, regardless of its status
+func (r *Registry) IsValidRegion(regionID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.regions[regionID]
+	return ok
+}
+
+// GetRegionDetails returns a copy of a region, or nil if it does not exist
+func (r *Registry) GetRegionDetails(regionID string) *models.Region {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	region, ok := r.regions[regionID]
+	if !ok {
+		return nil
+	}
+	copied := *region
+	return &copied
+}
+
+// GetAllRegions returns copies of all regions in display order
+func (r *Registry) GetAllRegions() []*models.Region {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	regions := make([]*models.Region, 0, len(r.order))
+	for _, id := range r.order {
+		copied := *r.regions[id]
+		regions = append(regions, &copied)
+	}
+	return regions
+}
+
+// RegionIDs returns all region IDs in display order
+func (r *Registry) RegionIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string{}, r.order...)
+}
+
+// DefaultRegion returns the first region in display order
+func (r *Registry) DefaultRegion() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.order) == 0 {
+		return ""
+	}
+	return r.order[0]
+}
+
+// NormalizeRegionID converts user input such as "US-West" to a region ID
+func NormalizeRegionID(regionID string) string {
+	return strings.ToLower(strings.TrimSpace(regionID))
+}
+
+// SaveRegion inserts or updates a region and refreshes the cache. A region
+// saved without a sort order keeps its place, or goes last if it is new, so
+// adding a region never changes the default region by accident.
+func (r *Registry) SaveRegion(ctx context.Context, region *models.Region) error {
+	if !IsValidStatus(region.Status) {
+		return fmt.Errorf("invalid region status: %s", region.Status)
+	}
+
+	query := `
+		INSERT INTO regions (id, display_name, websocket_url, status, max_players, latency_estimate, min_protocol_version, sort_order)
+		SELECT $1, $2, $3, $4, $5, $6, $7,
+		       CASE WHEN $8 = 0 THEN COALESCE(MAX(sort_order), 0) + 1 ELSE $8 END
+		FROM regions
+		ON CONFLICT (id) DO UPDATE SET
+			display_name = EXCLUDED.display_name,
+			websocket_url = EXCLUDED.websocket_url,
+			status = EXCLUDED.status,
+			max_players = EXCLUDED.max_players,
+			latency_estimate = EXCLUDED.latency_estimate,
+			min_protocol_version = EXCLUDED.min_protocol_version,
+			sort_order = CASE WHEN $8 = 0 THEN regions.sort_order ELSE EXCLUDED.sort_order END,
+			updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		region.ID,
+		region.DisplayName,
+		region.WebSocketURL,
+		region.Status,
+		region.MaxPlayers,
+		region.LatencyEstimate,
+		region.MinProtocolVersion,
+		region.SortOrder,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save region: %w", err)
+	}
+
+	return r.Load(ctx)
+}
+
+// SetStatus changes a region's status and refreshes the cache
+func (r *Registry) SetStatus(ctx context.Context, regionID, status string) error {
+	if !IsValidStatus(status) {
+		return fmt.Errorf("invalid region status: %s", status)
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE regions SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		regionID, status,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update region status: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return r.Load(ctx)
+}
+
+// IsValidStatus checks a region status value
+func IsValidStatus(status string) bool {
+	switch status {
+	case models.RegionStatusOnline, models.RegionStatusOffline, models.RegionStatusMaintenance:
+		return true
+	}
+	return false
+}
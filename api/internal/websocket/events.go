@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	redisClient "github.com/omega-realm/api/internal/redis"
+)
+
+// Event types pushed to clients
+const (
+	EventFriendRequest  = "friend_request"
+	EventFriendAccepted = "friend_accepted"
+	EventPartyInvite    = "party_invite"
+	EventPartyLeft      = "party_left"
+	EventQueuePromoted  = "queue_promoted"
+	EventMatchFound     = "match_found"
+	EventRankChanged    = "rank_changed"
+	EventBanned         = "banned"
+	EventChatMessage    = "chat_message"
+	EventMuted          = "muted"
+)
+
+// Redis pub/sub channel prefixes. Every API replica pattern-subscribes to
+// eventChannelPattern and delivers to the clients connected to it.
+const (
+	userChannelPrefix   = "events:user:"
+	topicChannelPrefix  = "events:topic:"
+	eventChannelPattern = "events:*"
+)
+
+// Event is a message pushed to connected clients. Topic is empty for events
+// addressed to a single user.
+type Event struct {
+	Type   string          `json:"type"`
+	Topic  string          `json:"topic,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	SentAt time.Time       `json:"sent_at"`
+}
+
+// PartyLeft is the data of EventPartyLeft. The hub drops the party's topic
+// from the user's connections when it delivers one.
+type PartyLeft struct {
+	PartyID string `json:"party_id"`
+	Kicked  bool   `json:"kicked"`
+}
+
+// Publisher sends events through Redis so they reach clients on any replica
+type Publisher struct {
+	redis *redisClient.Client
+}
+
+// NewPublisher creates an event publisher
+func NewPublisher(redis *redisClient.Client) *Publisher {
+	return &Publisher{redis: redis}
+}
+
+// PublishUser sends an event to every connection of one user
+func (p *Publisher) PublishUser(ctx context.Context, userID int, eventType string, data any) error {
+	return p.publish(ctx, fmt.Sprintf("%s%d", userChannelPrefix, userID), "", eventType, data)
+}
+
+// PublishTopic sends an event to every connection subscribed to topic
+func (p *Publisher) PublishTopic(ctx context.Context, topic, eventType string, data any) error {
+	return p.publish(ctx, topicChannelPrefix+topic, topic, eventType, data)
+}
+
+func (p *Publisher) publish(ctx context.Context, channel, topic, eventType string, data any) error {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+
+	eventJSON, err := json.Marshal(Event{
+		Type:   eventType,
+		Topic:  topic,
+		Data:   dataJSON,
+		SentAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+
+	if err := p.redis.Publish(ctx, channel, eventJSON).Err(); err != nil {
+		return fmt.Errorf("failed to publish %s event: %w", eventType, err)
+	}
+	return nil
+}
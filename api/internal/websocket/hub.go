@@ -0,0 +1,426 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/omega-realm/api/internal/auth"
+	redisClient "github.com/omega-realm/api/internal/redis"
+)
+
+const (
+	// writeWait is how long a single write to a client may take
+	writeWait = 10 * time.Second
+	// pongWait is how long a client may stay silent before it is dropped
+	pongWait = 60 * time.Second
+	// pingPeriod must be shorter than pongWait
+	pingPeriod = pongWait * 9 / 10
+	// maxMessageSize bounds client → server messages (subscriptions and actions)
+	maxMessageSize = 4096
+	// sendBuffer is how many events may queue for a slow client before it is dropped
+	sendBuffer = 64
+	// maxTopics bounds how many topics one connection may follow
+	maxTopics = 32
+)
+
+// TopicAuthorizer decides whether a user may subscribe to a topic
+type TopicAuthorizer func(ctx context.Context, userID int, topic string) bool
+
+// ActionHandler handles a client action registered with Hub.Handle. The
+// returned error is shown to the client, so it must not carry internal details.
+type ActionHandler func(ctx context.Context, userID int, data json.RawMessage) error
+
+// ClientMessage is sent by clients to manage their topic subscriptions or to
+// perform a registered action, such as sending chat, with Data as its payload
+type ClientMessage struct {
+	Action string          `json:"action"`
+	Topic  string          `json:"topic"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// client is one WebSocket connection
+type client struct {
+	hub    *Hub
+	conn   *ws.Conn
+	userID int
+	send   chan []byte
+
+	mu     sync.Mutex
+	topics map[string]bool
+}
+
+// Hub delivers events from Redis to the WebSocket clients connected to this
+// replica. Events are addressed either to a user (all of their connections)
+// or to a topic (every connection that subscribed to it).
+type Hub struct {
+	redis     *redisClient.Client
+	authorize TopicAuthorizer
+	upgrader  ws.Upgrader
+	actions   map[string]ActionHandler
+
+	mu      sync.RWMutex
+	clients map[int]map[*client]bool
+}
+
+// NewHub creates a hub. authorize is consulted for every topic subscription.
+func NewHub(redis *redisClient.Client, authorize TopicAuthorizer) *Hub {
+	return &Hub{
+		redis:     redis,
+		authorize: authorize,
+		upgrader: ws.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			// Clients are game builds and tools, not third-party pages holding cookies
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		actions: make(map[string]ActionHandler),
+		clients: make(map[int]map[*client]bool),
+	}
+}
+
+// Handle registers the handler for a client action. It must be called before
+// the hub starts serving connections.
+func (h *Hub) Handle(action string, handler ActionHandler) {
+	h.actions[action] = handler
+}
+
+// Run relays events from Redis until ctx is cancelled
+func (h *Hub) Run(ctx context.Context) {
+	pubsub := h.redis.PSubscribe(ctx, eventChannelPattern)
+	defer pubsub.Close()
+
+	log.Println("[Events] Hub started")
+
+	channel := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			h.closeAll()
+			return
+		case msg, ok := <-channel:
+			if !ok {
+				return
+			}
+			h.dispatch(msg.Channel, []byte(msg.Payload))
+		}
+	}
+}
+
+// dispatch hands one Redis message to the matching local clients
+func (h *Hub) dispatch(channel string, payload []byte) {
+	switch {
+	case strings.HasPrefix(channel, userChannelPrefix):
+		userID, err := strconv.Atoi(strings.TrimPrefix(channel, userChannelPrefix))
+		if err != nil {
+			return
+		}
+
+		var event Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			log.Printf("[Events] Dropping malformed event on %s: %v", channel, err)
+			return
+		}
+
+		h.mu.RLock()
+		targets := make([]*client, 0, len(h.clients[userID]))
+		for c := range h.clients[userID] {
+			targets = append(targets, c)
+		}
+		h.mu.RUnlock()
+
+		// A member who left or was kicked must stop hearing the party. Redis
+		// delivers in order, so no later party event reaches these clients.
+		var leftTopic string
+		if event.Type == EventPartyLeft {
+			var left PartyLeft
+			if err := json.Unmarshal(event.Data, &left); err == nil {
+				leftTopic = PartyTopic(left.PartyID)
+			}
+		}
+
+		for _, c := range targets {
+			if leftTopic != "" {
+				c.unsubscribe(leftTopic)
+			}
+			c.deliver(payload)
+			if event.Type == EventBanned {
+				// Let the ban notice flush, then hang up
+				c.closeSend()
+			}
+		}
+
+	case strings.HasPrefix(channel, topicChannelPrefix):
+		topic := strings.TrimPrefix(channel, topicChannelPrefix)
+
+		h.mu.RLock()
+		var targets []*client
+		for _, conns := range h.clients {
+			for c := range conns {
+				if c.subscribed(topic) {
+					targets = append(targets, c)
+				}
+			}
+		}
+		h.mu.RUnlock()
+
+		for _, c := range targets {
+			c.deliver(payload)
+		}
+	}
+}
+
+// ServeWS upgrades an authenticated request to a WebSocket event stream. The
+// access token is taken from the Authorization header, or from ?token= for
+// clients that cannot set headers on the handshake, and its session must
+// still be live.
+func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+	claims, err := auth.ValidateToken(token)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid or expired token"})
+		return
+	}
+
+	// Logging out, bans and account deletion end the session before the
+	// token expires
+	session, err := h.redis.GetSession(r.Context(), token)
+	if err != nil || session.UserID != claims.UserID {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Session has ended"})
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an error
+		log.Printf("[Events] Upgrade failed for user %d: %v", claims.UserID, err)
+		return
+	}
+
+	c := &client{
+		hub:    h,
+		conn:   conn,
+		userID: claims.UserID,
+		send:   make(chan []byte, sendBuffer),
+		topics: make(map[string]bool),
+	}
+	h.register(c)
+
+	go c.writePump()
+	go c.readPump()
+}
+
+// ConnectionCount returns how many clients are connected to this replica
+func (h *Hub) ConnectionCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	count := 0
+	for _, conns := range h.clients {
+		count += len(conns)
+	}
+	return count
+}
+
+func (h *Hub) register(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.clients[c.userID] == nil {
+		h.clients[c.userID] = make(map[*client]bool)
+	}
+	h.clients[c.userID][c] = true
+}
+
+// unregister removes the client and closes its send queue, once
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	conns := h.clients[c.userID]
+	if !conns[c] {
+		return
+	}
+	delete(conns, c)
+	if len(conns) == 0 {
+		delete(h.clients, c.userID)
+	}
+	close(c.send)
+}
+
+func (h *Hub) closeAll() {
+	h.mu.RLock()
+	var all []*client
+	for _, conns := range h.clients {
+		for c := range conns {
+			all = append(all, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range all {
+		c.closeSend()
+	}
+}
+
+// deliver queues an event. A client that cannot keep up is disconnected
+// rather than allowed to hold up everyone else.
+func (c *client) deliver(payload []byte) {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+
+	if !c.hub.clients[c.userID][c] {
+		return
+	}
+	select {
+	case c.send <- payload:
+	default:
+		go c.closeSend()
+	}
+}
+
+// closeSend ends the connection after any queued events are written
+func (c *client) closeSend() {
+	c.hub.unregister(c)
+}
+
+func (c *client) subscribed(topic string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.topics[topic]
+}
+
+func (c *client) unsubscribe(topic string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.topics, topic)
+}
+
+// readPump handles subscription messages and pongs. It owns all reads on the
+// connection.
+func (c *client) readPump() {
+	defer func() {
+		c.closeSend()
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var msg ClientMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if ws.IsUnexpectedCloseError(err, ws.CloseGoingAway, ws.CloseNormalClosure) {
+				log.Printf("[Events] Connection for user %d closed: %v", c.userID, err)
+			}
+			return
+		}
+		c.handleMessage(&msg)
+	}
+}
+
+func (c *client) handleMessage(msg *ClientMessage) {
+	switch msg.Action {
+	case "subscribe":
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		allowed := c.hub.authorize == nil || c.hub.authorize(ctx, c.userID, msg.Topic)
+		cancel()
+
+		c.mu.Lock()
+		if allowed && len(c.topics) >= maxTopics {
+			allowed = false
+		}
+		if allowed {
+			c.topics[msg.Topic] = true
+		}
+		c.mu.Unlock()
+
+		c.reply("subscribed", msg.Topic, allowed, "")
+	case "unsubscribe":
+		c.unsubscribe(msg.Topic)
+
+		c.reply("unsubscribed", msg.Topic, true, "")
+	default:
+		handler, ok := c.hub.actions[msg.Action]
+		if !ok {
+			c.reply("error", msg.Topic, false, "unknown action")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := handler(ctx, c.userID, msg.Data)
+		cancel()
+
+		if err != nil {
+			c.reply(msg.Action, msg.Topic, false, err.Error())
+		} else {
+			c.reply(msg.Action, msg.Topic, true, "")
+		}
+	}
+}
+
+// actionReply is the data of an acknowledgement
+type actionReply struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// reply acknowledges a client message
+func (c *client) reply(eventType, topic string, ok bool, reason string) {
+	data, _ := json.Marshal(actionReply{OK: ok, Error: reason})
+	payload, err := json.Marshal(Event{
+		Type:   eventType,
+		Topic:  topic,
+		Data:   data,
+		SentAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return
+	}
+	c.deliver(payload)
+}
+
+// writePump writes queued events and keepalive pings. It owns all writes on
+// the connection.
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(ws.CloseMessage, ws.FormatCloseMessage(ws.CloseNormalClosure, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(ws.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(ws.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func connect(h *Hub, userID int, topics ...string) *client {
+	c := &client{
+		hub:    h,
+		userID: userID,
+		send:   make(chan []byte, sendBuffer),
+		topics: make(map[string]bool),
+	}
+	for _, topic := range topics {
+		c.topics[topic] = true
+	}
+	h.register(c)
+	return c
+}
+
+func event(t *testing.T, eventType, topic string, data any) []byte {
+	t.Helper()
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(Event{Type: eventType, Topic: topic, Data: dataJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+// received drains the client's queue and returns the event types in order
+func received(t *testing.T, c *client) []string {
+	t.Helper()
+	var types []string
+	for {
+		select {
+		case payload := <-c.send:
+			var e Event
+			if err := json.Unmarshal(payload, &e); err != nil {
+				t.Fatal(err)
+			}
+			types = append(types, e.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func TestKickedMemberStopsReceivingPartyEvents(t *testing.T) {
+	h := NewHub(nil, nil)
+	topic := PartyTopic("p1")
+	kicked := connect(h, 1, topic, TopicGlobal)
+	stays := connect(h, 2, topic)
+
+	h.dispatch(fmt.Sprintf("%s%d", userChannelPrefix, 1),
+		event(t, EventPartyLeft, "", PartyLeft{PartyID: "p1", Kicked: true}))
+	h.dispatch(topicChannelPrefix+topic, event(t, EventChatMessage, topic, "still here?"))
+	h.dispatch(topicChannelPrefix+TopicGlobal, event(t, EventChatMessage, TopicGlobal, "hello"))
+
+	if got := received(t, kicked); fmt.Sprint(got) != fmt.Sprint([]string{EventPartyLeft, EventChatMessage}) {
+		t.Errorf("kicked member received %v, want the notice and the global message only", got)
+	}
+	if kicked.subscribed(topic) {
+		t.Error("kicked member is still subscribed to the party topic")
+	}
+	if got := received(t, stays); len(got) != 1 || got[0] != EventChatMessage {
+		t.Errorf("remaining member received %v, want the party message", got)
+	}
+}
+
+func TestPartyLeftKeepsOtherParties(t *testing.T) {
+	h := NewHub(nil, nil)
+	// A stale subscription from an earlier party is not this notice's concern
+	c := connect(h, 1, PartyTopic("p1"), PartyTopic("p2"))
+
+	h.dispatch(fmt.Sprintf("%s%d", userChannelPrefix, 1),
+		event(t, EventPartyLeft, "", PartyLeft{PartyID: "p1"}))
+
+	if c.subscribed(PartyTopic("p1")) {
+		t.Error("still subscribed to the party that was left")
+	}
+	if !c.subscribed(PartyTopic("p2")) {
+		t.Error("lost the subscription to another party")
+	}
+}
@@ -0,0 +1,40 @@
+package websocket
+
+import (
+	"context"
+	"strings"
+
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/regions"
+)
+
+// TopicGlobal reaches every client that subscribed to it
+const TopicGlobal = "global"
+
+// RegionTopic is the topic for events about one region
+func RegionTopic(regionID string) string {
+	return "region:" + regionID
+}
+
+// PartyTopic is the topic for events about one party
+func PartyTopic(partyID string) string {
+	return "party:" + partyID
+}
+
+// NewTopicAuthorizer admits the global topic, any known region's topic, and
+// the topic of the party the user currently belongs to
+func NewTopicAuthorizer(redis *redisClient.Client, registry *regions.Registry) TopicAuthorizer {
+	return func(ctx context.Context, userID int, topic string) bool {
+		switch {
+		case topic == TopicGlobal:
+			return true
+		case strings.HasPrefix(topic, "region:"):
+			return registry.IsValidRegion(strings.TrimPrefix(topic, "region:"))
+		case strings.HasPrefix(topic, "party:"):
+			party, err := redis.GetUserParty(ctx, userID)
+			return err == nil && party.ID == strings.TrimPrefix(topic, "party:")
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+	redisClient "github.com/omega-realm/api/internal/redis"
+)
+
+// AccountDeleter executes account deletions once their grace period ends
+type AccountDeleter struct {
+	db       *database.DB
+	redis    *redisClient.Client
+	interval time.Duration
+}
+
+// NewAccountDeleter creates an account deletion worker
+func NewAccountDeleter(db *database.DB, redis *redisClient.Client, interval time.Duration) *AccountDeleter {
+	return &AccountDeleter{db: db, redis: redis, interval: interval}
+}
+
+// Run processes due deletions until ctx is cancelled
+func (d *AccountDeleter) Run(ctx context.Context) {
+	log.Printf("[Workers] Account deleter started (interval: %s)", d.interval)
+	runEvery(ctx, d.interval, d.processDue)
+}
+
+func (d *AccountDeleter) processDue(ctx context.Context) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT user_id FROM account_deletions
+		WHERE scheduled_for <= CURRENT_TIMESTAMP
+		ORDER BY scheduled_for
+		LIMIT 100
+	`)
+	if err != nil {
+		log.Printf("[Workers] Failed to query due account deletions: %v", err)
+		return
+	}
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			log.Printf("[Workers] Failed to scan account deletion: %v", err)
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+
+	for _, userID := range userIDs {
+		if err := d.DeleteAccount(ctx, userID); err != nil {
+			log.Printf("[Workers] Failed to delete account %d: %v", userID, err)
+		}
+	}
+}
+
+// DeleteAccount permanently removes a user. Redis state is purged first so
+// that a failure leaves the Postgres row (and the pending deletion) in place
+// to be retried on the next pass.
+func (d *AccountDeleter) DeleteAccount(ctx context.Context, userID int) error {
+	var characterID int
+	err := d.db.QueryRowContext(ctx, `SELECT id FROM characters WHERE user_id = $1`, userID).Scan(&characterID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if characterID != 0 {
+		if err := d.redis.RemovePlayer(ctx, characterID); err != nil {
+			return err
+		}
+	}
+	if err := d.redis.InvalidateUserSessions(ctx, userID); err != nil {
+		return err
+	}
+	if err := d.redis.LeaveQueue(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := d.redis.LeaveParty(ctx, userID); err != nil && !errors.Is(err, redisClient.ErrNotInParty) {
+		return err
+	}
+
+	// Characters, leaderboards, sessions and the pending deletion cascade
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+		return err
+	}
+
+	if err := d.db.RecordAuditEvent(ctx, 0, database.AuditAccountDeleted, map[string]any{
+		"deleted_user_id": userID,
+	}); err != nil {
+		log.Printf("[Workers] %v", err)
+	}
+
+	log.Printf("[Workers] Deleted account %d", userID)
+	return nil
+}
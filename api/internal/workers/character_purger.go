@@ -0,0 +1,63 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+)
+
+// CharacterPurger hard-deletes soft-deleted characters whose restore window
+// has passed, which also releases their names
+type CharacterPurger struct {
+	db            *database.DB
+	restoreWindow time.Duration
+	interval      time.Duration
+}
+
+// NewCharacterPurger creates a character purge worker
+func NewCharacterPurger(db *database.DB, restoreWindow, interval time.Duration) *CharacterPurger {
+	return &CharacterPurger{db: db, restoreWindow: restoreWindow, interval: interval}
+}
+
+// Run purges expired characters until ctx is cancelled
+func (p *CharacterPurger) Run(ctx context.Context) {
+	log.Printf("[Workers] Character purger started (restore window: %s, interval: %s)", p.restoreWindow, p.interval)
+	runEvery(ctx, p.interval, p.purgeExpired)
+}
+
+func (p *CharacterPurger) purgeExpired(ctx context.Context) {
+	// Leaderboard and session rows cascade from the character
+	rows, err := p.db.QueryContext(ctx, `
+		DELETE FROM characters
+		WHERE deleted_at IS NOT NULL
+		  AND deleted_at <= CURRENT_TIMESTAMP - make_interval(secs => $1)
+		RETURNING id, user_id
+	`, p.restoreWindow.Seconds())
+	if err != nil {
+		log.Printf("[Workers] Failed to purge characters: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	purged := 0
+	for rows.Next() {
+		var characterID, userID int
+		if err := rows.Scan(&characterID, &userID); err != nil {
+			log.Printf("[Workers] Failed to scan purged character: %v", err)
+			continue
+		}
+		purged++
+
+		if err := p.db.RecordAuditEvent(ctx, userID, database.AuditCharacterPurged, map[string]any{
+			"character_id": characterID,
+		}); err != nil {
+			log.Printf("[Workers] %v", err)
+		}
+	}
+
+	if purged > 0 {
+		log.Printf("[Workers] Purged %d soft-deleted characters", purged)
+	}
+}
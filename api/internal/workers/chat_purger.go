@@ -0,0 +1,42 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+)
+
+// ChatPurger deletes chat history older than the retention period
+type ChatPurger struct {
+	db        *database.DB
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewChatPurger creates a chat history purge worker
+func NewChatPurger(db *database.DB, retention, interval time.Duration) *ChatPurger {
+	return &ChatPurger{db: db, retention: retention, interval: interval}
+}
+
+// Run purges old chat messages until ctx is cancelled
+func (p *ChatPurger) Run(ctx context.Context) {
+	log.Printf("[Workers] Chat purger started (retention: %s, interval: %s)", p.retention, p.interval)
+	runEvery(ctx, p.interval, p.purgeExpired)
+}
+
+func (p *ChatPurger) purgeExpired(ctx context.Context) {
+	result, err := p.db.ExecContext(ctx, `
+		DELETE FROM chat_messages
+		WHERE created_at <= CURRENT_TIMESTAMP - make_interval(secs => $1)
+	`, p.retention.Seconds())
+	if err != nil {
+		log.Printf("[Workers] Failed to purge chat messages: %v", err)
+		return
+	}
+
+	if purged, _ := result.RowsAffected(); purged > 0 {
+		log.Printf("[Workers] Purged %d chat messages", purged)
+	}
+}
@@ -0,0 +1,86 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config holds background worker configuration
+type Config struct {
+	AccountDeletionDelay    time.Duration
+	AccountDeletionInterval time.Duration
+	CharacterRestoreWindow  time.Duration
+	CharacterPurgeInterval  time.Duration
+	RegionRefreshInterval   time.Duration
+	QueuePromotionInterval  time.Duration
+	QueueHeartbeatTimeout   time.Duration
+	ChatHistoryRetention    time.Duration
+	ChatPurgeInterval       time.Duration
+	SessionReapInterval     time.Duration
+}
+
+// LoadConfigFromEnv loads worker configuration from environment variables
+func LoadConfigFromEnv() *Config {
+	return &Config{
+		AccountDeletionDelay:    getEnvAsDuration("ACCOUNT_DELETION_DELAY", 7*24*time.Hour),
+		AccountDeletionInterval: getEnvAsDuration("ACCOUNT_DELETION_INTERVAL", 5*time.Minute),
+		CharacterRestoreWindow:  time.Duration(getEnvAsInt("CHARACTER_RESTORE_DAYS", 30)) * 24 * time.Hour,
+		CharacterPurgeInterval:  getEnvAsDuration("CHARACTER_PURGE_INTERVAL", time.Hour),
+		RegionRefreshInterval:   getEnvAsDuration("REGION_REFRESH_INTERVAL", 30*time.Second),
+		QueuePromotionInterval:  getEnvAsDuration("QUEUE_PROMOTION_INTERVAL", 2*time.Second),
+		QueueHeartbeatTimeout:   getEnvAsDuration("QUEUE_HEARTBEAT_TIMEOUT", 30*time.Second),
+		ChatHistoryRetention:    time.Duration(getEnvAsInt("CHAT_HISTORY_DAYS", 30)) * 24 * time.Hour,
+		ChatPurgeInterval:       getEnvAsDuration("CHAT_PURGE_INTERVAL", time.Hour),
+		SessionReapInterval:     getEnvAsDuration("SESSION_REAP_INTERVAL", time.Minute),
+	}
+}
+
+func getEnvAsInt(key string, defaultValue int) int {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	// Every worker integer is a number of days to keep something, and zero
+	// or less would delete it at once
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value <= 0 {
+		log.Printf("[Workers] Invalid integer value for %s: %s, using default: %d", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	// Every worker duration is an interval or a delay, and tickers panic
+	// on anything but a positive interval
+	value, err := time.ParseDuration(valueStr)
+	if err != nil || value <= 0 {
+		log.Printf("[Workers] Invalid duration value for %s: %s, using default: %s", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// runEvery calls fn immediately and then on every tick until ctx is done
+func runEvery(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	fn(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn(ctx)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package workers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/omega-realm/api/internal/models"
+	redisClient "github.com/omega-realm/api/internal/redis"
+	"github.com/omega-realm/api/internal/regions"
+	"github.com/omega-realm/api/internal/websocket"
+	"github.com/redis/go-redis/v9"
+)
+
+// promoterLockKey elects the one replica that promotes a region's queue
+func promoterLockKey(region string) string {
+	return fmt.Sprintf("queue_promoter_lock:%s", region)
+}
+
+// renewLockScript extends a lock only if this replica still holds it
+var renewLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// QueuePromoter moves players from the front of each region's waiting queue
+// onto a game server as slots free up, and drops players who stopped polling
+type QueuePromoter struct {
+	redis            *redisClient.Client
+	registry         *regions.Registry
+	events           *websocket.Publisher
+	reservationTTL   time.Duration
+	heartbeatTimeout time.Duration
+	interval         time.Duration
+	replicaID        string
+}
+
+// NewQueuePromoter creates a queue promotion worker
+func NewQueuePromoter(redis *redisClient.Client, registry *regions.Registry, events *websocket.Publisher, reservationTTL, heartbeatTimeout, interval time.Duration) *QueuePromoter {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+
+	return &QueuePromoter{
+		redis:            redis,
+		registry:         registry,
+		events:           events,
+		reservationTTL:   reservationTTL,
+		heartbeatTimeout: heartbeatTimeout,
+		interval:         interval,
+		replicaID:        hex.EncodeToString(idBytes),
+	}
+}
+
+// Run promotes queued players until ctx is cancelled
+func (p *QueuePromoter) Run(ctx context.Context) {
+	log.Printf("[Workers] Queue promoter started (heartbeat timeout: %s, interval: %s)", p.heartbeatTimeout, p.interval)
+	runEvery(ctx, p.interval, p.promoteAll)
+}
+
+func (p *QueuePromoter) promoteAll(ctx context.Context) {
+	for _, region := range p.registry.GetAllRegions() {
+		dropped, err := p.redis.DropStaleQueued(ctx, region.ID, p.heartbeatTimeout)
+		if err != nil {
+			log.Printf("[Workers] %v", err)
+		}
+		if len(dropped) > 0 {
+			log.Printf("[Workers] Dropped %d idle players from the %s queue", len(dropped), region.ID)
+		}
+
+		if region.Status != models.RegionStatusOnline {
+			continue
+		}
+
+		leader, err := p.acquireLock(ctx, region.ID)
+		if err != nil {
+			log.Printf("[Workers] %v", err)
+			continue
+		}
+		if leader {
+			p.promoteRegion(ctx, region)
+		}
+	}
+}
+
+// acquireLock takes or renews the promotion lock for a region. Two replicas
+// reading the same queue head would each reserve a slot for the user, and
+// the second reservation replaces the first. The lock lapses after a few
+// missed intervals so another replica can take over.
+func (p *QueuePromoter) acquireLock(ctx context.Context, region string) (bool, error) {
+	ttl := 3 * p.interval
+	ok, err := p.redis.SetNX(ctx, promoterLockKey(region), p.replicaID, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire queue promoter lock: %w", err)
+	}
+	if ok {
+		return true, nil
+	}
+
+	renewed, err := renewLockScript.Run(ctx, p.redis, []string{promoterLockKey(region)}, p.replicaID, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew queue promoter lock: %w", err)
+	}
+	return renewed == 1, nil
+}
+
+// promoteRegion reserves slots for queued players in order until the region
+// is full or the queue is empty. Only the holder of the region's lock calls
+// it, so the reservation released after a failed promotion is always the
+// one just made here.
+func (p *QueuePromoter) promoteRegion(ctx context.Context, region *models.Region) {
+	promoted := 0
+	for {
+		capacity, err := p.redis.GetRegionCapacity(ctx, region.ID)
+		if err != nil {
+			log.Printf("[Workers] %v", err)
+			break
+		}
+		if capacity.PlayerCount+capacity.Reserved >= int64(region.MaxPlayers) {
+			break
+		}
+
+		userID, err := p.redis.QueueHead(ctx, region.ID)
+		if err != nil {
+			log.Printf("[Workers] %v", err)
+			break
+		}
+		if userID == 0 {
+			break
+		}
+
+		reservation, err := p.redis.ReserveSlots(ctx, region.ID, []int{userID}, p.reservationTTL)
+		if errors.Is(err, redisClient.ErrRegionFull) || errors.Is(err, redisClient.ErrNoGameServers) {
+			break
+		}
+		if err != nil {
+			log.Printf("[Workers] Failed to reserve slot for queued user %d: %v", userID, err)
+			break
+		}
+
+		ok, err := p.redis.PromoteQueued(ctx, region.ID, userID, reservation, p.reservationTTL)
+		if err != nil || !ok {
+			// The user left the queue in the meantime
+			if releaseErr := p.redis.ReleaseReservation(ctx, userID); releaseErr != nil {
+				log.Printf("[Workers] %v", releaseErr)
+			}
+			if err != nil {
+				log.Printf("[Workers] %v", err)
+				break
+			}
+			continue
+		}
+		promoted++
+
+		err = p.events.PublishUser(ctx, userID, websocket.EventQueuePromoted, map[string]any{
+			"region":                 region.ID,
+			"instance_id":            reservation.Instance.InstanceID,
+			"websocket_url":          reservation.Instance.Address,
+			"reservation_expires_at": reservation.ExpiresAt,
+		})
+		if err != nil {
+			log.Printf("[Workers] %v", err)
+		}
+	}
+
+	if promoted > 0 {
+		log.Printf("[Workers] Promoted %d players from the %s queue", promoted, region.ID)
+	}
+}
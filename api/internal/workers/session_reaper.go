@@ -0,0 +1,86 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/omega-realm/api/internal/database"
+	"github.com/omega-realm/api/internal/models"
+	redisClient "github.com/omega-realm/api/internal/redis"
+)
+
+// SessionReaper closes sessions left open by game servers that went away
+// without deregistering. An instance that stopped heartbeating has dropped
+// out of the registry, so its open sessions can never be closed normally.
+type SessionReaper struct {
+	db       *database.DB
+	redis    *redisClient.Client
+	interval time.Duration
+}
+
+// NewSessionReaper creates an orphaned session reaper
+func NewSessionReaper(db *database.DB, redis *redisClient.Client, interval time.Duration) *SessionReaper {
+	return &SessionReaper{db: db, redis: redis, interval: interval}
+}
+
+// Run reaps orphaned sessions until ctx is cancelled
+func (r *SessionReaper) Run(ctx context.Context) {
+	log.Printf("[Workers] Session reaper started (interval: %s)", r.interval)
+	runEvery(ctx, r.interval, r.reapOrphaned)
+}
+
+func (r *SessionReaper) reapOrphaned(ctx context.Context) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT DISTINCT COALESCE(instance_id, '') FROM sessions WHERE ended_at IS NULL`,
+	)
+	if err != nil {
+		log.Printf("[Workers] Failed to list open sessions: %v", err)
+		return
+	}
+
+	var instanceIDs []string
+	for rows.Next() {
+		var instanceID string
+		if err := rows.Scan(&instanceID); err != nil {
+			rows.Close()
+			log.Printf("[Workers] Failed to scan session instance: %v", err)
+			return
+		}
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Printf("[Workers] Failed to list open sessions: %v", err)
+		return
+	}
+
+	for _, instanceID := range instanceIDs {
+		// Sessions without an instance predate instance tracking
+		if instanceID != "" {
+			_, err := r.redis.GetGameServer(ctx, instanceID)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, redisClient.ErrGameServerNotFound) {
+				log.Printf("[Workers] %v", err)
+				continue
+			}
+		}
+
+		userIDs, err := r.db.CloseInstanceSessions(ctx, instanceID, models.SessionEndOrphaned)
+		if err != nil {
+			log.Printf("[Workers] %v", err)
+			continue
+		}
+		for _, userID := range userIDs {
+			if err := r.redis.ClearUserSessionGameServer(ctx, userID, instanceID); err != nil {
+				log.Printf("[Workers] Failed to update presence for user %d: %v", userID, err)
+			}
+		}
+		if len(userIDs) > 0 {
+			log.Printf("[Workers] Closed %d orphaned sessions on %q", len(userIDs), instanceID)
+		}
+	}
+}
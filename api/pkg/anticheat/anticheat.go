@@ -0,0 +1,361 @@
+package anticheat
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+// Category is a kind of suspicious behaviour
+type Category uint8
+
+// Categories
+const (
+	Speed Category = iota
+	Corrections
+	AimSnaps
+	ShotRate
+	numCategories
+)
+
+var categoryNames = [numCategories]string{"speed", "corrections", "aim_snaps", "shot_rate"}
+
+func (c Category) String() string {
+	if c < numCategories {
+		return categoryNames[c]
+	}
+	return fmt.Sprintf("category(%d)", uint8(c))
+}
+
+// halfScores are the recent violations at which each category scores 50. A
+// single teleport is rarely innocent, but aim snaps happen to honest players.
+var halfScores = [numCategories]float64{
+	Speed:       2,
+	Corrections: 4,
+	AimSnaps:    8,
+	ShotRate:    2,
+}
+
+// Config holds the limits a player is held to
+type Config struct {
+	// MaxSpeed is the fastest a player moves, in units per second
+	// (GameConstants.PLAYER_SPRINT_SPEED)
+	MaxSpeed float32
+	// PositionTolerance is how far beyond MaxSpeed a player may get, for
+	// latency (GameConstants.POSITION_TOLERANCE)
+	PositionTolerance float32
+	// SpeedWindow is the span of movement checked against MaxSpeed. Longer
+	// windows smooth out packets that arrive bunched together.
+	SpeedWindow time.Duration
+
+	// MaxCorrections is how many position corrections a player may get
+	// within CorrectionWindow before each further one is a violation
+	MaxCorrections   int
+	CorrectionWindow time.Duration
+
+	// SnapAngle is the turn, in radians, that makes a shot an aim snap when
+	// it happens within SnapInterval
+	SnapAngle    float64
+	SnapInterval time.Duration
+
+	// MaxShotInputs is how many inputs with the shoot flag a player may send
+	// within ShotWindow. The client sends input at 10Hz, and a window of a
+	// few seconds leaves room for inputs delayed by a stall arriving at once.
+	MaxShotInputs int
+	ShotWindow    time.Duration
+
+	// HalfLife is how long a violation takes to count half as much
+	HalfLife time.Duration
+}
+
+// DefaultConfig matches GameConstants and the client's input rate
+func DefaultConfig() Config {
+	return Config{
+		MaxSpeed:          320,
+		PositionTolerance: 75,
+		SpeedWindow:       time.Second,
+		MaxCorrections:    5,
+		CorrectionWindow:  time.Minute,
+		SnapAngle:         100 * math.Pi / 180,
+		SnapInterval:      150 * time.Millisecond,
+		MaxShotInputs:     65,
+		ShotWindow:        5 * time.Second,
+		HalfLife:          10 * time.Minute,
+	}
+}
+
+// Report is a player's scores and how much evidence they rest on
+type Report struct {
+	// Score combines the category scores, 0 to 100
+	Score           int `json:"score"`
+	SpeedScore      int `json:"speed_score"`
+	CorrectionScore int `json:"correction_score"`
+	AimSnapScore    int `json:"aim_snap_score"`
+	ShotRateScore   int `json:"shot_rate_score"`
+
+	// Violations over the whole session, without fading. Corrections counts
+	// every position correction, not only the ones over the allowance.
+	SpeedViolations    int `json:"speed_violations"`
+	Corrections        int `json:"corrections"`
+	AimSnaps           int `json:"aim_snaps"`
+	ShotRateViolations int `json:"shot_rate_violations"`
+
+	Inputs int `json:"inputs"`
+}
+
+// sample is a claimed position and when it arrived
+type sample struct {
+	at       time.Time
+	position protocol.Vector2
+}
+
+// Player scores one player's session. It is not safe for concurrent use.
+type Player struct {
+	config Config
+
+	moves       []sample // within SpeedWindow, oldest first
+	respawns    []sample // respawn points announced within SpeedWindow
+	corrections []time.Time
+	shots       []time.Time
+
+	aim     float64
+	aimAt   time.Time
+	aimSeen bool
+
+	// recent holds each category's violations, faded to fadedAt
+	recent  [numCategories]float64
+	fadedAt time.Time
+	total   [numCategories]int
+
+	inputs    int
+	corrected int
+
+	// entityID is the player's own entity, 0 until it is known. Only its
+	// respawns excuse a jump.
+	entityID uint16
+	// nearTick is the snapshot being searched for the player's entity, and
+	// near and nearID count the player entities close to the player in it
+	nearTick uint32
+	near     int
+	nearID   uint16
+}
+
+// NewPlayer starts scoring a player with no history
+func NewPlayer(config Config) *Player {
+	return &Player{config: config}
+}
+
+// Packet records a packet seen at at, in either direction. Packets the
+// analysis does not use are ignored.
+func (p *Player) Packet(at time.Time, packet protocol.Packet) {
+	switch packet := packet.(type) {
+	case *protocol.PlayerInput:
+		p.Input(at, packet)
+	case *protocol.ActionConfirm:
+		p.Confirm(at, packet)
+	case *protocol.GameEvent:
+		p.Event(at, packet)
+	case *protocol.StateUpdate:
+		p.State(at, packet)
+	}
+}
+
+// SetEntityID tells the analysis which entity is the player
+func (p *Player) SetEntityID(id uint16) {
+	p.entityID = id
+}
+
+// EntityID returns the player's entity, or 0 if it is not known yet
+func (p *Player) EntityID() uint16 {
+	return p.entityID
+}
+
+// State records a STATE_UPDATE from the server, or a snapshot decoded from
+// STATE_DELTAs. Until the player's entity is known, it is learned from the
+// snapshots: once a whole tick has arrived with exactly one player entity
+// within PositionTolerance of the player's latest position, that entity is
+// the player. Chunks of one tick may arrive as separate updates.
+func (p *Player) State(at time.Time, update *protocol.StateUpdate) {
+	if p.entityID != 0 || len(p.moves) == 0 {
+		return
+	}
+	if update.ServerTick != p.nearTick {
+		if p.near == 1 {
+			p.entityID = p.nearID
+			return
+		}
+		p.nearTick, p.near = update.ServerTick, 0
+	}
+	position := p.moves[len(p.moves)-1].position
+	for _, entity := range update.Entities {
+		if entity.EntityType == protocol.EntityPlayer && distance(entity.Position, position) <= p.config.PositionTolerance {
+			p.near++
+			p.nearID = entity.EntityID
+		}
+	}
+}
+
+// Input records a PLAYER_INPUT from the client
+func (p *Player) Input(at time.Time, input *protocol.PlayerInput) {
+	p.inputs++
+	p.checkSpeed(at, input.Position)
+	shooting := input.InputFlags.Has(protocol.InputShoot)
+	p.checkAim(at, input.AimAngle, shooting)
+	if shooting {
+		p.checkShotRate(at)
+	}
+}
+
+// Confirm records an ACTION_CONFIRM from the server
+func (p *Player) Confirm(at time.Time, confirm *protocol.ActionConfirm) {
+	switch {
+	case confirm.ActionType == protocol.ActionMove && confirm.ResultCode == protocol.ResultFailedInvalidPosition:
+		p.corrected++
+		p.corrections = append(dropBefore(p.corrections, at.Add(-p.config.CorrectionWindow)), at)
+		if len(p.corrections) > p.config.MaxCorrections {
+			p.violate(at, Corrections)
+		}
+		// Inputs sent before the client heard of the correction are still
+		// on their way, so movement starts over
+		p.moves = p.moves[:0]
+	case confirm.ActionType == protocol.ActionShoot && confirm.ResultCode == protocol.ResultFailedCooldown:
+		p.violate(at, ShotRate)
+	}
+}
+
+// Event records a GAME_EVENT from the server. The player's own respawns
+// excuse a jump to the respawn point; until the player's entity is known,
+// no respawn does.
+func (p *Player) Event(at time.Time, event *protocol.GameEvent) {
+	if event.EventType != protocol.EventRespawn || p.entityID == 0 || event.TargetID != p.entityID {
+		return
+	}
+	cutoff := at.Add(-p.config.SpeedWindow)
+	kept := p.respawns[:0]
+	for _, respawn := range p.respawns {
+		if respawn.at.After(cutoff) {
+			kept = append(kept, respawn)
+		}
+	}
+	p.respawns = append(kept, sample{at: at, position: event.Position})
+}
+
+func (p *Player) checkSpeed(at time.Time, position protocol.Vector2) {
+	// The newest sample from before the window starts the span checked
+	cutoff := at.Add(-p.config.SpeedWindow)
+	start := 0
+	for start+1 < len(p.moves) && !p.moves[start+1].at.After(cutoff) {
+		start++
+	}
+	p.moves = append(p.moves[:0], p.moves[start:]...)
+
+	if len(p.moves) > 0 {
+		from := p.moves[0]
+		elapsed := float32(at.Sub(from.at).Seconds())
+		if distance(from.position, position) > p.config.MaxSpeed*elapsed+p.config.PositionTolerance {
+			if !p.respawnedAt(at, position) {
+				p.violate(at, Speed)
+			}
+			// Movement is measured from where the player landed
+			p.moves = p.moves[:0]
+		}
+	}
+	p.moves = append(p.moves, sample{at: at, position: position})
+}
+
+// respawnedAt reports whether the server recently announced a respawn at
+// position
+func (p *Player) respawnedAt(at time.Time, position protocol.Vector2) bool {
+	cutoff := at.Add(-p.config.SpeedWindow)
+	for _, respawn := range p.respawns {
+		if respawn.at.After(cutoff) && distance(respawn.position, position) <= p.config.PositionTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Player) checkAim(at time.Time, angle float64, shooting bool) {
+	if shooting && p.aimSeen && at.Sub(p.aimAt) <= p.config.SnapInterval && turn(p.aim, angle) >= p.config.SnapAngle {
+		p.violate(at, AimSnaps)
+	}
+	p.aim, p.aimAt, p.aimSeen = angle, at, true
+}
+
+func (p *Player) checkShotRate(at time.Time) {
+	p.shots = append(dropBefore(p.shots, at.Add(-p.config.ShotWindow)), at)
+	if len(p.shots) > p.config.MaxShotInputs {
+		p.violate(at, ShotRate)
+		// One violation per burst
+		p.shots = p.shots[:0]
+	}
+}
+
+func (p *Player) violate(at time.Time, c Category) {
+	p.fade(at)
+	p.recent[c]++
+	p.total[c]++
+}
+
+// fade decays the recent violations to at. Packets from the two directions
+// can arrive slightly out of order, so time never goes backwards.
+func (p *Player) fade(at time.Time) {
+	if !at.After(p.fadedAt) {
+		return
+	}
+	if !p.fadedAt.IsZero() {
+		factor := math.Exp2(-float64(at.Sub(p.fadedAt)) / float64(p.config.HalfLife))
+		for c := range p.recent {
+			p.recent[c] *= factor
+		}
+	}
+	p.fadedAt = at
+}
+
+// Report returns the player's scores as of at
+func (p *Player) Report(at time.Time) Report {
+	p.fade(at)
+	var scores [numCategories]float64
+	innocent := 1.0
+	for c := range scores {
+		scores[c] = 1 - math.Exp2(-p.recent[c]/halfScores[c])
+		innocent *= 1 - scores[c]
+	}
+	return Report{
+		Score:              percent(1 - innocent),
+		SpeedScore:         percent(scores[Speed]),
+		CorrectionScore:    percent(scores[Corrections]),
+		AimSnapScore:       percent(scores[AimSnaps]),
+		ShotRateScore:      percent(scores[ShotRate]),
+		SpeedViolations:    p.total[Speed],
+		Corrections:        p.corrected,
+		AimSnaps:           p.total[AimSnaps],
+		ShotRateViolations: p.total[ShotRate],
+		Inputs:             p.inputs,
+	}
+}
+
+func percent(f float64) int {
+	return int(math.Round(f * 100))
+}
+
+// dropBefore removes the times before cutoff from the front of times
+func dropBefore(times []time.Time, cutoff time.Time) []time.Time {
+	start := 0
+	for start < len(times) && times[start].Before(cutoff) {
+		start++
+	}
+	return append(times[:0], times[start:]...)
+}
+
+func distance(a, b protocol.Vector2) float32 {
+	return float32(math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y)))
+}
+
+// turn returns the smallest angle between two aim angles, in radians
+func turn(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 2*math.Pi)
+	return min(d, 2*math.Pi-d)
+}
@@ -0,0 +1,358 @@
+package anticheat_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/omega-realm/api/pkg/anticheat"
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+const inputInterval = 100 * time.Millisecond
+
+// playerID is the session player's entity, and otherID another player's
+const (
+	playerID uint16 = 7
+	otherID  uint16 = 8
+)
+
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// session simulates a player sending input at 10Hz, with arrival times
+// jittered as on a real connection
+type session struct {
+	player   *anticheat.Player
+	rng      *rand.Rand
+	now      time.Time
+	position protocol.Vector2
+	heading  float64
+	aim      float64
+	sequence uint8
+	tick     uint32
+
+	// speed is how fast the player moves, in units per second
+	speed float64
+	// shooting is the chance each input holds the shoot flag
+	shooting float64
+	// snap turns the aim this far, in radians, before every shot
+	snap float64
+	// interval is the time between inputs
+	interval time.Duration
+}
+
+func newSession(seed uint64) *session {
+	return &session{
+		player:   anticheat.NewPlayer(anticheat.DefaultConfig()),
+		rng:      rand.New(rand.NewPCG(seed, 1)),
+		now:      start,
+		speed:    320,
+		shooting: 0.5,
+		interval: inputInterval,
+	}
+}
+
+// step sends one input
+func (s *session) step() {
+	s.now = s.now.Add(s.interval)
+	s.heading += (s.rng.Float64() - 0.5) * 0.6
+	dt := s.interval.Seconds()
+	s.position.X += float32(math.Cos(s.heading) * s.speed * dt)
+	s.position.Y += float32(math.Sin(s.heading) * s.speed * dt)
+
+	var flags protocol.InputFlags
+	if s.rng.Float64() < s.shooting {
+		flags |= protocol.InputShoot
+		s.aim += s.snap
+	}
+	// A human sweeps the aim smoothly
+	s.aim += (s.rng.Float64() - 0.5) * 0.8
+
+	jitter := time.Duration((s.rng.Float64() - 0.5) * float64(40*time.Millisecond))
+	s.player.Input(s.now.Add(jitter), &protocol.PlayerInput{
+		Position:       s.position,
+		InputFlags:     flags | protocol.InputSprint,
+		AimAngle:       s.aim,
+		SequenceNumber: s.sequence,
+	})
+	s.sequence++
+
+	// The server broadcasts the player and someone else far away
+	s.tick++
+	s.player.State(s.now, &protocol.StateUpdate{ServerTick: s.tick, Entities: []protocol.EntityState{
+		{EntityID: otherID, EntityType: protocol.EntityPlayer, Position: protocol.Vector2{X: s.position.X + 500, Y: s.position.Y}},
+		{EntityID: playerID, EntityType: protocol.EntityPlayer, Position: s.position},
+	}})
+}
+
+func (s *session) run(d time.Duration) {
+	for end := s.now.Add(d); s.now.Before(end); {
+		s.step()
+	}
+}
+
+func (s *session) correct() {
+	s.player.Confirm(s.now, &protocol.ActionConfirm{
+		SequenceNumber:    s.sequence,
+		ActionType:        protocol.ActionMove,
+		CorrectedPosition: s.position,
+		ResultCode:        protocol.ResultFailedInvalidPosition,
+	})
+}
+
+func (s *session) respawn(position protocol.Vector2) {
+	s.player.Event(s.now, &protocol.GameEvent{EventType: protocol.EventRespawn, TargetID: playerID, Position: position})
+	s.position = position
+}
+
+func TestHonestPlayer(t *testing.T) {
+	s := newSession(1)
+	for minute := 0; minute < 10; minute++ {
+		s.run(25 * time.Second)
+		// Lag gets a player corrected now and then
+		s.correct()
+		s.run(25 * time.Second)
+		s.correct()
+		// and they die
+		s.respawn(protocol.Vector2{X: float32(s.rng.IntN(2000) - 1000), Y: float32(s.rng.IntN(2000) - 1000)})
+		s.run(10 * time.Second)
+	}
+
+	report := s.player.Report(s.now)
+	if report.Score > 10 {
+		t.Errorf("honest player scored %+v", report)
+	}
+	if report.Corrections != 20 {
+		t.Errorf("Corrections = %d, want 20", report.Corrections)
+	}
+	if report.Inputs != 6000 {
+		t.Errorf("Inputs = %d, want 6000", report.Inputs)
+	}
+}
+
+func TestCheats(t *testing.T) {
+	tests := []struct {
+		name   string
+		cheat  func(s *session)
+		scored func(r anticheat.Report) int
+	}{
+		{"speed hack", func(s *session) {
+			s.speed = 500
+			s.run(time.Minute)
+		}, func(r anticheat.Report) int { return r.SpeedScore }},
+		{"teleports", func(s *session) {
+			for range 5 {
+				s.run(10 * time.Second)
+				s.position.X += 800
+				s.step()
+			}
+		}, func(r anticheat.Report) int { return r.SpeedScore }},
+		{"aimbot", func(s *session) {
+			s.shooting = 0.2
+			s.snap = 2.5
+			s.run(time.Minute)
+		}, func(r anticheat.Report) int { return r.AimSnapScore }},
+		{"rapid fire", func(s *session) {
+			s.shooting = 1
+			s.interval = 40 * time.Millisecond
+			s.run(time.Minute)
+		}, func(r anticheat.Report) int { return r.ShotRateScore }},
+		{"constant corrections", func(s *session) {
+			for range 20 {
+				s.run(3 * time.Second)
+				s.correct()
+			}
+		}, func(r anticheat.Report) int { return r.CorrectionScore }},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSession(uint64(i) + 10)
+			tt.cheat(s)
+			report := s.player.Report(s.now)
+			if got := tt.scored(report); got < 80 {
+				t.Errorf("category score = %d, want at least 80 (%+v)", got, report)
+			}
+			if report.Score < tt.scored(report) {
+				t.Errorf("Score = %d, below its category's", report.Score)
+			}
+		})
+	}
+}
+
+func TestRespawnExcusesJump(t *testing.T) {
+	s := newSession(2)
+	s.run(5 * time.Second)
+	s.respawn(protocol.Vector2{X: s.position.X + 900, Y: s.position.Y})
+	s.run(5 * time.Second)
+	if report := s.player.Report(s.now); report.SpeedViolations != 0 {
+		t.Errorf("respawn counted as %d speed violations", report.SpeedViolations)
+	}
+
+	// A respawn far away excuses nothing
+	s.respawn(s.position)
+	s.position.X -= 900
+	s.run(time.Second)
+	if report := s.player.Report(s.now); report.SpeedViolations != 1 {
+		t.Errorf("SpeedViolations = %d after a teleport, want 1", report.SpeedViolations)
+	}
+
+	// Nor does another player's respawn at the point the player jumps to
+	target := protocol.Vector2{X: s.position.X + 900, Y: s.position.Y}
+	s.player.Event(s.now, &protocol.GameEvent{EventType: protocol.EventRespawn, TargetID: otherID, Position: target})
+	s.position = target
+	s.run(time.Second)
+	if report := s.player.Report(s.now); report.SpeedViolations != 2 {
+		t.Errorf("SpeedViolations = %d after another player's respawn, want 2", report.SpeedViolations)
+	}
+}
+
+func TestEntityIDFromSnapshots(t *testing.T) {
+	here := protocol.Vector2{X: 100, Y: 100}
+	player := func(id uint16, position protocol.Vector2) protocol.EntityState {
+		return protocol.EntityState{EntityID: id, EntityType: protocol.EntityPlayer, Position: position}
+	}
+	monster := protocol.EntityState{EntityID: 3, EntityType: protocol.EntityMonster, Position: here}
+	far := protocol.Vector2{X: 600, Y: 100}
+
+	tests := []struct {
+		name    string
+		moved   bool
+		updates []protocol.StateUpdate
+		want    uint16
+	}{
+		{
+			name:  "Learned once the tick is complete",
+			moved: true,
+			updates: []protocol.StateUpdate{
+				{ServerTick: 1, Entities: []protocol.EntityState{monster, player(otherID, far), player(playerID, here)}},
+				{ServerTick: 2},
+			},
+			want: playerID,
+		},
+		{
+			name:  "Not before the tick is complete",
+			moved: true,
+			updates: []protocol.StateUpdate{
+				{ServerTick: 1, Entities: []protocol.EntityState{player(playerID, here)}},
+			},
+		},
+		{
+			name: "Not before the player has moved",
+			updates: []protocol.StateUpdate{
+				{ServerTick: 1, Entities: []protocol.EntityState{player(playerID, here)}},
+				{ServerTick: 2},
+			},
+		},
+		{
+			name:  "Not while two players stand together",
+			moved: true,
+			updates: []protocol.StateUpdate{
+				{ServerTick: 1, Entities: []protocol.EntityState{player(otherID, here), player(playerID, here)}},
+				{ServerTick: 2},
+			},
+		},
+		{
+			name:  "Not while two players stand together in different chunks",
+			moved: true,
+			updates: []protocol.StateUpdate{
+				{ServerTick: 1, Entities: []protocol.EntityState{player(playerID, here)}},
+				{ServerTick: 1, Entities: []protocol.EntityState{player(otherID, here)}},
+				{ServerTick: 2},
+			},
+		},
+		{
+			name:  "Until they part",
+			moved: true,
+			updates: []protocol.StateUpdate{
+				{ServerTick: 1, Entities: []protocol.EntityState{player(otherID, here), player(playerID, here)}},
+				{ServerTick: 2, Entities: []protocol.EntityState{player(otherID, far), player(playerID, here)}},
+				{ServerTick: 3},
+			},
+			want: playerID,
+		},
+		{
+			name:  "Monsters do not count",
+			moved: true,
+			updates: []protocol.StateUpdate{
+				{ServerTick: 1, Entities: []protocol.EntityState{monster}},
+				{ServerTick: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player := anticheat.NewPlayer(anticheat.DefaultConfig())
+			if tt.moved {
+				player.Input(start, &protocol.PlayerInput{Position: here})
+			}
+			for _, update := range tt.updates {
+				player.State(start, &update)
+			}
+			if got := player.EntityID(); got != tt.want {
+				t.Errorf("EntityID() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespawnBeforeEntityIDIsKnown(t *testing.T) {
+	player := anticheat.NewPlayer(anticheat.DefaultConfig())
+	player.Input(start, &protocol.PlayerInput{})
+	target := protocol.Vector2{X: 900}
+	player.Event(start, &protocol.GameEvent{EventType: protocol.EventRespawn, TargetID: playerID, Position: target})
+	player.Input(start.Add(inputInterval), &protocol.PlayerInput{Position: target})
+	if report := player.Report(start.Add(inputInterval)); report.SpeedViolations != 1 {
+		t.Errorf("SpeedViolations = %d, want 1", report.SpeedViolations)
+	}
+
+	// Once the entity is known, its respawns excuse the jump back
+	player.SetEntityID(playerID)
+	player.Event(start.Add(inputInterval), &protocol.GameEvent{EventType: protocol.EventRespawn, TargetID: playerID})
+	player.Input(start.Add(2*inputInterval), &protocol.PlayerInput{})
+	if report := player.Report(start.Add(2 * inputInterval)); report.SpeedViolations != 1 {
+		t.Errorf("SpeedViolations = %d after the player's own respawn, want 1", report.SpeedViolations)
+	}
+}
+
+func TestScoresFade(t *testing.T) {
+	s := newSession(3)
+	for range 4 {
+		s.run(10 * time.Second)
+		s.position.X += 800
+		s.step()
+	}
+	before := s.player.Report(s.now)
+
+	later := s.player.Report(s.now.Add(time.Hour))
+	if later.Score >= before.Score/10 {
+		t.Errorf("Score %d an hour after %d, want it mostly faded", later.Score, before.Score)
+	}
+	if later.SpeedViolations != before.SpeedViolations {
+		t.Errorf("SpeedViolations changed from %d to %d", before.SpeedViolations, later.SpeedViolations)
+	}
+}
+
+func TestCooldownRefusals(t *testing.T) {
+	player := anticheat.NewPlayer(anticheat.DefaultConfig())
+	for i := range 3 {
+		player.Packet(start.Add(time.Duration(i)*time.Second), &protocol.ActionConfirm{
+			ActionType: protocol.ActionShoot,
+			ResultCode: protocol.ResultFailedCooldown,
+		})
+	}
+	// Packets the analysis does not use are ignored
+	player.Packet(start, &protocol.Heartbeat{})
+	if report := player.Report(start.Add(3 * time.Second)); report.ShotRateViolations != 3 || report.ShotRateScore < 60 {
+		t.Errorf("report after 3 cooldown refusals = %+v", report)
+	}
+}
+
+func BenchmarkInput(b *testing.B) {
+	s := newSession(4)
+	b.ReportAllocs()
+	for b.Loop() {
+		s.step()
+	}
+}
@@ -0,0 +1,31 @@
+// Package anticheat scores how likely a player is to be cheating from the
+// packets they exchange with a game server. It sees the same traffic the
+// server does, so it can run wherever both directions pass: cmd/gateway
+// scores every live session, and cmd/replay scores recordings.
+//
+// A Player is fed the client's PLAYER_INPUTs and the server's ACTION_CONFIRMs,
+// GAME_EVENTs and snapshots, each with the time it was seen, and counts
+// violations in four categories:
+//
+//   - Speed: the client's claimed position moved further than
+//     Config.MaxSpeed allows over Config.SpeedWindow, plus
+//     Config.PositionTolerance for latency. Jumps to a point where the server
+//     respawned the player, and the first input after a correction, are
+//     excused. The player's entity is learned from the snapshots, so other
+//     players' respawns excuse nothing.
+//   - Corrections: the server sent more than Config.MaxCorrections position
+//     corrections (ACTION_CONFIRM with FAILED_INVALID_POSITION) within
+//     Config.CorrectionWindow. Lag causes a few; every one past that counts.
+//   - AimSnaps: an input firing a shot turned the aim by at least
+//     Config.SnapAngle since an input at most Config.SnapInterval earlier.
+//   - ShotRate: more than Config.MaxShotInputs inputs carrying the shoot flag
+//     arrived within Config.ShotWindow, or the server refused a shot on
+//     cooldown.
+//
+// Violations fade with Config.HalfLife, so a player who lagged badly an hour
+// ago is not still under suspicion. Each category scores 0 to 100, reaching 50
+// at a category's own number of recent violations, and the overall score
+// combines them so that several weak signals add up. Scores are leads for
+// moderators to review, not proof: a player who circles the cursor around
+// their character can snap their aim too.
+package anticheat
@@ -0,0 +1,27 @@
+// Package interest decides which entities each player is sent, so a state
+// broadcast only carries what a player can see instead of every entity on
+// the map.
+//
+// Entities live in a Grid, a uniform grid of square cells over the map.
+// Moving an entity only touches the grid when it crosses into another cell,
+// and finding the entities in a rectangle only visits the cells it overlaps.
+//
+// A Manager holds one Viewport per player. On each Update it rebuilds every
+// player's visible set from the grid and reports what changed as Enter and
+// Leave events, which a server turns into spawns and despawns on the client.
+// Visibility has hysteresis: an entity enters once it is inside the
+// viewport, but only leaves once it is further than Config.Margin outside
+// it, so an entity walking along the edge does not flicker in and out.
+//
+// This is the reference implementation for the Godot server's interest
+// management. The rules it must match are:
+//
+//   - An entity is inside a viewport when |x - center.x| <= half.x and
+//     |y - center.y| <= half.y, edges included
+//   - A visible entity stays visible while it is inside the viewport grown by
+//     Margin on every side, and an entity that is not visible needs to be
+//     inside the viewport itself to enter
+//   - Removed entities leave every player that could see them
+//   - Events are ordered by viewer ID, then entity ID, and a player added
+//     with AddViewer gets an Enter for everything it already sees
+package interest
@@ -0,0 +1,217 @@
+package interest
+
+import (
+	"errors"
+	"math"
+
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+// Errors returned for an invalid Config
+var (
+	ErrInvalidCellSize = errors.New("interest: cell size must be positive")
+	ErrInvalidBounds   = errors.New("interest: bounds must have a positive size")
+	ErrInvalidMargin   = errors.New("interest: margin cannot be negative")
+)
+
+// Config sizes a grid and the hysteresis of visibility
+type Config struct {
+	// Min and Max are the corners of the area the grid covers. Entities
+	// outside it are still tracked, in the nearest edge cell.
+	Min, Max protocol.Vector2
+
+	// CellSize is the side of one square cell. Around the size of a
+	// viewport's half extents keeps queries to a few cells.
+	CellSize float32
+
+	// Margin is how far outside a viewport a visible entity can go before it
+	// leaves
+	Margin float32
+}
+
+// DefaultConfig covers the arena (GameConstants.MAP_MIN and MAP_MAX) with
+// 125 unit cells and a margin of twice the player hitbox
+func DefaultConfig() Config {
+	return Config{
+		Min:      protocol.Vector2{X: -1000, Y: -1000},
+		Max:      protocol.Vector2{X: 1000, Y: 1000},
+		CellSize: 125,
+		Margin:   32,
+	}
+}
+
+func (c Config) validate() error {
+	if !(c.CellSize > 0) {
+		return ErrInvalidCellSize
+	}
+	if !(c.Max.X > c.Min.X) || !(c.Max.Y > c.Min.Y) {
+		return ErrInvalidBounds
+	}
+	if !(c.Margin >= 0) {
+		return ErrInvalidMargin
+	}
+	return nil
+}
+
+// Rect is an axis-aligned rectangle, edges included
+type Rect struct {
+	Min, Max protocol.Vector2
+}
+
+// Contains reports whether p is inside r
+func (r Rect) Contains(p protocol.Vector2) bool {
+	return p.X >= r.Min.X && p.X <= r.Max.X && p.Y >= r.Min.Y && p.Y <= r.Max.Y
+}
+
+// cellEntry is an entity in a cell. Positions are kept in the cells so a
+// query reads them without a lookup.
+type cellEntry struct {
+	id       uint16
+	position protocol.Vector2
+}
+
+// gridEntity is where an entity is stored
+type gridEntity struct {
+	cell int
+	slot int
+}
+
+// Grid is a uniform grid of entity positions. It is not safe for concurrent
+// use.
+type Grid struct {
+	min        protocol.Vector2
+	cellSize   float32
+	cols, rows int
+	cells      [][]cellEntry
+	entities   map[uint16]*gridEntity
+}
+
+// NewGrid creates an empty grid
+func NewGrid(config Config) (*Grid, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	cols := int(math.Ceil(float64((config.Max.X - config.Min.X) / config.CellSize)))
+	rows := int(math.Ceil(float64((config.Max.Y - config.Min.Y) / config.CellSize)))
+	return &Grid{
+		min:      config.Min,
+		cellSize: config.CellSize,
+		cols:     cols,
+		rows:     rows,
+		cells:    make([][]cellEntry, cols*rows),
+		entities: make(map[uint16]*gridEntity),
+	}, nil
+}
+
+// Len returns the number of entities in the grid
+func (g *Grid) Len() int {
+	return len(g.entities)
+}
+
+// column returns the column x falls in, clamped to the grid
+func (g *Grid) column(x float32) int {
+	return clampIndex((x-g.min.X)/g.cellSize, g.cols)
+}
+
+func (g *Grid) row(y float32) int {
+	return clampIndex((y-g.min.Y)/g.cellSize, g.rows)
+}
+
+func clampIndex(f float32, n int) int {
+	switch {
+	case !(f >= 0): // negative or NaN
+		return 0
+	case f >= float32(n):
+		return n - 1
+	}
+	return int(f)
+}
+
+// Set adds an entity or moves it to position
+func (g *Grid) Set(id uint16, position protocol.Vector2) {
+	cell := g.row(position.Y)*g.cols + g.column(position.X)
+	e, ok := g.entities[id]
+	if !ok {
+		e = &gridEntity{cell: -1}
+		g.entities[id] = e
+	}
+	if e.cell == cell {
+		g.cells[cell][e.slot].position = position
+		return
+	}
+	if e.cell >= 0 {
+		g.unlink(e)
+	}
+	e.cell = cell
+	e.slot = len(g.cells[cell])
+	g.cells[cell] = append(g.cells[cell], cellEntry{id: id, position: position})
+}
+
+// Remove removes an entity. Removing one that is not in the grid does
+// nothing.
+func (g *Grid) Remove(id uint16) {
+	e, ok := g.entities[id]
+	if !ok {
+		return
+	}
+	g.unlink(e)
+	delete(g.entities, id)
+}
+
+// unlink takes e out of its cell by moving the cell's last entity into its
+// slot
+func (g *Grid) unlink(e *gridEntity) {
+	entries := g.cells[e.cell]
+	last := entries[len(entries)-1]
+	entries[e.slot] = last
+	g.entities[last.id].slot = e.slot
+	g.cells[e.cell] = entries[:len(entries)-1]
+}
+
+// Position returns an entity's position
+func (g *Grid) Position(id uint16) (protocol.Vector2, bool) {
+	e, ok := g.entities[id]
+	if !ok {
+		return protocol.Vector2{}, false
+	}
+	return g.cells[e.cell][e.slot].position, true
+}
+
+// appendEntries appends the entries inside r to dst, in no particular order
+func (g *Grid) appendEntries(dst []cellEntry, r Rect) []cellEntry {
+	if !(r.Max.X >= r.Min.X) || !(r.Max.Y >= r.Min.Y) {
+		return dst
+	}
+	minCol, maxCol := g.column(r.Min.X), g.column(r.Max.X)
+	minRow, maxRow := g.row(r.Min.Y), g.row(r.Max.Y)
+	for row := minRow; row <= maxRow; row++ {
+		for _, entries := range g.cells[row*g.cols+minCol : row*g.cols+maxCol+1] {
+			for _, entry := range entries {
+				if r.Contains(entry.position) {
+					dst = append(dst, entry)
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// AppendInRect appends the IDs of the entities inside r to dst, in no
+// particular order
+func (g *Grid) AppendInRect(dst []uint16, r Rect) []uint16 {
+	if !(r.Max.X >= r.Min.X) || !(r.Max.Y >= r.Min.Y) {
+		return dst
+	}
+	minCol, maxCol := g.column(r.Min.X), g.column(r.Max.X)
+	minRow, maxRow := g.row(r.Min.Y), g.row(r.Max.Y)
+	for row := minRow; row <= maxRow; row++ {
+		for _, entries := range g.cells[row*g.cols+minCol : row*g.cols+maxCol+1] {
+			for _, entry := range entries {
+				if r.Contains(entry.position) {
+					dst = append(dst, entry.id)
+				}
+			}
+		}
+	}
+	return dst
+}
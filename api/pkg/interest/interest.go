@@ -0,0 +1,191 @@
+package interest
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+// ViewerID identifies a player's viewport, such as its connection or
+// entity ID
+type ViewerID uint32
+
+// Viewport is the area a player sees, centred on its camera
+type Viewport struct {
+	Center protocol.Vector2
+	// Half is half the viewport's width and height
+	Half protocol.Vector2
+}
+
+// Rect returns the viewport grown by margin on every side
+func (v Viewport) Rect(margin float32) Rect {
+	return Rect{
+		Min: protocol.Vector2{X: v.Center.X - v.Half.X - margin, Y: v.Center.Y - v.Half.Y - margin},
+		Max: protocol.Vector2{X: v.Center.X + v.Half.X + margin, Y: v.Center.Y + v.Half.Y + margin},
+	}
+}
+
+// EventKind says whether an entity became visible or stopped being visible
+type EventKind uint8
+
+// Event kinds
+const (
+	Enter EventKind = 1
+	Leave EventKind = 2
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Enter:
+		return "ENTER"
+	case Leave:
+		return "LEAVE"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", uint8(k))
+	}
+}
+
+// Event is a change to one player's visible set
+type Event struct {
+	Viewer ViewerID
+	Entity uint16
+	Kind   EventKind
+}
+
+// viewer is one player's viewport and what it saw on the last Update
+type viewer struct {
+	id       ViewerID
+	viewport Viewport
+	visible  []uint16
+	next     []uint16
+}
+
+// Manager tracks entity positions and player viewports, and works out who
+// sees what. It is not safe for concurrent use.
+type Manager struct {
+	grid    *Grid
+	margin  float32
+	viewers []*viewer // sorted by ID
+	scratch []cellEntry
+
+	// seen[id] == stamp marks the entities the viewer being updated could
+	// see, without clearing a set per viewer
+	seen  []uint32
+	stamp uint32
+}
+
+// NewManager creates a manager with no entities or viewers
+func NewManager(config Config) (*Manager, error) {
+	grid, err := NewGrid(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{grid: grid, margin: config.Margin, seen: make([]uint32, 1<<16)}, nil
+}
+
+// Grid returns the grid of entity positions, for queries such as who is
+// near an explosion
+func (m *Manager) Grid() *Grid {
+	return m.grid
+}
+
+// SetEntity adds an entity or moves it. Visibility changes on the next
+// Update.
+func (m *Manager) SetEntity(id uint16, position protocol.Vector2) {
+	m.grid.Set(id, position)
+}
+
+// RemoveEntity removes an entity. Players that could see it get a Leave on
+// the next Update.
+func (m *Manager) RemoveEntity(id uint16) {
+	m.grid.Remove(id)
+}
+
+func (m *Manager) find(id ViewerID) (int, bool) {
+	return slices.BinarySearchFunc(m.viewers, id, func(v *viewer, id ViewerID) int { return cmp.Compare(v.id, id) })
+}
+
+// SetViewer adds a player's viewport or moves it. A new viewer sees nothing
+// until the next Update, which sends an Enter for everything in view.
+func (m *Manager) SetViewer(id ViewerID, viewport Viewport) {
+	i, ok := m.find(id)
+	if ok {
+		m.viewers[i].viewport = viewport
+		return
+	}
+	m.viewers = slices.Insert(m.viewers, i, &viewer{id: id, viewport: viewport})
+}
+
+// RemoveViewer forgets a player. No Leave events are sent, since there is no
+// one left to send them to.
+func (m *Manager) RemoveViewer(id ViewerID) {
+	if i, ok := m.find(id); ok {
+		m.viewers = slices.Delete(m.viewers, i, i+1)
+	}
+}
+
+// Visible returns the entities a player saw on the last Update, in no
+// particular order. The slice is only valid until the next Update.
+func (m *Manager) Visible(id ViewerID) []uint16 {
+	if i, ok := m.find(id); ok {
+		return m.viewers[i].visible
+	}
+	return nil
+}
+
+// IsVisible reports whether a player saw an entity on the last Update
+func (m *Manager) IsVisible(id ViewerID, entity uint16) bool {
+	return slices.Contains(m.Visible(id), entity)
+}
+
+// Update recomputes every player's visible set and appends the changes
+// since the last Update to events
+func (m *Manager) Update(events []Event) []Event {
+	for _, v := range m.viewers {
+		events = m.updateViewer(events, v)
+	}
+	return events
+}
+
+func (m *Manager) updateViewer(events []Event, v *viewer) []Event {
+	// Each viewer gets two fresh stamps, so nothing needs clearing between
+	// viewers
+	if m.stamp >= math.MaxUint32-2 {
+		clear(m.seen)
+		m.stamp = 0
+	}
+	m.stamp += 2
+	wasVisible, kept := m.stamp, m.stamp+1
+	for _, id := range v.visible {
+		m.seen[id] = wasVisible
+	}
+
+	// A visible entity stays while it is inside the margin, and a new one
+	// must be inside the viewport
+	start := len(events)
+	inner := v.viewport.Rect(0)
+	candidates := m.grid.appendEntries(m.scratch[:0], v.viewport.Rect(m.margin))
+	m.scratch = candidates
+	next := v.next[:0]
+	for _, c := range candidates {
+		if m.seen[c.id] == wasVisible {
+			m.seen[c.id] = kept
+			next = append(next, c.id)
+		} else if inner.Contains(c.position) {
+			next = append(next, c.id)
+			events = append(events, Event{Viewer: v.id, Entity: c.id, Kind: Enter})
+		}
+	}
+	for _, id := range v.visible {
+		if m.seen[id] != kept {
+			events = append(events, Event{Viewer: v.id, Entity: id, Kind: Leave})
+		}
+	}
+	slices.SortFunc(events[start:], func(a, b Event) int { return cmp.Compare(a.Entity, b.Entity) })
+
+	v.visible, v.next = next, v.visible
+	return events
+}
@@ -0,0 +1,340 @@
+package interest_test
+
+import (
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/omega-realm/api/pkg/interest"
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+// A 1280x720 window
+var testHalf = protocol.Vector2{X: 640, Y: 360}
+
+func vec(x, y float32) protocol.Vector2 {
+	return protocol.Vector2{X: x, Y: y}
+}
+
+func newManager(t testing.TB) *interest.Manager {
+	t.Helper()
+	m, err := interest.NewManager(interest.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *interest.Config)
+		want   error
+	}{
+		{"zero cell size", func(c *interest.Config) { c.CellSize = 0 }, interest.ErrInvalidCellSize},
+		{"empty bounds", func(c *interest.Config) { c.Max.X = c.Min.X }, interest.ErrInvalidBounds},
+		{"negative margin", func(c *interest.Config) { c.Margin = -1 }, interest.ErrInvalidMargin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := interest.DefaultConfig()
+			tt.modify(&config)
+			if _, err := interest.NewManager(config); !errors.Is(err, tt.want) {
+				t.Fatalf("NewManager = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrid(t *testing.T) {
+	grid, err := interest.NewGrid(interest.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid.Set(1, vec(0, 0))
+	grid.Set(2, vec(100, 100))
+	grid.Set(3, vec(-5000, 5000)) // outside the bounds, kept in a corner cell
+	grid.Set(4, vec(999, -999))
+	grid.Set(2, vec(400, 100)) // moves to another cell
+	grid.Remove(4)
+	grid.Remove(99)
+
+	query := func(r interest.Rect) []uint16 {
+		ids := grid.AppendInRect(nil, r)
+		slices.Sort(ids)
+		return ids
+	}
+	if got := query(interest.Rect{Min: vec(-10, -10), Max: vec(500, 500)}); !reflect.DeepEqual(got, []uint16{1, 2}) {
+		t.Errorf("query around the origin = %v, want [1 2]", got)
+	}
+	if got := query(interest.Rect{Min: vec(-6000, 4000), Max: vec(-4000, 6000)}); !reflect.DeepEqual(got, []uint16{3}) {
+		t.Errorf("query outside the bounds = %v, want [3]", got)
+	}
+	if got := query(interest.Rect{Min: vec(900, -1000), Max: vec(1000, -900)}); len(got) != 0 {
+		t.Errorf("query for a removed entity = %v, want none", got)
+	}
+	if position, ok := grid.Position(2); !ok || position != vec(400, 100) {
+		t.Errorf("Position(2) = %v, %v", position, ok)
+	}
+	if grid.Len() != 3 {
+		t.Errorf("Len = %d, want 3", grid.Len())
+	}
+}
+
+func TestHysteresis(t *testing.T) {
+	m := newManager(t)
+	m.SetViewer(1, interest.Viewport{Center: vec(0, 0), Half: testHalf})
+	m.SetEntity(10, vec(650, 0)) // just outside
+
+	steps := []struct {
+		x    float32
+		want []interest.Event
+	}{
+		// Inside the margin but never visible: stays out
+		{650, nil},
+		// On the edge: enters
+		{640, []interest.Event{{Viewer: 1, Entity: 10, Kind: interest.Enter}}},
+		// Back out, but within the 32 unit margin: stays
+		{660, nil},
+		{672, nil},
+		// Past the margin: leaves
+		{673, []interest.Event{{Viewer: 1, Entity: 10, Kind: interest.Leave}}},
+		// Within the margin again: does not re-enter
+		{660, nil},
+	}
+	for _, step := range steps {
+		m.SetEntity(10, vec(step.x, 0))
+		if got := m.Update(nil); !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("at x=%v events = %v, want %v", step.x, got, step.want)
+		}
+	}
+}
+
+func TestViewersAndRemovals(t *testing.T) {
+	m := newManager(t)
+	m.SetEntity(1, vec(0, 0))
+	m.SetEntity(2, vec(500, 0))
+	m.SetEntity(3, vec(-500, 0))
+	m.SetViewer(20, interest.Viewport{Center: vec(400, 0), Half: testHalf})
+	m.SetViewer(10, interest.Viewport{Center: vec(-400, 0), Half: testHalf})
+
+	want := []interest.Event{
+		{Viewer: 10, Entity: 1, Kind: interest.Enter},
+		{Viewer: 10, Entity: 3, Kind: interest.Enter},
+		{Viewer: 20, Entity: 1, Kind: interest.Enter},
+		{Viewer: 20, Entity: 2, Kind: interest.Enter},
+	}
+	if got := m.Update(nil); !reflect.DeepEqual(got, want) {
+		t.Fatalf("first Update = %v, want %v", got, want)
+	}
+	if got := slices.Sorted(slices.Values(m.Visible(20))); !reflect.DeepEqual(got, []uint16{1, 2}) {
+		t.Fatalf("Visible(20) = %v", got)
+	}
+
+	m.RemoveEntity(1)
+	m.RemoveViewer(20)
+	want = []interest.Event{{Viewer: 10, Entity: 1, Kind: interest.Leave}}
+	if got := m.Update(nil); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Update after removals = %v, want %v", got, want)
+	}
+	if m.IsVisible(10, 1) || !m.IsVisible(10, 3) || m.Visible(20) != nil {
+		t.Fatal("visible sets not updated after removals")
+	}
+}
+
+// reference is the rules in the package documentation, applied to every
+// entity for every viewer
+type reference struct {
+	margin   float32
+	entities map[uint16]protocol.Vector2
+	viewers  map[interest.ViewerID]interest.Viewport
+	visible  map[interest.ViewerID]map[uint16]bool
+}
+
+func (r *reference) update() []interest.Event {
+	var events []interest.Event
+	viewerIDs := make([]interest.ViewerID, 0, len(r.viewers))
+	for id := range r.viewers {
+		viewerIDs = append(viewerIDs, id)
+	}
+	slices.Sort(viewerIDs)
+
+	for _, viewerID := range viewerIDs {
+		viewport := r.viewers[viewerID]
+		old := r.visible[viewerID]
+		next := make(map[uint16]bool)
+		for id := uint16(0); id < 1000; id++ {
+			position, ok := r.entities[id]
+			switch {
+			case ok && old[id] && viewport.Rect(r.margin).Contains(position):
+				next[id] = true
+			case ok && !old[id] && viewport.Rect(0).Contains(position):
+				next[id] = true
+				events = append(events, interest.Event{Viewer: viewerID, Entity: id, Kind: interest.Enter})
+			case old[id]:
+				events = append(events, interest.Event{Viewer: viewerID, Entity: id, Kind: interest.Leave})
+			}
+		}
+		r.visible[viewerID] = next
+	}
+	return events
+}
+
+func TestManagerMatchesReference(t *testing.T) {
+	m := newManager(t)
+	ref := &reference{
+		margin:   interest.DefaultConfig().Margin,
+		entities: make(map[uint16]protocol.Vector2),
+		viewers:  make(map[interest.ViewerID]interest.Viewport),
+		visible:  make(map[interest.ViewerID]map[uint16]bool),
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	random := func() protocol.Vector2 {
+		return vec(float32(rng.Float64()*2400-1200), float32(rng.Float64()*2400-1200))
+	}
+
+	for tick := 0; tick < 300; tick++ {
+		for i := 0; i < 40; i++ {
+			id := uint16(rng.IntN(1000))
+			switch position, ok := ref.entities[id]; {
+			case ok && rng.Float64() < 0.05:
+				m.RemoveEntity(id)
+				delete(ref.entities, id)
+			case ok:
+				position.X += float32(rng.Float64()*80 - 40)
+				position.Y += float32(rng.Float64()*80 - 40)
+				m.SetEntity(id, position)
+				ref.entities[id] = position
+			default:
+				position = random()
+				m.SetEntity(id, position)
+				ref.entities[id] = position
+			}
+		}
+
+		id := interest.ViewerID(rng.IntN(20))
+		switch _, ok := ref.viewers[id]; {
+		case ok && rng.Float64() < 0.1:
+			m.RemoveViewer(id)
+			delete(ref.viewers, id)
+			delete(ref.visible, id)
+		default:
+			viewport := interest.Viewport{Center: random(), Half: testHalf}
+			m.SetViewer(id, viewport)
+			ref.viewers[id] = viewport
+		}
+
+		got, want := m.Update(nil), ref.update()
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("tick %d: events differ from the reference:\n got %v\nwant %v", tick, got, want)
+		}
+	}
+}
+
+// arena is the 100 players and 200 monsters of the benchmarks, moving at
+// GameConstants speeds with 10Hz ticks
+type arena struct {
+	rng       *rand.Rand
+	positions []protocol.Vector2
+	headings  []protocol.Vector2
+}
+
+const (
+	arenaPlayers  = 100
+	arenaMonsters = 200
+)
+
+func newArena() *arena {
+	a := &arena{rng: rand.New(rand.NewPCG(3, 4))}
+	for i := 0; i < arenaPlayers+arenaMonsters; i++ {
+		a.positions = append(a.positions, vec(float32(a.rng.Float64()*2000-1000), float32(a.rng.Float64()*2000-1000)))
+		a.headings = append(a.headings, protocol.Vector2{})
+	}
+	return a
+}
+
+// step moves everyone one tick: players at PLAYER_SPEED, monsters at
+// MONSTER_SPEED, turning now and then and staying on the map
+func (a *arena) step() {
+	for i := range a.positions {
+		speed := float32(20)
+		if i >= arenaPlayers {
+			speed = 12
+		}
+		if a.headings[i] == (protocol.Vector2{}) || a.rng.Float64() < 0.05 {
+			a.headings[i] = vec(float32(a.rng.Float64()*2-1)*speed, float32(a.rng.Float64()*2-1)*speed)
+		}
+		p := &a.positions[i]
+		p.X = min(max(p.X+a.headings[i].X, -1000), 1000)
+		p.Y = min(max(p.Y+a.headings[i].Y, -1000), 1000)
+	}
+}
+
+// BenchmarkManager100x200 is one broadcast tick: every entity moves, then
+// every player's visible set is recomputed. sent-% is the share of entities
+// a player is sent compared with broadcasting all of them.
+func BenchmarkManager100x200(b *testing.B) {
+	m := newManager(b)
+	a := newArena()
+	var events []interest.Event
+	visible, changes := 0, 0
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.step()
+		for id, position := range a.positions {
+			m.SetEntity(uint16(id), position)
+		}
+		for id := 0; id < arenaPlayers; id++ {
+			m.SetViewer(interest.ViewerID(id), interest.Viewport{Center: a.positions[id], Half: testHalf})
+		}
+		events = m.Update(events[:0])
+		changes += len(events)
+		for id := 0; id < arenaPlayers; id++ {
+			visible += len(m.Visible(interest.ViewerID(id)))
+		}
+	}
+	b.ReportMetric(float64(visible)/float64(b.N*arenaPlayers), "visible/player")
+	b.ReportMetric(float64(changes)/float64(b.N), "events/tick")
+	b.ReportMetric(float64(visible)/float64(b.N*arenaPlayers*(arenaPlayers+arenaMonsters))*100, "sent-%")
+}
+
+// BenchmarkBruteForce100x200 checks every entity against every viewport, the
+// O(n²) pattern ARCHITECTURE.md warns about, for comparison. It does less
+// than the manager: no hysteresis and no events.
+func BenchmarkBruteForce100x200(b *testing.B) {
+	a := newArena()
+	visible := make([]uint16, 0, arenaPlayers+arenaMonsters)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.step()
+		for player := 0; player < arenaPlayers; player++ {
+			r := interest.Viewport{Center: a.positions[player], Half: testHalf}.Rect(0)
+			visible = visible[:0]
+			for id, position := range a.positions {
+				if r.Contains(position) {
+					visible = append(visible, uint16(id))
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkGridSet(b *testing.B) {
+	grid, err := interest.NewGrid(interest.DefaultConfig())
+	if err != nil {
+		b.Fatal(err)
+	}
+	a := newArena()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%len(a.positions) == 0 {
+			a.step()
+		}
+		id := i % len(a.positions)
+		grid.Set(uint16(id), a.positions[id])
+	}
+}
@@ -0,0 +1,310 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Quantization scales, matching PacketWriter and PacketReader
+const (
+	PositionScale = 100.0 // 0.01 unit precision, range ±327.67
+	VelocityScale = 10.0  // 0.1 unit precision, range ±3276.7
+	AngleScale    = 100.0 // 0.01 radian precision
+)
+
+// Errors returned while encoding and decoding
+var (
+	ErrShortBuffer     = errors.New("protocol: buffer too short")
+	ErrUnknownType     = errors.New("protocol: unknown packet type")
+	ErrPayloadTooLarge = errors.New("protocol: payload exceeds 65535 bytes")
+	ErrStringTooLong   = errors.New("protocol: string exceeds 65535 bytes")
+	ErrTooManyEntities = errors.New("protocol: state update exceeds 255 entities")
+	ErrUnexpectedType  = errors.New("protocol: packet is not of the expected type")
+	ErrInvalidDelta    = errors.New("protocol: malformed state delta")
+)
+
+// Vector2 is a 2D position or velocity
+type Vector2 struct {
+	X float32 `json:"x"`
+	Y float32 `json:"y"`
+}
+
+// quantize converts v to fixed point the way the client does: truncate
+// towards zero, then clamp to the s16 range
+func quantize(v, scale float64) int16 {
+	q := math.Trunc(v * scale)
+	switch {
+	case math.IsNaN(q):
+		return 0
+	case q < math.MinInt16:
+		return math.MinInt16
+	case q > math.MaxInt16:
+		return math.MaxInt16
+	}
+	return int16(q)
+}
+
+// Writer appends little-endian values to a byte slice. The first error is
+// kept and reported by Err; writes after it are still appended so sizes stay
+// predictable.
+type Writer struct {
+	buf []byte
+	err error
+}
+
+// NewWriter creates a writer that appends to buf
+func NewWriter(buf []byte) *Writer {
+	return &Writer{buf: buf}
+}
+
+// Reset makes the writer append to buf and clears its error
+func (w *Writer) Reset(buf []byte) {
+	w.buf = buf
+	w.err = nil
+}
+
+// Bytes returns everything written so far
+func (w *Writer) Bytes() []byte {
+	return w.buf
+}
+
+// Len returns the number of bytes written so far
+func (w *Writer) Len() int {
+	return len(w.buf)
+}
+
+// Err returns the first error encountered while writing
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) setErr(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// WriteU8 writes an unsigned 8-bit integer
+func (w *Writer) WriteU8(v uint8) {
+	w.buf = append(w.buf, v)
+}
+
+// WriteS8 writes a signed 8-bit integer
+func (w *Writer) WriteS8(v int8) {
+	w.buf = append(w.buf, uint8(v))
+}
+
+// WriteU16 writes an unsigned 16-bit integer
+func (w *Writer) WriteU16(v uint16) {
+	w.buf = binary.LittleEndian.AppendUint16(w.buf, v)
+}
+
+// WriteS16 writes a signed 16-bit integer
+func (w *Writer) WriteS16(v int16) {
+	w.buf = binary.LittleEndian.AppendUint16(w.buf, uint16(v))
+}
+
+// WriteU32 writes an unsigned 32-bit integer
+func (w *Writer) WriteU32(v uint32) {
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, v)
+}
+
+// WriteS32 writes a signed 32-bit integer
+func (w *Writer) WriteS32(v int32) {
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, uint32(v))
+}
+
+// WriteFloat32 writes an IEEE 754 single
+func (w *Writer) WriteFloat32(v float32) {
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, math.Float32bits(v))
+}
+
+// WriteFloat64 writes an IEEE 754 double
+func (w *Writer) WriteFloat64(v float64) {
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(v))
+}
+
+// WriteBool writes a boolean as one byte
+func (w *Writer) WriteBool(v bool) {
+	if v {
+		w.WriteU8(1)
+	} else {
+		w.WriteU8(0)
+	}
+}
+
+// WriteString writes a [u16 length][utf8 bytes] string
+func (w *Writer) WriteString(s string) {
+	if len(s) > math.MaxUint16 {
+		w.setErr(ErrStringTooLong)
+		s = s[:math.MaxUint16]
+	}
+	w.WriteU16(uint16(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// WriteBytes writes raw bytes
+func (w *Writer) WriteBytes(b []byte) {
+	w.buf = append(w.buf, b...)
+}
+
+// WriteVector2 writes a position quantized at PositionScale (4 bytes)
+func (w *Writer) WriteVector2(v Vector2) {
+	w.WriteS16(quantize(float64(v.X), PositionScale))
+	w.WriteS16(quantize(float64(v.Y), PositionScale))
+}
+
+// WriteVelocity writes a velocity quantized at VelocityScale (4 bytes)
+func (w *Writer) WriteVelocity(v Vector2) {
+	w.WriteS16(quantize(float64(v.X), VelocityScale))
+	w.WriteS16(quantize(float64(v.Y), VelocityScale))
+}
+
+// WriteAngle writes an angle in radians quantized at AngleScale (2 bytes)
+func (w *Writer) WriteAngle(radians float64) {
+	w.WriteS16(quantize(radians, AngleScale))
+}
+
+// Reader reads little-endian values from a byte slice. A read past the end
+// returns zero and sets ErrShortBuffer, which Err reports; callers check it
+// once after a sequence of reads.
+type Reader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+// NewReader creates a reader over buf
+func NewReader(buf []byte) *Reader {
+	return &Reader{buf: buf}
+}
+
+// Reset makes the reader read buf from the start and clears its error
+func (r *Reader) Reset(buf []byte) {
+	r.buf = buf
+	r.pos = 0
+	r.err = nil
+}
+
+// Err returns ErrShortBuffer if any read ran past the end of the buffer
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Remaining returns the number of unread bytes
+func (r *Reader) Remaining() int {
+	return len(r.buf) - r.pos
+}
+
+// Position returns the current read offset
+func (r *Reader) Position() int {
+	return r.pos
+}
+
+// next returns the next n bytes, or nil after recording ErrShortBuffer
+func (r *Reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.buf)-r.pos {
+		r.err = ErrShortBuffer
+		r.pos = len(r.buf)
+		return nil
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// ReadU8 reads an unsigned 8-bit integer
+func (r *Reader) ReadU8() uint8 {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+// ReadS8 reads a signed 8-bit integer
+func (r *Reader) ReadS8() int8 {
+	return int8(r.ReadU8())
+}
+
+// ReadU16 reads an unsigned 16-bit integer
+func (r *Reader) ReadU16() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+// ReadS16 reads a signed 16-bit integer
+func (r *Reader) ReadS16() int16 {
+	return int16(r.ReadU16())
+}
+
+// ReadU32 reads an unsigned 32-bit integer
+func (r *Reader) ReadU32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+// ReadS32 reads a signed 32-bit integer
+func (r *Reader) ReadS32() int32 {
+	return int32(r.ReadU32())
+}
+
+// ReadFloat32 reads an IEEE 754 single
+func (r *Reader) ReadFloat32() float32 {
+	return math.Float32frombits(r.ReadU32())
+}
+
+// ReadFloat64 reads an IEEE 754 double
+func (r *Reader) ReadFloat64() float64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+// ReadBool reads a one byte boolean
+func (r *Reader) ReadBool() bool {
+	return r.ReadU8() != 0
+}
+
+// ReadString reads a [u16 length][utf8 bytes] string
+func (r *Reader) ReadString() string {
+	n := r.ReadU16()
+	if n == 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+// ReadBytes returns the next n bytes. The slice aliases the reader's buffer.
+func (r *Reader) ReadBytes(n int) []byte {
+	return r.next(n)
+}
+
+// ReadVector2 reads a position quantized at PositionScale
+func (r *Reader) ReadVector2() Vector2 {
+	x, y := r.ReadS16(), r.ReadS16()
+	return Vector2{X: float32(float64(x) / PositionScale), Y: float32(float64(y) / PositionScale)}
+}
+
+// ReadVelocity reads a velocity quantized at VelocityScale
+func (r *Reader) ReadVelocity() Vector2 {
+	x, y := r.ReadS16(), r.ReadS16()
+	return Vector2{X: float32(float64(x) / VelocityScale), Y: float32(float64(y) / VelocityScale)}
+}
+
+// ReadAngle reads an angle in radians quantized at AngleScale
+func (r *Reader) ReadAngle() float64 {
+	return float64(r.ReadS16()) / AngleScale
+}
@@ -0,0 +1,397 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// DeltaHistory is how many snapshots each side of a delta stream keeps. A
+// client whose last ack is older than this gets a full snapshot instead of a
+// delta.
+const DeltaHistory = 32
+
+// Errors returned by DeltaEncoder and DeltaDecoder
+var (
+	ErrDeltaTick       = errors.New("protocol: delta ticks must be nonzero and increasing")
+	ErrDuplicateEntity = errors.New("protocol: entity appears twice in a snapshot")
+	ErrUnknownBaseline = errors.New("protocol: state delta baseline is not held")
+)
+
+// deltaEntity is an entity as both sides hold it in their history: quantized,
+// so the encoder diffs exactly what the decoder will have
+type deltaEntity struct {
+	id             uint16
+	entityType     EntityType
+	x, y           int16
+	animationState AnimationState
+	flags          EntityFlags
+}
+
+// deltaSnapshot is one tick's entities, sorted by ID
+type deltaSnapshot struct {
+	tick     uint32
+	entities []deltaEntity
+}
+
+// deltaHistory is a ring of the last DeltaHistory snapshots, indexed by tick
+type deltaHistory [DeltaHistory]deltaSnapshot
+
+func (h *deltaHistory) get(tick uint32) *deltaSnapshot {
+	s := &h[tick%DeltaHistory]
+	if tick == 0 || s.tick != tick {
+		return nil
+	}
+	return s
+}
+
+// put stores entities as tick's snapshot, reusing the slot's slice, and
+// returns the stored copy
+func (h *deltaHistory) put(tick uint32, entities []deltaEntity) []deltaEntity {
+	s := &h[tick%DeltaHistory]
+	s.tick = tick
+	s.entities = append(s.entities[:0], entities...)
+	return s.entities
+}
+
+// DeltaEncoder turns a server's per-tick entity lists into state deltas for
+// one client. Each snapshot is diffed against the newest one the client has
+// acknowledged, so a lost delta never needs resending: the next one is
+// simply diffed against an older baseline. It is not safe for concurrent use.
+type DeltaEncoder struct {
+	history  deltaHistory
+	acked    uint32
+	lastSent uint32
+	current  []deltaEntity
+}
+
+// NewDeltaEncoder creates an encoder for a client with no baseline
+func NewDeltaEncoder() *DeltaEncoder {
+	return &DeltaEncoder{}
+}
+
+// Ack records a STATE_ACK from the client. Acks for ticks that were never
+// sent, or that are older than the current baseline, are ignored.
+func (e *DeltaEncoder) Ack(tick uint32) {
+	if tick > e.acked && tick <= e.lastSent && e.history.get(tick) != nil {
+		e.acked = tick
+	}
+}
+
+// Baseline returns the tick the next delta will be diffed against, or 0 if
+// the next one will be a full snapshot
+func (e *DeltaEncoder) Baseline() uint32 {
+	if e.acked == 0 || e.lastSent-e.acked >= DeltaHistory || e.history.get(e.acked) == nil {
+		return 0
+	}
+	return e.acked
+}
+
+// Encode diffs tick's entities against the client's baseline and returns
+// the packets to send, in chunk order. Ticks must increase from call to
+// call. There is always at least one packet, so the client can ack a tick in
+// which nothing changed.
+func (e *DeltaEncoder) Encode(tick uint32, entities []EntityState) ([]*StateDelta, error) {
+	if tick == 0 || tick <= e.lastSent {
+		return nil, fmt.Errorf("%w: %d after %d", ErrDeltaTick, tick, e.lastSent)
+	}
+
+	current := e.current[:0]
+	for i := range entities {
+		entity := &entities[i]
+		current = append(current, deltaEntity{
+			id:             entity.EntityID,
+			entityType:     entity.EntityType,
+			x:              quantize(float64(entity.Position.X), PositionScale),
+			y:              quantize(float64(entity.Position.Y), PositionScale),
+			animationState: entity.AnimationState,
+			flags:          entity.Flags,
+		})
+	}
+	slices.SortFunc(current, func(a, b deltaEntity) int { return int(a.id) - int(b.id) })
+	e.current = current
+	for i := 1; i < len(current); i++ {
+		if current[i].id == current[i-1].id {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateEntity, current[i].id)
+		}
+	}
+
+	var base []deltaEntity
+	baseline := e.Baseline()
+	if baseline != 0 {
+		base = e.history.get(baseline).entities
+	}
+	changes, removed := diffSnapshots(base, current)
+
+	chunks := max((len(changes)+MaxEntities-1)/MaxEntities, (len(removed)+MaxEntities-1)/MaxEntities, 1)
+	if chunks > math.MaxUint8 {
+		return nil, ErrTooManyEntities
+	}
+
+	packets := make([]*StateDelta, chunks)
+	for i := range packets {
+		packets[i] = &StateDelta{
+			ServerTick:   tick,
+			BaselineTick: baseline,
+			Chunk:        uint8(i),
+			ChunkCount:   uint8(chunks),
+			Entities:     chunkOf(changes, i),
+			Removed:      chunkOf(removed, i),
+		}
+	}
+
+	e.history.put(tick, current)
+	e.lastSent = tick
+	return packets, nil
+}
+
+// chunkOf returns the i-th MaxEntities-sized chunk of items, which is empty
+// past the end
+func chunkOf[T any](items []T, i int) []T {
+	start := min(i*MaxEntities, len(items))
+	end := min(start+MaxEntities, len(items))
+	return items[start:end:end]
+}
+
+// diffSnapshots returns the changes that turn base into current, and the IDs
+// in base that current no longer has. Both inputs are sorted by ID.
+func diffSnapshots(base, current []deltaEntity) (changes []EntityDelta, removed []uint16) {
+	changes = make([]EntityDelta, 0)
+	removed = make([]uint16, 0)
+	i := 0
+	for _, c := range current {
+		for i < len(base) && base[i].id < c.id {
+			removed = append(removed, base[i].id)
+			i++
+		}
+
+		change := EntityDelta{
+			EntityID:       c.id,
+			EntityType:     c.entityType,
+			X:              c.x,
+			Y:              c.y,
+			AnimationState: c.animationState,
+			Flags:          c.flags,
+		}
+		if i == len(base) || base[i].id != c.id {
+			change.Mask = DeltaFull
+			changes = append(changes, change)
+			continue
+		}
+
+		b := base[i]
+		i++
+		if c.entityType != b.entityType {
+			change.Mask |= DeltaEntityType
+		}
+		if dx, dy := int(c.x)-int(b.x), int(c.y)-int(b.y); dx != 0 || dy != 0 {
+			if dx >= math.MinInt8 && dx <= math.MaxInt8 && dy >= math.MinInt8 && dy <= math.MaxInt8 {
+				change.Mask |= DeltaPositionNudge
+				change.X, change.Y = int16(dx), int16(dy)
+			} else {
+				change.Mask |= DeltaPosition
+			}
+		}
+		if c.animationState != b.animationState {
+			change.Mask |= DeltaAnimation
+		}
+		if c.flags != b.flags {
+			change.Mask |= DeltaFlags
+		}
+		if change.Mask != 0 {
+			changes = append(changes, change)
+		}
+	}
+	for ; i < len(base); i++ {
+		removed = append(removed, base[i].id)
+	}
+	return changes, removed
+}
+
+// DeltaDecoder rebuilds snapshots from the state deltas of one server. After
+// each complete snapshot the client sends a STATE_ACK for its tick. It is not
+// safe for concurrent use.
+type DeltaDecoder struct {
+	history deltaHistory
+	latest  uint32
+
+	// The snapshot whose chunks are arriving. Chunks of one tick arrive in
+	// one frame or in order, so only the newest tick is kept; a newer tick
+	// abandons an incomplete one.
+	pending        uint32
+	pendingBase    uint32
+	pendingCount   uint8
+	pendingChunks  [4]uint64
+	pendingChanges []EntityDelta
+	pendingRemoved []uint16
+	scratch        []deltaEntity
+}
+
+// NewDeltaDecoder creates a decoder with no snapshots
+func NewDeltaDecoder() *DeltaDecoder {
+	return &DeltaDecoder{}
+}
+
+// Latest returns the tick of the newest complete snapshot, or 0 if there is
+// none
+func (d *DeltaDecoder) Latest() uint32 {
+	return d.latest
+}
+
+// Apply adds one packet to the snapshot it belongs to. Once every chunk of
+// a snapshot has arrived, complete is true and entities is the whole
+// snapshot, sorted by ID. Packets older than the latest snapshot are
+// ignored. ErrUnknownBaseline means the server and client disagree about
+// the baseline; the client should keep acking Latest until a full snapshot
+// arrives.
+func (d *DeltaDecoder) Apply(p *StateDelta) (entities []EntityState, complete bool, err error) {
+	if p.ServerTick == 0 || p.ChunkCount == 0 || p.Chunk >= p.ChunkCount ||
+		(p.BaselineTick != 0 && p.BaselineTick >= p.ServerTick) {
+		return nil, false, ErrInvalidDelta
+	}
+	if p.ServerTick <= d.latest || p.ServerTick < d.pending {
+		return nil, false, nil
+	}
+	if p.BaselineTick != 0 && d.history.get(p.BaselineTick) == nil {
+		return nil, false, fmt.Errorf("%w: %d", ErrUnknownBaseline, p.BaselineTick)
+	}
+
+	if p.ServerTick != d.pending {
+		d.pending = p.ServerTick
+		d.pendingBase = p.BaselineTick
+		d.pendingCount = p.ChunkCount
+		d.pendingChunks = [4]uint64{}
+		d.pendingChanges = d.pendingChanges[:0]
+		d.pendingRemoved = d.pendingRemoved[:0]
+	} else if p.BaselineTick != d.pendingBase || p.ChunkCount != d.pendingCount {
+		return nil, false, ErrInvalidDelta
+	}
+
+	word, bit := p.Chunk/64, uint64(1)<<(p.Chunk%64)
+	if d.pendingChunks[word]&bit != 0 {
+		return nil, false, nil
+	}
+	d.pendingChunks[word] |= bit
+	d.pendingChanges = append(d.pendingChanges, p.Entities...)
+	d.pendingRemoved = append(d.pendingRemoved, p.Removed...)
+
+	received := 0
+	for _, w := range d.pendingChunks {
+		received += bits.OnesCount64(w)
+	}
+	if received < int(d.pendingCount) {
+		return nil, false, nil
+	}
+
+	tick := d.pending
+	d.pending = 0
+	var base []deltaEntity
+	if d.pendingBase != 0 {
+		base = d.history.get(d.pendingBase).entities
+	}
+	snapshot, err := applyDelta(d.scratch[:0], base, d.pendingChanges, d.pendingRemoved)
+	d.scratch = snapshot
+	if err != nil {
+		return nil, false, err
+	}
+
+	stored := d.history.put(tick, snapshot)
+	d.latest = tick
+
+	entities = make([]EntityState, len(stored))
+	for i, e := range stored {
+		entities[i] = EntityState{
+			EntityID:       e.id,
+			EntityType:     e.entityType,
+			Position:       Vector2{X: float32(float64(e.x) / PositionScale), Y: float32(float64(e.y) / PositionScale)},
+			AnimationState: e.animationState,
+			Flags:          e.flags,
+		}
+	}
+	return entities, true, nil
+}
+
+// applyDelta appends base with changes and removals applied to dst. Every
+// ID must be changed or removed at most once, removed IDs must be in base,
+// and entities not in base must carry DeltaFull.
+func applyDelta(dst, base []deltaEntity, changes []EntityDelta, removed []uint16) ([]deltaEntity, error) {
+	slices.SortFunc(changes, func(a, b EntityDelta) int { return int(a.EntityID) - int(b.EntityID) })
+	slices.Sort(removed)
+	for i := 1; i < len(changes); i++ {
+		if changes[i].EntityID == changes[i-1].EntityID {
+			return dst, ErrInvalidDelta
+		}
+	}
+	for i := 1; i < len(removed); i++ {
+		if removed[i] == removed[i-1] {
+			return dst, ErrInvalidDelta
+		}
+	}
+
+	i, j, k := 0, 0, 0
+	for i < len(base) || j < len(changes) {
+		var id uint16
+		switch {
+		case j == len(changes):
+			id = base[i].id
+		case i == len(base):
+			id = changes[j].EntityID
+		default:
+			id = min(base[i].id, changes[j].EntityID)
+		}
+		for k < len(removed) && removed[k] < id {
+			// Removing an entity the baseline does not have
+			return dst, ErrInvalidDelta
+		}
+		isRemoved := k < len(removed) && removed[k] == id
+		if isRemoved {
+			k++
+		}
+
+		var entity deltaEntity
+		inBase := i < len(base) && base[i].id == id
+		if inBase {
+			entity = base[i]
+			i++
+		}
+		if j == len(changes) || changes[j].EntityID != id {
+			if !isRemoved {
+				dst = append(dst, entity)
+			}
+			continue
+		}
+
+		change := &changes[j]
+		j++
+		if isRemoved || !change.Mask.Valid() || (!inBase && change.Mask != DeltaFull) {
+			return dst, ErrInvalidDelta
+		}
+		entity.id = id
+		if change.Mask.Has(DeltaEntityType) {
+			entity.entityType = change.EntityType
+		}
+		if change.Mask.Has(DeltaPosition) {
+			entity.x, entity.y = change.X, change.Y
+		}
+		if change.Mask.Has(DeltaPositionNudge) {
+			x, y := int(entity.x)+int(change.X), int(entity.y)+int(change.Y)
+			if x < math.MinInt16 || x > math.MaxInt16 || y < math.MinInt16 || y > math.MaxInt16 {
+				return dst, ErrInvalidDelta
+			}
+			entity.x, entity.y = int16(x), int16(y)
+		}
+		if change.Mask.Has(DeltaAnimation) {
+			entity.animationState = change.AnimationState
+		}
+		if change.Mask.Has(DeltaFlags) {
+			entity.flags = change.Flags
+		}
+		dst = append(dst, entity)
+	}
+	if k < len(removed) {
+		return dst, ErrInvalidDelta
+	}
+	return dst, nil
+}
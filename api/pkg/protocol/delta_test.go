@@ -0,0 +1,554 @@
+package protocol_test
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/omega-realm/api/pkg/protocol"
+	"github.com/omega-realm/api/pkg/recording"
+)
+
+// The delta benchmarks report the bytes per tick STATE_UPDATE and STATE_DELTA
+// take for the same traffic: a recorded session, and a simulated world with
+// more entities than the recording. testdata/loadbot.orec is 15 seconds of
+// four cmd/loadbot bots, recorded with -offline -bots 4 -duration 15s -record
+// against a stand-in server that broadcasts every player, monster and
+// projectile at 10Hz. To measure another session, record it with cmd/wsproxy
+// or cmd/loadbot -record and run
+//
+//	go test -run NONE -bench Delta ./pkg/protocol/ -delta.recording session.orec
+var deltaRecording = flag.String("delta.recording", "testdata/loadbot.orec", "recording whose STATE_UPDATE traffic the delta benchmarks replay")
+
+// world is a deterministic simulation of the entities a server broadcasts:
+// players and monsters wander, projectiles fly in straight lines, and
+// entities spawn and despawn
+type world struct {
+	rng      *rand.Rand
+	entities []protocol.EntityState
+	velocity map[uint16]protocol.Vector2
+	nextID   uint16
+}
+
+func newWorld(seed uint64, count int) *world {
+	w := &world{rng: rand.New(rand.NewPCG(seed, 0)), velocity: make(map[uint16]protocol.Vector2)}
+	for i := 0; i < count; i++ {
+		w.spawn()
+	}
+	return w
+}
+
+func (w *world) spawn() {
+	w.nextID++
+	entityType := protocol.EntityType(1 + w.rng.IntN(3))
+	w.entities = append(w.entities, protocol.EntityState{
+		EntityID:       w.nextID,
+		EntityType:     entityType,
+		Position:       protocol.Vector2{X: float32(w.rng.Float64()*600 - 300), Y: float32(w.rng.Float64()*600 - 300)},
+		AnimationState: protocol.AnimationSpawn,
+		Flags:          protocol.EntityAlive | protocol.EntityVisible,
+	})
+	speed := 0.3
+	if entityType == protocol.EntityProjectile {
+		speed = 2
+	}
+	w.velocity[w.nextID] = protocol.Vector2{X: float32((w.rng.Float64()*2 - 1) * speed), Y: float32((w.rng.Float64()*2 - 1) * speed)}
+}
+
+// step advances one tick
+func (w *world) step() {
+	kept := w.entities[:0]
+	for _, e := range w.entities {
+		if w.rng.Float64() < 0.01 {
+			delete(w.velocity, e.EntityID)
+			continue
+		}
+		// Players and monsters alternate between standing still and walking
+		v := w.velocity[e.EntityID]
+		if e.EntityType != protocol.EntityProjectile && w.rng.Float64() < 0.05 {
+			if v == (protocol.Vector2{}) {
+				v = protocol.Vector2{X: float32(w.rng.Float64()*0.6 - 0.3), Y: float32(w.rng.Float64()*0.6 - 0.3)}
+			} else {
+				v = protocol.Vector2{}
+			}
+			w.velocity[e.EntityID] = v
+		}
+		e.Position.X = min(max(e.Position.X+v.X, -320), 320)
+		e.Position.Y = min(max(e.Position.Y+v.Y, -320), 320)
+		e.Flags &^= protocol.EntityMoving
+		e.AnimationState = protocol.AnimationIdle
+		if v != (protocol.Vector2{}) {
+			e.Flags |= protocol.EntityMoving
+			e.AnimationState = protocol.AnimationWalk
+		}
+		if w.rng.Float64() < 0.02 {
+			e.AnimationState = protocol.AnimationAttack
+		}
+		kept = append(kept, e)
+	}
+	w.entities = kept
+	for w.rng.Float64() < 0.5 {
+		w.spawn()
+	}
+}
+
+// quantized returns what a client decodes from a STATE_UPDATE of entities,
+// sorted by ID
+func quantized(t testing.TB, entities []protocol.EntityState) []protocol.EntityState {
+	t.Helper()
+	want := make([]protocol.EntityState, 0, len(entities))
+	for start := 0; start < len(entities); start += protocol.MaxEntities {
+		end := min(start+protocol.MaxEntities, len(entities))
+		raw, err := protocol.Encode(&protocol.StateUpdate{Entities: entities[start:end]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := protocol.Decode(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, p.(*protocol.StateUpdate).Entities...)
+	}
+	slices.SortFunc(want, func(a, b protocol.EntityState) int { return int(a.EntityID) - int(b.EntityID) })
+	return want
+}
+
+// sendDelta encodes a packet and decodes it again, as it would cross the wire
+func sendDelta(t testing.TB, p *protocol.StateDelta) *protocol.StateDelta {
+	t.Helper()
+	raw, err := protocol.Encode(p)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if len(raw) != p.Size() {
+		t.Fatalf("encoded %d bytes, Size() = %d", len(raw), p.Size())
+	}
+	var decoded protocol.StateDelta
+	if err := protocol.DecodeInto(raw, &decoded); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	return &decoded
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	encoder := protocol.NewDeltaEncoder()
+	decoder := protocol.NewDeltaDecoder()
+	w := newWorld(1, 200)
+	rng := rand.New(rand.NewPCG(2, 0))
+
+	// Acks take a few ticks to arrive, and both packets and acks get lost
+	const ackDelay = 3
+	acks := make(map[uint32]uint32)
+	completed := 0
+	deltas := 0
+
+	for tick := uint32(1); tick <= 500; tick++ {
+		w.step()
+		if ack, ok := acks[tick]; ok {
+			encoder.Ack(ack)
+		}
+
+		packets, err := encoder.Encode(tick, w.entities)
+		if err != nil {
+			t.Fatalf("tick %d: Encode: %v", tick, err)
+		}
+		if packets[0].BaselineTick != 0 {
+			deltas++
+		}
+		if rng.Float64() < 0.1 {
+			continue
+		}
+
+		for _, p := range packets {
+			entities, complete, err := decoder.Apply(sendDelta(t, p))
+			if err != nil {
+				t.Fatalf("tick %d: Apply: %v", tick, err)
+			}
+			if !complete {
+				continue
+			}
+			completed++
+			if want := quantized(t, w.entities); !reflect.DeepEqual(entities, want) {
+				t.Fatalf("tick %d: snapshot differs from a full state update (%d entities, want %d)", tick, len(entities), len(want))
+			}
+			if rng.Float64() >= 0.1 {
+				acks[tick+ackDelay] = decoder.Latest()
+			}
+		}
+	}
+
+	if completed < 400 {
+		t.Errorf("only %d of 500 snapshots completed", completed)
+	}
+	if deltas < 400 {
+		t.Errorf("only %d of 500 snapshots were deltas", deltas)
+	}
+}
+
+func TestDeltaChunking(t *testing.T) {
+	encoder := protocol.NewDeltaEncoder()
+	decoder := protocol.NewDeltaDecoder()
+	w := newWorld(3, 700)
+
+	packets, err := encoder.Encode(1, w.entities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 3 {
+		t.Fatalf("700 new entities took %d chunks, want 3", len(packets))
+	}
+
+	// Chunks may arrive in any order, and a repeated one is ignored
+	for i, index := range []int{2, 0, 0, 1} {
+		entities, complete, err := decoder.Apply(sendDelta(t, packets[index]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if complete != (i == 3) {
+			t.Fatalf("after chunk %d complete = %v", index, complete)
+		}
+		if complete && !reflect.DeepEqual(entities, quantized(t, w.entities)) {
+			t.Fatal("chunked snapshot differs from the world")
+		}
+	}
+	encoder.Ack(1)
+
+	// Remove 600 entities, more than one chunk's worth of removals
+	remaining := w.entities[600:]
+	packets, err = encoder.Encode(2, remaining)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 3 {
+		t.Fatalf("600 removals took %d chunks, want 3", len(packets))
+	}
+	removed := 0
+	for _, p := range packets {
+		if p.BaselineTick != 1 {
+			t.Fatalf("baseline = %d, want 1", p.BaselineTick)
+		}
+		if len(p.Entities) != 0 {
+			t.Fatalf("unchanged entities were sent: %+v", p.Entities)
+		}
+		removed += len(p.Removed)
+	}
+	if removed != 600 {
+		t.Fatalf("%d entities removed, want 600", removed)
+	}
+
+	var entities []protocol.EntityState
+	for _, p := range packets {
+		entities, _, err = decoder.Apply(sendDelta(t, p))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(entities, quantized(t, remaining)) {
+		t.Fatal("snapshot after removals differs from the world")
+	}
+}
+
+func TestDeltaEncoding(t *testing.T) {
+	encoder := protocol.NewDeltaEncoder()
+	base := []protocol.EntityState{
+		{EntityID: 1, EntityType: protocol.EntityPlayer, Position: protocol.Vector2{X: 10, Y: 10}, Flags: protocol.EntityAlive},
+		{EntityID: 2, EntityType: protocol.EntityMonster, Position: protocol.Vector2{X: -50, Y: 20}, Flags: protocol.EntityAlive},
+		{EntityID: 3, EntityType: protocol.EntityMonster, Position: protocol.Vector2{X: 0, Y: 0}},
+		{EntityID: 4, EntityType: protocol.EntityProjectile, Position: protocol.Vector2{X: 5, Y: 5}},
+	}
+	if _, err := encoder.Encode(10, base); err != nil {
+		t.Fatal(err)
+	}
+	encoder.Ack(10)
+
+	next := []protocol.EntityState{
+		// Moved 0.5 units: a nudge of 50 steps
+		{EntityID: 1, EntityType: protocol.EntityPlayer, Position: protocol.Vector2{X: 10.5, Y: 10}, Flags: protocol.EntityAlive},
+		// Moved 10 units, too far for a nudge, and died
+		{EntityID: 2, EntityType: protocol.EntityMonster, Position: protocol.Vector2{X: -40, Y: 20}, AnimationState: protocol.AnimationDeath},
+		// Unchanged
+		{EntityID: 3, EntityType: protocol.EntityMonster, Position: protocol.Vector2{X: 0, Y: 0}},
+		// 4 is gone and 9 is new
+		{EntityID: 9, EntityType: protocol.EntityProjectile, Position: protocol.Vector2{X: -1, Y: 1}, Flags: protocol.EntityVisible},
+	}
+	packets, err := encoder.Encode(11, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*protocol.StateDelta{{
+		ServerTick:   11,
+		BaselineTick: 10,
+		ChunkCount:   1,
+		Entities: []protocol.EntityDelta{
+			{EntityID: 1, Mask: protocol.DeltaPositionNudge, EntityType: protocol.EntityPlayer, X: 50, Y: 0, Flags: protocol.EntityAlive},
+			{EntityID: 2, Mask: protocol.DeltaPosition | protocol.DeltaAnimation | protocol.DeltaFlags, EntityType: protocol.EntityMonster,
+				X: -4000, Y: 2000, AnimationState: protocol.AnimationDeath},
+			{EntityID: 9, Mask: protocol.DeltaFull, EntityType: protocol.EntityProjectile, X: -100, Y: 100, Flags: protocol.EntityVisible},
+		},
+		Removed: []uint16{4},
+	}}
+	if !reflect.DeepEqual(packets, want) {
+		t.Fatalf("Encode =\n%+v\nwant\n%+v", packets[0], want[0])
+	}
+
+	// Without an ack for tick 11 the next delta is still against tick 10
+	packets, err = encoder.Encode(12, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packets[0].BaselineTick != 10 || len(packets[0].Entities) != 3 {
+		t.Fatalf("unacked delta = %+v, want the tick 11 changes against tick 10", packets[0])
+	}
+	encoder.Ack(12)
+	packets, err = encoder.Encode(13, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packets[0].BaselineTick != 12 || len(packets[0].Entities) != 0 || len(packets[0].Removed) != 0 {
+		t.Fatalf("delta of an unchanged tick = %+v, want an empty one", packets[0])
+	}
+}
+
+func TestDeltaBaselineExpires(t *testing.T) {
+	encoder := protocol.NewDeltaEncoder()
+	entities := newWorld(4, 10).entities
+	// The baseline lasts until the client is DeltaHistory snapshots behind
+	for tick := uint32(1); tick <= protocol.DeltaHistory+2; tick++ {
+		packets, err := encoder.Encode(tick, entities)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tick == 1 {
+			encoder.Ack(1)
+			continue
+		}
+		want := uint32(1)
+		if tick == protocol.DeltaHistory+2 {
+			want = 0
+		}
+		if packets[0].BaselineTick != want {
+			t.Fatalf("tick %d: baseline = %d, want %d", tick, packets[0].BaselineTick, want)
+		}
+	}
+
+	// An ack that was never sent, or older than the baseline, is ignored
+	encoder.Ack(100)
+	encoder.Ack(30)
+	if got := encoder.Baseline(); got != 30 {
+		t.Fatalf("Baseline = %d, want 30", got)
+	}
+	encoder.Ack(20)
+	if got := encoder.Baseline(); got != 30 {
+		t.Fatalf("Baseline after a stale ack = %d, want 30", got)
+	}
+}
+
+func TestDeltaEncoderErrors(t *testing.T) {
+	encoder := protocol.NewDeltaEncoder()
+	if _, err := encoder.Encode(0, nil); !errors.Is(err, protocol.ErrDeltaTick) {
+		t.Errorf("Encode of tick 0 = %v, want ErrDeltaTick", err)
+	}
+	if _, err := encoder.Encode(5, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encoder.Encode(5, nil); !errors.Is(err, protocol.ErrDeltaTick) {
+		t.Errorf("Encode of a repeated tick = %v, want ErrDeltaTick", err)
+	}
+	twice := []protocol.EntityState{{EntityID: 7}, {EntityID: 7}}
+	if _, err := encoder.Encode(6, twice); !errors.Is(err, protocol.ErrDuplicateEntity) {
+		t.Errorf("Encode with a duplicate ID = %v, want ErrDuplicateEntity", err)
+	}
+}
+
+func TestDeltaDecoderErrors(t *testing.T) {
+	full := &protocol.StateDelta{
+		ServerTick: 1,
+		ChunkCount: 1,
+		Entities:   []protocol.EntityDelta{{EntityID: 1, Mask: protocol.DeltaFull, EntityType: protocol.EntityPlayer}},
+	}
+
+	tests := []struct {
+		name string
+		p    *protocol.StateDelta
+		want error
+	}{
+		{"unknown baseline", &protocol.StateDelta{ServerTick: 5, BaselineTick: 4, ChunkCount: 1}, protocol.ErrUnknownBaseline},
+		{"tick 0", &protocol.StateDelta{ChunkCount: 1}, protocol.ErrInvalidDelta},
+		{"baseline after tick", &protocol.StateDelta{ServerTick: 2, BaselineTick: 3, ChunkCount: 1}, protocol.ErrInvalidDelta},
+		{"no chunks", &protocol.StateDelta{ServerTick: 2, BaselineTick: 1}, protocol.ErrInvalidDelta},
+		{"partial new entity", &protocol.StateDelta{ServerTick: 2, BaselineTick: 1, ChunkCount: 1,
+			Entities: []protocol.EntityDelta{{EntityID: 2, Mask: protocol.DeltaPositionNudge}}}, protocol.ErrInvalidDelta},
+		{"removing an unknown entity", &protocol.StateDelta{ServerTick: 2, BaselineTick: 1, ChunkCount: 1,
+			Removed: []uint16{2}}, protocol.ErrInvalidDelta},
+		{"changing a removed entity", &protocol.StateDelta{ServerTick: 2, BaselineTick: 1, ChunkCount: 1,
+			Entities: []protocol.EntityDelta{{EntityID: 1, Mask: protocol.DeltaFlags}}, Removed: []uint16{1}}, protocol.ErrInvalidDelta},
+		{"changing an entity twice", &protocol.StateDelta{ServerTick: 2, BaselineTick: 1, ChunkCount: 1,
+			Entities: []protocol.EntityDelta{{EntityID: 1, Mask: protocol.DeltaFlags}, {EntityID: 1, Mask: protocol.DeltaAnimation}}}, protocol.ErrInvalidDelta},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := protocol.NewDeltaDecoder()
+			if _, complete, err := decoder.Apply(full); err != nil || !complete {
+				t.Fatalf("Apply of a full snapshot = %v, %v", complete, err)
+			}
+			if _, _, err := decoder.Apply(tt.p); !errors.Is(err, tt.want) {
+				t.Fatalf("Apply = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Packets for ticks already completed are ignored
+	decoder := protocol.NewDeltaDecoder()
+	decoder.Apply(full)
+	if entities, complete, err := decoder.Apply(full); entities != nil || complete || err != nil {
+		t.Fatalf("Apply of a stale tick = %v, %v, %v", entities, complete, err)
+	}
+}
+
+// deltaTraffic is a sequence of per-tick entity lists from one connection
+type deltaTraffic [][]protocol.EntityState
+
+// simulatedDeltaTraffic is 600 ticks of a simulated world of 150 entities
+func simulatedDeltaTraffic() []deltaTraffic {
+	traffic := make(deltaTraffic, 0, 600)
+	w := newWorld(5, 150)
+	for i := 0; i < 600; i++ {
+		w.step()
+		traffic = append(traffic, slices.Clone(w.entities))
+	}
+	return []deltaTraffic{traffic}
+}
+
+// recordedDeltaTraffic returns the STATE_UPDATE packets of every
+// server-to-client connection in -delta.recording
+func recordedDeltaTraffic(b *testing.B) []deltaTraffic {
+	b.Helper()
+	reader, err := recording.Open(*deltaRecording)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer reader.Close()
+
+	// Consecutive STATE_UPDATEs for the same tick are chunks of one snapshot
+	connections := make(map[uint32]deltaTraffic)
+	lastTick := make(map[uint32]uint32)
+	var order []uint32
+	var update protocol.StateUpdate
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+		if record.Kind != recording.KindPacket || record.Direction != recording.ServerToClient ||
+			len(record.Data) == 0 || protocol.Type(record.Data[0]) != protocol.TypeStateUpdate {
+			continue
+		}
+		if protocol.DecodeInto(record.Data, &update) != nil {
+			continue
+		}
+		traffic, seen := connections[record.Connection]
+		if !seen {
+			order = append(order, record.Connection)
+		}
+		if seen && len(traffic) > 0 && lastTick[record.Connection] == update.ServerTick {
+			traffic[len(traffic)-1] = append(traffic[len(traffic)-1], update.Entities...)
+		} else {
+			traffic = append(traffic, slices.Clone(update.Entities))
+		}
+		connections[record.Connection] = traffic
+		lastTick[record.Connection] = update.ServerTick
+	}
+
+	all := make([]deltaTraffic, 0, len(order))
+	for _, connection := range order {
+		all = append(all, connections[connection])
+	}
+	if len(all) == 0 {
+		b.Fatalf("%s has no STATE_UPDATE packets", *deltaRecording)
+	}
+	return all
+}
+
+// fullSize is the bytes STATE_UPDATE takes for one tick
+func fullSize(entities []protocol.EntityState) int {
+	size := 0
+	for start := 0; start == 0 || start < len(entities); start += protocol.MaxEntities {
+		end := min(start+protocol.MaxEntities, len(entities))
+		size += (&protocol.StateUpdate{Entities: entities[start:end]}).Size()
+	}
+	return size
+}
+
+// benchmarkDelta replays traffic through an encoder and decoder, acking each
+// snapshot ackDelay ticks after it is sent, and reports the bytes per tick
+// of STATE_UPDATE against STATE_DELTA, counting the client's STATE_ACKs
+// against the delta
+func benchmarkDelta(b *testing.B, traffic []deltaTraffic, ackDelay int) {
+	var ticks, full, delta int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ticks, full, delta = 0, 0, 0
+		for _, connection := range traffic {
+			encoder := protocol.NewDeltaEncoder()
+			decoder := protocol.NewDeltaDecoder()
+			acks := make([]uint32, len(connection)+ackDelay+1)
+			buf := make([]byte, 0, protocol.MaxPacketSize)
+			for index, entities := range connection {
+				tick := uint32(index + 1)
+				if acks[tick] != 0 {
+					encoder.Ack(acks[tick])
+					delta += protocol.HeaderSize + 4
+				}
+				packets, err := encoder.Encode(tick, entities)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, p := range packets {
+					buf, err = protocol.AppendPacket(buf[:0], p)
+					if err != nil {
+						b.Fatal(err)
+					}
+					delta += len(buf)
+					if _, complete, err := decoder.Apply(p); err != nil {
+						b.Fatal(err)
+					} else if complete {
+						acks[int(tick)+ackDelay] = tick
+					}
+				}
+				full += fullSize(entities)
+				ticks++
+			}
+		}
+	}
+	b.ReportMetric(float64(full)/float64(ticks), "full-B/tick")
+	b.ReportMetric(float64(delta)/float64(ticks), "delta-B/tick")
+	b.ReportMetric(100*(1-float64(delta)/float64(full)), "saved-%")
+}
+
+// benchmarkDeltaTraffic runs benchmarkDelta on the recording, then on the
+// simulated world
+func benchmarkDeltaTraffic(b *testing.B, ackDelay int) {
+	b.Run("recorded", func(b *testing.B) {
+		benchmarkDelta(b, recordedDeltaTraffic(b), ackDelay)
+	})
+	b.Run("simulated", func(b *testing.B) {
+		benchmarkDelta(b, simulatedDeltaTraffic(), ackDelay)
+	})
+}
+
+// An ack arrives the tick after its snapshot, as on a LAN
+func BenchmarkDeltaLowLatency(b *testing.B) {
+	benchmarkDeltaTraffic(b, 1)
+}
+
+// An ack takes 3 ticks, a 300ms round trip at 10Hz
+func BenchmarkDeltaHighLatency(b *testing.B) {
+	benchmarkDeltaTraffic(b, 3)
+}
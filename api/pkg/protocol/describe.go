@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseType looks a packet type up by its name, e.g. "STATE_UPDATE". The
+// match is case-insensitive.
+func ParseType(name string) (Type, bool) {
+	name = strings.ToUpper(name)
+	for t := TypePlayerInput; t <= TypeStateAck; t++ {
+		if t.String() == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// Describe renders a packet's fields on one line for logs and debugging
+// tools. Tokens are shortened so they do not end up in logs whole.
+func Describe(p Packet) string {
+	switch p := p.(type) {
+	case *PlayerInput:
+		return fmt.Sprintf("seq=%d pos=%s vel=%s flags=%08b aim=%.2f",
+			p.SequenceNumber, describeVector(p.Position), describeVector(p.Velocity), uint8(p.InputFlags), p.AimAngle)
+	case *StateUpdate:
+		parts := make([]string, 0, len(p.Entities))
+		for _, e := range p.Entities {
+			parts = append(parts, fmt.Sprintf("#%d(type=%d pos=%s anim=%d flags=%06b)",
+				e.EntityID, e.EntityType, describeVector(e.Position), e.AnimationState, uint8(e.Flags)))
+		}
+		return fmt.Sprintf("tick=%d entities=%d %s", p.ServerTick, len(p.Entities), strings.Join(parts, " "))
+	case *GameEvent:
+		detail := ""
+		switch p.EventType {
+		case EventDamage:
+			detail = fmt.Sprintf(" amount=%d damage_type=%d", p.Amount, p.DamageType)
+		case EventRespawn:
+			detail = " pos=" + describeVector(p.Position)
+		case EventEffectApply:
+			detail = fmt.Sprintf(" effect=%d duration=%dms", p.EffectID, p.DurationMs)
+		case EventEffectRemove:
+			detail = fmt.Sprintf(" effect=%d", p.EffectID)
+		default:
+			if len(p.Data) > 0 {
+				detail = fmt.Sprintf(" data=% x", p.Data)
+			}
+		}
+		return fmt.Sprintf("%s source=%d target=%d%s", p.EventType, p.SourceID, p.TargetID, detail)
+	case *Heartbeat:
+		return fmt.Sprintf("ts=%d", p.TimestampMs)
+	case *ActionConfirm:
+		return fmt.Sprintf("seq=%d action=%d pos=%s result=%d tick=%d",
+			p.SequenceNumber, p.ActionType, describeVector(p.CorrectedPosition), p.ResultCode, p.ServerTick)
+	case *ConnectAuth:
+		token := p.Token
+		if len(token) > 20 {
+			token = token[:20] + "..."
+		}
+		return fmt.Sprintf("character=%s region=%s version=%d caps=%s token=%s",
+			p.CharacterID, p.Region.RegionID(), p.ProtocolVersion, p.Capabilities, token)
+	case *Disconnect:
+		return fmt.Sprintf("reason=%s ts=%d", p.Reason, p.TimestampMs)
+	case *StateDelta:
+		parts := make([]string, 0, len(p.Entities))
+		for _, e := range p.Entities {
+			parts = append(parts, describeEntityDelta(e))
+		}
+		removed := ""
+		if len(p.Removed) > 0 {
+			removed = fmt.Sprintf(" removed=%v", p.Removed)
+		}
+		return fmt.Sprintf("tick=%d baseline=%d chunk=%d/%d entities=%d %s%s",
+			p.ServerTick, p.BaselineTick, p.Chunk+1, p.ChunkCount, len(p.Entities), strings.Join(parts, " "), removed)
+	case *StateAck:
+		return fmt.Sprintf("tick=%d", p.ServerTick)
+	default:
+		return ""
+	}
+}
+
+// describeEntityDelta lists only the fields in the entity's mask
+func describeEntityDelta(e EntityDelta) string {
+	var fields []string
+	if e.Mask.Has(DeltaEntityType) {
+		fields = append(fields, fmt.Sprintf("type=%d", e.EntityType))
+	}
+	if e.Mask.Has(DeltaPosition) {
+		fields = append(fields, "pos="+describeVector(Vector2{X: float32(float64(e.X) / PositionScale), Y: float32(float64(e.Y) / PositionScale)}))
+	}
+	if e.Mask.Has(DeltaPositionNudge) {
+		fields = append(fields, fmt.Sprintf("nudge=(%+d,%+d)", e.X, e.Y))
+	}
+	if e.Mask.Has(DeltaAnimation) {
+		fields = append(fields, fmt.Sprintf("anim=%d", e.AnimationState))
+	}
+	if e.Mask.Has(DeltaFlags) {
+		fields = append(fields, fmt.Sprintf("flags=%06b", uint8(e.Flags)))
+	}
+	return fmt.Sprintf("#%d(%s)", e.EntityID, strings.Join(fields, " "))
+}
+
+func describeVector(v Vector2) string {
+	return fmt.Sprintf("(%.2f,%.2f)", v.X, v.Y)
+}
@@ -0,0 +1,29 @@
+// Package protocol encodes and decodes the binary packets exchanged between
+// game clients and game servers.
+//
+// It is the Go counterpart of the client's PacketTypes, PacketWriter,
+// PacketReader and packet classes in client/scripts/shared/networking, and
+// must stay byte-for-byte compatible with them. Every packet is
+//
+//	[u8 type][u16 payload_length][payload]
+//
+// with all integers little-endian. Positions and velocities travel as pairs
+// of s16 quantized at 0.01 and 0.1 units, and angles as an s16 at 0.01
+// radians.
+//
+// Clients append their protocol Version and Capabilities to CONNECT_AUTH.
+// Each region sets the oldest version it accepts, and older clients are
+// turned away with DisconnectVersionMismatch.
+//
+// Clients offering CapDeltaState get STATE_DELTA instead of STATE_UPDATE.
+// Each delta carries only what changed since a baseline snapshot the client
+// acknowledged with STATE_ACK, so lost packets are never resent: the next
+// delta is against an older baseline, or is a full snapshot once the client
+// is DeltaHistory snapshots behind. DeltaEncoder and DeltaDecoder are the
+// reference implementation. Both packets are new types behind a capability,
+// so they did not need a Version bump.
+//
+// Decoding never panics on malformed input: reads past the end of a buffer
+// fail with ErrShortBuffer, and strings and raw bytes are the only values
+// that allocate.
+package protocol
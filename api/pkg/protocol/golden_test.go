@@ -0,0 +1,171 @@
+package protocol_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/omega-realm/api/pkg/protocol"
+)
+
+// fixture is the JSON half of a golden vector; the other half is the .bin
+// file with the same name. See testdata/golden/README.md.
+type fixture struct {
+	Description string          `json:"description"`
+	Type        string          `json:"type"`
+	Fields      json.RawMessage `json:"fields"`
+	EncodeFrom  json.RawMessage `json:"encode_from"`
+	Error       string          `json:"error"`
+}
+
+var fixtureErrors = map[string]error{
+	"short_buffer":  protocol.ErrShortBuffer,
+	"unknown_type":  protocol.ErrUnknownType,
+	"invalid_delta": protocol.ErrInvalidDelta,
+}
+
+func loadFixtures(t *testing.T) map[string]fixture {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join("testdata", "golden", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no golden fixtures found")
+	}
+
+	fixtures := make(map[string]fixture, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var f fixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		fixtures[strings.TrimSuffix(path, ".json")] = f
+	}
+	return fixtures
+}
+
+func parseType(t *testing.T, name string) protocol.Type {
+	t.Helper()
+	for pt := protocol.TypePlayerInput; pt <= protocol.TypeStateAck; pt++ {
+		if pt.String() == name {
+			return pt
+		}
+	}
+	t.Fatalf("unknown packet type %q", name)
+	return 0
+}
+
+// packetFromJSON builds a packet of type pt from fixture fields
+func packetFromJSON(t *testing.T, pt protocol.Type, data json.RawMessage) protocol.Packet {
+	t.Helper()
+	p, err := protocol.New(pt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(p); err != nil {
+		t.Fatalf("invalid fields for %s: %v", pt, err)
+	}
+	return p
+}
+
+func TestGoldenVectors(t *testing.T) {
+	for base, f := range loadFixtures(t) {
+		t.Run(filepath.Base(base), func(t *testing.T) {
+			raw, err := os.ReadFile(base + ".bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if f.Error != "" {
+				want, ok := fixtureErrors[f.Error]
+				if !ok {
+					t.Fatalf("unknown fixture error %q", f.Error)
+				}
+				if _, err := protocol.Decode(raw); !errors.Is(err, want) {
+					t.Fatalf("Decode error = %v, want %v", err, want)
+				}
+				return
+			}
+
+			pt := parseType(t, f.Type)
+			want := packetFromJSON(t, pt, f.Fields)
+
+			got, err := protocol.Decode(raw)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Decode =\n%+v\nwant\n%+v", got, want)
+			}
+
+			// Quantized fixtures give the writer input separately, since
+			// the decoded values need not encode back to the same bytes
+			input := want
+			if f.EncodeFrom != nil {
+				input = packetFromJSON(t, pt, f.EncodeFrom)
+			}
+			encoded, err := protocol.Encode(input)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if !bytes.Equal(encoded, raw) {
+				t.Fatalf("Encode =\n% x\nwant\n% x", encoded, raw)
+			}
+
+			// Decoding into a reused packet must give the same result
+			reused, _ := protocol.New(pt)
+			for i := 0; i < 2; i++ {
+				if err := protocol.DecodeInto(raw, reused); err != nil {
+					t.Fatalf("DecodeInto: %v", err)
+				}
+			}
+			if !reflect.DeepEqual(reused, want) {
+				t.Fatalf("DecodeInto =\n%+v\nwant\n%+v", reused, want)
+			}
+		})
+	}
+}
+
+// Every prefix of a valid packet is too short and must fail cleanly
+func TestGoldenVectorsTruncated(t *testing.T) {
+	for base, f := range loadFixtures(t) {
+		if f.Error != "" {
+			continue
+		}
+		raw, err := os.ReadFile(base + ".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for n := 0; n < len(raw); n++ {
+			if _, err := protocol.Decode(raw[:n]); !errors.Is(err, protocol.ErrShortBuffer) {
+				t.Errorf("%s: Decode of first %d bytes = %v, want ErrShortBuffer", filepath.Base(base), n, err)
+			}
+		}
+	}
+}
+
+// Every packet type must have at least one fixture
+func TestGoldenVectorsCoverAllTypes(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, f := range loadFixtures(t) {
+		covered[f.Type] = true
+	}
+	for pt := protocol.TypePlayerInput; pt <= protocol.TypeStateAck; pt++ {
+		if !covered[pt.String()] {
+			t.Errorf("no fixture for %s", pt)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Packet is implemented by pointers to every packet struct
+type Packet interface {
+	// Type returns the packet type written in the header
+	Type() Type
+	// WritePayload appends the payload, without the header
+	WritePayload(w *Writer)
+	// ReadPayload replaces the packet's contents with a decoded payload,
+	// reusing its slices where possible
+	ReadPayload(r *Reader) error
+}
+
+// Header is the [u8 type][u16 payload_length] prefix of every packet
+type Header struct {
+	Type          Type
+	PayloadLength uint16
+}
+
+// ReadHeader decodes the header at the start of buf
+func ReadHeader(buf []byte) (Header, error) {
+	if len(buf) < HeaderSize {
+		return Header{}, ErrShortBuffer
+	}
+	return Header{Type: Type(buf[0]), PayloadLength: binary.LittleEndian.Uint16(buf[1:])}, nil
+}
+
+// Split separates the first packet in buf from whatever follows it, for
+// buffers that carry several packets back to back
+func Split(buf []byte) (packet, rest []byte, err error) {
+	header, err := ReadHeader(buf)
+	if err != nil {
+		return nil, buf, err
+	}
+	size := HeaderSize + int(header.PayloadLength)
+	if len(buf) < size {
+		return nil, buf, ErrShortBuffer
+	}
+	return buf[:size], buf[size:], nil
+}
+
+// New returns an empty packet of type t
+func New(t Type) (Packet, error) {
+	switch t {
+	case TypePlayerInput:
+		return &PlayerInput{}, nil
+	case TypeStateUpdate:
+		return &StateUpdate{}, nil
+	case TypeGameEvent:
+		return &GameEvent{}, nil
+	case TypeHeartbeat:
+		return &Heartbeat{}, nil
+	case TypeActionConfirm:
+		return &ActionConfirm{}, nil
+	case TypeConnectAuth:
+		return &ConnectAuth{}, nil
+	case TypeDisconnect:
+		return &Disconnect{}, nil
+	case TypeStateDelta:
+		return &StateDelta{}, nil
+	case TypeStateAck:
+		return &StateAck{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownType, uint8(t))
+	}
+}
+
+// AppendPacket appends p, header included, to dst. On error dst is returned
+// unchanged.
+func AppendPacket(dst []byte, p Packet) ([]byte, error) {
+	start := len(dst)
+	w := Writer{buf: append(dst, uint8(p.Type()), 0, 0)}
+	p.WritePayload(&w)
+	if w.err != nil {
+		return dst[:start], w.err
+	}
+
+	length := len(w.buf) - start - HeaderSize
+	if length > MaxPayloadSize {
+		return dst[:start], ErrPayloadTooLarge
+	}
+	binary.LittleEndian.PutUint16(w.buf[start+1:], uint16(length))
+	return w.buf, nil
+}
+
+// Encode returns p as a complete packet
+func Encode(p Packet) ([]byte, error) {
+	return AppendPacket(nil, p)
+}
+
+// Decode decodes the packet at the start of buf. Bytes after the payload
+// length given in the header are ignored, as are unread payload bytes.
+func Decode(buf []byte) (Packet, error) {
+	header, err := ReadHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+	p, err := New(header.Type)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodePayload(buf, header, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DecodeInto decodes the packet at the start of buf into p, which must be of
+// the same type. Reusing p across calls avoids allocating per packet.
+func DecodeInto(buf []byte, p Packet) error {
+	header, err := ReadHeader(buf)
+	if err != nil {
+		return err
+	}
+	if header.Type != p.Type() {
+		return fmt.Errorf("%w: got %s, want %s", ErrUnexpectedType, header.Type, p.Type())
+	}
+	return decodePayload(buf, header, p)
+}
+
+func decodePayload(buf []byte, header Header, p Packet) error {
+	end := HeaderSize + int(header.PayloadLength)
+	if len(buf) < end {
+		return ErrShortBuffer
+	}
+	r := Reader{buf: buf[HeaderSize:end]}
+	if err := p.ReadPayload(&r); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", header.Type, err)
+	}
+	return nil
+}
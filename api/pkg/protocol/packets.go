@@ -0,0 +1,463 @@
+package protocol
+
+import "math"
+
+// EntitySize is the encoded size of one EntityState
+const EntitySize = 9
+
+// MaxEntities is the most entities one state update can carry
+const MaxEntities = 255
+
+// PlayerInput is the client's input, sent at 10Hz (12 byte payload)
+type PlayerInput struct {
+	Position       Vector2    `json:"position"`
+	Velocity       Vector2    `json:"velocity"`
+	InputFlags     InputFlags `json:"input_flags"`
+	AimAngle       float64    `json:"aim_angle"`
+	SequenceNumber uint8      `json:"sequence_number"`
+}
+
+func (p *PlayerInput) Type() Type { return TypePlayerInput }
+
+func (p *PlayerInput) WritePayload(w *Writer) {
+	w.WriteVector2(p.Position)
+	w.WriteVelocity(p.Velocity)
+	w.WriteU8(uint8(p.InputFlags))
+	w.WriteAngle(p.AimAngle)
+	w.WriteU8(p.SequenceNumber)
+}
+
+func (p *PlayerInput) ReadPayload(r *Reader) error {
+	p.Position = r.ReadVector2()
+	p.Velocity = r.ReadVelocity()
+	p.InputFlags = InputFlags(r.ReadU8())
+	p.AimAngle = r.ReadAngle()
+	p.SequenceNumber = r.ReadU8()
+	return r.Err()
+}
+
+// EntityState is one entity in a state update
+type EntityState struct {
+	EntityID       uint16         `json:"entity_id"`
+	EntityType     EntityType     `json:"entity_type"`
+	Position       Vector2        `json:"position"`
+	AnimationState AnimationState `json:"animation_state"`
+	Flags          EntityFlags    `json:"flags"`
+}
+
+// StateUpdate is the server's broadcast of every visible entity, sent at
+// 10Hz. It holds at most MaxEntities entities.
+type StateUpdate struct {
+	ServerTick uint32        `json:"server_tick"`
+	Entities   []EntityState `json:"entities"`
+}
+
+func (p *StateUpdate) Type() Type { return TypeStateUpdate }
+
+// Size returns the encoded packet size, header included
+func (p *StateUpdate) Size() int {
+	return HeaderSize + 4 + 1 + len(p.Entities)*EntitySize
+}
+
+func (p *StateUpdate) WritePayload(w *Writer) {
+	entities := p.Entities
+	if len(entities) > MaxEntities {
+		w.setErr(ErrTooManyEntities)
+		entities = entities[:MaxEntities]
+	}
+
+	w.WriteU32(p.ServerTick)
+	w.WriteU8(uint8(len(entities)))
+	for i := range entities {
+		e := &entities[i]
+		w.WriteU16(e.EntityID)
+		w.WriteU8(uint8(e.EntityType))
+		w.WriteVector2(e.Position)
+		w.WriteU8(uint8(e.AnimationState))
+		w.WriteU8(uint8(e.Flags))
+	}
+}
+
+func (p *StateUpdate) ReadPayload(r *Reader) error {
+	p.ServerTick = r.ReadU32()
+	count := int(r.ReadU8())
+	// Checked up front so a bogus count cannot make us allocate
+	if count*EntitySize > r.Remaining() {
+		return ErrShortBuffer
+	}
+
+	if cap(p.Entities) < count || p.Entities == nil {
+		p.Entities = make([]EntityState, 0, count)
+	}
+	p.Entities = p.Entities[:0]
+	for i := 0; i < count; i++ {
+		p.Entities = append(p.Entities, EntityState{
+			EntityID:       r.ReadU16(),
+			EntityType:     EntityType(r.ReadU8()),
+			Position:       r.ReadVector2(),
+			AnimationState: AnimationState(r.ReadU8()),
+			Flags:          EntityFlags(r.ReadU8()),
+		})
+	}
+	return r.Err()
+}
+
+// GameEvent is a server event such as damage or a kill. Which fields are
+// encoded depends on EventType; event types without a defined layout carry
+// their payload in Data, which the client ignores.
+type GameEvent struct {
+	EventType GameEventType `json:"event_type"`
+	SourceID  uint16        `json:"source_id"`
+	TargetID  uint16        `json:"target_id"`
+
+	Amount     uint16  `json:"amount,omitempty"`      // DAMAGE
+	DamageType uint8   `json:"damage_type,omitempty"` // DAMAGE
+	Position   Vector2 `json:"position"`              // RESPAWN
+	EffectID   uint8   `json:"effect_id,omitempty"`   // EFFECT_APPLY, EFFECT_REMOVE
+	DurationMs uint16  `json:"duration_ms,omitempty"` // EFFECT_APPLY
+	Data       []byte  `json:"data,omitempty"`        // any other event type
+}
+
+func (p *GameEvent) Type() Type { return TypeGameEvent }
+
+func (p *GameEvent) WritePayload(w *Writer) {
+	w.WriteU8(uint8(p.EventType))
+	w.WriteU16(p.SourceID)
+	w.WriteU16(p.TargetID)
+
+	switch p.EventType {
+	case EventDamage:
+		w.WriteU16(p.Amount)
+		w.WriteU8(p.DamageType)
+	case EventKill:
+	case EventRespawn:
+		w.WriteVector2(p.Position)
+	case EventEffectApply:
+		w.WriteU8(p.EffectID)
+		w.WriteU16(p.DurationMs)
+	case EventEffectRemove:
+		w.WriteU8(p.EffectID)
+	default:
+		w.WriteBytes(p.Data)
+	}
+}
+
+func (p *GameEvent) ReadPayload(r *Reader) error {
+	*p = GameEvent{Data: p.Data[:0]}
+	p.EventType = GameEventType(r.ReadU8())
+	p.SourceID = r.ReadU16()
+	p.TargetID = r.ReadU16()
+
+	switch p.EventType {
+	case EventDamage:
+		p.Amount = r.ReadU16()
+		p.DamageType = r.ReadU8()
+	case EventKill:
+	case EventRespawn:
+		p.Position = r.ReadVector2()
+	case EventEffectApply:
+		p.EffectID = r.ReadU8()
+		p.DurationMs = r.ReadU16()
+	case EventEffectRemove:
+		p.EffectID = r.ReadU8()
+	default:
+		p.Data = append(p.Data, r.ReadBytes(r.Remaining())...)
+	}
+	if len(p.Data) == 0 {
+		p.Data = nil
+	}
+	return r.Err()
+}
+
+// Heartbeat is the keep-alive sent both ways (4 byte payload)
+type Heartbeat struct {
+	TimestampMs uint32 `json:"timestamp_ms"`
+}
+
+func (p *Heartbeat) Type() Type { return TypeHeartbeat }
+
+func (p *Heartbeat) WritePayload(w *Writer) {
+	w.WriteU32(p.TimestampMs)
+}
+
+func (p *Heartbeat) ReadPayload(r *Reader) error {
+	p.TimestampMs = r.ReadU32()
+	return r.Err()
+}
+
+// ActionConfirm is the server's authoritative result for a client input,
+// used for prediction reconciliation (9 byte payload)
+type ActionConfirm struct {
+	SequenceNumber    uint8      `json:"sequence_number"`
+	ActionType        ActionType `json:"action_type"`
+	CorrectedPosition Vector2    `json:"corrected_position"`
+	ResultCode        ResultCode `json:"result_code"`
+	ServerTick        uint16     `json:"server_tick"`
+}
+
+func (p *ActionConfirm) Type() Type { return TypeActionConfirm }
+
+func (p *ActionConfirm) WritePayload(w *Writer) {
+	w.WriteU8(p.SequenceNumber)
+	w.WriteU8(uint8(p.ActionType))
+	w.WriteVector2(p.CorrectedPosition)
+	w.WriteU8(uint8(p.ResultCode))
+	w.WriteU16(p.ServerTick)
+}
+
+func (p *ActionConfirm) ReadPayload(r *Reader) error {
+	p.SequenceNumber = r.ReadU8()
+	p.ActionType = ActionType(r.ReadU8())
+	p.CorrectedPosition = r.ReadVector2()
+	p.ResultCode = ResultCode(r.ReadU8())
+	p.ServerTick = r.ReadU16()
+	return r.Err()
+}
+
+// ConnectAuth is the client's authentication handshake (AuthPacket). The
+// [u16 protocol_version][u32 capabilities] trailer is absent in packets from
+// unversioned clients, which decode as VersionUnversioned, and is left out
+// when encoding one.
+type ConnectAuth struct {
+	Token           string       `json:"token"`
+	CharacterID     string       `json:"character_id"`
+	Region          RegionCode   `json:"region"`
+	ProtocolVersion uint16       `json:"protocol_version"`
+	Capabilities    Capabilities `json:"capabilities"`
+}
+
+func (p *ConnectAuth) Type() Type { return TypeConnectAuth }
+
+func (p *ConnectAuth) WritePayload(w *Writer) {
+	w.WriteString(p.Token)
+	w.WriteString(p.CharacterID)
+	w.WriteU8(uint8(p.Region))
+	if p.ProtocolVersion != VersionUnversioned || p.Capabilities != 0 {
+		w.WriteU16(p.ProtocolVersion)
+		w.WriteU32(uint32(p.Capabilities))
+	}
+}
+
+func (p *ConnectAuth) ReadPayload(r *Reader) error {
+	p.Token = r.ReadString()
+	p.CharacterID = r.ReadString()
+	p.Region = RegionCode(r.ReadU8())
+	p.ProtocolVersion, p.Capabilities = VersionUnversioned, 0
+	if r.Remaining() > 0 {
+		p.ProtocolVersion = r.ReadU16()
+		p.Capabilities = Capabilities(r.ReadU32())
+	}
+	return r.Err()
+}
+
+// Disconnect announces a clean disconnect (5 byte payload)
+type Disconnect struct {
+	Reason      DisconnectReason `json:"reason"`
+	TimestampMs uint32           `json:"timestamp_ms"`
+}
+
+func (p *Disconnect) Type() Type { return TypeDisconnect }
+
+func (p *Disconnect) WritePayload(w *Writer) {
+	w.WriteU8(uint8(p.Reason))
+	w.WriteU32(p.TimestampMs)
+}
+
+func (p *Disconnect) ReadPayload(r *Reader) error {
+	p.Reason = DisconnectReason(r.ReadU8())
+	p.TimestampMs = r.ReadU32()
+	return r.Err()
+}
+
+// EntityDelta is one entity's changes in a state delta. Only the fields in
+// Mask are encoded. X and Y are in quantized position steps (1/PositionScale
+// units): absolute with DeltaPosition, relative to the baseline position
+// with DeltaPositionNudge, when they must fit in an s8.
+type EntityDelta struct {
+	EntityID       uint16         `json:"entity_id"`
+	Mask           DeltaMask      `json:"mask"`
+	EntityType     EntityType     `json:"entity_type"`
+	X              int16          `json:"x"`
+	Y              int16          `json:"y"`
+	AnimationState AnimationState `json:"animation_state"`
+	Flags          EntityFlags    `json:"flags"`
+}
+
+// Size returns the encoded size of the entity
+func (e *EntityDelta) Size() int {
+	size := 3
+	if e.Mask.Has(DeltaEntityType) {
+		size++
+	}
+	if e.Mask.Has(DeltaPosition) {
+		size += 4
+	}
+	if e.Mask.Has(DeltaPositionNudge) {
+		size += 2
+	}
+	if e.Mask.Has(DeltaAnimation) {
+		size++
+	}
+	if e.Mask.Has(DeltaFlags) {
+		size++
+	}
+	return size
+}
+
+// StateDelta is a state update sent as the changes since BaselineTick, a
+// snapshot the client has acknowledged with STATE_ACK. A BaselineTick of 0
+// means there is no baseline and every entity is new. A snapshot with more
+// changes or removals than one packet holds is split into ChunkCount chunks
+// with the same ServerTick, each carrying at most MaxEntities of either.
+// DeltaEncoder and DeltaDecoder implement both ends.
+type StateDelta struct {
+	ServerTick   uint32        `json:"server_tick"`
+	BaselineTick uint32        `json:"baseline_tick"`
+	Chunk        uint8         `json:"chunk"`
+	ChunkCount   uint8         `json:"chunk_count"`
+	Entities     []EntityDelta `json:"entities"`
+	Removed      []uint16      `json:"removed"`
+}
+
+func (p *StateDelta) Type() Type { return TypeStateDelta }
+
+// Size returns the encoded packet size, header included
+func (p *StateDelta) Size() int {
+	size := HeaderSize + 4 + 4 + 1 + 1 + 1 + 1 + len(p.Removed)*2
+	for i := range p.Entities {
+		size += p.Entities[i].Size()
+	}
+	return size
+}
+
+func (p *StateDelta) WritePayload(w *Writer) {
+	entities, removed := p.Entities, p.Removed
+	if len(entities) > MaxEntities {
+		w.setErr(ErrTooManyEntities)
+		entities = entities[:MaxEntities]
+	}
+	if len(removed) > MaxEntities {
+		w.setErr(ErrTooManyEntities)
+		removed = removed[:MaxEntities]
+	}
+	if p.ChunkCount == 0 || p.Chunk >= p.ChunkCount {
+		w.setErr(ErrInvalidDelta)
+	}
+
+	w.WriteU32(p.ServerTick)
+	w.WriteU32(p.BaselineTick)
+	w.WriteU8(p.Chunk)
+	w.WriteU8(p.ChunkCount)
+	w.WriteU8(uint8(len(entities)))
+	for i := range entities {
+		e := &entities[i]
+		if !e.Mask.Valid() {
+			w.setErr(ErrInvalidDelta)
+		}
+		w.WriteU16(e.EntityID)
+		w.WriteU8(uint8(e.Mask))
+		if e.Mask.Has(DeltaEntityType) {
+			w.WriteU8(uint8(e.EntityType))
+		}
+		if e.Mask.Has(DeltaPosition) {
+			w.WriteS16(e.X)
+			w.WriteS16(e.Y)
+		}
+		if e.Mask.Has(DeltaPositionNudge) {
+			if e.X < math.MinInt8 || e.X > math.MaxInt8 || e.Y < math.MinInt8 || e.Y > math.MaxInt8 {
+				w.setErr(ErrInvalidDelta)
+			}
+			w.WriteS8(int8(e.X))
+			w.WriteS8(int8(e.Y))
+		}
+		if e.Mask.Has(DeltaAnimation) {
+			w.WriteU8(uint8(e.AnimationState))
+		}
+		if e.Mask.Has(DeltaFlags) {
+			w.WriteU8(uint8(e.Flags))
+		}
+	}
+	w.WriteU8(uint8(len(removed)))
+	for _, id := range removed {
+		w.WriteU16(id)
+	}
+}
+
+func (p *StateDelta) ReadPayload(r *Reader) error {
+	p.ServerTick = r.ReadU32()
+	p.BaselineTick = r.ReadU32()
+	p.Chunk = r.ReadU8()
+	p.ChunkCount = r.ReadU8()
+	count := int(r.ReadU8())
+	// Each entity is at least an ID and a mask
+	if count*3 > r.Remaining() {
+		return ErrShortBuffer
+	}
+
+	if cap(p.Entities) < count || p.Entities == nil {
+		p.Entities = make([]EntityDelta, 0, count)
+	}
+	p.Entities = p.Entities[:0]
+	for i := 0; i < count; i++ {
+		e := EntityDelta{EntityID: r.ReadU16(), Mask: DeltaMask(r.ReadU8())}
+		if e.Mask.Has(DeltaEntityType) {
+			e.EntityType = EntityType(r.ReadU8())
+		}
+		if e.Mask.Has(DeltaPosition) {
+			e.X, e.Y = r.ReadS16(), r.ReadS16()
+		}
+		if e.Mask.Has(DeltaPositionNudge) {
+			e.X, e.Y = int16(r.ReadS8()), int16(r.ReadS8())
+		}
+		if e.Mask.Has(DeltaAnimation) {
+			e.AnimationState = AnimationState(r.ReadU8())
+		}
+		if e.Mask.Has(DeltaFlags) {
+			e.Flags = EntityFlags(r.ReadU8())
+		}
+		if r.Err() != nil {
+			return r.Err()
+		}
+		if !e.Mask.Valid() {
+			return ErrInvalidDelta
+		}
+		p.Entities = append(p.Entities, e)
+	}
+
+	count = int(r.ReadU8())
+	if count*2 > r.Remaining() {
+		return ErrShortBuffer
+	}
+	if cap(p.Removed) < count || p.Removed == nil {
+		p.Removed = make([]uint16, 0, count)
+	}
+	p.Removed = p.Removed[:0]
+	for i := 0; i < count; i++ {
+		p.Removed = append(p.Removed, r.ReadU16())
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if p.ChunkCount == 0 || p.Chunk >= p.ChunkCount {
+		return ErrInvalidDelta
+	}
+	return nil
+}
+
+// StateAck acknowledges the newest state snapshot the client has
+// completely received, making it the server's next delta baseline (4 byte
+// payload)
+type StateAck struct {
+	ServerTick uint32 `json:"server_tick"`
+}
+
+func (p *StateAck) Type() Type { return TypeStateAck }
+
+func (p *StateAck) WritePayload(w *Writer) {
+	w.WriteU32(p.ServerTick)
+}
+
+func (p *StateAck) ReadPayload(r *Reader) error {
+	p.ServerTick = r.ReadU32()
+	return r.Err()
+}
@@ -0,0 +1,280 @@
+package protocol
+
+import "fmt"
+
+// HeaderSize is the size of the [u8 type][u16 payload_length] packet header
+const HeaderSize = 3
+
+// MaxPacketSize is the largest packet the u16 payload length allows
+const MaxPacketSize = 65535
+
+// MaxPayloadSize is the largest payload a packet can carry
+const MaxPayloadSize = MaxPacketSize
+
+// Type identifies a packet (PacketTypes.Type)
+type Type uint8
+
+// Packet types
+const (
+	TypePlayerInput   Type = 1 // Client -> Server: movement and actions
+	TypeStateUpdate   Type = 2 // Server -> Client: entity positions and animations
+	TypeGameEvent     Type = 3 // Server -> Client: damage, kills, status effects
+	TypeHeartbeat     Type = 4 // Bidirectional: keep-alive
+	TypeActionConfirm Type = 5 // Server -> Client: authoritative action result
+	TypeConnectAuth   Type = 6 // Client -> Server: authentication handshake
+	TypeDisconnect    Type = 7 // Client -> Server: clean disconnect
+	TypeStateDelta    Type = 8 // Server -> Client: entity changes since an acked snapshot
+	TypeStateAck      Type = 9 // Client -> Server: last complete snapshot received
+)
+
+// Valid reports whether t is a known packet type
+func (t Type) Valid() bool {
+	return t >= TypePlayerInput && t <= TypeStateAck
+}
+
+func (t Type) String() string {
+	switch t {
+	case TypePlayerInput:
+		return "PLAYER_INPUT"
+	case TypeStateUpdate:
+		return "STATE_UPDATE"
+	case TypeGameEvent:
+		return "GAME_EVENT"
+	case TypeHeartbeat:
+		return "HEARTBEAT"
+	case TypeActionConfirm:
+		return "ACTION_CONFIRM"
+	case TypeConnectAuth:
+		return "CONNECT_AUTH"
+	case TypeDisconnect:
+		return "DISCONNECT"
+	case TypeStateDelta:
+		return "STATE_DELTA"
+	case TypeStateAck:
+		return "STATE_ACK"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", uint8(t))
+	}
+}
+
+// EntityType is the kind of entity in a state update
+type EntityType uint8
+
+// Entity types
+const (
+	EntityPlayer     EntityType = 1
+	EntityMonster    EntityType = 2
+	EntityProjectile EntityType = 3
+)
+
+// AnimationState is an entity's current animation
+type AnimationState uint8
+
+// Animation states
+const (
+	AnimationIdle   AnimationState = 0
+	AnimationWalk   AnimationState = 1
+	AnimationRun    AnimationState = 2
+	AnimationAttack AnimationState = 3
+	AnimationHit    AnimationState = 4
+	AnimationDeath  AnimationState = 5
+	AnimationSpawn  AnimationState = 6
+)
+
+// InputFlags is the bitfield of inputs held in a PlayerInput
+type InputFlags uint8
+
+// Input flags
+const (
+	InputMoveUp InputFlags = 1 << iota
+	InputMoveDown
+	InputMoveLeft
+	InputMoveRight
+	InputShoot
+	InputAbility
+	InputSprint
+	InputInteract
+)
+
+// Has reports whether every flag in f is set
+func (i InputFlags) Has(f InputFlags) bool {
+	return i&f == f
+}
+
+// EntityFlags is the bitfield of entity status in a state update
+type EntityFlags uint8
+
+// Entity flags
+const (
+	EntityAlive EntityFlags = 1 << iota
+	EntityMoving
+	EntityAttacking
+	EntityInvulnerable
+	EntityStunned
+	EntityVisible
+)
+
+// Has reports whether every flag in f is set
+func (e EntityFlags) Has(f EntityFlags) bool {
+	return e&f == f
+}
+
+// DeltaMask is the bitfield of fields present for one entity in a state
+// delta
+type DeltaMask uint8
+
+// Delta mask bits. Fields are encoded in bit order after the mask.
+const (
+	DeltaEntityType    DeltaMask = 1 << iota // u8 entity type
+	DeltaPosition                            // s16 x, s16 y: absolute position in quantized steps
+	DeltaPositionNudge                       // s8 dx, s8 dy: quantized steps from the baseline position
+	DeltaAnimation                           // u8 animation state
+	DeltaFlags                               // u8 entity flags
+)
+
+// DeltaFull is the mask of an entity the baseline does not have
+const DeltaFull = DeltaEntityType | DeltaPosition | DeltaAnimation | DeltaFlags
+
+// deltaMaskKnown is every defined mask bit
+const deltaMaskKnown = DeltaFull | DeltaPositionNudge
+
+// Has reports whether every bit in f is set
+func (m DeltaMask) Has(f DeltaMask) bool {
+	return m&f == f
+}
+
+// Valid reports whether m only uses defined bits and sets at most one of
+// DeltaPosition and DeltaPositionNudge
+func (m DeltaMask) Valid() bool {
+	return m&^deltaMaskKnown == 0 && !m.Has(DeltaPosition|DeltaPositionNudge)
+}
+
+// GameEventType identifies the event in a GameEvent
+type GameEventType uint8
+
+// Game event types
+const (
+	EventDamage       GameEventType = 1
+	EventKill         GameEventType = 2
+	EventRespawn      GameEventType = 3
+	EventEffectApply  GameEventType = 4
+	EventEffectRemove GameEventType = 5
+	EventPickup       GameEventType = 6
+	EventLevelUp      GameEventType = 7
+	EventChatMessage  GameEventType = 8
+)
+
+func (e GameEventType) String() string {
+	switch e {
+	case EventDamage:
+		return "DAMAGE"
+	case EventKill:
+		return "KILL"
+	case EventRespawn:
+		return "RESPAWN"
+	case EventEffectApply:
+		return "EFFECT_APPLY"
+	case EventEffectRemove:
+		return "EFFECT_REMOVE"
+	case EventPickup:
+		return "PICKUP"
+	case EventLevelUp:
+		return "LEVEL_UP"
+	case EventChatMessage:
+		return "CHAT_MESSAGE"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", uint8(e))
+	}
+}
+
+// DisconnectReason says why a connection ended
+type DisconnectReason uint8
+
+// Disconnect reasons
+const (
+	DisconnectUserQuit         DisconnectReason = 0
+	DisconnectTimeout          DisconnectReason = 1
+	DisconnectKicked           DisconnectReason = 2
+	DisconnectServerShutdown   DisconnectReason = 3
+	DisconnectInvalidAuth      DisconnectReason = 4
+	DisconnectDuplicateSession DisconnectReason = 5
+	DisconnectVersionMismatch  DisconnectReason = 6
+)
+
+func (d DisconnectReason) String() string {
+	switch d {
+	case DisconnectUserQuit:
+		return "USER_QUIT"
+	case DisconnectTimeout:
+		return "TIMEOUT"
+	case DisconnectKicked:
+		return "KICKED"
+	case DisconnectServerShutdown:
+		return "SERVER_SHUTDOWN"
+	case DisconnectInvalidAuth:
+		return "INVALID_AUTH"
+	case DisconnectDuplicateSession:
+		return "DUPLICATE_SESSION"
+	case DisconnectVersionMismatch:
+		return "VERSION_MISMATCH"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", uint8(d))
+	}
+}
+
+// RegionCode is the region selected in CONNECT_AUTH (AuthPacket.Region)
+type RegionCode uint8
+
+// Region codes
+const (
+	RegionAsia   RegionCode = 0
+	RegionEurope RegionCode = 1
+	RegionUSWest RegionCode = 2
+	RegionUSEast RegionCode = 3
+)
+
+var regionIDs = map[RegionCode]string{
+	RegionAsia:   "asia",
+	RegionEurope: "europe",
+	RegionUSWest: "us-west",
+	RegionUSEast: "us-east",
+}
+
+// RegionID returns the API region ID for the code, or "" for unknown codes
+func (r RegionCode) RegionID() string {
+	return regionIDs[r]
+}
+
+// RegionCodeFromID returns the code for an API region ID
+func RegionCodeFromID(regionID string) (RegionCode, bool) {
+	for code, id := range regionIDs {
+		if id == regionID {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// ActionType is the kind of action confirmed by an ActionConfirm
+type ActionType uint8
+
+// Action types
+const (
+	ActionMove     ActionType = 0
+	ActionShoot    ActionType = 1
+	ActionAbility  ActionType = 2
+	ActionInteract ActionType = 3
+)
+
+// ResultCode is the outcome of a confirmed action
+type ResultCode uint8
+
+// Result codes
+const (
+	ResultSuccess               ResultCode = 0
+	ResultFailedInvalidPosition ResultCode = 1
+	ResultFailedCooldown        ResultCode = 2
+	ResultFailedNoTarget        ResultCode = 3
+	ResultFailedBlocked         ResultCode = 4
+	ResultFailedInvalidState    ResultCode = 5
+)
@@ -0,0 +1,62 @@
+package protocol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Version is the protocol version this package implements. It goes up
+// whenever a packet layout changes in a way older peers cannot read.
+const Version uint16 = 1
+
+// VersionUnversioned is the version of clients from before versioning,
+// whose CONNECT_AUTH ends after the region
+const VersionUnversioned uint16 = 0
+
+// Capabilities is the bitfield of optional features a client supports,
+// sent in CONNECT_AUTH. The server only uses features the client offered.
+type Capabilities uint32
+
+// Capabilities
+const (
+	// CapBatchedPackets lets the server send several packets back to back
+	// in one WebSocket frame
+	CapBatchedPackets Capabilities = 1 << iota
+	// CapDeltaState lets the server send STATE_DELTA instead of
+	// STATE_UPDATE. The client acks each complete snapshot with STATE_ACK.
+	CapDeltaState
+)
+
+// SupportedCapabilities is every capability this package can read
+const SupportedCapabilities = CapBatchedPackets | CapDeltaState
+
+var capabilityNames = []string{
+	"BATCHED_PACKETS",
+	"DELTA_STATE",
+}
+
+// Has reports whether every capability in f is set
+func (c Capabilities) Has(f Capabilities) bool {
+	return c&f == f
+}
+
+func (c Capabilities) String() string {
+	if c == 0 {
+		return "NONE"
+	}
+	var names []string
+	for i, name := range capabilityNames {
+		if c.Has(1 << i) {
+			names = append(names, name)
+		}
+	}
+	if unknown := c &^ (1<<len(capabilityNames) - 1); unknown != 0 {
+		names = append(names, fmt.Sprintf("UNKNOWN(%#x)", uint32(unknown)))
+	}
+	return strings.Join(names, "|")
+}
+
+// Negotiate returns the capabilities both the client and the server support
+func Negotiate(client, server Capabilities) Capabilities {
+	return client & server
+}
@@ -0,0 +1,236 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Reader reads the records of a recording in order
+type Reader struct {
+	src      io.ReadSeeker
+	in       *bufio.Reader
+	closer   io.Closer
+	start    time.Time
+	end      int64 // file position where records stop
+	position int64
+	index    []IndexEntry
+	header   [recordHeaderSize]byte
+}
+
+// Open opens the recording at path
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	r.closer = file
+	return r, nil
+}
+
+// NewReader reads a recording from src. If the recording has no index, as
+// when its writer was never closed, it is rebuilt with one pass over src.
+func NewReader(src io.ReadSeeker) (*Reader, error) {
+	r := &Reader{src: src}
+
+	var header [headerSize]byte
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(src, header[:]); err != nil {
+		return nil, ErrNotRecording
+	}
+	if [4]byte(header[:4]) != magicHeader {
+		return nil, ErrNotRecording
+	}
+	if version := binary.LittleEndian.Uint16(header[4:]); version != Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	r.start = time.Unix(0, int64(binary.LittleEndian.Uint64(header[8:])))
+
+	size, err := src.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	r.end = size
+	if err := r.loadIndex(size); err != nil {
+		if err := r.rebuildIndex(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.seekPosition(headerSize); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// loadIndex reads the index written by Writer.Close
+func (r *Reader) loadIndex(size int64) error {
+	if size < headerSize+trailerSize {
+		return ErrCorrupt
+	}
+	var trailer [trailerSize]byte
+	if _, err := r.src.Seek(size-trailerSize, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r.src, trailer[:]); err != nil {
+		return err
+	}
+	if [4]byte(trailer[8:]) != magicTrailer {
+		return ErrCorrupt
+	}
+	indexPosition := int64(binary.LittleEndian.Uint64(trailer[:8]))
+	if indexPosition < headerSize || indexPosition > size-trailerSize-8 {
+		return ErrCorrupt
+	}
+
+	if _, err := r.src.Seek(indexPosition, io.SeekStart); err != nil {
+		return err
+	}
+	var indexHeader [8]byte
+	if _, err := io.ReadFull(r.src, indexHeader[:]); err != nil {
+		return err
+	}
+	count := int64(binary.LittleEndian.Uint32(indexHeader[4:]))
+	if [4]byte(indexHeader[:4]) != magicIndex || indexPosition+8+count*16+trailerSize != size {
+		return ErrCorrupt
+	}
+
+	entries := make([]byte, count*16)
+	if _, err := io.ReadFull(r.src, entries); err != nil {
+		return err
+	}
+	r.index = make([]IndexEntry, count)
+	for i := range r.index {
+		r.index[i] = IndexEntry{
+			Offset:   time.Duration(binary.LittleEndian.Uint64(entries[i*16:])),
+			Position: int64(binary.LittleEndian.Uint64(entries[i*16+8:])),
+		}
+	}
+	r.end = indexPosition
+	return nil
+}
+
+// rebuildIndex scans every record, stopping at the first incomplete one
+func (r *Reader) rebuildIndex() error {
+	if err := r.seekPosition(headerSize); err != nil {
+		return err
+	}
+	r.index = nil
+	lastIndex := -IndexInterval
+	for {
+		position := r.position
+		record, err := r.Next()
+		if err == io.EOF || err == ErrCorrupt {
+			// A crash can leave a partial record at the end
+			r.end = position
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if record.Offset-lastIndex >= IndexInterval {
+			r.index = append(r.index, IndexEntry{Offset: record.Offset, Position: position})
+			lastIndex = record.Offset
+		}
+	}
+}
+
+func (r *Reader) seekPosition(position int64) error {
+	if _, err := r.src.Seek(position, io.SeekStart); err != nil {
+		return err
+	}
+	if r.in == nil {
+		r.in = bufio.NewReaderSize(r.src, 64*1024)
+	} else {
+		r.in.Reset(r.src)
+	}
+	r.position = position
+	return nil
+}
+
+// Start returns when the recording started
+func (r *Reader) Start() time.Time {
+	return r.start
+}
+
+// Index returns the seek index
+func (r *Reader) Index() []IndexEntry {
+	return r.index
+}
+
+// Next returns the next record, or io.EOF after the last one
+func (r *Reader) Next() (*Record, error) {
+	if r.position+recordHeaderSize > r.end {
+		if r.position == r.end {
+			return nil, io.EOF
+		}
+		return nil, ErrCorrupt
+	}
+	h := r.header[:]
+	if _, err := io.ReadFull(r.in, h); err != nil {
+		return nil, ErrCorrupt
+	}
+	length := int64(binary.LittleEndian.Uint32(h[14:]))
+	if r.position+recordHeaderSize+length > r.end {
+		return nil, ErrCorrupt
+	}
+
+	record := &Record{
+		Kind:       Kind(h[0]),
+		Direction:  Direction(h[1]),
+		Connection: binary.LittleEndian.Uint32(h[2:]),
+		Offset:     time.Duration(binary.LittleEndian.Uint64(h[6:])),
+		Data:       make([]byte, length),
+	}
+	if _, err := io.ReadFull(r.in, record.Data); err != nil {
+		return nil, ErrCorrupt
+	}
+	r.position += recordHeaderSize + length
+	return record, nil
+}
+
+// Seek positions the reader at the first record at or after offset
+func (r *Reader) Seek(offset time.Duration) error {
+	// The last index entry at or before offset
+	i := sort.Search(len(r.index), func(i int) bool { return r.index[i].Offset > offset }) - 1
+	position := int64(headerSize)
+	if i >= 0 {
+		position = r.index[i].Position
+	}
+	if err := r.seekPosition(position); err != nil {
+		return err
+	}
+
+	for {
+		before := r.position
+		record, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if record.Offset >= offset {
+			return r.seekPosition(before)
+		}
+	}
+}
+
+// Close closes the file when the reader was made by Open
+func (r *Reader) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
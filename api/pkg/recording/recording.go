@@ -0,0 +1,251 @@
+// Package recording stores the packets of game connections with their
+// timing, so a session can be inspected or replayed after the fact.
+//
+// A recording is
+//
+//	header   [4]byte "OREC" [u16 version][u16 reserved][i64 start unix ns]
+//	records  [u8 kind][u8 direction][u32 connection][i64 offset ns][u32 length][data]...
+//	index    [4]byte "OIDX" [u32 count] count × [i64 offset ns][i64 file position]
+//	trailer  [i64 index file position] [4]byte "OEND"
+//
+// with all integers little-endian. Offsets are relative to the start time.
+// The index points at a record at least every IndexInterval, so a reader
+// can seek without scanning. A recording that was never closed has no index
+// or trailer; readers rebuild the index by scanning it.
+package recording
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// Version is the format version written to new recordings
+const Version = 1
+
+// IndexInterval is the longest stretch of recording between index entries
+const IndexInterval = time.Second
+
+const (
+	headerSize       = 16
+	recordHeaderSize = 18
+	trailerSize      = 12
+)
+
+var (
+	magicHeader  = [4]byte{'O', 'R', 'E', 'C'}
+	magicIndex   = [4]byte{'O', 'I', 'D', 'X'}
+	magicTrailer = [4]byte{'O', 'E', 'N', 'D'}
+)
+
+// Errors returned by readers and writers
+var (
+	ErrNotRecording       = errors.New("recording: not a recording")
+	ErrUnsupportedVersion = errors.New("recording: unsupported version")
+	ErrCorrupt            = errors.New("recording: corrupt record")
+	ErrClosed             = errors.New("recording: writer is closed")
+)
+
+// Kind is the type of a record
+type Kind uint8
+
+// Record kinds
+const (
+	KindPacket Kind = 0 // Data is one packet
+	KindOpen   Kind = 1 // A connection opened; Data is a label such as its address
+	KindClose  Kind = 2 // A connection closed; Data is the reason
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindPacket:
+		return "packet"
+	case KindOpen:
+		return "open"
+	case KindClose:
+		return "close"
+	default:
+		return fmt.Sprintf("kind(%d)", uint8(k))
+	}
+}
+
+// Direction is which way a packet travelled
+type Direction uint8
+
+// Directions
+const (
+	ClientToServer Direction = 0
+	ServerToClient Direction = 1
+)
+
+func (d Direction) String() string {
+	if d == ServerToClient {
+		return "S->C"
+	}
+	return "C->S"
+}
+
+// Record is one entry of a recording
+type Record struct {
+	Kind       Kind
+	Direction  Direction
+	Connection uint32
+	Offset     time.Duration // since the recording started
+	Data       []byte
+}
+
+// IndexEntry points at the first record at or after Offset
+type IndexEntry struct {
+	Offset   time.Duration
+	Position int64
+}
+
+// Writer appends records to a recording. It is safe for concurrent use, so
+// every connection of a server or proxy can share one.
+type Writer struct {
+	mu        sync.Mutex
+	out       *bufio.Writer
+	closer    io.Closer
+	start     time.Time
+	position  int64
+	lastIndex time.Duration
+	last      time.Duration
+	index     []IndexEntry
+	scratch   [recordHeaderSize]byte
+	closed    bool
+}
+
+// Create creates a recording file at path, starting now
+func Create(path string) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(file, time.Now())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	w.closer = file
+	return w, nil
+}
+
+// NewWriter starts a recording on out. Record times are stored relative to
+// start.
+func NewWriter(out io.Writer, start time.Time) (*Writer, error) {
+	w := &Writer{out: bufio.NewWriterSize(out, 64*1024), start: start, lastIndex: -IndexInterval}
+
+	var header [headerSize]byte
+	copy(header[:4], magicHeader[:])
+	binary.LittleEndian.PutUint16(header[4:], Version)
+	binary.LittleEndian.PutUint64(header[8:], uint64(start.UnixNano()))
+	if _, err := w.out.Write(header[:]); err != nil {
+		return nil, err
+	}
+	w.position = headerSize
+	return w, nil
+}
+
+// Start returns when the recording started
+func (w *Writer) Start() time.Time {
+	return w.start
+}
+
+// WritePacket records a packet sent on connection at time at
+func (w *Writer) WritePacket(connection uint32, direction Direction, at time.Time, packet []byte) error {
+	return w.write(KindPacket, direction, connection, at, packet)
+}
+
+// OpenConnection records that a connection opened. The label identifies it
+// in timelines, for example its remote address.
+func (w *Writer) OpenConnection(connection uint32, at time.Time, label string) error {
+	return w.write(KindOpen, ClientToServer, connection, at, []byte(label))
+}
+
+// CloseConnection records that a connection closed and why
+func (w *Writer) CloseConnection(connection uint32, at time.Time, reason string) error {
+	return w.write(KindClose, ClientToServer, connection, at, []byte(reason))
+}
+
+func (w *Writer) write(kind Kind, direction Direction, connection uint32, at time.Time, data []byte) error {
+	if len(data) > math.MaxUint32 {
+		return fmt.Errorf("recording: record of %d bytes is too large", len(data))
+	}
+	offset := max(at.Sub(w.start), 0)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	// Concurrent callers can arrive slightly out of order; offsets never go
+	// backwards so seeking stays exact
+	offset = max(offset, w.last)
+	w.last = offset
+
+	if offset-w.lastIndex >= IndexInterval {
+		w.index = append(w.index, IndexEntry{Offset: offset, Position: w.position})
+		w.lastIndex = offset
+	}
+
+	h := w.scratch[:]
+	h[0] = uint8(kind)
+	h[1] = uint8(direction)
+	binary.LittleEndian.PutUint32(h[2:], connection)
+	binary.LittleEndian.PutUint64(h[6:], uint64(offset))
+	binary.LittleEndian.PutUint32(h[14:], uint32(len(data)))
+	if _, err := w.out.Write(h); err != nil {
+		return err
+	}
+	if _, err := w.out.Write(data); err != nil {
+		return err
+	}
+	w.position += int64(recordHeaderSize + len(data))
+	return nil
+}
+
+// Flush writes buffered records to the underlying writer
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.out.Flush()
+}
+
+// Close writes the index and trailer and flushes. It closes the file when
+// the writer was made by Create.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	indexPosition := w.position
+	buf := make([]byte, 0, 8+len(w.index)*16+trailerSize)
+	buf = append(buf, magicIndex[:]...)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(w.index)))
+	for _, entry := range w.index {
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(entry.Offset))
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(entry.Position))
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(indexPosition))
+	buf = append(buf, magicTrailer[:]...)
+
+	_, err := w.out.Write(buf)
+	if err == nil {
+		err = w.out.Flush()
+	}
+	if w.closer != nil {
+		if closeErr := w.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
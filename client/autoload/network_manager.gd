@@ -261,7 +261,9 @@ func send_auth_handshake() -> void:
 	var auth_data = {
 		"token": auth_token,
 		"character_id": character_id,
-		"region": region
+		"region": region,
+		"protocol_version": PacketTypes.PROTOCOL_VERSION,
+		"capabilities": PacketTypes.SUPPORTED_CAPABILITIES
 	}
 	send_message(MessageType.CONNECT_AUTH, auth_data)
 	print("[NetworkManager] Authentication handshake sent")
@@ -287,10 +289,16 @@ func _handle_incoming_packet() -> void:
 	if ws_client == null:
 		return
 
-	var packet = ws_client.get_packet()
+	var frame = ws_client.get_packet()
 	stats.packets_received += 1
-	stats.bytes_received += packet.size()
+	stats.bytes_received += frame.size()
+
+	# A frame may carry several packets back to back (CAPABILITY_BATCHED_PACKETS)
+	for packet in _split_packets(frame):
+		_handle_server_packet(packet)
 
+## Handle one packet from the server
+func _handle_server_packet(packet: PackedByteArray) -> void:
 	# Decode packet (binary format as per ARCHITECTURE.md)
 	var message = _decode_packet(packet)
 
@@ -304,6 +312,8 @@ func _handle_incoming_packet() -> void:
 	if message_type == MessageType.HEARTBEAT:
 		last_heartbeat_received = Time.get_ticks_msec() / 1000.0
 		_handle_heartbeat_response(message)
+	elif message_type == MessageType.DISCONNECT:
+		_handle_server_disconnect(message.get("data", {}))
 	else:
 		# Emit signal for other systems to handle
 		server_message_received.emit(message_type, message.get("data", {}))
@@ -369,6 +379,12 @@ func disconnect_client(peer_id: int, reason: String = "Server disconnect") -> vo
 	ws_peer.close(1000, reason)
 
 
+## Turn a client away with a DISCONNECT saying why, then close the connection
+func reject_client(peer_id: int, reason: int, message: String = "") -> void:
+	send_to_client(peer_id, MessageType.DISCONNECT, {"reason": reason})
+	disconnect_client(peer_id, message if not message.is_empty() else "Rejected")
+
+
 ## Get all connected peer IDs (server mode)
 func get_connected_peer_ids() -> Array:
 	return connected_peers.keys()
@@ -445,6 +461,8 @@ func _encode_packet(message_type: MessageType, data: Dictionary) -> PackedByteAr
 			writer.write_string(data.get("token", ""))
 			writer.write_string(data.get("character_id", ""))
 			writer.write_u8(AuthPacket.region_from_string(data.get("region", "Asia")))
+			writer.write_u16(data.get("protocol_version", PacketTypes.PROTOCOL_VERSION))
+			writer.write_u32(data.get("capabilities", PacketTypes.SUPPORTED_CAPABILITIES))
 
 		MessageType.DISCONNECT:
 			writer.write_u8(data.get("reason", PacketTypes.DisconnectReason.USER_QUIT))
@@ -515,6 +533,8 @@ func _decode_packet(packet: PackedByteArray) -> Dictionary:
 		PacketTypes.Type.CONNECT_AUTH:
 			var auth_packet = AuthPacket.read(reader)
 			result.data = auth_packet.to_dict()
+			# to_dict() shortens the token for logs; the server verifies all of it
+			result.data.token = auth_packet.token
 
 		PacketTypes.Type.DISCONNECT:
 			var disconnect_packet = DisconnectPacket.read(reader)
@@ -522,6 +542,30 @@ func _decode_packet(packet: PackedByteArray) -> Dictionary:
 
 	return result
 
+## Split a WebSocket frame into the packets it carries
+func _split_packets(frame: PackedByteArray) -> Array[PackedByteArray]:
+	var packets: Array[PackedByteArray] = []
+	var offset := 0
+	while frame.size() - offset >= PacketTypes.HEADER_SIZE:
+		var end := offset + PacketTypes.HEADER_SIZE + frame.decode_u16(offset + 1)
+		if end > frame.size():
+			push_error("[NetworkManager] Truncated packet in frame of %d bytes" % frame.size())
+			break
+		packets.append(frame.slice(offset, end))
+		offset = end
+	return packets
+
+## Handle a DISCONNECT the server sends before closing the connection
+func _handle_server_disconnect(data: Dictionary) -> void:
+	print("[NetworkManager] Disconnected by server: %s" % data.get("reason_name", "Unknown"))
+	if data.get("reason", -1) != PacketTypes.DisconnectReason.VERSION_MISMATCH:
+		return
+
+	# Reconnecting cannot help an outdated client
+	ws_client.close(1000, "Version mismatch")
+	current_state = ConnectionState.ERROR
+	connection_error.emit("This version of the game is no longer supported. Please update to keep playing.")
+
 ## Handle connection closed
 func _on_connection_closed(reason: String) -> void:
 	current_state = ConnectionState.DISCONNECTED
@@ -14,6 +14,8 @@ var character_name: String = ""
 var connected_at: float = 0.0
 var authenticated: bool = false
 var last_heartbeat: float = 0.0
+var protocol_version: int = 0   ## From CONNECT_AUTH
+var capabilities: int = 0       ## Negotiated by the API; only use features set here
 
 # Position & Movement
 var position: Vector2 = Vector2.ZERO
@@ -260,6 +262,8 @@ func to_dict() -> Dictionary:
 		"character_id": character_id,
 		"character_name": character_name,
 		"authenticated": authenticated,
+		"protocol_version": protocol_version,
+		"capabilities": capabilities,
 		"position": position,
 		"velocity": velocity,
 		"health": health,
@@ -11,7 +11,11 @@ const DEFAULTS := {
 	"region": "asia",
 	"debug_logging": true,
 	"heartbeat_timeout_seconds": 5.0,
-	"api_server_url": "http://localhost:8080"
+	"api_server_url": "http://localhost:8080",
+	"public_address": "ws://localhost:8081",
+	"internal_address": "",
+	"instance_id": "",
+	"server_api_key": ""
 }
 
 ## Configuration file paths (priority order)
@@ -43,6 +47,23 @@ var heartbeat_timeout_seconds: float:
 var api_server_url: String:
 	get: return _config.get("api_server_url", DEFAULTS.api_server_url)
 
+## Address clients use to reach this server (advertised to the API registry)
+var public_address: String:
+	get: return _config.get("public_address", DEFAULTS.public_address)
+
+## Address a gateway in front of this server reaches it at (empty = no gateway,
+## clients connect to public_address directly)
+var internal_address: String:
+	get: return _config.get("internal_address", DEFAULTS.internal_address)
+
+## Registry instance ID (empty = assigned by the API)
+var instance_id: String:
+	get: return _config.get("instance_id", DEFAULTS.instance_id)
+
+## Shared key for the API's /api/internal endpoints
+var server_api_key: String:
+	get: return _config.get("server_api_key", DEFAULTS.server_api_key)
+
 
 ## Initialize and load configuration
 func _init() -> void:
@@ -118,3 +139,6 @@ func print_config() -> void:
 	print("  debug_logging: %s" % str(debug_logging))
 	print("  heartbeat_timeout: %.1fs" % heartbeat_timeout_seconds)
 	print("  api_server_url: %s" % api_server_url)
+	print("  public_address: %s" % public_address)
+	if not internal_address.is_empty():
+		print("  internal_address: %s" % internal_address)
@@ -23,6 +23,9 @@ var monster_spawner: MonsterSpawner = null
 ## Monster AI system (TASK-016)
 var monster_ai: MonsterAI = null
 
+## API registry client (registration + heartbeats)
+var registration: ServerRegistration = null
+
 ## Entity management (entity_id -> EntityState)
 ## Used for additional entities beyond players/projectiles/monsters
 var game_entities: Dictionary = {}
@@ -121,6 +124,10 @@ func _initialize_server() -> void:
 	_tick_times.clear()
 	metrics.last_metrics_time = Time.get_ticks_msec() / 1000.0
 
+	# Register with the API so clients can be routed to this instance
+	registration = ServerRegistration.new(config, get_metrics)
+	add_child(registration)
+
 	set_process(true)
 	print("[ServerMain] Server running at %d Hz tick rate" % config.tick_rate)
 
@@ -408,13 +415,44 @@ func _handle_auth_request(peer_id: int, data: Dictionary) -> void:
 	if config.debug_logging:
 		print("[ServerMain] Auth request from peer %d" % peer_id)
 
-	var character_id = data.get("character_id", "")
+	# Without an API key (local development) players are admitted unchecked
+	if registration == null or not registration.is_enabled():
+		_admit_player(peer_id, data, {})
+		return
+
+	registration.verify_connection(data, func(accepted: bool, response: Dictionary) -> void:
+		# The player may have left while the API was answering
+		if not player_manager.has_player(peer_id):
+			return
+		if not accepted:
+			print("[ServerMain] Rejected peer %d: %s" % [peer_id, response.get("error", "")])
+			NetworkManager.reject_client(peer_id, int(response.get("disconnect_reason", PacketTypes.DisconnectReason.INVALID_AUTH)), response.get("error", ""))
+			return
+		_admit_player(peer_id, data, response)
+	)
+
+
+## Admit a player whose CONNECT_AUTH passed verification
+## verified is the API's response, empty when running without the API
+func _admit_player(peer_id: int, data: Dictionary, verified: Dictionary) -> void:
+	var character_id = str(data.get("character_id", ""))
 	var character_name = data.get("character_name", "Player_%d" % peer_id)
+	if not verified.is_empty():
+		# JSON numbers parse as floats
+		character_id = str(int(verified.character_id))
+		character_name = verified.character_name
 
 	# Authenticate player via PlayerManager
-	# TODO: Validate character_id with API server
 	player_manager.authenticate_player(peer_id, character_id, character_name)
 
+	var state = player_manager.get_player(peer_id)
+	state.protocol_version = data.get("protocol_version", 0)
+	state.capabilities = int(verified.get("capabilities", data.get("capabilities", 0) & PacketTypes.SUPPORTED_CAPABILITIES))
+
+	# Convert the slot reserved by SelectRegion into a connected player
+	if registration != null and character_id.is_valid_int():
+		registration.claim_reservation(int(character_id))
+
 
 ## Record tick processing time for metrics
 func _record_tick_time(time_ms: float) -> void:
@@ -479,6 +517,9 @@ func shutdown(reason: String = "Server shutdown") -> void:
 	print("[ServerMain] Shutting down: %s" % reason)
 	server_running = false
 
+	if registration != null:
+		registration.deregister()
+
 	# Notify all connected clients
 	var network_manager = _get_network_manager()
 	if network_manager != null:
@@ -0,0 +1,226 @@
+## ServerRegistration - Registers this dedicated server with the Go API
+## Sends periodic heartbeats with player count and tick metrics so the API
+## can aggregate live region capacity. Registrations expire on the API side
+## when heartbeats stop.
+class_name ServerRegistration
+extends Node
+
+## Registration states
+enum State {
+	UNREGISTERED,
+	REGISTERING,
+	REGISTERED
+}
+
+const REGISTER_PATH := "/api/internal/servers/register"
+const HEARTBEAT_PATH := "/api/internal/servers/heartbeat"
+const DEREGISTER_PATH := "/api/internal/servers/deregister"
+const CLAIM_PATH := "/api/internal/servers/claim"
+const VERIFY_PATH := "/api/internal/servers/verify"
+const SERVER_KEY_HEADER := "X-Server-Key"
+const RETRY_DELAY_SECONDS := 5.0
+
+## Server configuration
+var config: ServerConfig = null
+
+## Returns the current ServerMain metrics dictionary
+var metrics_provider: Callable
+
+var state: State = State.UNREGISTERED
+var instance_id: String = ""
+var heartbeat_interval: float = 5.0
+
+var _http_request: HTTPRequest = null
+var _timer: float = 0.0
+var _request_in_flight: bool = false
+
+
+func _init(server_config: ServerConfig, provider: Callable) -> void:
+	config = server_config
+	metrics_provider = provider
+	instance_id = config.instance_id
+
+
+func _ready() -> void:
+	_http_request = HTTPRequest.new()
+	_http_request.timeout = 5.0
+	add_child(_http_request)
+	_http_request.request_completed.connect(_on_request_completed)
+
+	if config.server_api_key.is_empty():
+		push_warning("[ServerRegistration] server_api_key is not set, registration disabled")
+		set_process(false)
+		return
+
+	register()
+
+
+func _process(delta: float) -> void:
+	_timer += delta
+
+	match state:
+		State.UNREGISTERED:
+			if _timer >= RETRY_DELAY_SECONDS:
+				register()
+		State.REGISTERED:
+			if _timer >= heartbeat_interval:
+				send_heartbeat()
+
+
+## Register (or re-register) with the API server
+func register() -> void:
+	if _request_in_flight:
+		return
+
+	state = State.REGISTERING
+	var metrics: Dictionary = metrics_provider.call()
+	_post(REGISTER_PATH, {
+		"instance_id": instance_id,
+		"region": config.region,
+		"address": config.public_address,
+		"internal_address": config.internal_address,
+		"capacity": config.max_players,
+		"player_count": metrics.get("player_count", 0)
+	})
+
+
+## Send a heartbeat with the current player count and tick metrics
+func send_heartbeat() -> void:
+	if _request_in_flight:
+		return
+
+	var metrics: Dictionary = metrics_provider.call()
+	_post(HEARTBEAT_PATH, {
+		"instance_id": instance_id,
+		"player_count": metrics.get("player_count", 0),
+		"metrics": {
+			"tick_count": metrics.get("tick_count", 0),
+			"tick_rate": config.tick_rate,
+			"avg_tick_time_ms": metrics.get("avg_tick_time_ms", 0.0),
+			"max_tick_time_ms": metrics.get("max_tick_time_ms", 0.0),
+			"entity_count": metrics.get("entity_count", 0)
+		}
+	})
+
+
+## Remove this instance from the registry (best effort, on shutdown)
+func deregister() -> void:
+	if state != State.REGISTERED:
+		return
+
+	state = State.UNREGISTERED
+	set_process(false)
+	if _request_in_flight:
+		_http_request.cancel_request()
+	_post(DEREGISTER_PATH, {"instance_id": instance_id})
+
+
+## Claim the slot the API reserved for a connecting player
+## Uses a one-off request so claims don't wait on heartbeats
+func claim_reservation(character_id: int) -> void:
+	if state != State.REGISTERED:
+		return
+
+	var request := HTTPRequest.new()
+	request.timeout = 5.0
+	add_child(request)
+	request.request_completed.connect(func(result: int, response_code: int, _response_headers: PackedStringArray, _body: PackedByteArray) -> void:
+		if result != HTTPRequest.RESULT_SUCCESS or response_code != 200:
+			push_warning("[ServerRegistration] Failed to claim reservation for character %d (Result: %d, HTTP %d)" % [character_id, result, response_code])
+		request.queue_free()
+	)
+
+	var body := JSON.stringify({"instance_id": instance_id, "character_id": character_id})
+	if request.request(config.api_server_url + CLAIM_PATH, _headers(), HTTPClient.METHOD_POST, body) != OK:
+		request.queue_free()
+
+
+## Whether this server talks to the API at all
+func is_enabled() -> bool:
+	return not config.server_api_key.is_empty()
+
+
+## Check a player's CONNECT_AUTH with the API before admitting them
+## callback receives (accepted: bool, response: Dictionary). Accepted responses
+## carry the character name and negotiated capabilities; rejections carry the
+## disconnect_reason to send the player.
+func verify_connection(auth: Dictionary, callback: Callable) -> void:
+	var request := HTTPRequest.new()
+	request.timeout = 5.0
+	add_child(request)
+	request.request_completed.connect(func(result: int, response_code: int, _response_headers: PackedStringArray, body: PackedByteArray) -> void:
+		request.queue_free()
+		var json = JSON.parse_string(body.get_string_from_utf8())
+		if result != HTTPRequest.RESULT_SUCCESS or typeof(json) != TYPE_DICTIONARY or (response_code != 200 and not json.has("disconnect_reason")):
+			push_warning("[ServerRegistration] Failed to verify connection (Result: %d, HTTP %d)" % [result, response_code])
+			# The player is not at fault, so they are free to retry
+			callback.call(false, {"error": "Verification unavailable", "disconnect_reason": PacketTypes.DisconnectReason.TIMEOUT})
+			return
+		callback.call(response_code == 200, json)
+	)
+
+	var body := JSON.stringify({
+		"instance_id": instance_id,
+		"token": auth.get("token", ""),
+		"character_id": str(auth.get("character_id", "")).to_int(),
+		"protocol_version": auth.get("protocol_version", 0),
+		"capabilities": auth.get("capabilities", 0)
+	})
+	if request.request(config.api_server_url + VERIFY_PATH, _headers(), HTTPClient.METHOD_POST, body) != OK:
+		request.queue_free()
+		callback.call(false, {"error": "Verification unavailable", "disconnect_reason": PacketTypes.DisconnectReason.TIMEOUT})
+
+
+func _headers() -> PackedStringArray:
+	return PackedStringArray([
+		"Content-Type: application/json",
+		"%s: %s" % [SERVER_KEY_HEADER, config.server_api_key]
+	])
+
+
+func _post(path: String, body: Dictionary) -> void:
+	var error := _http_request.request(config.api_server_url + path, _headers(), HTTPClient.METHOD_POST, JSON.stringify(body))
+	if error != OK:
+		push_error("[ServerRegistration] Failed to send request to %s (Error: %d)" % [path, error])
+		_on_failure()
+		return
+
+	_request_in_flight = true
+	_timer = 0.0
+
+
+func _on_request_completed(result: int, response_code: int, _response_headers: PackedStringArray, body: PackedByteArray) -> void:
+	_request_in_flight = false
+
+	if result != HTTPRequest.RESULT_SUCCESS:
+		push_warning("[ServerRegistration] Request failed (Result: %d)" % result)
+		_on_failure()
+		return
+
+	# Registration expired (missed heartbeats, API restart) - register again
+	if response_code == 404 and state == State.REGISTERED:
+		print("[ServerRegistration] Registration expired, re-registering")
+		state = State.UNREGISTERED
+		register()
+		return
+
+	if response_code < 200 or response_code >= 300:
+		push_warning("[ServerRegistration] API returned %d: %s" % [response_code, body.get_string_from_utf8()])
+		_on_failure()
+		return
+
+	var json = JSON.parse_string(body.get_string_from_utf8())
+	if typeof(json) != TYPE_DICTIONARY:
+		return
+
+	heartbeat_interval = float(json.get("heartbeat_interval_seconds", heartbeat_interval))
+	if state == State.REGISTERING:
+		var instance: Dictionary = json.get("instance", {})
+		instance_id = instance.get("instance_id", instance_id)
+		state = State.REGISTERED
+		print("[ServerRegistration] Registered as %s in region %s" % [instance_id, config.region])
+
+
+func _on_failure() -> void:
+	if state == State.REGISTERING:
+		state = State.UNREGISTERED
@@ -9,6 +9,19 @@ const HEADER_SIZE := 3
 ## Maximum packet size (64KB)
 const MAX_PACKET_SIZE := 65535
 
+## Protocol version sent in CONNECT_AUTH (pkg/protocol Version in the API)
+## Bump it whenever a packet layout changes in a way older peers cannot read
+const PROTOCOL_VERSION := 1
+
+## Capabilities bitfield sent in CONNECT_AUTH (fits in u32)
+## The server only uses features the client offered
+const CAPABILITY_BATCHED_PACKETS := 1 << 0  ## Several packets in one WebSocket frame
+const CAPABILITY_DELTA_STATE := 1 << 1      ## STATE_DELTA instead of STATE_UPDATE, acked with STATE_ACK
+
+## Capabilities this client supports
+## Add CAPABILITY_DELTA_STATE once STATE_DELTA is decoded (reference: api/pkg/protocol/delta.go)
+const SUPPORTED_CAPABILITIES := CAPABILITY_BATCHED_PACKETS
+
 ## Packet types as per ARCHITECTURE.md
 enum Type {
 	PLAYER_INPUT = 1,      ## Client -> Server: Movement, actions (~12 bytes)
@@ -17,7 +30,9 @@ enum Type {
 	HEARTBEAT = 4,         ## Bidirectional: Keep-alive (4 bytes)
 	ACTION_CONFIRM = 5,    ## Server -> Client: Confirm attack (20 bytes)
 	CONNECT_AUTH = 6,      ## Client -> Server: Authentication handshake (variable)
-	DISCONNECT = 7         ## Client -> Server: Clean disconnect (4 bytes)
+	DISCONNECT = 7,        ## Client -> Server: Clean disconnect (4 bytes)
+	STATE_DELTA = 8,       ## Server -> Client: Entity changes since an acked snapshot (variable)
+	STATE_ACK = 9          ## Client -> Server: Last complete snapshot received (4 bytes)
 }
 
 ## Entity types for state updates
@@ -57,6 +72,15 @@ const ENTITY_FLAG_INVULNERABLE := 1 << 3
 const ENTITY_FLAG_STUNNED := 1 << 4
 const ENTITY_FLAG_VISIBLE := 1 << 5
 
+## State delta mask bitfield (fits in u8), one per entity
+## Fields follow the mask in bit order
+const DELTA_MASK_ENTITY_TYPE := 1 << 0     ## u8 entity type
+const DELTA_MASK_POSITION := 1 << 1        ## s16 x, s16 y in quantized steps
+const DELTA_MASK_POSITION_NUDGE := 1 << 2  ## s8 dx, s8 dy steps from the baseline position
+const DELTA_MASK_ANIMATION := 1 << 3       ## u8 animation state
+const DELTA_MASK_FLAGS := 1 << 4           ## u8 entity flags
+const DELTA_MASK_FULL := DELTA_MASK_ENTITY_TYPE | DELTA_MASK_POSITION | DELTA_MASK_ANIMATION | DELTA_MASK_FLAGS
+
 ## Game event types
 enum GameEventType {
 	DAMAGE = 1,            ## Entity took damage
@@ -76,7 +100,8 @@ enum DisconnectReason {
 	KICKED = 2,            ## Kicked by server
 	SERVER_SHUTDOWN = 3,   ## Server is shutting down
 	INVALID_AUTH = 4,      ## Authentication failed
-	DUPLICATE_SESSION = 5  ## Another session started
+	DUPLICATE_SESSION = 5, ## Another session started
+	VERSION_MISMATCH = 6   ## Client protocol version not accepted in this region
 }
 
 
@@ -90,12 +115,14 @@ static func get_type_name(packet_type: int) -> String:
 		Type.ACTION_CONFIRM: return "ACTION_CONFIRM"
 		Type.CONNECT_AUTH: return "CONNECT_AUTH"
 		Type.DISCONNECT: return "DISCONNECT"
+		Type.STATE_DELTA: return "STATE_DELTA"
+		Type.STATE_ACK: return "STATE_ACK"
 		_: return "UNKNOWN(%d)" % packet_type
 
 
 ## Helper: Check if packet type is valid
 static func is_valid_type(packet_type: int) -> bool:
-	return packet_type >= Type.PLAYER_INPUT and packet_type <= Type.DISCONNECT
+	return packet_type >= Type.PLAYER_INPUT and packet_type <= Type.STATE_ACK
 
 
 ## Helper: Encode input flags from dictionary
@@ -4,6 +4,10 @@
 ##   [u16 token_length][utf8 token]       variable - JWT auth token
 ##   [u16 char_id_length][utf8 char_id]   variable - character ID
 ##   [u8 region_code]                     1 byte   - region enum
+##   [u16 protocol_version]               2 bytes  - PacketTypes.PROTOCOL_VERSION
+##   [u32 capabilities]                   4 bytes  - PacketTypes capability bitfield
+## Clients from before versioning end the packet after the region; they
+## read back as protocol version 0.
 class_name AuthPacket
 extends RefCounted
 
@@ -21,6 +25,10 @@ var token: String = ""
 var character_id: String = ""
 ## Selected region
 var region: int = Region.ASIA
+## Protocol version the client speaks
+var protocol_version: int = PacketTypes.PROTOCOL_VERSION
+## Optional features the client supports
+var capabilities: int = PacketTypes.SUPPORTED_CAPABILITIES
 
 
 func _init() -> void:
@@ -41,7 +49,7 @@ func write() -> PackedByteArray:
 	# Calculate approximate size
 	var token_bytes = token.to_utf8_buffer().size()
 	var char_bytes = character_id.to_utf8_buffer().size()
-	var size = 3 + 2 + token_bytes + 2 + char_bytes + 1 + 4  # header + strings + region + safety
+	var size = 3 + 2 + token_bytes + 2 + char_bytes + 1 + 6 + 4  # header + strings + region + version + safety
 
 	var writer = PacketWriter.new(size)
 	writer.write_header(PacketTypes.Type.CONNECT_AUTH)
@@ -56,6 +64,9 @@ func write_payload(writer: PacketWriter) -> void:
 	writer.write_string(token)
 	writer.write_string(character_id)
 	writer.write_u8(region)
+	if protocol_version != 0 or capabilities != 0:
+		writer.write_u16(protocol_version)
+		writer.write_u32(capabilities)
 
 
 ## Read packet from reader (assumes header already read)
@@ -64,6 +75,11 @@ static func read(reader: PacketReader) -> AuthPacket:
 	packet.token = reader.read_string()
 	packet.character_id = reader.read_string()
 	packet.region = reader.read_u8()
+	packet.protocol_version = 0
+	packet.capabilities = 0
+	if reader.remaining() > 0:
+		packet.protocol_version = reader.read_u16()
+		packet.capabilities = reader.read_u32()
 	return packet
 
 
@@ -100,5 +116,7 @@ func to_dict() -> Dictionary:
 		"token": token.substr(0, 20) + "..." if token.length() > 20 else token,
 		"character_id": character_id,
 		"region": region,
-		"region_name": get_region_name()
+		"region_name": get_region_name(),
+		"protocol_version": protocol_version,
+		"capabilities": capabilities
 	}
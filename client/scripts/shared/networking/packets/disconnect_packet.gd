@@ -62,6 +62,7 @@ func get_reason_name() -> String:
 		PacketTypes.DisconnectReason.SERVER_SHUTDOWN: return "Server shutdown"
 		PacketTypes.DisconnectReason.INVALID_AUTH: return "Invalid authentication"
 		PacketTypes.DisconnectReason.DUPLICATE_SESSION: return "Duplicate session"
+		PacketTypes.DisconnectReason.VERSION_MISMATCH: return "Client version not supported"
 		_: return "Unknown (%d)" % reason
 
 